@@ -2,15 +2,30 @@ package main
 
 import (
 	"bullet-cloud-api/internal/addresses"
+	"bullet-cloud-api/internal/apikeys"
 	"bullet-cloud-api/internal/auth"
 	"bullet-cloud-api/internal/cart"
 	"bullet-cloud-api/internal/categories"
 	"bullet-cloud-api/internal/config"
 	"bullet-cloud-api/internal/database"
+	"bullet-cloud-api/internal/exports"
+	"bullet-cloud-api/internal/fulfillment"
 	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/identities"
+	"bullet-cloud-api/internal/inventory"
+	"bullet-cloud-api/internal/lockout"
+	"bullet-cloud-api/internal/mfa"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/notify"
+	"bullet-cloud-api/internal/oidc"
 	"bullet-cloud-api/internal/orders"
+	"bullet-cloud-api/internal/pricing"
 	"bullet-cloud-api/internal/products"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/tokens"
 	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/variants"
+	"bullet-cloud-api/internal/webutils"
 	"context"
 	"fmt"
 	"log"
@@ -26,7 +41,21 @@ import (
 )
 
 const defaultPortStart = 4445
-const defaultJWTExpiry = 24 * time.Hour
+
+// totpIssuer is the account issuer shown in authenticator apps.
+const totpIssuer = "Bullet Cloud API"
+
+// oidcRequestTimeout bounds each request made to an external identity provider.
+const oidcRequestTimeout = 10 * time.Second
+
+// webhookTimeout bounds each message posted by the webhook notifier.
+const webhookTimeout = 10 * time.Second
+
+// Background data exports: how many are generated at once, and how often expired ones are removed.
+const (
+	exportWorkers         = 2
+	exportCleanupInterval = time.Hour
+)
 
 func main() {
 	cfg := config.Load()
@@ -37,29 +66,117 @@ func main() {
 	}
 	defer dbPool.Close()
 
+	// Choose how orders pick the stock locations they ship from
+	allocationStrategy, err := fulfillment.ParseStrategy(cfg.AllocationStrategy)
+	if err != nil {
+		log.Fatalf("Could not set up stock allocation: %v", err)
+	}
+
 	// Instantiate repositories
 	userRepo := users.NewPostgresUserRepository(dbPool)
 	productRepo := products.NewPostgresProductRepository(dbPool)
+	variantRepo := variants.NewPostgresVariantRepository(dbPool)
+	inventoryRepo := inventory.NewPostgresInventoryRepository(dbPool)
+	pricingRepo := pricing.NewPostgresPricingRepository(dbPool)
 	categoryRepo := categories.NewPostgresCategoryRepository(dbPool)
 	addressRepo := addresses.NewPostgresAddressRepository(dbPool)
 	cartRepo := cart.NewPostgresCartRepository(dbPool)
-	orderRepo := orders.NewPostgresOrderRepository(dbPool)
+	orderRepo := orders.NewPostgresOrderRepository(dbPool, allocationStrategy)
+	refreshTokenRepo := tokens.NewPostgresRefreshTokenRepository(dbPool)
+	loginAttemptStore := lockout.NewPostgresAttemptStore(dbPool)
+	passwordResetRepo := tokens.NewPostgresPasswordResetRepository(dbPool)
+	mfaRepo := mfa.NewPostgresMFARepository(dbPool)
+	// Sessions are checked on every authenticated request, so lookups are cached briefly
+	sessionRepo := sessions.NewCachedRepository(sessions.NewPostgresSessionRepository(dbPool), cfg.SessionCacheTTL)
+	apiKeyRepo := apikeys.NewPostgresAPIKeyRepository(dbPool)
+	identityRepo := identities.NewPostgresIdentityRepository(dbPool)
+	exportRepo := exports.NewPostgresExportRepository(dbPool)
+
+	// Load the keys used to sign and verify access tokens
+	tokenKeys, err := auth.LoadKeySet(cfg.JWTAlgorithm, cfg.JWTSecret, cfg.JWTPrivateKeyFile, cfg.JWTPublicKeyFiles)
+	if err != nil {
+		log.Fatalf("Could not load JWT signing keys: %v", err)
+	}
+	log.Printf("Signing access tokens with %s", tokenKeys.Algorithm())
 
 	// Instantiate the password hasher
-	hasher := auth.NewBcryptPasswordHasher()
+	hasher, err := auth.NewPasswordHasher(cfg.PasswordHashAlgorithm, cfg.BcryptCost, auth.Argon2Params{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(min(cfg.Argon2Parallelism, 255)),
+	})
+	if err != nil {
+		log.Fatalf("Could not set up password hashing: %v", err)
+	}
+
+	// Instantiate the rules for new passwords
+	passwordPolicy, err := newPasswordPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Could not set up password policy: %v", err)
+	}
+
+	// Instantiate the login brute-force limiter
+	loginLimiter := lockout.NewLimiter(loginAttemptStore, cfg.MaxLoginAttempts, cfg.LockoutDuration)
+
+	// Instantiate the notifier used for messages sent to users
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		log.Fatalf("Could not set up notifier: %v", err)
+	}
+
+	// Instantiate the external identity providers users can sign in with
+	oidcClient := &http.Client{Timeout: oidcRequestTimeout}
+	oidcProviders := make([]*oidc.Provider, 0, len(cfg.OIDCProviders))
+	for _, providerCfg := range cfg.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(providerCfg, oidcClient))
+		log.Printf("Sign-in enabled for identity provider %s", providerCfg.Name)
+	}
+
+	// Instantiate the background generator of personal data exports, resuming unfinished ones
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	exportCollector := exports.NewCollector(userRepo, addressRepo, cartRepo, orderRepo, sessionRepo, apiKeyRepo, identityRepo)
+	exportRunner, err := exports.NewRunner(exportCollector, exportRepo, cfg.ExportDir, cfg.ExportLinkTTL, exportWorkers)
+	if err != nil {
+		log.Fatalf("Could not set up data exports: %v", err)
+	}
+	if err := exportRunner.Resume(backgroundCtx); err != nil {
+		log.Printf("Error resuming pending data exports: %v", err)
+	}
+	go exportRunner.RunCleanup(backgroundCtx, exportCleanupInterval)
+
+	// Instantiate the background checker telling purchasing of products low on stock
+	if cfg.LowStockAlertRecipient != "" {
+		lowStockChecker := inventory.NewLowStockChecker(inventoryRepo, notifier, cfg.LowStockAlertRecipient, cfg.LowStockSalesWindow)
+		go lowStockChecker.Run(backgroundCtx, cfg.LowStockCheckInterval)
+	}
 
 	// Instantiate handlers
-	authHandler := handlers.NewAuthHandler(userRepo, hasher, cfg.JWTSecret, defaultJWTExpiry)
-	userHandler := handlers.NewUserHandler(userRepo, addressRepo)
-	productHandler := handlers.NewProductHandler(productRepo)
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(userRepo, notifier, cfg.JWTSecret, cfg.EmailVerifyTTL, cfg.AppBaseURL)
+	authHandler := handlers.NewAuthHandler(userRepo, refreshTokenRepo, sessionRepo, mfaRepo, hasher, passwordPolicy, loginLimiter, emailVerificationHandler, tokenKeys, cfg.JWTSecret, cfg.JWTAccessExpiry, cfg.JWTRefreshExpiry).
+		WithOIDC(identityRepo, oidcProviders...)
+	passwordResetHandler := handlers.NewPasswordResetHandler(userRepo, passwordResetRepo, sessionRepo, hasher, passwordPolicy, notifier, loginLimiter.Named("password-reset"), cfg.PasswordResetTTL, cfg.AppBaseURL)
+	mfaHandler := handlers.NewMFAHandler(userRepo, mfaRepo, loginLimiter, totpIssuer)
+	sessionHandler := handlers.NewSessionHandler(sessionRepo)
+	exportHandler := handlers.NewExportHandler(exportCollector, exportRepo, exportRunner, cfg.JWTSecret, cfg.ExportSyncMaxOrders)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo)
+	jwksHandler := handlers.NewJWKSHandler(tokenKeys)
+	adminUserHandler := handlers.NewAdminUserHandler(userRepo, addressRepo, orderRepo, sessionRepo, passwordResetHandler)
+	userHandler := handlers.NewUserHandler(userRepo, addressRepo, sessionRepo, hasher, passwordPolicy, emailVerificationHandler, loginLimiter, exportRunner)
+	productHandler := handlers.NewProductHandler(productRepo, variantRepo, pricingRepo)
+	variantHandler := handlers.NewVariantHandler(variantRepo, productRepo)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryRepo, cfg.LowStockSalesWindow)
+	pricingHandler := handlers.NewPricingHandler(pricingRepo, productRepo)
 	categoryHandler := handlers.NewCategoryHandler(categoryRepo)
-	cartHandler := handlers.NewCartHandler(cartRepo, productRepo)
-	orderHandler := handlers.NewOrderHandler(orderRepo, cartRepo, addressRepo)
+	cartHandler := handlers.NewCartHandler(cartRepo, productRepo, variantRepo, pricingRepo)
+	orderHandler := handlers.NewOrderHandler(orderRepo, cartRepo, addressRepo, userRepo, pricingRepo, cfg.RequireVerifiedEmailForCheckout)
 
 	// Instantiate middleware
-	authMiddleware := auth.NewMiddleware(cfg.JWTSecret, userRepo)
+	authMiddleware := auth.NewMiddleware(tokenKeys, userRepo, sessionRepo, apiKeyRepo)
 
-	r := setupRoutes(authHandler, userHandler, productHandler, categoryHandler, cartHandler, orderHandler, authMiddleware)
+	r := setupRoutes(authHandler, passwordResetHandler, emailVerificationHandler, mfaHandler, sessionHandler, apiKeyHandler, exportHandler, jwksHandler, userHandler, adminUserHandler, productHandler, variantHandler, inventoryHandler, pricingHandler, categoryHandler, cartHandler, orderHandler, authMiddleware)
+	// Lockouts and sessions key on the client IP, not on the proxy's
+	r.Use(webutils.RealIP(cfg.TrustedProxies))
 
 	// --- Determine Port ---
 	port := os.Getenv("PORT")
@@ -105,6 +222,7 @@ func main() {
 
 	<-done
 	log.Println("Server shutting down gracefully...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -112,69 +230,202 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server shutdown failed: %+v", err)
 	}
+	passwordResetHandler.Wait()
 
 	log.Println("Server exited properly")
 }
 
+// newNotifier builds the Notifier selected by the NOTIFIER setting.
+func newNotifier(cfg *config.Config) (notify.Notifier, error) {
+	switch cfg.Notifier {
+	case "log":
+		return notify.NewLogNotifier(), nil
+	case "outbox":
+		return notify.NewOutboxNotifier(cfg.OutboxDir)
+	case "webhook":
+		return notify.NewWebhookNotifier(cfg.NotifierWebhookURL, &http.Client{Timeout: webhookTimeout})
+	default:
+		return nil, fmt.Errorf("unknown notifier %q (expected \"log\", \"outbox\" or \"webhook\")", cfg.Notifier)
+	}
+}
+
+// newPasswordPolicy builds the password policy, loading the breached password list if enabled.
+func newPasswordPolicy(cfg *config.Config) (*auth.PasswordPolicy, error) {
+	policy := &auth.PasswordPolicy{
+		MinLength:      cfg.PasswordMinLength,
+		MinCharClasses: cfg.PasswordMinCharClasses,
+	}
+	if !cfg.CheckBreachedPasswords {
+		return policy, nil
+	}
+
+	if cfg.BreachedPasswordsFile == "" {
+		policy.Breached = auth.DefaultBreachedPasswordList()
+	} else {
+		list, err := auth.LoadBreachedPasswordList(cfg.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = list
+	}
+	log.Printf("Rejecting %d known breached passwords", policy.Breached.Len())
+	return policy, nil
+}
+
 // setupRoutes configures the API routes using mux router.
 func setupRoutes(
 	ah *handlers.AuthHandler,
+	prh *handlers.PasswordResetHandler,
+	evh *handlers.EmailVerificationHandler,
+	mfah *handlers.MFAHandler,
+	sh *handlers.SessionHandler,
+	akh *handlers.APIKeyHandler,
+	eh *handlers.ExportHandler,
+	jwksh *handlers.JWKSHandler,
 	uh *handlers.UserHandler,
+	auh *handlers.AdminUserHandler,
 	ph *handlers.ProductHandler,
+	vh *handlers.VariantHandler,
+	ih *handlers.InventoryHandler,
+	prch *handlers.PricingHandler,
 	ch *handlers.CategoryHandler,
 	cartH *handlers.CartHandler,
 	oh *handlers.OrderHandler,
 	mw *auth.Middleware,
 ) *mux.Router {
 	r := mux.NewRouter()
+	r.HandleFunc("/.well-known/jwks.json", jwksh.GetJWKS).Methods("GET")
 	apiV1 := r.PathPrefix("/api").Subrouter()
 
 	// Public routes
 	apiV1.HandleFunc("/health", handlers.HealthCheck).Methods("GET")
 	apiV1.HandleFunc("/auth/register", ah.Register).Methods("POST")
 	apiV1.HandleFunc("/auth/login", ah.Login).Methods("POST")
+	apiV1.HandleFunc("/auth/login/2fa", ah.LoginMFA).Methods("POST")
+	apiV1.HandleFunc("/auth/refresh", ah.Refresh).Methods("POST")
+	apiV1.HandleFunc("/auth/logout", ah.Logout).Methods("POST")
+	apiV1.HandleFunc("/auth/oidc/{provider}/login", ah.OIDCLogin).Methods("GET")
+	apiV1.HandleFunc("/auth/oidc/{provider}/callback", ah.OIDCCallback).Methods("GET")
+	apiV1.HandleFunc("/auth/forgot-password", prh.ForgotPassword).Methods("POST")
+	apiV1.HandleFunc("/auth/reset-password", prh.ResetPassword).Methods("POST")
+	apiV1.HandleFunc("/auth/verify-email", evh.VerifyEmail).Methods("POST")
+	apiV1.HandleFunc("/auth/resend-verification", evh.ResendVerification).Methods("POST")
+	apiV1.HandleFunc("/exports/{id:[0-9a-fA-F-]+}/download", eh.DownloadExport).Methods("GET") // Authorized by the signed link
 	apiV1.HandleFunc("/products", ph.GetAllProducts).Methods("GET")
 	apiV1.HandleFunc("/products/search", ph.SearchProducts).Methods("GET")
+	apiV1.HandleFunc("/products/suggest", ph.SuggestProducts).Methods("GET")
 	apiV1.HandleFunc("/products/{id:[0-9a-fA-F-]+}", ph.GetProduct).Methods("GET")
+	apiV1.HandleFunc("/products/{id:[0-9a-fA-F-]+}/variants", vh.ListVariants).Methods("GET")
+	apiV1.HandleFunc("/products/{id:[0-9a-fA-F-]+}/prices", prch.ListPrices).Methods("GET")
+	apiV1.HandleFunc("/exchange-rates", prch.ListRates).Methods("GET")
 	apiV1.HandleFunc("/categories", ch.GetAllCategories).Methods("GET")
 	apiV1.HandleFunc("/categories/{id:[0-9a-fA-F-]+}", ch.GetCategory).Methods("GET")
 
-	// Protected routes
+	// Protected routes. They accept JWTs and API keys; API keys also need the scope required by each route.
+	scoped := func(scope string, h http.HandlerFunc) http.Handler { return mw.RequireScope(scope)(h) }
+
 	protectedUserRoutes := apiV1.PathPrefix("/users").Subrouter()
 	protectedUserRoutes.Use(mw.Authenticate)
-	protectedUserRoutes.HandleFunc("/me", uh.GetMe).Methods("GET")
-	protectedUserRoutes.HandleFunc("/{userId:[0-9a-fA-F-]+}/addresses", uh.ListAddresses).Methods("GET")
-	protectedUserRoutes.HandleFunc("/{userId:[0-9a-fA-F-]+}/addresses", uh.AddAddress).Methods("POST")
-	protectedUserRoutes.HandleFunc("/{userId:[0-9a-fA-F-]+}/addresses/{addressId:[0-9a-fA-F-]+}", uh.UpdateAddress).Methods("PUT")
-	protectedUserRoutes.HandleFunc("/{userId:[0-9a-fA-F-]+}/addresses/{addressId:[0-9a-fA-F-]+}", uh.DeleteAddress).Methods("DELETE")
-	protectedUserRoutes.HandleFunc("/{userId:[0-9a-fA-F-]+}/addresses/{addressId:[0-9a-fA-F-]+}/default", uh.SetDefaultAddress).Methods("PATCH")
+	protectedUserRoutes.Handle("/me", scoped(auth.ScopeProfileRead, uh.GetMe)).Methods("GET")
+	protectedUserRoutes.Handle("/{userId:[0-9a-fA-F-]+}/addresses", scoped(auth.ScopeAddressesRead, uh.ListAddresses)).Methods("GET")
+	protectedUserRoutes.Handle("/{userId:[0-9a-fA-F-]+}/addresses", scoped(auth.ScopeAddressesWrite, uh.AddAddress)).Methods("POST")
+	protectedUserRoutes.Handle("/{userId:[0-9a-fA-F-]+}/addresses/{addressId:[0-9a-fA-F-]+}", scoped(auth.ScopeAddressesWrite, uh.UpdateAddress)).Methods("PUT")
+	protectedUserRoutes.Handle("/{userId:[0-9a-fA-F-]+}/addresses/{addressId:[0-9a-fA-F-]+}", scoped(auth.ScopeAddressesWrite, uh.DeleteAddress)).Methods("DELETE")
+	protectedUserRoutes.Handle("/{userId:[0-9a-fA-F-]+}/addresses/{addressId:[0-9a-fA-F-]+}/default", scoped(auth.ScopeAddressesWrite, uh.SetDefaultAddress)).Methods("PATCH")
+
+	// Account security routes can only be used with the user's own login, never with an API key
+	accountRoutes := apiV1.PathPrefix("/users/me").Subrouter()
+	accountRoutes.Use(mw.Authenticate, mw.DenyAPIKeys)
+	accountRoutes.HandleFunc("", uh.UpdateMe).Methods("PATCH")
+	accountRoutes.HandleFunc("", uh.DeleteMe).Methods("DELETE")
+	accountRoutes.HandleFunc("/password", uh.ChangePassword).Methods("POST")
+	accountRoutes.HandleFunc("/export", eh.ExportMe).Methods("GET")
+	accountRoutes.HandleFunc("/exports/{id:[0-9a-fA-F-]+}", eh.GetExport).Methods("GET")
+	accountRoutes.HandleFunc("/2fa/enroll", mfah.Enroll).Methods("POST")
+	accountRoutes.HandleFunc("/2fa/confirm", mfah.Confirm).Methods("POST")
+	accountRoutes.HandleFunc("/2fa/disable", mfah.Disable).Methods("POST")
+	accountRoutes.HandleFunc("/sessions", sh.ListSessions).Methods("GET")
+	accountRoutes.HandleFunc("/sessions/revoke-others", sh.RevokeOtherSessions).Methods("POST")
+	accountRoutes.HandleFunc("/sessions/{id:[0-9a-fA-F-]+}", sh.RevokeSession).Methods("DELETE")
+	accountRoutes.HandleFunc("/api-keys", akh.ListAPIKeys).Methods("GET")
+	accountRoutes.HandleFunc("/api-keys", akh.CreateAPIKey).Methods("POST")
+	accountRoutes.HandleFunc("/api-keys/{id:[0-9a-fA-F-]+}", akh.DeleteAPIKey).Methods("DELETE")
+
+	// Customer management is reserved to admins signed in themselves, never to API keys
+	adminUserRoutes := apiV1.PathPrefix("/admin/users").Subrouter()
+	adminUserRoutes.Use(mw.Authenticate, mw.DenyAPIKeys, mw.RequireRole(models.RoleAdmin))
+	adminUserRoutes.HandleFunc("", auh.ListUsers).Methods("GET")
+	adminUserRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", auh.GetUser).Methods("GET")
+	adminUserRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/addresses", auh.ListUserAddresses).Methods("GET")
+	adminUserRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/orders", auh.ListUserOrders).Methods("GET")
+	adminUserRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/role", auh.UpdateRole).Methods("PATCH")
+	adminUserRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/disable", auh.DisableUser).Methods("POST")
+	adminUserRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/enable", auh.EnableUser).Methods("POST")
+	adminUserRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/force-password-reset", auh.ForcePasswordReset).Methods("POST")
 
 	protectedProductRoutes := apiV1.PathPrefix("/products").Subrouter()
-	protectedProductRoutes.Use(mw.Authenticate)
+	protectedProductRoutes.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin), mw.RequireScope(auth.ScopeProductsWrite))
 	protectedProductRoutes.HandleFunc("", ph.CreateProduct).Methods("POST")
+	protectedProductRoutes.HandleFunc("/low-stock", ih.ListLowStock).Methods("GET")
 	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", ph.UpdateProduct).Methods("PUT")
 	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", ph.DeleteProduct).Methods("DELETE")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants", vh.CreateVariant).Methods("POST")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants/{variantId:[0-9a-fA-F-]+}", vh.UpdateVariant).Methods("PUT")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants/{variantId:[0-9a-fA-F-]+}", vh.DeleteVariant).Methods("DELETE")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/stock", ih.GetStock).Methods("GET")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/stock", ih.SetStock).Methods("PUT")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/stock/adjustments", ih.AdjustStock).Methods("POST")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/reorder-threshold", ih.SetReorderThreshold).Methods("PUT")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants/{variantId:[0-9a-fA-F-]+}/stock", ih.GetStock).Methods("GET")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants/{variantId:[0-9a-fA-F-]+}/stock", ih.SetStock).Methods("PUT")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants/{variantId:[0-9a-fA-F-]+}/stock/adjustments", ih.AdjustStock).Methods("POST")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/prices/{currency:[a-zA-Z]{3}}", prch.SetPrice).Methods("PUT")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/prices/{currency:[a-zA-Z]{3}}", prch.DeletePrice).Methods("DELETE")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants/{variantId:[0-9a-fA-F-]+}/prices/{currency:[a-zA-Z]{3}}", prch.SetPrice).Methods("PUT")
+	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/variants/{variantId:[0-9a-fA-F-]+}/prices/{currency:[a-zA-Z]{3}}", prch.DeletePrice).Methods("DELETE")
+
+	// Stock locations, and the transfers between them, are managed by admins
+	stockLocationRoutes := apiV1.PathPrefix("/stock-locations").Subrouter()
+	stockLocationRoutes.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin), mw.RequireScope(auth.ScopeProductsWrite))
+	stockLocationRoutes.HandleFunc("", ih.ListLocations).Methods("GET")
+	stockLocationRoutes.HandleFunc("", ih.CreateLocation).Methods("POST")
+	stockLocationRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", ih.GetLocation).Methods("GET")
+	stockLocationRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", ih.UpdateLocation).Methods("PUT")
+	stockLocationRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", ih.DeleteLocation).Methods("DELETE")
+	stockLocationRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/picking-list", ih.GetPickingList).Methods("GET")
+
+	stockTransferRoutes := apiV1.PathPrefix("/stock-transfers").Subrouter()
+	stockTransferRoutes.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin), mw.RequireScope(auth.ScopeProductsWrite))
+	stockTransferRoutes.HandleFunc("", ih.ListTransfers).Methods("GET")
+	stockTransferRoutes.HandleFunc("", ih.CreateTransfer).Methods("POST")
+
+	// Exchange rates of the currencies prices are shown in are maintained by admins
+	exchangeRateRoutes := apiV1.PathPrefix("/exchange-rates").Subrouter()
+	exchangeRateRoutes.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin), mw.RequireScope(auth.ScopeProductsWrite))
+	exchangeRateRoutes.HandleFunc("/{currency:[a-zA-Z]{3}}", prch.SetRate).Methods("PUT")
+	exchangeRateRoutes.HandleFunc("/{currency:[a-zA-Z]{3}}", prch.DeleteRate).Methods("DELETE")
 
 	protectedCategoryRoutes := apiV1.PathPrefix("/categories").Subrouter()
-	protectedCategoryRoutes.Use(mw.Authenticate)
+	protectedCategoryRoutes.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin), mw.RequireScope(auth.ScopeCategoriesWrite))
 	protectedCategoryRoutes.HandleFunc("", ch.CreateCategory).Methods("POST")
 	protectedCategoryRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", ch.UpdateCategory).Methods("PUT")
 	protectedCategoryRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", ch.DeleteCategory).Methods("DELETE")
 
 	protectedCartRoutes := apiV1.PathPrefix("/cart").Subrouter()
 	protectedCartRoutes.Use(mw.Authenticate)
-	protectedCartRoutes.HandleFunc("", cartH.GetCart).Methods("GET")
-	protectedCartRoutes.HandleFunc("/items", cartH.AddItem).Methods("POST")
-	protectedCartRoutes.HandleFunc("/items/{productId:[0-9a-fA-F-]+}", cartH.UpdateItem).Methods("PUT")
-	protectedCartRoutes.HandleFunc("/items/{productId:[0-9a-fA-F-]+}", cartH.DeleteItem).Methods("DELETE")
-	protectedCartRoutes.HandleFunc("", cartH.ClearCart).Methods("DELETE")
+	protectedCartRoutes.Handle("", scoped(auth.ScopeCartRead, cartH.GetCart)).Methods("GET")
+	protectedCartRoutes.Handle("/items", scoped(auth.ScopeCartWrite, cartH.AddItem)).Methods("POST")
+	protectedCartRoutes.Handle("/items/{productId:[0-9a-fA-F-]+}", scoped(auth.ScopeCartWrite, cartH.UpdateItem)).Methods("PUT")
+	protectedCartRoutes.Handle("/items/{productId:[0-9a-fA-F-]+}", scoped(auth.ScopeCartWrite, cartH.DeleteItem)).Methods("DELETE")
+	protectedCartRoutes.Handle("", scoped(auth.ScopeCartWrite, cartH.ClearCart)).Methods("DELETE")
 
 	protectedOrderRoutes := apiV1.PathPrefix("/orders").Subrouter()
 	protectedOrderRoutes.Use(mw.Authenticate)
-	protectedOrderRoutes.HandleFunc("", oh.CreateOrder).Methods("POST")
-	protectedOrderRoutes.HandleFunc("", oh.ListOrders).Methods("GET")
-	protectedOrderRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", oh.GetOrder).Methods("GET")
-	protectedOrderRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}/cancel", oh.CancelOrder).Methods("PATCH")
+	protectedOrderRoutes.Handle("", scoped(auth.ScopeOrdersWrite, oh.CreateOrder)).Methods("POST")
+	protectedOrderRoutes.Handle("", scoped(auth.ScopeOrdersRead, oh.ListOrders)).Methods("GET")
+	protectedOrderRoutes.Handle("/{id:[0-9a-fA-F-]+}", scoped(auth.ScopeOrdersRead, oh.GetOrder)).Methods("GET")
+	protectedOrderRoutes.Handle("/{id:[0-9a-fA-F-]+}/cancel", scoped(auth.ScopeOrdersWrite, oh.CancelOrder)).Methods("PATCH")
+	protectedOrderRoutes.Handle("/{id:[0-9a-fA-F-]+}/status", mw.RequireRole(models.RoleAdmin)(scoped(auth.ScopeOrdersWrite, oh.UpdateOrderStatus))).Methods("PATCH")
 
 	return r
 }
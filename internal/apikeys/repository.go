@@ -0,0 +1,128 @@
+package apikeys
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// APIKeyRepository defines the interface for personal API key data operations.
+type APIKeyRepository interface {
+	// Create stores a new API key.
+	Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	// FindByHash returns the key with the given hash (expired or not), or ErrAPIKeyNotFound.
+	FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	// ListByUser returns the user's keys, newest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	// Delete removes a key owned by userID, or returns ErrAPIKeyNotFound.
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+	// TouchLastUsed records that the key was used. Writes are throttled to one per minute per key.
+	TouchLastUsed(ctx context.Context, id uuid.UUID) error
+}
+
+// postgresAPIKeyRepository implements APIKeyRepository using PostgreSQL.
+type postgresAPIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresAPIKeyRepository creates a new instance of postgresAPIKeyRepository.
+func NewPostgresAPIKeyRepository(db *pgxpool.Pool) APIKeyRepository {
+	return &postgresAPIKeyRepository{db: db}
+}
+
+// apiKeyColumns lists the columns read by scanAPIKey, in order.
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at"
+
+// scanAPIKey scans a row selected with apiKeyColumns.
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// Create inserts a new API key into the database.
+func (r *postgresAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + apiKeyColumns
+	return scanAPIKey(r.db.QueryRow(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt))
+}
+
+// FindByHash retrieves an API key by the hash of the presented key.
+func (r *postgresAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	return scanAPIKey(r.db.QueryRow(ctx, query, keyHash))
+}
+
+// ListByUser retrieves all API keys of a user.
+func (r *postgresAPIKeyRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *key)
+	}
+	return list, rows.Err()
+}
+
+// Delete removes an API key, checking that it belongs to the user.
+func (r *postgresAPIKeyRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed updates the last-used time, at most once per minute.
+func (r *postgresAPIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
@@ -0,0 +1,111 @@
+package apikeys
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAPIKeyRepository is a mock type for the APIKeyRepository interface
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, key
+func (_m *MockAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 *models.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, *models.APIKey) *models.APIKey); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.APIKey) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByHash provides a mock function with given fields: ctx, keyHash
+func (_m *MockAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	ret := _m.Called(ctx, keyHash)
+
+	var r0 *models.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.APIKey); ok {
+		r0 = rf(ctx, keyHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListByUser provides a mock function with given fields: ctx, userID
+func (_m *MockAPIKeyRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.APIKey); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, id, userID
+func (_m *MockAPIKeyRepository) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	ret := _m.Called(ctx, id, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(ctx, id, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TouchLastUsed provides a mock function with given fields: ctx, id
+func (_m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
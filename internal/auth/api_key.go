@@ -0,0 +1,54 @@
+package auth
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognize (e.g. by secret scanners).
+const APIKeyPrefix = "bca_"
+
+// apiKeyDisplayLength is how many leading characters of a key are stored in clear to identify it.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// Scopes that can be granted to API keys. Requests authenticated with a JWT are not limited by scopes.
+const (
+	ScopeProfileRead     = "profile:read"
+	ScopeAddressesRead   = "addresses:read"
+	ScopeAddressesWrite  = "addresses:write"
+	ScopeCartRead        = "cart:read"
+	ScopeCartWrite       = "cart:write"
+	ScopeOrdersRead      = "orders:read"
+	ScopeOrdersWrite     = "orders:write"
+	ScopeProductsWrite   = "products:write"   // Also requires the key's owner to be an admin
+	ScopeCategoriesWrite = "categories:write" // Also requires the key's owner to be an admin
+)
+
+// APIKeyScopes lists every scope an API key may be granted.
+var APIKeyScopes = []string{
+	ScopeProfileRead,
+	ScopeAddressesRead,
+	ScopeAddressesWrite,
+	ScopeCartRead,
+	ScopeCartWrite,
+	ScopeOrdersRead,
+	ScopeOrdersWrite,
+	ScopeProductsWrite,
+	ScopeCategoriesWrite,
+}
+
+// IsValidScope reports whether scope is one of APIKeyScopes.
+func IsValidScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAPIKey generates a new API key. It returns the key, to be shown to the user once,
+// the prefix stored to identify it and the hash used to look it up.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	token, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + token
+	return key, key[:apiKeyDisplayLength], HashOpaqueToken(key), nil
+}
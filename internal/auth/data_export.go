@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// dataExportAudience marks tokens that may only be used to download a personal data export.
+const dataExportAudience = "data-export"
+
+// DataExportClaims are carried by the signed token in an export's download link.
+type DataExportClaims struct {
+	ExportID uuid.UUID `json:"eid"`
+	UserID   uuid.UUID `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+// GenerateDataExportToken creates a signed token allowing the export to be downloaded until expiresAt.
+func GenerateDataExportToken(exportID, userID uuid.UUID, jwtSecret string, expiresAt time.Time) (string, error) {
+	claims := &DataExportClaims{
+		ExportID: exportID,
+		UserID:   userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{dataExportAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "bullet-cloud-api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(derivedSigningKey(dataExportAudience, jwtSecret))
+}
+
+// ValidateDataExportToken parses a download token, returning ErrInvalidToken
+// if it is malformed, expired or was not issued for a data export.
+func ValidateDataExportToken(tokenString, jwtSecret string) (*DataExportClaims, error) {
+	claims := &DataExportClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return derivedSigningKey(dataExportAudience, jwtSecret), nil
+	}, jwt.WithAudience(dataExportAudience))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
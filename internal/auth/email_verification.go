@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// emailVerificationAudience marks tokens that may only be used to verify an email address.
+const emailVerificationAudience = "email-verification"
+
+// EmailVerificationClaims are carried by the signed token sent to a user's inbox.
+// The email and when it was set are included so the token stops working once the address
+// changes, even if it is later changed back.
+type EmailVerificationClaims struct {
+	UserID       uuid.UUID `json:"uid"`
+	Email        string    `json:"email"`
+	EmailVersion int64     `json:"ev"` // The user's EmailChangedAt, in Unix microseconds
+	jwt.RegisteredClaims
+}
+
+// GenerateEmailVerificationToken creates a signed, expiring token proving ownership of email,
+// as set at emailChangedAt.
+func GenerateEmailVerificationToken(userID uuid.UUID, email string, emailChangedAt time.Time, jwtSecret string, expiryDuration time.Duration) (string, error) {
+	now := time.Now()
+	claims := &EmailVerificationClaims{
+		UserID:       userID,
+		Email:        email,
+		EmailVersion: emailChangedAt.UnixMicro(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiryDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "bullet-cloud-api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(derivedSigningKey(emailVerificationAudience, jwtSecret))
+}
+
+// ValidateEmailVerificationToken parses a verification token, returning ErrInvalidToken
+// if it is malformed, expired or was not signed for email verification.
+func ValidateEmailVerificationToken(tokenString, jwtSecret string) (*EmailVerificationClaims, error) {
+	claims := &EmailVerificationClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return derivedSigningKey(emailVerificationAudience, jwtSecret), nil
+	}, jwt.WithAudience(emailVerificationAudience))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
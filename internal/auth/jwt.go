@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/sha256"
 	"errors"
 	"time"
 
@@ -15,29 +16,30 @@ var (
 // Claims defines the structure of the JWT claims.
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role,omitempty"` // Role at issue time, for services that only see the token
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT for a given user ID and secret.
-func GenerateToken(userID uuid.UUID, jwtSecret string, expiryDuration time.Duration) (string, error) {
+// GenerateToken creates a new JWT for a given user ID and role, signed with the key set's current signing key.
+// The session ID becomes the jti claim, which the middleware checks against the sessions table.
+func GenerateToken(userID uuid.UUID, role string, sessionID uuid.UUID, keys *KeySet, expiryDuration time.Duration) (string, error) {
 	// Define expiration time
 	expirationTime := time.Now().Add(expiryDuration)
 
 	// Create the claims
 	claims := &Claims{
 		UserID: userID,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID.String(),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "bullet-cloud-api", // Optional: identify the issuer
 		},
 	}
 
-	// Create the token using HS256 signing method and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token with the secret
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	// Sign the token with the configured algorithm (HS256 by default)
+	tokenString, err := keys.sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -45,20 +47,17 @@ func GenerateToken(userID uuid.UUID, jwtSecret string, expiryDuration time.Durat
 	return tokenString, nil
 }
 
-// ValidateToken parses and validates a JWT string, returning the claims if valid.
-func ValidateToken(tokenString string, jwtSecret string) (*Claims, error) {
+// ValidateToken parses and validates a JWT string against any verification key in the set,
+// returning the claims if valid.
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Check the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(jwtSecret), nil
-	})
+	// The key is picked by the kid header and must match the token's algorithm
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyfunc, jwt.WithValidMethods(keys.validMethods()))
 
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) || errors.Is(err, jwt.ErrSignatureInvalid) {
+		if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) || errors.Is(err, jwt.ErrSignatureInvalid) ||
+			errors.Is(err, jwt.ErrTokenSignatureInvalid) || errors.Is(err, jwt.ErrTokenUnverifiable) {
 			return nil, ErrInvalidToken
 		}
 		return nil, err // Other parsing errors
@@ -70,3 +69,11 @@ func ValidateToken(tokenString string, jwtSecret string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// derivedSigningKey derives a per-purpose signing key from the JWT secret, so a token
+// issued for one purpose (e.g. email verification) is never accepted for another
+// (e.g. as an access token).
+func derivedSigningKey(purpose, jwtSecret string) []byte {
+	sum := sha256.Sum256([]byte(purpose + ":" + jwtSecret))
+	return sum[:]
+}
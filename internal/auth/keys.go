@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported access token signing algorithms.
+const (
+	AlgorithmHS256 = "HS256" // Shared secret (default); tokens can only be verified by holders of JWT_SECRET
+	AlgorithmRS256 = "RS256" // RSA private key, public keys published in the JWKS
+	AlgorithmEdDSA = "EdDSA" // Ed25519 private key, public keys published in the JWKS
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signing or verification.
+const minRSAKeyBits = 2048
+
+// verificationKey is a key accepted when validating access tokens.
+type verificationKey struct {
+	id     string // kid header value; empty for the HS256 secret
+	method jwt.SigningMethod
+	key    interface{} // []byte for HMAC, *rsa.PublicKey or ed25519.PublicKey otherwise
+}
+
+// KeySet holds the key used to sign access tokens and every key accepted when verifying them.
+// Keeping the previous public keys in the set lets tokens signed before a key rotation
+// stay valid until they expire.
+type KeySet struct {
+	signingID     string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{} // []byte, *rsa.PrivateKey or ed25519.PrivateKey
+	verification  map[string]verificationKey
+	published     []JWK // Public keys, in the order they were loaded
+}
+
+// NewHMACKeySet returns a KeySet that signs and verifies with a shared HS256 secret.
+// Tokens carry no kid header, matching tokens issued before key sets existed.
+func NewHMACKeySet(secret string) *KeySet {
+	key := []byte(secret)
+	return &KeySet{
+		signingMethod: jwt.SigningMethodHS256,
+		signingKey:    key,
+		verification: map[string]verificationKey{
+			"": {method: jwt.SigningMethodHS256, key: key},
+		},
+	}
+}
+
+// LoadKeySet builds the access token KeySet for the given algorithm.
+// HS256 (or an empty algorithm) uses hmacSecret. RS256 and EdDSA sign with the PEM private
+// key at privateKeyFile; publicKeyFiles lists extra PEM public keys (e.g. the previous key
+// during a rotation) that are still accepted for verification and published in the JWKS.
+func LoadKeySet(algorithm, hmacSecret, privateKeyFile string, publicKeyFiles []string) (*KeySet, error) {
+	switch algorithm {
+	case "", AlgorithmHS256:
+		if hmacSecret == "" {
+			return nil, errors.New("HS256 requires a secret")
+		}
+		return NewHMACKeySet(hmacSecret), nil
+	case AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q (expected %s, %s or %s)", algorithm, AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA)
+	}
+
+	if privateKeyFile == "" {
+		return nil, fmt.Errorf("%s requires a private key file", algorithm)
+	}
+	private, err := readPEMPrivateKey(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	publics := make([]crypto.PublicKey, 0, len(publicKeyFiles))
+	for _, path := range publicKeyFiles {
+		public, err := readPEMPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		publics = append(publics, public)
+	}
+
+	ks, err := NewKeySet(private, publics...)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Algorithm() != algorithm {
+		return nil, fmt.Errorf("%s: key type does not match algorithm %s", privateKeyFile, algorithm)
+	}
+	return ks, nil
+}
+
+// NewKeySet builds an asymmetric KeySet from in-memory keys. The signing key's public half is
+// always accepted; extra public keys may be passed for verification only.
+func NewKeySet(signer crypto.Signer, extraPublicKeys ...crypto.PublicKey) (*KeySet, error) {
+	signing, err := newVerificationKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeySet{
+		signingID:     signing.id,
+		signingMethod: signing.method,
+		signingKey:    signer,
+		verification:  map[string]verificationKey{},
+	}
+	ks.addVerificationKey(signing, signer.Public())
+
+	for _, public := range extraPublicKeys {
+		vk, err := newVerificationKey(public)
+		if err != nil {
+			return nil, err
+		}
+		ks.addVerificationKey(vk, public)
+	}
+	return ks, nil
+}
+
+// Algorithm returns the name of the algorithm used to sign new tokens.
+func (ks *KeySet) Algorithm() string {
+	return ks.signingMethod.Alg()
+}
+
+// sign signs claims with the current signing key, setting the kid header when the key has one.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signingMethod, claims)
+	if ks.signingID != "" {
+		token.Header["kid"] = ks.signingID
+	}
+	return token.SignedString(ks.signingKey)
+}
+
+// keyfunc selects the verification key named by the token's kid header. The key's own
+// algorithm must match the token's, so an attacker cannot, for example, present an HS256
+// token "signed" with a published RSA public key.
+func (ks *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	vk, ok := ks.verification[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != vk.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return vk.key, nil
+}
+
+// validMethods lists the algorithms of every verification key, for jwt.WithValidMethods.
+func (ks *KeySet) validMethods() []string {
+	seen := map[string]bool{}
+	var methods []string
+	for _, vk := range ks.verification {
+		if alg := vk.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+func (ks *KeySet) addVerificationKey(vk verificationKey, public crypto.PublicKey) {
+	if _, exists := ks.verification[vk.id]; exists {
+		return
+	}
+	ks.verification[vk.id] = vk
+	ks.published = append(ks.published, newJWK(vk, public))
+}
+
+// newVerificationKey wraps a public key, picking its algorithm and computing its kid.
+func newVerificationKey(public crypto.PublicKey) (verificationKey, error) {
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return verificationKey{}, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		vk := verificationKey{method: jwt.SigningMethodRS256, key: pub}
+		vk.id = thumbprint(newJWK(vk, pub))
+		return vk, nil
+	case ed25519.PublicKey:
+		vk := verificationKey{method: jwt.SigningMethodEdDSA, key: pub}
+		vk.id = thumbprint(newJWK(vk, pub))
+		return vk, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %T (expected RSA or Ed25519)", public)
+	}
+}
+
+// --- JWKS ---
+
+// JWK is the public JSON Web Key representation of a verification key (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA public exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys. Shared HS256 secrets are never published,
+// so the set is empty when the default algorithm is used.
+func (ks *KeySet) JWKS() JWKSet {
+	keys := make([]JWK, 0, len(ks.published))
+	keys = append(keys, ks.published...)
+	return JWKSet{Keys: keys}
+}
+
+func newJWK(vk verificationKey, public crypto.PublicKey) JWK {
+	jwk := JWK{KeyID: vk.id, Use: "sig", Algorithm: vk.method.Alg()}
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, used as the key's kid.
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+	data, _ := json.Marshal(members) // Only strings, cannot fail
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// --- PEM loading ---
+
+// readPEMPrivateKey reads an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key.
+func readPEMPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block %q (expected a private key)", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key type %T", path, key)
+	}
+	return signer, nil
+}
+
+// readPEMPublicKey reads an RSA (PKIX or PKCS#1) or Ed25519 (PKIX) public key.
+// A private key file is accepted too, in which case its public half is used.
+func readPEMPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return key, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return key, nil
+	case "RSA PRIVATE KEY", "PRIVATE KEY":
+		signer, err := readPEMPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block %q (expected a public key)", path, block.Type)
+	}
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// mfaChallengeAudience marks tokens that only allow completing a two-factor login.
+const mfaChallengeAudience = "mfa-challenge"
+
+// MFAChallengeClaims are carried by the intermediate token returned by a password login
+// when the account has two-factor authentication enabled.
+type MFAChallengeClaims struct {
+	UserID uuid.UUID `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+// GenerateMFAChallengeToken creates a short-lived token proving the user passed the password step.
+func GenerateMFAChallengeToken(userID uuid.UUID, jwtSecret string, expiryDuration time.Duration) (string, error) {
+	now := time.Now()
+	claims := &MFAChallengeClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiryDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "bullet-cloud-api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(derivedSigningKey(mfaChallengeAudience, jwtSecret))
+}
+
+// ValidateMFAChallengeToken parses a challenge token, returning ErrInvalidToken
+// if it is malformed, expired or was not issued as an MFA challenge.
+func ValidateMFAChallengeToken(tokenString, jwtSecret string) (*MFAChallengeClaims, error) {
+	claims := &MFAChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return derivedSigningKey(mfaChallengeAudience, jwtSecret), nil
+	}, jwt.WithAudience(mfaChallengeAudience))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
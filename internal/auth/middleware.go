@@ -1,61 +1,100 @@
 package auth
 
 import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/sessions"
 	"bullet-cloud-api/internal/users"    // For UserRepository
 	"bullet-cloud-api/internal/webutils" // Changed from handlers
 	"context"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
 // ContextKey is a type used for context keys to avoid collisions.
 type ContextKey string
 
-const UserIDContextKey ContextKey = "userID"
+const (
+	UserIDContextKey    ContextKey = "userID"
+	UserRoleContextKey  ContextKey = "userRole"
+	SessionIDContextKey ContextKey = "sessionID"
+	APIKeyIDContextKey  ContextKey = "apiKeyID" // Set only for requests authenticated with an API key
+	ScopesContextKey    ContextKey = "scopes"   // Scopes of the API key, set only with APIKeyIDContextKey
+)
+
+var (
+	// ErrInvalidAPIKey is returned for unknown API keys.
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAccountDisabled is returned when an admin has disabled the user's account.
+	ErrAccountDisabled = errors.New("account is disabled")
+)
 
 // Middleware provides authentication middleware.
 type Middleware struct {
-	jwtSecret string
-	userRepo  users.UserRepository
+	keys        *KeySet // Keys accepted when validating access tokens
+	userRepo    users.UserRepository
+	sessionRepo sessions.SessionRepository // Should be cached, it is consulted on every request
+	apiKeyRepo  apikeys.APIKeyRepository
 }
 
 // NewMiddleware creates a new instance of Middleware.
-func NewMiddleware(jwtSecret string, userRepo users.UserRepository) *Middleware {
+func NewMiddleware(keys *KeySet, userRepo users.UserRepository, sessionRepo sessions.SessionRepository, apiKeyRepo apikeys.APIKeyRepository) *Middleware {
 	return &Middleware{
-		jwtSecret: jwtSecret,
-		userRepo:  userRepo,
+		keys:        keys,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		apiKeyRepo:  apiKeyRepo,
 	}
 }
 
-// Authenticate verifies the JWT token from the Authorization header.
-// If valid, it adds the UserID to the request context.
+// Authenticate verifies the credentials of the request: either a JWT access token
+// ("Authorization: Bearer <token>") whose session is still active, or a personal API key
+// ("X-API-Key: <key>" or "Authorization: ApiKey <key>").
+// If valid, it adds the UserID and role to the request context, plus the session ID for
+// tokens or the key ID and scopes for API keys.
 func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			webutils.ErrorJSON(w, errors.New("authorization header required"), http.StatusUnauthorized) // Use webutils
-			return
-		}
+		var (
+			ctx    context.Context
+			userID uuid.UUID
+			ok     bool
+		)
 
-		// Check if the header is in the format "Bearer <token>"
-		headerParts := strings.Split(authHeader, " ")
-		if len(headerParts) != 2 || strings.ToLower(headerParts[0]) != "bearer" {
-			webutils.ErrorJSON(w, errors.New("invalid authorization header format"), http.StatusUnauthorized) // Use webutils
-			return
-		}
-
-		tokenString := headerParts[1]
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			ctx, userID, ok = m.authenticateAPIKey(w, r, apiKey)
+		} else {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				webutils.ErrorJSON(w, errors.New("authorization header required"), http.StatusUnauthorized) // Use webutils
+				return
+			}
 
-		// Validate the token
-		claims, err := ValidateToken(tokenString, m.jwtSecret)
-		if err != nil {
-			webutils.ErrorJSON(w, ErrInvalidToken, http.StatusUnauthorized) // Use webutils
+			// Check if the header is in the format "Bearer <token>" or "ApiKey <key>"
+			headerParts := strings.Split(authHeader, " ")
+			if len(headerParts) != 2 {
+				webutils.ErrorJSON(w, errors.New("invalid authorization header format"), http.StatusUnauthorized) // Use webutils
+				return
+			}
+			switch strings.ToLower(headerParts[0]) {
+			case "bearer":
+				ctx, userID, ok = m.authenticateToken(w, r, headerParts[1])
+			case "apikey":
+				ctx, userID, ok = m.authenticateAPIKey(w, r, headerParts[1])
+			default:
+				webutils.ErrorJSON(w, errors.New("invalid authorization header format"), http.StatusUnauthorized) // Use webutils
+				return
+			}
+		}
+		if !ok {
 			return
 		}
 
-		// Optional: Check if user still exists in the database
-		_, err = m.userRepo.FindByID(r.Context(), claims.UserID)
+		// Check if user still exists in the database (and pick up their current role)
+		user, err := m.userRepo.FindByID(r.Context(), userID)
 		if err != nil {
 			if errors.Is(err, users.ErrUserNotFound) {
 				webutils.ErrorJSON(w, errors.New("user associated with token not found"), http.StatusUnauthorized) // Use webutils
@@ -64,11 +103,131 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 			}
 			return
 		}
+		// Disabling takes effect immediately, even for tokens and keys issued before
+		if user.IsDisabled() {
+			webutils.ErrorJSON(w, ErrAccountDisabled, http.StatusForbidden)
+			return
+		}
 
-		// Add user ID to context
-		ctx := context.WithValue(r.Context(), UserIDContextKey, claims.UserID)
+		// Add user ID and role to context. The role comes from the database rather than
+		// the token so that demotions take effect immediately.
+		ctx = context.WithValue(ctx, UserIDContextKey, userID)
+		ctx = context.WithValue(ctx, UserRoleContextKey, user.Role)
 
 		// Call the next handler with the new context
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// authenticateToken validates a JWT access token and checks that its session was not revoked.
+// On failure it writes the error response and returns ok == false.
+func (m *Middleware) authenticateToken(w http.ResponseWriter, r *http.Request, tokenString string) (context.Context, uuid.UUID, bool) {
+	// Validate the token
+	claims, err := ValidateToken(tokenString, m.keys)
+	if err != nil {
+		webutils.ErrorJSON(w, ErrInvalidToken, http.StatusUnauthorized) // Use webutils
+		return nil, uuid.Nil, false
+	}
+
+	// Reject tokens whose session was logged out or revoked
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		webutils.ErrorJSON(w, ErrInvalidToken, http.StatusUnauthorized)
+		return nil, uuid.Nil, false
+	}
+	session, err := m.sessionRepo.FindByID(r.Context(), sessionID)
+	if err != nil && !errors.Is(err, sessions.ErrSessionNotFound) {
+		webutils.ErrorJSON(w, errors.New("error verifying session"), http.StatusInternalServerError)
+		return nil, uuid.Nil, false
+	}
+	if !session.IsActive() || session.UserID != claims.UserID {
+		webutils.ErrorJSON(w, errors.New("session has been revoked"), http.StatusUnauthorized)
+		return nil, uuid.Nil, false
+	}
+	if err := m.sessionRepo.Touch(r.Context(), sessionID); err != nil {
+		log.Printf("Error updating session last seen time: %v", err)
+	}
+
+	return context.WithValue(r.Context(), SessionIDContextKey, sessionID), claims.UserID, true
+}
+
+// authenticateAPIKey looks up a personal API key by its hash and checks that it has not expired.
+// On failure it writes the error response and returns ok == false.
+func (m *Middleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string) (context.Context, uuid.UUID, bool) {
+	apiKey, err := m.apiKeyRepo.FindByHash(r.Context(), HashOpaqueToken(key))
+	if err != nil {
+		if errors.Is(err, apikeys.ErrAPIKeyNotFound) {
+			webutils.ErrorJSON(w, ErrInvalidAPIKey, http.StatusUnauthorized)
+		} else {
+			webutils.ErrorJSON(w, errors.New("error verifying API key"), http.StatusInternalServerError)
+		}
+		return nil, uuid.Nil, false
+	}
+	if apiKey.IsExpired() {
+		webutils.ErrorJSON(w, errors.New("API key has expired"), http.StatusUnauthorized)
+		return nil, uuid.Nil, false
+	}
+	if err := m.apiKeyRepo.TouchLastUsed(r.Context(), apiKey.ID); err != nil {
+		log.Printf("Error updating API key last used time: %v", err)
+	}
+
+	ctx := context.WithValue(r.Context(), APIKeyIDContextKey, apiKey.ID)
+	ctx = context.WithValue(ctx, ScopesContextKey, apiKey.Scopes)
+	return ctx, apiKey.UserID, true
+}
+
+// RequireRole returns a middleware that only lets through users holding one of the given roles.
+// It must run after Authenticate, which puts the user's role in the request context.
+func (m *Middleware) RequireRole(roles ...models.UserRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value(UserRoleContextKey).(models.UserRole)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			webutils.ErrorJSON(w, errors.New("forbidden"), http.StatusForbidden)
+		})
+	}
+}
+
+// RequireScope returns a middleware that only lets API keys through if they were granted scope.
+// Requests authenticated with a JWT are not restricted. It must run after Authenticate.
+func (m *Middleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, isAPIKey := r.Context().Value(APIKeyIDContextKey).(uuid.UUID); isAPIKey {
+				scopes, _ := r.Context().Value(ScopesContextKey).([]string)
+				if !hasScope(scopes, scope) {
+					webutils.ErrorJSON(w, errors.New("insufficient scope"), http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyAPIKeys rejects requests authenticated with an API key. It protects account management
+// routes (API keys, sessions, 2FA) that must only be used by the user themselves.
+// It must run after Authenticate.
+func (m *Middleware) DenyAPIKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, isAPIKey := r.Context().Value(APIKeyIDContextKey).(uuid.UUID); isAPIKey {
+			webutils.ErrorJSON(w, errors.New("API keys cannot access this resource"), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"bullet-cloud-api/internal/oidc"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcFlowAudience marks tokens that carry an in-progress external login.
+const oidcFlowAudience = "oidc-flow"
+
+// OIDCFlowClaims carry the state, nonce and PKCE verifier of an external login between
+// the redirect to the identity provider and the callback. The token is kept in an
+// HttpOnly cookie, so the verifier never travels through the provider.
+type OIDCFlowClaims struct {
+	oidc.Flow
+	jwt.RegisteredClaims
+}
+
+// GenerateOIDCFlowToken signs the flow into a short-lived token.
+func GenerateOIDCFlowToken(flow *oidc.Flow, jwtSecret string, expiryDuration time.Duration) (string, error) {
+	now := time.Now()
+	claims := &OIDCFlowClaims{
+		Flow: *flow,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcFlowAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiryDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "bullet-cloud-api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(derivedSigningKey(oidcFlowAudience, jwtSecret))
+}
+
+// ValidateOIDCFlowToken parses a flow token, returning ErrInvalidToken
+// if it is malformed, expired or was not issued for an external login.
+func ValidateOIDCFlowToken(tokenString, jwtSecret string) (*OIDCFlowClaims, error) {
+	claims := &OIDCFlowClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return derivedSigningKey(oidcFlowAudience, jwtSecret), nil
+	}, jwt.WithAudience(oidcFlowAudience))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
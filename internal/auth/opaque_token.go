@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// opaqueTokenBytes is the amount of random data in an opaque token (256 bits).
+const opaqueTokenBytes = 32
+
+// NewOpaqueToken generates a random, URL-safe token and returns it together with its hash.
+// Only the hash should be persisted; the plain token is handed to the client once.
+func NewOpaqueToken() (token string, hash string, err error) {
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken returns the hex-encoded SHA-256 hash of an opaque token.
+// A fast hash is fine here because the tokens carry 256 bits of entropy.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
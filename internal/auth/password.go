@@ -1,26 +1,80 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Supported password hashing algorithms.
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrPasswordMismatch        = errors.New("password does not match")
+	ErrUnsupportedPasswordHash = errors.New("unsupported password hash format")
+)
+
 // PasswordHasher defines the interface for hashing and verifying passwords.
 type PasswordHasher interface {
 	HashPassword(password string) (string, error)
+	// CheckPassword verifies a password against a hash in any supported format,
+	// so hashes created with older algorithms or parameters keep working.
 	CheckPassword(hashedPassword, password string) error
+	// NeedsRehash reports whether the hash was created with a different algorithm or parameters
+	// than the ones HashPassword currently uses.
+	NeedsRehash(hashedPassword string) bool
 }
 
+// NewPasswordHasher returns the hasher for the configured algorithm.
+func NewPasswordHasher(algorithm string, bcryptCost int, argonParams Argon2Params) (PasswordHasher, error) {
+	switch algorithm {
+	case PasswordAlgorithmBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return NewBcryptPasswordHasher(bcryptCost), nil
+	case PasswordAlgorithmArgon2id:
+		if argonParams.Memory == 0 || argonParams.Iterations == 0 || argonParams.Parallelism == 0 {
+			return nil, errors.New("argon2id memory, iterations and parallelism must be positive")
+		}
+		return NewArgon2idPasswordHasher(argonParams), nil
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %q (expected %s or %s)", algorithm, PasswordAlgorithmBcrypt, PasswordAlgorithmArgon2id)
+	}
+}
+
+// checkPassword verifies a password against a bcrypt or argon2id hash.
+func checkPassword(hashedPassword, password string) error {
+	if strings.HasPrefix(hashedPassword, "$argon2id$") {
+		return checkArgon2idPassword(hashedPassword, password)
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+}
+
+// --- bcrypt ---
+
 // BcryptPasswordHasher implements PasswordHasher using bcrypt.
-type BcryptPasswordHasher struct{}
+type BcryptPasswordHasher struct {
+	Cost int
+}
 
 // NewBcryptPasswordHasher creates a new instance of BcryptPasswordHasher.
-func NewBcryptPasswordHasher() PasswordHasher {
-	return &BcryptPasswordHasher{}
+func NewBcryptPasswordHasher(cost int) PasswordHasher {
+	return &BcryptPasswordHasher{Cost: cost}
 }
 
 // HashPassword hashes the given password using bcrypt.
 func (h *BcryptPasswordHasher) HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	if err != nil {
 		return "", err
 	}
@@ -29,5 +83,99 @@ func (h *BcryptPasswordHasher) HashPassword(password string) (string, error) {
 
 // CheckPassword compares a hashed password with a plaintext password.
 func (h *BcryptPasswordHasher) CheckPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return checkPassword(hashedPassword, password)
+}
+
+// NeedsRehash reports whether the hash is not a bcrypt hash with the configured cost.
+func (h *BcryptPasswordHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != h.Cost
+}
+
+// --- argon2id ---
+
+// Argon2Params are the argon2id cost parameters.
+type Argon2Params struct {
+	Memory      uint32 // Memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Argon2idPasswordHasher implements PasswordHasher using argon2id. Hashes are stored in the
+// PHC string format, e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>.
+type Argon2idPasswordHasher struct {
+	Params Argon2Params
+}
+
+// NewArgon2idPasswordHasher creates a new instance of Argon2idPasswordHasher.
+func NewArgon2idPasswordHasher(params Argon2Params) PasswordHasher {
+	return &Argon2idPasswordHasher{Params: params}
+}
+
+// HashPassword hashes the given password using argon2id and a random salt.
+func (h *Argon2idPasswordHasher) HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Params.Iterations, h.Params.Memory, h.Params.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Params.Memory, h.Params.Iterations, h.Params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword compares a hashed password with a plaintext password.
+func (h *Argon2idPasswordHasher) CheckPassword(hashedPassword, password string) error {
+	return checkPassword(hashedPassword, password)
+}
+
+// NeedsRehash reports whether the hash is not an argon2id hash with the configured parameters.
+func (h *Argon2idPasswordHasher) NeedsRehash(hashedPassword string) bool {
+	params, salt, key, err := decodeArgon2idHash(hashedPassword)
+	return err != nil || params != h.Params || len(salt) != argon2SaltLength || len(key) != argon2KeyLength
+}
+
+// checkArgon2idPassword recomputes the hash with the stored parameters and compares in constant time.
+func checkArgon2idPassword(hashedPassword, password string) error {
+	params, salt, key, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// decodeArgon2idHash parses a PHC-formatted argon2id hash.
+func decodeArgon2idHash(hashedPassword string) (params Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+	return params, salt, key, nil
 }
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordBytes is the longest password bcrypt can hash.
+const maxPasswordBytes = 72
+
+// minPersonalInfoLength is the shortest name or email part a password may not contain.
+// Shorter fragments (e.g. "jo") would reject too many legitimate passwords.
+const minPersonalInfoLength = 3
+
+// Password policy violation codes, returned to clients in field errors.
+const (
+	PasswordTooShort             = "too_short"
+	PasswordTooLong              = "too_long"
+	PasswordTooFewCharClasses    = "too_few_character_classes"
+	PasswordContainsPersonalInfo = "contains_personal_info"
+	PasswordBreached             = "breached"
+)
+
+// PasswordViolation is one reason a password was rejected by the policy.
+type PasswordViolation struct {
+	Code    string
+	Message string
+}
+
+// PasswordPolicy describes the passwords users may choose.
+type PasswordPolicy struct {
+	MinLength      int                   // Minimum number of characters
+	MinCharClasses int                   // Minimum of distinct classes among lowercase, uppercase, digits and symbols
+	Breached       *BreachedPasswordList // Optional; passwords on the list are rejected
+}
+
+// Validate checks a password against the policy. personalInfo holds the user's name and email,
+// which the password may not contain. It returns every violation found, or nil.
+func (p *PasswordPolicy) Validate(password string, personalInfo ...string) []PasswordViolation {
+	var violations []PasswordViolation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if len(password) > maxPasswordBytes {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("password must be at most %d bytes long", maxPasswordBytes),
+		})
+	}
+	if countCharClasses(password) < p.MinCharClasses {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooFewCharClasses,
+			Message: fmt.Sprintf("password must mix at least %d of: lowercase letters, uppercase letters, digits, symbols", p.MinCharClasses),
+		})
+	}
+	if containsPersonalInfo(password, personalInfo) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordContainsPersonalInfo,
+			Message: "password must not contain your name or email",
+		})
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordBreached,
+			Message: "password is too common or has appeared in a data breach",
+		})
+	}
+
+	return violations
+}
+
+// countCharClasses counts how many of lowercase, uppercase, digits and symbols the password uses.
+func countCharClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// containsPersonalInfo reports whether the password contains, ignoring case, an email address,
+// its local part or any word of a name from personalInfo.
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	lowered := strings.ToLower(password)
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		fragments := strings.FieldsFunc(info, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '@' || r == '.' || r == '-' || r == '_' || r == '+'
+		})
+		fragments = append(fragments, info)
+		if local, _, found := strings.Cut(info, "@"); found {
+			fragments = append(fragments, local)
+		}
+		for _, fragment := range fragments {
+			if utf8.RuneCountInString(fragment) >= minPersonalInfoLength && strings.Contains(lowered, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// --- Breached passwords ---
+
+//go:embed breached_passwords.txt
+var bundledBreachedPasswords string
+
+// BreachedPasswordList is an offline set of breached passwords. Like the Pwned Passwords range
+// API, it only holds SHA-1 hashes, grouped by their 5-character prefix.
+type BreachedPasswordList struct {
+	ranges map[string]map[string]struct{}
+	size   int
+}
+
+// DefaultBreachedPasswordList returns the list bundled with the application.
+func DefaultBreachedPasswordList() *BreachedPasswordList {
+	list, err := parseBreachedPasswords(strings.NewReader(bundledBreachedPasswords))
+	if err != nil {
+		panic(fmt.Sprintf("bundled breached password list is invalid: %v", err)) // Only possible if the embedded file is broken
+	}
+	return list
+}
+
+// LoadBreachedPasswordList reads a list from a file. Each line holds either the SHA-1 of a
+// password in hex (optionally followed by ":count", as in Pwned Passwords downloads) or a
+// plain password. Blank lines and lines starting with # are ignored.
+func LoadBreachedPasswordList(path string) (*BreachedPasswordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseBreachedPasswords(f)
+}
+
+// NewBreachedPasswordList builds a list from plain passwords.
+func NewBreachedPasswordList(passwords ...string) *BreachedPasswordList {
+	list := &BreachedPasswordList{ranges: map[string]map[string]struct{}{}}
+	for _, password := range passwords {
+		list.add(sha1Hex(password))
+	}
+	return list
+}
+
+// Contains reports whether the password is on the list.
+func (l *BreachedPasswordList) Contains(password string) bool {
+	hash := sha1Hex(password)
+	_, found := l.ranges[hash[:5]][hash[5:]]
+	return found
+}
+
+// Len returns the number of passwords on the list.
+func (l *BreachedPasswordList) Len() int {
+	return l.size
+}
+
+func (l *BreachedPasswordList) add(hash string) {
+	prefix, suffix := hash[:5], hash[5:]
+	if l.ranges[prefix] == nil {
+		l.ranges[prefix] = map[string]struct{}{}
+	}
+	if _, exists := l.ranges[prefix][suffix]; !exists {
+		l.ranges[prefix][suffix] = struct{}{}
+		l.size++
+	}
+}
+
+func parseBreachedPasswords(r io.Reader) (*BreachedPasswordList, error) {
+	list := &BreachedPasswordList{ranges: map[string]map[string]struct{}{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			list.add(strings.ToUpper(hash))
+		} else {
+			list.add(sha1Hex(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// sha1Hex returns the uppercase hex SHA-1 of a password, the format used by Pwned Passwords.
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238 defaults, understood by every authenticator app).
+const (
+	totpPeriod     = 30 // Seconds per time step
+	totpDigits     = 6
+	totpSkewSteps  = 1  // Accept codes from one step before/after to absorb clock drift
+	totpSecretSize = 20 // 160-bit secret, as recommended by RFC 4226
+
+	recoveryCodeSize = 10 // Base32 characters per recovery code (50 bits)
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random base32-encoded TOTP secret.
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(b), nil
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI that authenticator apps import (usually as a QR code).
+func TOTPProvisioningURI(secret, issuer, account string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// GenerateTOTPCode returns the code for secret at time t.
+func GenerateTOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, t.Unix()/totpPeriod), nil
+}
+
+// ValidateTOTP checks code against secret at time t, allowing for clock drift.
+// It returns the matching time step so callers can reject reuse of the same code.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkewSteps; step <= current+totpSkewSteps; step++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp computes an RFC 4226 HOTP value for the given counter.
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	return base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+}
+
+// GenerateRecoveryCodes returns n single-use recovery codes formatted as "xxxxx-xxxxx".
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 8) // 64 bits, trimmed to recoveryCodeSize characters below
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32NoPadding.EncodeToString(b))[:recoveryCodeSize]
+		codes[i] = raw[:recoveryCodeSize/2] + "-" + raw[recoveryCodeSize/2:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code.
+// Codes are normalized first, so users may type them without the dash or in upper case.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashOpaqueToken(normalized)
+}
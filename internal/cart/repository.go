@@ -2,6 +2,7 @@ package cart
 
 import (
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
 	"context"
 	"errors"
 
@@ -20,18 +21,22 @@ var (
 type CartRepository interface {
 	// GetOrCreateCartByUserID finds the cart for a user or creates one if it doesn't exist.
 	GetOrCreateCartByUserID(ctx context.Context, userID uuid.UUID) (*models.Cart, error)
+	// FindCartByUserID returns the cart of a user without creating one, or ErrCartNotFound.
+	FindCartByUserID(ctx context.Context, userID uuid.UUID) (*models.Cart, error)
 	// GetCartItems retrieves all items currently in the specified cart.
 	GetCartItems(ctx context.Context, cartID uuid.UUID) ([]models.CartItem, error)
 	// AddItem adds a product to the cart or updates its quantity if it already exists.
-	AddItem(ctx context.Context, cartID, productID uuid.UUID, quantity int, price float64) (*models.CartItem, error)
+	// variantID names the variant for products sold in variants, and is nil otherwise;
+	// the same holds for the other item methods.
+	AddItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int, price money.Money) (*models.CartItem, error)
 	// UpdateItemQuantity changes the quantity of an existing item in the cart.
-	UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, quantity int) (*models.CartItem, error)
+	UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int) (*models.CartItem, error)
 	// RemoveItem removes a specific product from the cart.
-	RemoveItem(ctx context.Context, cartID, productID uuid.UUID) error
+	RemoveItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID) error
 	// ClearCart removes all items from a specific cart.
 	ClearCart(ctx context.Context, cartID uuid.UUID) error
 	// FindCartItem retrieves a specific item from a cart.
-	FindCartItem(ctx context.Context, cartID, productID uuid.UUID) (*models.CartItem, error)
+	FindCartItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID) (*models.CartItem, error)
 }
 
 // postgresCartRepository implements CartRepository using PostgreSQL.
@@ -78,6 +83,20 @@ func (r *postgresCartRepository) GetOrCreateCartByUserID(ctx context.Context, us
 	return nil, err
 }
 
+// FindCartByUserID finds the cart of a user.
+func (r *postgresCartRepository) FindCartByUserID(ctx context.Context, userID uuid.UUID) (*models.Cart, error) {
+	query := `SELECT id, user_id, created_at, updated_at FROM carts WHERE user_id = $1`
+	cart := &models.Cart{}
+	err := r.db.QueryRow(ctx, query, userID).Scan(&cart.ID, &cart.UserID, &cart.CreatedAt, &cart.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCartNotFound
+		}
+		return nil, err
+	}
+	return cart, nil
+}
+
 // GetCartItems retrieves all items for a given cart ID.
 func (r *postgresCartRepository) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]models.CartItem, error) {
 	// Query includes JOIN to get product details (optional, adjust fields as needed)
@@ -90,10 +109,12 @@ func (r *postgresCartRepository) GetCartItems(ctx context.Context, cartID uuid.U
 	// 	ORDER BY ci.created_at ASC
 	// `
 	query := `
-		SELECT id, cart_id, product_id, quantity, price, created_at, updated_at
-		FROM cart_items
-		WHERE cart_id = $1
-		ORDER BY created_at ASC
+		SELECT ci.id, ci.cart_id, ci.product_id, ci.variant_id, ci.quantity, ci.price,
+		       pv.price IS NOT NULL AS variant_priced, ci.created_at, ci.updated_at
+		FROM cart_items ci
+		LEFT JOIN product_variants pv ON pv.id = ci.variant_id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.created_at ASC
 	`
 	rows, err := r.db.Query(ctx, query, cartID)
 	if err != nil {
@@ -109,21 +130,22 @@ func (r *postgresCartRepository) GetCartItems(ctx context.Context, cartID uuid.U
 }
 
 // AddItem adds or updates a product in the cart.
-func (r *postgresCartRepository) AddItem(ctx context.Context, cartID, productID uuid.UUID, quantity int, price float64) (*models.CartItem, error) {
+func (r *postgresCartRepository) AddItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int, price money.Money) (*models.CartItem, error) {
 	query := `
-		INSERT INTO cart_items (cart_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (cart_id, product_id) DO UPDATE SET
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity, price)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ON CONSTRAINT unique_cart_product_variant DO UPDATE SET
 			quantity = cart_items.quantity + EXCLUDED.quantity,
 			price = EXCLUDED.price, -- Update price in case it changed
 			updated_at = NOW()
-		RETURNING id, cart_id, product_id, quantity, price, created_at, updated_at
+		RETURNING id, cart_id, product_id, variant_id, quantity, price, created_at, updated_at
 	`
 	item := &models.CartItem{}
-	err := r.db.QueryRow(ctx, query, cartID, productID, quantity, price).Scan(
+	err := r.db.QueryRow(ctx, query, cartID, productID, variantID, quantity, price).Scan(
 		&item.ID,
 		&item.CartID,
 		&item.ProductID,
+		&item.VariantID,
 		&item.Quantity,
 		&item.Price,
 		&item.CreatedAt,
@@ -138,23 +160,24 @@ func (r *postgresCartRepository) AddItem(ctx context.Context, cartID, productID
 }
 
 // UpdateItemQuantity updates the quantity of a specific item.
-func (r *postgresCartRepository) UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, quantity int) (*models.CartItem, error) {
+func (r *postgresCartRepository) UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int) (*models.CartItem, error) {
 	if quantity <= 0 {
 		// If quantity is zero or less, remove the item instead
-		return nil, r.RemoveItem(ctx, cartID, productID)
+		return nil, r.RemoveItem(ctx, cartID, productID, variantID)
 	}
 
 	query := `
 		UPDATE cart_items
 		SET quantity = $1, updated_at = NOW()
-		WHERE cart_id = $2 AND product_id = $3
-		RETURNING id, cart_id, product_id, quantity, price, created_at, updated_at
+		WHERE cart_id = $2 AND product_id = $3 AND variant_id IS NOT DISTINCT FROM $4
+		RETURNING id, cart_id, product_id, variant_id, quantity, price, created_at, updated_at
 	`
 	item := &models.CartItem{}
-	err := r.db.QueryRow(ctx, query, quantity, cartID, productID).Scan(
+	err := r.db.QueryRow(ctx, query, quantity, cartID, productID, variantID).Scan(
 		&item.ID,
 		&item.CartID,
 		&item.ProductID,
+		&item.VariantID,
 		&item.Quantity,
 		&item.Price,
 		&item.CreatedAt,
@@ -171,9 +194,9 @@ func (r *postgresCartRepository) UpdateItemQuantity(ctx context.Context, cartID,
 }
 
 // RemoveItem deletes an item from the cart.
-func (r *postgresCartRepository) RemoveItem(ctx context.Context, cartID, productID uuid.UUID) error {
-	query := `DELETE FROM cart_items WHERE cart_id = $1 AND product_id = $2`
-	result, err := r.db.Exec(ctx, query, cartID, productID)
+func (r *postgresCartRepository) RemoveItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID) error {
+	query := `DELETE FROM cart_items WHERE cart_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3`
+	result, err := r.db.Exec(ctx, query, cartID, productID, variantID)
 	if err != nil {
 		return err
 	}
@@ -192,17 +215,18 @@ func (r *postgresCartRepository) ClearCart(ctx context.Context, cartID uuid.UUID
 }
 
 // FindCartItem retrieves a specific item from a cart.
-func (r *postgresCartRepository) FindCartItem(ctx context.Context, cartID, productID uuid.UUID) (*models.CartItem, error) {
+func (r *postgresCartRepository) FindCartItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID) (*models.CartItem, error) {
 	query := `
-		SELECT id, cart_id, product_id, quantity, price, created_at, updated_at
+		SELECT id, cart_id, product_id, variant_id, quantity, price, created_at, updated_at
 		FROM cart_items
-		WHERE cart_id = $1 AND product_id = $2
+		WHERE cart_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3
 	`
 	item := &models.CartItem{}
-	err := r.db.QueryRow(ctx, query, cartID, productID).Scan(
+	err := r.db.QueryRow(ctx, query, cartID, productID, variantID).Scan(
 		&item.ID,
 		&item.CartID,
 		&item.ProductID,
+		&item.VariantID,
 		&item.Quantity,
 		&item.Price,
 		&item.CreatedAt,
@@ -2,6 +2,7 @@ package cart
 
 import (
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
 	"context"
 
 	"github.com/google/uuid"
@@ -36,6 +37,29 @@ func (_m *MockCartRepository) GetOrCreateCartByUserID(ctx context.Context, userI
 	return r0, r1
 }
 
+// FindCartByUserID mocks base method
+func (_m *MockCartRepository) FindCartByUserID(ctx context.Context, userID uuid.UUID) (*models.Cart, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.Cart
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.Cart); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Cart)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCartItems mocks base method
 func (_m *MockCartRepository) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]models.CartItem, error) {
 	ret := _m.Called(ctx, cartID)
@@ -60,12 +84,12 @@ func (_m *MockCartRepository) GetCartItems(ctx context.Context, cartID uuid.UUID
 }
 
 // AddItem mocks base method
-func (_m *MockCartRepository) AddItem(ctx context.Context, cartID uuid.UUID, productID uuid.UUID, quantity int, price float64) (*models.CartItem, error) {
-	ret := _m.Called(ctx, cartID, productID, quantity, price)
+func (_m *MockCartRepository) AddItem(ctx context.Context, cartID uuid.UUID, productID uuid.UUID, variantID *uuid.UUID, quantity int, price money.Money) (*models.CartItem, error) {
+	ret := _m.Called(ctx, cartID, productID, variantID, quantity, price)
 
 	var r0 *models.CartItem
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, int, float64) *models.CartItem); ok {
-		r0 = rf(ctx, cartID, productID, quantity, price)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, int, money.Money) *models.CartItem); ok {
+		r0 = rf(ctx, cartID, productID, variantID, quantity, price)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.CartItem)
@@ -73,8 +97,8 @@ func (_m *MockCartRepository) AddItem(ctx context.Context, cartID uuid.UUID, pro
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, int, float64) error); ok {
-		r1 = rf(ctx, cartID, productID, quantity, price)
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, int, money.Money) error); ok {
+		r1 = rf(ctx, cartID, productID, variantID, quantity, price)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -83,12 +107,12 @@ func (_m *MockCartRepository) AddItem(ctx context.Context, cartID uuid.UUID, pro
 }
 
 // UpdateItemQuantity mocks base method
-func (_m *MockCartRepository) UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, quantity int) (*models.CartItem, error) {
-	ret := _m.Called(ctx, cartID, productID, quantity)
+func (_m *MockCartRepository) UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int) (*models.CartItem, error) {
+	ret := _m.Called(ctx, cartID, productID, variantID, quantity)
 
 	var r0 *models.CartItem
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, int) *models.CartItem); ok {
-		r0 = rf(ctx, cartID, productID, quantity)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, int) *models.CartItem); ok {
+		r0 = rf(ctx, cartID, productID, variantID, quantity)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.CartItem)
@@ -96,8 +120,8 @@ func (_m *MockCartRepository) UpdateItemQuantity(ctx context.Context, cartID, pr
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, int) error); ok {
-		r1 = rf(ctx, cartID, productID, quantity)
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, int) error); ok {
+		r1 = rf(ctx, cartID, productID, variantID, quantity)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -144,12 +168,12 @@ func (_m *MockCartRepository) DeleteItem(ctx context.Context, cartID uuid.UUID,
 }
 
 // RemoveItem mocks base method
-func (_m *MockCartRepository) RemoveItem(ctx context.Context, cartID, productID uuid.UUID) error {
-	ret := _m.Called(ctx, cartID, productID)
+func (_m *MockCartRepository) RemoveItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID) error {
+	ret := _m.Called(ctx, cartID, productID, variantID)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
-		r0 = rf(ctx, cartID, productID)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID) error); ok {
+		r0 = rf(ctx, cartID, productID, variantID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -172,12 +196,12 @@ func (_m *MockCartRepository) ClearCart(ctx context.Context, cartID uuid.UUID) e
 }
 
 // FindCartItem mocks base method
-func (_m *MockCartRepository) FindCartItem(ctx context.Context, cartID uuid.UUID, productID uuid.UUID) (*models.CartItem, error) {
-	ret := _m.Called(ctx, cartID, productID)
+func (_m *MockCartRepository) FindCartItem(ctx context.Context, cartID uuid.UUID, productID uuid.UUID, variantID *uuid.UUID) (*models.CartItem, error) {
+	ret := _m.Called(ctx, cartID, productID, variantID)
 
 	var r0 *models.CartItem
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.CartItem); ok {
-		r0 = rf(ctx, cartID, productID)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID) *models.CartItem); ok {
+		r0 = rf(ctx, cartID, productID, variantID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.CartItem)
@@ -185,8 +209,8 @@ func (_m *MockCartRepository) FindCartItem(ctx context.Context, cartID uuid.UUID
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
-		r1 = rf(ctx, cartID, productID)
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID) error); ok {
+		r1 = rf(ctx, cartID, productID, variantID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1,16 +1,83 @@
 package config
 
 import (
+	"bullet-cloud-api/internal/oidc"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Defaults used when the corresponding env vars are unset or invalid.
+const (
+	defaultJWTAccessExpiry  = 15 * time.Minute
+	defaultJWTRefreshExpiry = 7 * 24 * time.Hour
+	defaultMaxLoginAttempts = 5
+	defaultLockoutDuration  = 30 * time.Minute
+	defaultPasswordResetTTL = time.Hour
+	defaultEmailVerifyTTL   = 48 * time.Hour
+	defaultAppBaseURL       = "http://localhost:3000"
+	defaultNotifier         = "log"
+	defaultOutboxDir        = "outbox"
+	defaultJWTAlgorithm     = "HS256"
+	defaultSessionCacheTTL  = 30 * time.Second
+	defaultOIDCScopes       = "openid,email,profile"
+	defaultPasswordHashAlg  = "bcrypt"
+	defaultBcryptCost       = 10    // bcrypt.DefaultCost
+	defaultArgon2Memory     = 19456 // KiB; OWASP's recommended minimum for argon2id
+	defaultArgon2Iterations = 2
+	defaultArgon2Threads    = 1
+	defaultPasswordMinLen   = 8
+	defaultPasswordClasses  = 2
+	defaultExportDir        = "exports"
+	defaultExportLinkTTL    = 24 * time.Hour
+	defaultExportSyncOrders = 50
+	defaultAllocation       = "nearest"
+	defaultLowStockInterval = 15 * time.Minute
+	defaultLowStockWindow   = 30 * 24 * time.Hour
+	defaultTrustedProxies   = 0
+)
+
 // Config holds application configuration.
 type Config struct {
-	DatabaseURL string
-	JWTSecret   string
+	DatabaseURL                     string
+	JWTSecret                       string
+	JWTAlgorithm                    string   // "HS256" (default), "RS256" or "EdDSA"
+	JWTPrivateKeyFile               string   // PEM signing key, required for RS256/EdDSA
+	JWTPublicKeyFiles               []string // Extra PEM public keys still accepted (e.g. the key being rotated out)
+	JWTAccessExpiry                 time.Duration
+	JWTRefreshExpiry                time.Duration
+	PasswordHashAlgorithm           string // "bcrypt" (default) or "argon2id"; hashes of the other kind are upgraded on login
+	BcryptCost                      int
+	Argon2Memory                    int // argon2id memory in KiB
+	Argon2Iterations                int
+	Argon2Parallelism               int
+	PasswordMinLength               int
+	PasswordMinCharClasses          int           // Distinct classes (lowercase, uppercase, digits, symbols) a password must mix
+	CheckBreachedPasswords          bool          // Reject passwords found on the breached password list
+	BreachedPasswordsFile           string        // Replaces the bundled breached password list (SHA-1 hashes or plain passwords, one per line)
+	SessionCacheTTL                 time.Duration // How long session lookups are cached; bounds how late a revocation is seen by other instances
+	TrustedProxies                  int           // Reverse proxies in front of the server whose X-Forwarded-For is trusted for the client IP
+	MaxLoginAttempts                int           // Failed logins (per email or IP) before a lockout
+	LockoutDuration                 time.Duration // How long a lockout lasts
+	PasswordResetTTL                time.Duration // Lifetime of password reset tokens
+	EmailVerifyTTL                  time.Duration // Lifetime of email verification links
+	RequireVerifiedEmailForCheckout bool          // Block order creation until the user's email is verified
+	AppBaseURL                      string        // Frontend URL used to build links sent to users
+	Notifier                        string        // "log" (default), "outbox" or "webhook"
+	OutboxDir                       string        // Directory used by the outbox notifier
+	NotifierWebhookURL              string        // Where the webhook notifier posts messages
+	OIDCProviders                   []oidc.Config // External identity providers users can sign in with
+	ExportDir                       string        // Where personal data exports generated in the background are stored
+	ExportLinkTTL                   time.Duration // How long a background export can be downloaded
+	ExportSyncMaxOrders             int           // Accounts with more orders are exported in the background
+	AllocationStrategy              string        // How orders choose stock locations: "nearest" (default) or "fewest_shipments"
+	LowStockAlertRecipient          string        // Who is told of products below their reorder threshold; no alerts when empty
+	LowStockCheckInterval           time.Duration // How often the stock is checked against the reorder thresholds
+	LowStockSalesWindow             time.Duration // Recent sales used to estimate how many days the stock lasts
 }
 
 // Load loads configuration from environment variables.
@@ -36,7 +103,130 @@ func Load() *Config {
 	}
 
 	return &Config{
-		DatabaseURL: dbURL,
-		JWTSecret:   jwtSecret,
+		DatabaseURL:                     dbURL,
+		JWTSecret:                       jwtSecret,
+		JWTAlgorithm:                    getEnv("JWT_ALGORITHM", defaultJWTAlgorithm),
+		JWTPrivateKeyFile:               os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JWTPublicKeyFiles:               getEnvList("JWT_PUBLIC_KEY_FILES"),
+		JWTAccessExpiry:                 getEnvDuration("JWT_ACCESS_EXPIRY", defaultJWTAccessExpiry),
+		JWTRefreshExpiry:                getEnvDuration("JWT_REFRESH_EXPIRY", defaultJWTRefreshExpiry),
+		PasswordHashAlgorithm:           getEnv("PASSWORD_HASH_ALGORITHM", defaultPasswordHashAlg),
+		BcryptCost:                      getEnvInt("BCRYPT_COST", defaultBcryptCost),
+		Argon2Memory:                    getEnvInt("ARGON2_MEMORY", defaultArgon2Memory),
+		Argon2Iterations:                getEnvInt("ARGON2_ITERATIONS", defaultArgon2Iterations),
+		Argon2Parallelism:               getEnvInt("ARGON2_PARALLELISM", defaultArgon2Threads),
+		PasswordMinLength:               getEnvInt("PASSWORD_MIN_LENGTH", defaultPasswordMinLen),
+		PasswordMinCharClasses:          getEnvInt("PASSWORD_MIN_CHAR_CLASSES", defaultPasswordClasses),
+		CheckBreachedPasswords:          getEnvBool("PASSWORD_BREACH_CHECK", true),
+		BreachedPasswordsFile:           os.Getenv("BREACHED_PASSWORDS_FILE"),
+		SessionCacheTTL:                 getEnvDuration("SESSION_CACHE_TTL", defaultSessionCacheTTL),
+		TrustedProxies:                  getEnvInt("TRUSTED_PROXIES", defaultTrustedProxies),
+		MaxLoginAttempts:                getEnvInt("MAX_LOGIN_ATTEMPTS", defaultMaxLoginAttempts),
+		LockoutDuration:                 getEnvDuration("LOCKOUT_DURATION", defaultLockoutDuration),
+		PasswordResetTTL:                getEnvDuration("PASSWORD_RESET_EXPIRY", defaultPasswordResetTTL),
+		EmailVerifyTTL:                  getEnvDuration("EMAIL_VERIFICATION_EXPIRY", defaultEmailVerifyTTL),
+		RequireVerifiedEmailForCheckout: getEnvBool("REQUIRE_VERIFIED_EMAIL_FOR_CHECKOUT", false),
+		AppBaseURL:                      getEnv("APP_BASE_URL", defaultAppBaseURL),
+		Notifier:                        getEnv("NOTIFIER", defaultNotifier),
+		OutboxDir:                       getEnv("NOTIFIER_OUTBOX_DIR", defaultOutboxDir),
+		NotifierWebhookURL:              os.Getenv("NOTIFIER_WEBHOOK_URL"),
+		OIDCProviders:                   getOIDCProviders(),
+		ExportDir:                       getEnv("EXPORT_DIR", defaultExportDir),
+		ExportLinkTTL:                   getEnvDuration("EXPORT_LINK_EXPIRY", defaultExportLinkTTL),
+		ExportSyncMaxOrders:             getEnvInt("EXPORT_SYNC_MAX_ORDERS", defaultExportSyncOrders),
+		AllocationStrategy:              getEnv("STOCK_ALLOCATION_STRATEGY", defaultAllocation),
+		LowStockAlertRecipient:          os.Getenv("LOW_STOCK_ALERT_TO"),
+		LowStockCheckInterval:           getEnvDuration("LOW_STOCK_CHECK_INTERVAL", defaultLowStockInterval),
+		LowStockSalesWindow:             getEnvDuration("LOW_STOCK_SALES_WINDOW", defaultLowStockWindow),
+	}
+}
+
+// getOIDCProviders reads the providers named in OIDC_PROVIDERS (e.g. "google,keycloak").
+// Each one is configured with OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _SCOPES and _REDIRECT_URL.
+func getOIDCProviders() []oidc.Config {
+	var providers []oidc.Config
+	for _, name := range getEnvList("OIDC_PROVIDERS") {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       getEnvList(prefix + "SCOPES"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			log.Fatalf("OIDC provider %q requires %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = strings.Split(defaultOIDCScopes, ",")
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// getEnv reads a string from the environment, returning the fallback if it is unset.
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList reads a comma-separated list from the environment, skipping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvDuration reads a duration (e.g. "15m", "168h") from the environment.
+// It returns the fallback if the variable is unset or cannot be parsed.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid duration %q for %s, using default %s", value, key, fallback)
+		return fallback
+	}
+	return d
+}
+
+// getEnvBool reads a boolean ("true", "1", "false", ...) from the environment.
+// It returns the fallback if the variable is unset or invalid.
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean %q for %s, using default %t", value, key, fallback)
+		return fallback
+	}
+	return b
+}
+
+// getEnvInt reads a positive integer from the environment.
+// It returns the fallback if the variable is unset or invalid.
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid integer %q for %s, using default %d", value, key, fallback)
+		return fallback
 	}
+	return n
 }
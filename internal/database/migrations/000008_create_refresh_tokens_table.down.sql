@@ -0,0 +1,9 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indices on refresh_tokens
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+
+-- Drop the refresh_tokens table
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Refresh tokens are opaque random strings; only their SHA-256 hash is stored.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    family_id UUID NOT NULL, -- All tokens rotated from the same login share a family
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL, -- Set when the token is rotated, logged out or revoked
+    replaced_by UUID NULL, -- Token issued when this one was rotated
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_refresh_tokens_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE -- If user is deleted, their tokens are deleted
+);
+
+-- Indices for family revocation and per-user lookups
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+-- No policies: only the API (which bypasses RLS) may read or write tokens
+ALTER TABLE refresh_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE refresh_tokens FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,20 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop admin policies
+DROP POLICY IF EXISTS "Allow admin access" ON addresses;
+DROP POLICY IF EXISTS "Allow admin access" ON orders;
+
+-- Restore the catalog policies from migration 000007
+DROP POLICY IF EXISTS "Allow modification for admins" ON categories;
+CREATE POLICY "Allow modification for authenticated users" ON categories FOR ALL
+    USING (auth.role() = 'authenticated')
+    WITH CHECK (auth.role() = 'authenticated');
+
+DROP POLICY IF EXISTS "Allow modification for admins" ON products;
+CREATE POLICY "Allow modification for authenticated users" ON products FOR ALL
+    USING (auth.role() = 'authenticated')
+    WITH CHECK (auth.role() = 'authenticated');
+
+-- Drop the role column
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,31 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Add a role to every user; existing users become customers
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'customer' CHECK (role IN ('customer', 'admin'));
+
+-- Restrict catalog modifications to admins (replaces the policies from migration 000007)
+DROP POLICY IF EXISTS "Allow modification for authenticated users" ON products;
+CREATE POLICY "Allow modification for admins" ON products FOR ALL
+    USING ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') )
+    WITH CHECK ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') );
+
+DROP POLICY IF EXISTS "Allow modification for authenticated users" ON categories;
+CREATE POLICY "Allow modification for admins" ON categories FOR ALL
+    USING ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') )
+    WITH CHECK ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') );
+
+-- Admins can read and update any order (status changes)
+CREATE POLICY "Allow admin access" ON orders FOR ALL
+    USING ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') )
+    WITH CHECK ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') );
+
+-- Admins can manage any user's addresses
+CREATE POLICY "Allow admin access" ON addresses FOR ALL
+    USING ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') )
+    WITH CHECK ( EXISTS (SELECT 1 FROM users WHERE users.id = auth.uid() AND users.role = 'admin') );
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
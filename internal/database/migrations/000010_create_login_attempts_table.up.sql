@@ -0,0 +1,19 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Failed login counters used for brute-force lockouts.
+-- Keys are prefixed with their scope: 'email:<address>' or 'ip:<client ip>'.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ NULL, -- Set once the failure threshold is reached
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW() -- Time of the last failure
+);
+
+-- No policies: only the API (which bypasses RLS) may read or write login attempts
+ALTER TABLE login_attempts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE login_attempts FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
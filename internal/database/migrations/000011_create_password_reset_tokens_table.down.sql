@@ -0,0 +1,8 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indices on password_reset_tokens
+DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
+
+-- Drop the password_reset_tokens table
+DROP TABLE IF EXISTS password_reset_tokens;
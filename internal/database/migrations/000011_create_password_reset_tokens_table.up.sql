@@ -0,0 +1,27 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Password reset tokens are opaque random strings; only their SHA-256 hash is stored.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL, -- Set when the token is consumed or superseded by another reset
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_password_reset_tokens_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE -- If user is deleted, their tokens are deleted
+);
+
+-- Index for invalidating a user's outstanding tokens
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+-- No policies: only the API (which bypasses RLS) may read or write tokens
+ALTER TABLE password_reset_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE password_reset_tokens FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- NULL until the user opens the verification link sent at registration
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ NULL;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
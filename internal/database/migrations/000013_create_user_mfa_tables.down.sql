@@ -0,0 +1,6 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop the two-factor authentication tables
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,40 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- TOTP settings, one row per user. enabled_at stays NULL until enrollment is confirmed.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID PRIMARY KEY,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ NULL,
+    last_used_step BIGINT NULL, -- Last accepted TOTP time step, a code cannot be used twice
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_user_mfa_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE -- If user is deleted, their 2FA settings are deleted
+);
+
+-- Single-use recovery codes; only their SHA-256 hash is stored.
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_mfa_recovery_codes_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT uq_mfa_recovery_codes_user_hash UNIQUE (user_id, code_hash)
+);
+
+-- No policies: only the API (which bypasses RLS) may read or write 2FA secrets
+ALTER TABLE user_mfa ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_mfa FORCE ROW LEVEL SECURITY;
+ALTER TABLE mfa_recovery_codes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE mfa_recovery_codes FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
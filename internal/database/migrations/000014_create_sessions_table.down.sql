@@ -0,0 +1,8 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indices on sessions
+DROP INDEX IF EXISTS idx_sessions_user_id;
+
+-- Drop the sessions table
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,28 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- One row per login. The id is the jti of the login's access tokens and the family_id of its refresh tokens.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ NULL, -- Set when the session is logged out or revoked
+
+    CONSTRAINT fk_sessions_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE -- If user is deleted, their sessions are deleted
+);
+
+-- Index for listing and revoking a user's sessions
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- No policies: only the API (which bypasses RLS) may read or write sessions
+ALTER TABLE sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sessions FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,8 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indices on api_keys
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+
+-- Drop the api_keys table
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Personal API keys. Only the SHA-256 hash of each key is stored.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL, -- Start of the key, shown in listings
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMPTZ NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_api_keys_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE -- If user is deleted, their API keys are deleted
+);
+
+-- Index for listing a user's keys
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+-- No policies: only the API (which bypasses RLS) may read or write API keys
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,8 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indices on user_identities
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+
+-- Drop the user_identities table
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Accounts at external OpenID Connect providers linked to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    provider VARCHAR(50) NOT NULL, -- Configured provider name, e.g. 'google'
+    subject VARCHAR(255) NOT NULL, -- The provider's stable user ID (sub claim)
+    email VARCHAR(255) NOT NULL, -- Verified email reported when the identity was linked
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_user_identities_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE, -- If user is deleted, their linked identities are deleted
+
+    -- A provider account can only be linked to one user
+    CONSTRAINT uq_user_identities_provider_subject UNIQUE (provider, subject)
+);
+
+-- Index for looking up a user's identities
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- No policies: only the API (which bypasses RLS) may read or write linked identities
+ALTER TABLE user_identities ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_identities FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
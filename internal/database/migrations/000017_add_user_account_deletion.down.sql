@@ -0,0 +1,11 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Restore the original cascading foreign key
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS fk_orders_user;
+ALTER TABLE orders
+ADD CONSTRAINT fk_orders_user
+    FOREIGN KEY(user_id) REFERENCES users(id)
+    ON DELETE CASCADE;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
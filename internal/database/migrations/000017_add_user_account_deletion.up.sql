@@ -0,0 +1,19 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Set when the user deletes their account. The row is kept, with its personal data
+-- anonymized, so the user's orders remain in the sales history.
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+-- Orders must outlive their customer: refuse hard deletes of users who have ordered,
+-- instead of silently cascading to their order history
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS fk_orders_user;
+ALTER TABLE orders
+ADD CONSTRAINT fk_orders_user
+    FOREIGN KEY(user_id) REFERENCES users(id)
+    ON DELETE RESTRICT;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,8 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indices on data_exports
+DROP INDEX IF EXISTS idx_data_exports_user_id;
+
+-- Drop the data_exports table
+DROP TABLE IF EXISTS data_exports;
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Personal data exports generated in the background for large accounts
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('json', 'zip')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    file_path TEXT NOT NULL DEFAULT '', -- Set once the file is generated
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ NULL,
+    expires_at TIMESTAMPTZ NULL, -- The file and its download link are removed after this time
+
+    CONSTRAINT fk_data_exports_user
+        FOREIGN KEY(user_id) REFERENCES users(id)
+        ON DELETE CASCADE -- If user is deleted, their exports are deleted
+);
+
+-- Index for looking up a user's exports
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id);
+
+-- No policies: only the API (which bypasses RLS) may read or write data exports
+ALTER TABLE data_exports ENABLE ROW LEVEL SECURITY;
+ALTER TABLE data_exports FORCE ROW LEVEL SECURITY;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,7 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP INDEX IF EXISTS idx_users_created_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
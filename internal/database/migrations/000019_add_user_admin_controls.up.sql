@@ -0,0 +1,17 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Set while an admin has disabled the account; disabled users cannot log in or use their tokens
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ NULL;
+
+-- Set when an admin forces a password reset; cleared once the user chooses a new password
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+-- Index for listing users newest first
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,5 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP INDEX IF EXISTS idx_products_price;
+DROP INDEX IF EXISTS idx_products_created_at_id;
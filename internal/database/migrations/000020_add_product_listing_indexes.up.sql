@@ -0,0 +1,11 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Indices matching the sort orders of the product listing, so each page is an index range scan.
+-- created_at and id break ties in every order (keyset pagination).
+CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_products_price ON products(price, created_at DESC, id DESC);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
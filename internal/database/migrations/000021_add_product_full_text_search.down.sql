@@ -0,0 +1,11 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP INDEX IF EXISTS idx_products_search_vector;
+
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+
+DROP TEXT SEARCH CONFIGURATION IF EXISTS portuguese_unaccent;
+
+-- The unaccent extension is left installed, other objects may depend on it
+-- DROP EXTENSION IF EXISTS unaccent;
@@ -0,0 +1,29 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE EXTENSION IF NOT EXISTS unaccent; -- Needed so "cafe" matches "café"
+
+-- Portuguese stemming ("camisas" matches "camisa") applied to accent-free words
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'portuguese_unaccent') THEN
+        CREATE TEXT SEARCH CONFIGURATION portuguese_unaccent (COPY = portuguese);
+        ALTER TEXT SEARCH CONFIGURATION portuguese_unaccent
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, portuguese_stem;
+    END IF;
+END
+$$;
+
+-- Searchable text of each product, kept up to date by PostgreSQL. The name weighs more (A) than
+-- the description (B) when ranking.
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('portuguese_unaccent'::regconfig, coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('portuguese_unaccent'::regconfig, coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,8 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP INDEX IF EXISTS idx_categories_name_trgm;
+DROP INDEX IF EXISTS idx_products_name_trgm;
+
+-- The pg_trgm extension is left installed, other objects may depend on it
+-- DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,12 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm; -- Trigram similarity, so misspelled words still match
+
+-- Trigram indices serve both prefix matching (ILIKE 'term%') and similarity (<%) on names
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_categories_name_trgm ON categories USING GIN (name gin_trgm_ops);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,15 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP INDEX IF EXISTS idx_order_items_variant_id;
+
+-- Only one line per product can remain in each cart
+DELETE FROM cart_items WHERE variant_id IS NOT NULL;
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS unique_cart_product_variant;
+ALTER TABLE cart_items ADD CONSTRAINT unique_cart_product UNIQUE (cart_id, product_id);
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS variant_id;
+
+DROP TRIGGER IF EXISTS update_product_variants_updated_at ON product_variants;
+DROP TABLE IF EXISTS product_variants;
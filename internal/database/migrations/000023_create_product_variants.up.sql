@@ -0,0 +1,47 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Variants are the purchasable versions of a product (e.g. each size and color of a T-shirt)
+CREATE TABLE IF NOT EXISTS product_variants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}', -- Option name to value, e.g. {"size": "M", "color": "Azul"}
+    price NUMERIC(10, 2) CHECK (price >= 0), -- NULL uses the product price
+    barcode VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_product_variants_product
+        FOREIGN KEY(product_id) REFERENCES products(id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT unique_product_variants_sku UNIQUE (sku),
+    CONSTRAINT unique_product_variants_barcode UNIQUE (barcode),
+    -- Each combination of option values is sold once per product
+    CONSTRAINT unique_product_variants_options UNIQUE (product_id, options)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+
+-- Trigger for updated_at on product_variants
+CREATE TRIGGER update_product_variants_updated_at
+BEFORE UPDATE ON product_variants
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Cart and order items of products with variants name the variant bought
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS variant_id UUID
+    CONSTRAINT fk_cart_items_variant REFERENCES product_variants(id) ON DELETE CASCADE;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id UUID
+    CONSTRAINT fk_order_items_variant REFERENCES product_variants(id) ON DELETE RESTRICT;
+
+-- A cart holds each variant once (and a product without variants once)
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS unique_cart_product;
+ALTER TABLE cart_items ADD CONSTRAINT unique_cart_product_variant UNIQUE NULLS NOT DISTINCT (cart_id, product_id, variant_id);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_variant_id ON order_items(variant_id);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,5 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+ALTER TABLE product_variants DROP COLUMN IF EXISTS stock;
+ALTER TABLE products DROP COLUMN IF EXISTS stock;
@@ -0,0 +1,14 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Units available for sale. NULL means the stock is not tracked and sales are unlimited,
+-- which keeps existing products on sale until an admin sets their stock.
+-- Products sold in variants are stocked by variant.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock INTEGER
+    CONSTRAINT check_products_stock CHECK (stock >= 0);
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS stock INTEGER
+    CONSTRAINT check_product_variants_stock CHECK (stock >= 0);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,29 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Stock goes back to a single total per product and variant
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock INTEGER
+    CONSTRAINT check_products_stock CHECK (stock >= 0);
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS stock INTEGER
+    CONSTRAINT check_product_variants_stock CHECK (stock >= 0);
+
+UPDATE products p
+SET stock = s.total
+FROM (SELECT product_id, SUM(quantity) AS total FROM stock_levels WHERE variant_id IS NULL GROUP BY product_id) s
+WHERE p.id = s.product_id;
+
+UPDATE product_variants v
+SET stock = s.total
+FROM (SELECT variant_id, SUM(quantity) AS total FROM stock_levels WHERE variant_id IS NOT NULL GROUP BY variant_id) s
+WHERE v.id = s.variant_id;
+
+-- Order items split across locations stay split
+DROP INDEX IF EXISTS idx_order_items_location_id;
+ALTER TABLE order_items DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS stock_transfers;
+DROP TRIGGER IF EXISTS update_stock_levels_updated_at ON stock_levels;
+DROP TABLE IF EXISTS stock_levels;
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS unique_product_variants_id_product;
+DROP TRIGGER IF EXISTS update_stock_locations_updated_at ON stock_locations;
+DROP TABLE IF EXISTS stock_locations;
@@ -0,0 +1,110 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Warehouses and other places stock is kept and shipped from
+CREATE TABLE IF NOT EXISTS stock_locations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    street TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL, -- Orders are allocated to the locations nearest to their shipping state
+    postal_code TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_stock_locations_name UNIQUE (name)
+);
+
+CREATE TRIGGER update_stock_locations_updated_at
+BEFORE UPDATE ON stock_locations
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Lets stock_levels check that a variant belongs to the product it is stocked under
+ALTER TABLE product_variants ADD CONSTRAINT unique_product_variants_id_product UNIQUE (id, product_id);
+
+-- Units of a product, or of one of its variants, at each location. A product or variant
+-- without any row is not tracked: its sales are unlimited.
+CREATE TABLE IF NOT EXISTS stock_levels (
+    location_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    variant_id UUID, -- Set for products sold in variants
+    quantity INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_stock_levels_quantity CHECK (quantity >= 0),
+
+    CONSTRAINT fk_stock_levels_location
+        FOREIGN KEY(location_id) REFERENCES stock_locations(id)
+        ON DELETE CASCADE, -- Only locations left without units can be deleted
+
+    CONSTRAINT fk_stock_levels_product
+        FOREIGN KEY(product_id) REFERENCES products(id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_stock_levels_variant
+        FOREIGN KEY(variant_id, product_id) REFERENCES product_variants(id, product_id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT unique_stock_levels_item UNIQUE NULLS NOT DISTINCT (location_id, product_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_levels_product_variant ON stock_levels(product_id, variant_id);
+CREATE INDEX IF NOT EXISTS idx_stock_levels_variant_id ON stock_levels(variant_id);
+
+CREATE TRIGGER update_stock_levels_updated_at
+BEFORE UPDATE ON stock_levels
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- History of the units moved between locations
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    from_location_id UUID NOT NULL,
+    to_location_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    variant_id UUID,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    note TEXT NOT NULL DEFAULT '',
+    created_by UUID, -- The admin who made the transfer
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_stock_transfers_locations CHECK (from_location_id <> to_location_id),
+    CONSTRAINT fk_stock_transfers_from FOREIGN KEY(from_location_id) REFERENCES stock_locations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_stock_transfers_to FOREIGN KEY(to_location_id) REFERENCES stock_locations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_stock_transfers_product FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE,
+    CONSTRAINT fk_stock_transfers_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_stock_transfers_user FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_created_at ON stock_transfers(created_at);
+
+-- The location each order item ships from; NULL for items whose stock is not tracked.
+-- An item taken from several locations is split into one order item per location.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS location_id UUID
+    CONSTRAINT fk_order_items_location REFERENCES stock_locations(id) ON DELETE RESTRICT;
+CREATE INDEX IF NOT EXISTS idx_order_items_location_id ON order_items(location_id);
+
+-- Move the stock tracked so far to a first location, whose address admins complete later
+INSERT INTO stock_locations (name, state)
+SELECT 'Estoque principal', ''
+WHERE EXISTS (SELECT 1 FROM products WHERE stock IS NOT NULL)
+   OR EXISTS (SELECT 1 FROM product_variants WHERE stock IS NOT NULL);
+
+INSERT INTO stock_levels (location_id, product_id, variant_id, quantity)
+SELECT l.id, p.id, NULL, p.stock
+FROM products p, stock_locations l
+WHERE p.stock IS NOT NULL AND l.name = 'Estoque principal';
+
+INSERT INTO stock_levels (location_id, product_id, variant_id, quantity)
+SELECT l.id, v.product_id, v.id, v.stock
+FROM product_variants v, stock_locations l
+WHERE v.stock IS NOT NULL AND l.name = 'Estoque principal';
+
+ALTER TABLE products DROP COLUMN IF EXISTS stock;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS stock;
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,6 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP INDEX IF EXISTS idx_orders_created_at;
+ALTER TABLE products DROP COLUMN IF EXISTS low_stock_alerted_at;
+ALTER TABLE products DROP COLUMN IF EXISTS reorder_threshold;
@@ -0,0 +1,18 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- A product is low on stock when its units at all locations fall below its reorder
+-- threshold. NULL disables the alerts for the product.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_threshold INTEGER
+    CONSTRAINT check_products_reorder_threshold CHECK (reorder_threshold >= 0);
+
+-- When purchasing was last told the product is low on stock; cleared once the stock is
+-- back at the threshold, so each shortage is reported once.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS low_stock_alerted_at TIMESTAMPTZ;
+
+-- Sales velocity sums the recent order items of each product
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,11 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Orders paid in other currencies keep their amounts, now read as BRL
+ALTER TABLE orders DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE orders DROP COLUMN IF EXISTS currency;
+
+DROP TRIGGER IF EXISTS update_product_prices_updated_at ON product_prices;
+DROP TABLE IF EXISTS product_prices;
+DROP TRIGGER IF EXISTS update_exchange_rates_updated_at ON exchange_rates;
+DROP TABLE IF EXISTS exchange_rates;
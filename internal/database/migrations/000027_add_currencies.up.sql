@@ -0,0 +1,56 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Currencies the store can price in besides its own (BRL), maintained by admins
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) PRIMARY KEY, -- ISO 4217 code
+    rate NUMERIC(18, 8) NOT NULL, -- Units of the currency worth one unit of the store currency
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_exchange_rates_rate CHECK (rate > 0)
+);
+
+CREATE TRIGGER update_exchange_rates_updated_at
+BEFORE UPDATE ON exchange_rates
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Price lists: the price of a product, or of one of its variants, set in a currency instead
+-- of converted from the store currency
+CREATE TABLE IF NOT EXISTS product_prices (
+    product_id UUID NOT NULL,
+    variant_id UUID, -- NULL for the product, and its variants without a price of their own
+    currency CHAR(3) NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_product_prices_price CHECK (price > 0),
+
+    CONSTRAINT fk_product_prices_product
+        FOREIGN KEY(product_id) REFERENCES products(id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_product_prices_variant
+        FOREIGN KEY(variant_id, product_id) REFERENCES product_variants(id, product_id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT unique_product_prices_item UNIQUE NULLS NOT DISTINCT (product_id, variant_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_prices_variant_id ON product_prices(variant_id);
+
+CREATE TRIGGER update_product_prices_updated_at
+BEFORE UPDATE ON product_prices
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- The currency an order was paid in, and the exchange rate used at checkout. Its total and
+-- item prices are in that currency, so later rate changes never alter it.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(18, 8) NOT NULL DEFAULT 1
+    CONSTRAINT check_orders_exchange_rate CHECK (exchange_rate > 0);
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
@@ -0,0 +1,4 @@
+-- +migrate Down
+-- SQL in this section is executed when the migration is rolled back.
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_changed_at;
@@ -0,0 +1,11 @@
+-- +migrate Up
+-- SQL in this section is executed when the migration is applied.
+
+-- When the email was last set; verification links carry it and stop working once it changes,
+-- even if the user later goes back to the same address
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS email_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+
+-- +migrate Down
+-- SQL section moved to the .down.sql file
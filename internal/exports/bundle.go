@@ -0,0 +1,198 @@
+package exports
+
+import (
+	"archive/zip"
+	"bullet-cloud-api/internal/addresses"
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/cart"
+	"bullet-cloud-api/internal/identities"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/orders"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/users"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Supported export formats.
+const (
+	FormatJSON = "json" // A single JSON document
+	FormatZIP  = "zip"  // One JSON file per section
+)
+
+// Bundle is everything the application stores about a user, as handed over in a data export.
+type Bundle struct {
+	ExportedAt time.Time             `json:"exported_at"`
+	User       *models.User          `json:"user"`
+	Addresses  []models.Address      `json:"addresses"`
+	Cart       CartExport            `json:"cart"`
+	Orders     []OrderExport         `json:"orders"`
+	Sessions   []models.Session      `json:"sessions"`   // Login history, including revoked sessions
+	APIKeys    []models.APIKey       `json:"api_keys"`   // Key metadata; the keys themselves are never stored
+	Identities []models.UserIdentity `json:"identities"` // Linked external sign-in accounts
+}
+
+// CartExport is the user's cart with its items.
+type CartExport struct {
+	Cart  *models.Cart      `json:"cart"` // null for users without a cart
+	Items []models.CartItem `json:"items"`
+}
+
+// OrderExport is one order with its items.
+type OrderExport struct {
+	Order models.Order       `json:"order"`
+	Items []models.OrderItem `json:"items"`
+}
+
+// Collector gathers a user's data from the repositories.
+type Collector struct {
+	UserRepo     users.UserRepository
+	AddressRepo  addresses.AddressRepository
+	CartRepo     cart.CartRepository
+	OrderRepo    orders.OrderRepository
+	SessionRepo  sessions.SessionRepository
+	APIKeyRepo   apikeys.APIKeyRepository
+	IdentityRepo identities.IdentityRepository
+}
+
+// NewCollector creates a new Collector.
+func NewCollector(userRepo users.UserRepository, addressRepo addresses.AddressRepository, cartRepo cart.CartRepository, orderRepo orders.OrderRepository, sessionRepo sessions.SessionRepository, apiKeyRepo apikeys.APIKeyRepository, identityRepo identities.IdentityRepository) *Collector {
+	return &Collector{
+		UserRepo:     userRepo,
+		AddressRepo:  addressRepo,
+		CartRepo:     cartRepo,
+		OrderRepo:    orderRepo,
+		SessionRepo:  sessionRepo,
+		APIKeyRepo:   apiKeyRepo,
+		IdentityRepo: identityRepo,
+	}
+}
+
+// CountOrders returns how many orders the user has, which dominates the size of an export.
+func (c *Collector) CountOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	orderList, err := c.OrderRepo.FindUserOrders(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return len(orderList), nil
+}
+
+// Collect reads all of the user's data.
+func (c *Collector) Collect(ctx context.Context, userID uuid.UUID) (*Bundle, error) {
+	bundle := &Bundle{ExportedAt: time.Now().UTC()}
+	var err error
+
+	if bundle.User, err = c.UserRepo.FindByID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("loading user: %w", err)
+	}
+	bundle.User.PasswordHash = ""
+
+	if bundle.Addresses, err = c.AddressRepo.FindByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("loading addresses: %w", err)
+	}
+
+	// Exporting must not create a cart; users who never had one get none
+	bundle.Cart.Items = []models.CartItem{}
+	bundle.Cart.Cart, err = c.CartRepo.FindCartByUserID(ctx, userID)
+	switch {
+	case errors.Is(err, cart.ErrCartNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("loading cart: %w", err)
+	default:
+		if bundle.Cart.Items, err = c.CartRepo.GetCartItems(ctx, bundle.Cart.Cart.ID); err != nil {
+			return nil, fmt.Errorf("loading cart items: %w", err)
+		}
+	}
+
+	orderList, err := c.OrderRepo.FindUserOrders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("loading orders: %w", err)
+	}
+	bundle.Orders = make([]OrderExport, 0, len(orderList))
+	for _, order := range orderList {
+		_, items, err := c.OrderRepo.FindOrderByID(ctx, order.ID)
+		if err != nil {
+			return nil, fmt.Errorf("loading items of order %s: %w", order.ID, err)
+		}
+		bundle.Orders = append(bundle.Orders, OrderExport{Order: order, Items: items})
+	}
+
+	if bundle.Sessions, err = c.SessionRepo.ListByUser(ctx, userID); err != nil {
+		return nil, fmt.Errorf("loading sessions: %w", err)
+	}
+	if bundle.APIKeys, err = c.APIKeyRepo.ListByUser(ctx, userID); err != nil {
+		return nil, fmt.Errorf("loading API keys: %w", err)
+	}
+	if bundle.Identities, err = c.IdentityRepo.ListByUser(ctx, userID); err != nil {
+		return nil, fmt.Errorf("loading linked identities: %w", err)
+	}
+
+	return bundle, nil
+}
+
+// IsValidFormat reports whether format is one of the supported export formats.
+func IsValidFormat(format string) bool {
+	return format == FormatJSON || format == FormatZIP
+}
+
+// ContentType returns the media type of an export in the given format.
+func ContentType(format string) string {
+	if format == FormatZIP {
+		return "application/zip"
+	}
+	return "application/json"
+}
+
+// FileName returns the name offered for download, e.g. "bullet-cloud-export-20250102.zip".
+func FileName(format string, exportedAt time.Time) string {
+	return fmt.Sprintf("bullet-cloud-export-%s.%s", exportedAt.UTC().Format("20060102"), format)
+}
+
+// Write encodes the bundle in the given format.
+func Write(w io.Writer, bundle *Bundle, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(bundle)
+	case FormatZIP:
+		return writeZIP(w, bundle)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// writeZIP writes each section of the bundle as its own JSON file.
+func writeZIP(w io.Writer, bundle *Bundle) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"user.json", bundle.User},
+		{"addresses.json", bundle.Addresses},
+		{"cart.json", bundle.Cart},
+		{"orders.json", bundle.Orders},
+		{"sessions.json", bundle.Sessions},
+		{"api_keys.json", bundle.APIKeys},
+		{"identities.json", bundle.Identities},
+	}
+	for _, file := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: bundle.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,189 @@
+package exports
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrExportNotFound = errors.New("data export not found")
+)
+
+// ExportRepository defines the interface for background data export operations.
+type ExportRepository interface {
+	// Create records a pending export for the user.
+	Create(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error)
+	// FindByID returns an export, or ErrExportNotFound (also when its user has deleted the account).
+	FindByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error)
+	// FindPendingByUser returns the user's export still being generated, or ErrExportNotFound.
+	FindPendingByUser(ctx context.Context, userID uuid.UUID) (*models.DataExport, error)
+	// ListPending returns every export still being generated for an account that exists, oldest first.
+	ListPending(ctx context.Context) ([]models.DataExport, error)
+	// MarkReady records the generated file of a pending export and when it expires, or returns
+	// ErrExportNotFound when the export is gone or its user has deleted the account.
+	MarkReady(ctx context.Context, id uuid.UUID, filePath string, sizeBytes int64, expiresAt time.Time) error
+	// MarkFailed records that a pending export could not be generated.
+	MarkFailed(ctx context.Context, id uuid.UUID) error
+	// DeleteExpired removes expired exports and those of deleted accounts, returning them so their files can be removed.
+	DeleteExpired(ctx context.Context) ([]models.DataExport, error)
+	// DeleteForUser removes every export of the user, returning them so their files can be removed.
+	DeleteForUser(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error)
+}
+
+// postgresExportRepository implements ExportRepository using PostgreSQL.
+type postgresExportRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresExportRepository creates a new instance of postgresExportRepository.
+func NewPostgresExportRepository(db *pgxpool.Pool) ExportRepository {
+	return &postgresExportRepository{db: db}
+}
+
+// exportColumns lists the columns read by scanExport, in order.
+const exportColumns = "id, user_id, format, status, file_path, size_bytes, created_at, completed_at, expires_at"
+
+// scanExport scans a row selected with exportColumns.
+func scanExport(row pgx.Row) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	err := row.Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Format,
+		&export.Status,
+		&export.FilePath,
+		&export.SizeBytes,
+		&export.CreatedAt,
+		&export.CompletedAt,
+		&export.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// Create inserts a new pending export.
+func (r *postgresExportRepository) Create(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error) {
+	query := `
+		INSERT INTO data_exports (user_id, format)
+		VALUES ($1, $2)
+		RETURNING ` + exportColumns
+	return scanExport(r.db.QueryRow(ctx, query, userID, format))
+}
+
+// FindByID retrieves an export of an account that still exists.
+func (r *postgresExportRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM data_exports
+		WHERE id = $1 AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+	`
+	return scanExport(r.db.QueryRow(ctx, query, id))
+}
+
+// FindPendingByUser retrieves the user's most recent pending export.
+func (r *postgresExportRepository) FindPendingByUser(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM data_exports
+		WHERE user_id = $1 AND status = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	return scanExport(r.db.QueryRow(ctx, query, userID, models.ExportPending))
+}
+
+// ListPending retrieves the exports that have not been generated yet.
+func (r *postgresExportRepository) ListPending(ctx context.Context) ([]models.DataExport, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM data_exports
+		WHERE status = $1 AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+		ORDER BY created_at
+	`
+	return r.list(ctx, query, models.ExportPending)
+}
+
+// MarkReady completes a pending export of an account that still exists.
+func (r *postgresExportRepository) MarkReady(ctx context.Context, id uuid.UUID, filePath string, sizeBytes int64, expiresAt time.Time) error {
+	query := `
+		UPDATE data_exports
+		SET status = $1, file_path = $2, size_bytes = $3, completed_at = NOW(), expires_at = $4
+		WHERE id = $5 AND status = $6 AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+	`
+	result, err := r.db.Exec(ctx, query, models.ExportReady, filePath, sizeBytes, expiresAt, id, models.ExportPending)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrExportNotFound
+	}
+	return nil
+}
+
+// MarkFailed fails a pending export.
+func (r *postgresExportRepository) MarkFailed(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE data_exports
+		SET status = $1, completed_at = NOW()
+		WHERE id = $2 AND status = $3
+	`
+	result, err := r.db.Exec(ctx, query, models.ExportFailed, id, models.ExportPending)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrExportNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes exports past their expiry, failed exports older than a day and
+// every export of a deleted account.
+func (r *postgresExportRepository) DeleteExpired(ctx context.Context) ([]models.DataExport, error) {
+	query := `
+		DELETE FROM data_exports
+		WHERE expires_at < NOW()
+			OR (status = $1 AND created_at < NOW() - INTERVAL '1 day')
+			OR user_id IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)
+		RETURNING ` + exportColumns
+	return r.list(ctx, query, models.ExportFailed)
+}
+
+// DeleteForUser removes all of the user's exports, whatever their status.
+func (r *postgresExportRepository) DeleteForUser(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	query := `
+		DELETE FROM data_exports
+		WHERE user_id = $1
+		RETURNING ` + exportColumns
+	return r.list(ctx, query, userID)
+}
+
+// list runs a query selecting exportColumns and collects the rows.
+func (r *postgresExportRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.DataExport, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.DataExport{}
+	for rows.Next() {
+		export, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *export)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,181 @@
+package exports
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportRepository is a mock type for the ExportRepository interface
+type MockExportRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, userID, format
+func (_m *MockExportRepository) Create(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error) {
+	ret := _m.Called(ctx, userID, format)
+
+	var r0 *models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) *models.DataExport); ok {
+		r0 = rf(ctx, userID, format)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, userID, format)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByID provides a mock function with given fields: ctx, id
+func (_m *MockExportRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.DataExport); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindPendingByUser provides a mock function with given fields: ctx, userID
+func (_m *MockExportRepository) FindPendingByUser(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.DataExport); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPending provides a mock function with given fields: ctx
+func (_m *MockExportRepository) ListPending(ctx context.Context) ([]models.DataExport, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context) []models.DataExport); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkReady provides a mock function with given fields: ctx, id, filePath, sizeBytes, expiresAt
+func (_m *MockExportRepository) MarkReady(ctx context.Context, id uuid.UUID, filePath string, sizeBytes int64, expiresAt time.Time) error {
+	ret := _m.Called(ctx, id, filePath, sizeBytes, expiresAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, int64, time.Time) error); ok {
+		r0 = rf(ctx, id, filePath, sizeBytes, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkFailed provides a mock function with given fields: ctx, id
+func (_m *MockExportRepository) MarkFailed(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpired provides a mock function with given fields: ctx
+func (_m *MockExportRepository) DeleteExpired(ctx context.Context) ([]models.DataExport, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context) []models.DataExport); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteForUser provides a mock function with given fields: ctx, userID
+func (_m *MockExportRepository) DeleteForUser(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.DataExport); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
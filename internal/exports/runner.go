@@ -0,0 +1,164 @@
+package exports
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/users"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// jobTimeout bounds the generation of a single export.
+const jobTimeout = 10 * time.Minute
+
+// Runner generates exports in the background and removes them once they expire.
+type Runner struct {
+	Collector *Collector
+	Repo      ExportRepository
+	Dir       string        // Where export files are written
+	TTL       time.Duration // How long a generated export can be downloaded
+
+	slots chan struct{} // Bounds how many exports are generated at once
+}
+
+// NewRunner creates a Runner that writes into dir (creating it if needed) and generates at
+// most workers exports concurrently.
+func NewRunner(collector *Collector, repo ExportRepository, dir string, ttl time.Duration, workers int) (*Runner, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating export directory: %w", err)
+	}
+	return &Runner{
+		Collector: collector,
+		Repo:      repo,
+		Dir:       dir,
+		TTL:       ttl,
+		slots:     make(chan struct{}, max(workers, 1)),
+	}, nil
+}
+
+// Enqueue generates a pending export in the background.
+func (r *Runner) Enqueue(export *models.DataExport) {
+	job := *export
+	go func() {
+		r.slots <- struct{}{}
+		defer func() { <-r.slots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+		defer cancel()
+
+		if err := r.generate(ctx, &job); err != nil {
+			if errors.Is(err, users.ErrUserNotFound) || errors.Is(err, ErrExportNotFound) {
+				// The account was deleted, and its exports with it; nothing is kept
+				log.Printf("Dropped data export %s: the account was deleted", job.ID)
+				return
+			}
+			log.Printf("Error generating data export %s: %v", job.ID, err)
+			if err := r.Repo.MarkFailed(ctx, job.ID); err != nil {
+				log.Printf("Error marking data export %s as failed: %v", job.ID, err)
+			}
+		}
+	}()
+}
+
+// Resume enqueues the exports left pending by a previous run of the application.
+func (r *Runner) Resume(ctx context.Context) error {
+	pending, err := r.Repo.ListPending(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		r.Enqueue(&pending[i])
+	}
+	return nil
+}
+
+// RunCleanup deletes expired exports and their files every interval until ctx is cancelled.
+func (r *Runner) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteForUser deletes every export of a user and their files, when the account is deleted.
+// An export still being generated is dropped by generate once it finds the export gone.
+func (r *Runner) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	deleted, err := r.Repo.DeleteForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	removeFiles(deleted)
+	return nil
+}
+
+func (r *Runner) cleanup(ctx context.Context) {
+	expired, err := r.Repo.DeleteExpired(ctx)
+	if err != nil {
+		log.Printf("Error deleting expired data exports: %v", err)
+		return
+	}
+	removeFiles(expired)
+}
+
+// removeFiles removes the generated files of deleted exports.
+func removeFiles(deleted []models.DataExport) {
+	for _, export := range deleted {
+		if export.FilePath == "" {
+			continue
+		}
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing data export file %s: %v", export.FilePath, err)
+		}
+	}
+}
+
+// generate writes the export file and marks the export ready. The file is written under a
+// temporary name first, so a partial file is never served, and removed if the account was
+// deleted meanwhile (MarkReady then fails).
+func (r *Runner) generate(ctx context.Context, export *models.DataExport) error {
+	bundle, err := r.Collector.Collect(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(r.Dir, export.ID.String()+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err := Write(tmp, bundle, export.Format); err != nil {
+		tmp.Close()
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	path := filepath.Join(r.Dir, export.ID.String()+"."+export.Format)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if err := r.Repo.MarkReady(ctx, export.ID, path, info.Size(), time.Now().Add(r.TTL)); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
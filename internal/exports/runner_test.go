@@ -0,0 +1,56 @@
+package exports
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_DeleteForUser(t *testing.T) {
+	userID := uuid.New()
+
+	// newRunner returns a Runner writing into a temporary directory, with one generated file in it.
+	newRunner := func(t *testing.T) (*Runner, *MockExportRepository, string) {
+		t.Helper()
+		repo := new(MockExportRepository)
+		runner, err := NewRunner(nil, repo, t.TempDir(), time.Hour, 1)
+		require.NoError(t, err)
+
+		path := filepath.Join(runner.Dir, uuid.NewString()+"."+FormatJSON)
+		require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o600))
+		return runner, repo, path
+	}
+
+	t.Run("Rows And Files Are Deleted", func(t *testing.T) {
+		runner, repo, path := newRunner(t)
+		repo.On("DeleteForUser", mock.Anything, userID).Return([]models.DataExport{
+			{ID: uuid.New(), UserID: userID, Status: models.ExportReady, FilePath: path},
+			{ID: uuid.New(), UserID: userID, Status: models.ExportPending}, // Not generated yet
+			{ID: uuid.New(), UserID: userID, Status: models.ExportReady, FilePath: filepath.Join(runner.Dir, "already-removed.zip")},
+		}, nil).Once()
+
+		require.NoError(t, runner.DeleteForUser(context.Background(), userID))
+
+		assert.NoFileExists(t, path)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Files Are Kept When The Rows Are Not Deleted", func(t *testing.T) {
+		runner, repo, path := newRunner(t)
+		repo.On("DeleteForUser", mock.Anything, userID).Return(nil, assert.AnError).Once()
+
+		err := runner.DeleteForUser(context.Background(), userID)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.FileExists(t, path)
+		repo.AssertExpectations(t)
+	})
+}
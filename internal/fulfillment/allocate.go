@@ -0,0 +1,221 @@
+// Package fulfillment decides which stock locations the items of an order ship from.
+package fulfillment
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Strategy decides which stock locations an order ships from.
+type Strategy string
+
+const (
+	// StrategyNearest takes each item from the locations nearest to the shipping address.
+	StrategyNearest Strategy = "nearest"
+	// StrategyFewestShipments ships from as few locations as possible, the nearest among equals.
+	StrategyFewestShipments Strategy = "fewest_shipments"
+)
+
+// ParseStrategy validates the name of a Strategy.
+func ParseStrategy(name string) (Strategy, error) {
+	switch s := Strategy(name); s {
+	case StrategyNearest, StrategyFewestShipments:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown stock allocation strategy %q (expected %q or %q)", name, StrategyNearest, StrategyFewestShipments)
+}
+
+// Location is a stock location an order may ship from.
+type Location struct {
+	ID    uuid.UUID
+	Name  string
+	State string
+}
+
+// Demand is an order item to allocate, with the units of it at each location.
+type Demand struct {
+	Quantity int
+	Stock    map[uuid.UUID]int
+}
+
+// Pick is a quantity of demands[Item] taken from a location.
+type Pick struct {
+	Item       int
+	LocationID uuid.UUID
+	Quantity   int
+}
+
+// maxExactLocations bounds the candidate locations for which StrategyFewestShipments tries
+// every combination; with more of them, it adds locations greedily.
+const maxExactLocations = 12
+
+// Allocate splits each demand across the locations holding it, following strategy for an
+// order shipped to shipState. Within the locations chosen, the nearest are emptied first.
+// When the locations lack the units of any demand, it returns no picks and the indices of
+// those demands.
+func Allocate(strategy Strategy, shipState string, locations []Location, demands []Demand) ([]Pick, []int) {
+	ranked := rankLocations(shipState, locations)
+
+	var short []int
+	for i, d := range demands {
+		if available(d, ranked) < d.Quantity {
+			short = append(short, i)
+		}
+	}
+	if len(short) > 0 {
+		return nil, short
+	}
+
+	if strategy == StrategyFewestShipments {
+		ranked = fewestLocations(ranked, demands)
+	}
+	return pickNearest(ranked, demands), nil
+}
+
+// rankLocations sorts the locations nearest to shipState first, then by name.
+func rankLocations(shipState string, locations []Location) []Location {
+	ranked := append([]Location(nil), locations...)
+	distances := make(map[uuid.UUID]float64, len(ranked))
+	for _, l := range ranked {
+		distances[l.ID] = stateDistance(shipState, l.State)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		di, dj := distances[ranked[i].ID], distances[ranked[j].ID]
+		if di != dj {
+			return di < dj
+		}
+		if ranked[i].Name != ranked[j].Name {
+			return ranked[i].Name < ranked[j].Name
+		}
+		return ranked[i].ID.String() < ranked[j].ID.String()
+	})
+	return ranked
+}
+
+// available returns the units of d held by the locations.
+func available(d Demand, locations []Location) int {
+	total := 0
+	for _, l := range locations {
+		total += d.Stock[l.ID]
+	}
+	return total
+}
+
+// pickNearest takes each demand from the locations in order.
+func pickNearest(locations []Location, demands []Demand) []Pick {
+	var picks []Pick
+	for i, d := range demands {
+		remaining := d.Quantity
+		for _, l := range locations {
+			if remaining == 0 {
+				break
+			}
+			if take := min(remaining, d.Stock[l.ID]); take > 0 {
+				picks = append(picks, Pick{Item: i, LocationID: l.ID, Quantity: take})
+				remaining -= take
+			}
+		}
+	}
+	return picks
+}
+
+// fewestLocations returns the smallest set of the ranked locations holding every demand,
+// preferring the nearest sets, in ranked order.
+func fewestLocations(ranked []Location, demands []Demand) []Location {
+	var candidates []Location
+	for _, l := range ranked {
+		for _, d := range demands {
+			if d.Stock[l.ID] > 0 {
+				candidates = append(candidates, l)
+				break
+			}
+		}
+	}
+	if len(candidates) > maxExactLocations {
+		return greedyLocations(candidates, demands)
+	}
+
+	// Try every combination: the fewest locations win, then the lowest sum of ranks
+	bestMask, bestCount, bestCost := uint(0), len(candidates)+1, 0
+	for mask := uint(1); mask < 1<<len(candidates); mask++ {
+		count := bits.OnesCount(mask)
+		if count > bestCount {
+			continue
+		}
+		cost := 0
+		var chosen []Location
+		for i, l := range candidates {
+			if mask&(1<<i) != 0 {
+				cost += i
+				chosen = append(chosen, l)
+			}
+		}
+		if count == bestCount && cost >= bestCost {
+			continue
+		}
+		if coversAll(chosen, demands) {
+			bestMask, bestCount, bestCost = mask, count, cost
+		}
+	}
+
+	var best []Location
+	for i, l := range candidates {
+		if bestMask&(1<<i) != 0 {
+			best = append(best, l)
+		}
+	}
+	return best
+}
+
+// greedyLocations adds, until every demand is held, the location holding the most of the
+// units still missing, the nearest among equals.
+func greedyLocations(candidates []Location, demands []Demand) []Location {
+	missing := make([]int, len(demands))
+	for i, d := range demands {
+		missing[i] = d.Quantity
+	}
+	chosen := make(map[uuid.UUID]bool)
+	for {
+		best, bestUnits := -1, 0
+		for i, l := range candidates {
+			if chosen[l.ID] {
+				continue
+			}
+			units := 0
+			for j, d := range demands {
+				units += min(missing[j], d.Stock[l.ID])
+			}
+			if units > bestUnits {
+				best, bestUnits = i, units
+			}
+		}
+		if best < 0 {
+			break // Nothing is missing
+		}
+		chosen[candidates[best].ID] = true
+		for j, d := range demands {
+			missing[j] -= min(missing[j], d.Stock[candidates[best].ID])
+		}
+	}
+
+	var result []Location
+	for _, l := range candidates {
+		if chosen[l.ID] {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// coversAll reports whether the locations hold every demand.
+func coversAll(locations []Location, demands []Demand) bool {
+	for _, d := range demands {
+		if available(d, locations) < d.Quantity {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package fulfillment
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocate(t *testing.T) {
+	saoPaulo := Location{ID: uuid.New(), Name: "CD São Paulo", State: "SP"}
+	rio := Location{ID: uuid.New(), Name: "CD Rio", State: "Rio de Janeiro"}
+	manaus := Location{ID: uuid.New(), Name: "CD Manaus", State: "AM"}
+	locations := []Location{manaus, rio, saoPaulo}
+
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		shipState string
+		demands   []Demand
+		expected  []Pick
+		short     []int
+	}{
+		{
+			name:      "Nearest - Splits Across The Nearest Locations",
+			strategy:  StrategyNearest,
+			shipState: "São Paulo",
+			demands:   []Demand{{Quantity: 5, Stock: map[uuid.UUID]int{saoPaulo.ID: 2, rio.ID: 10, manaus.ID: 10}}},
+			expected: []Pick{
+				{Item: 0, LocationID: saoPaulo.ID, Quantity: 2},
+				{Item: 0, LocationID: rio.ID, Quantity: 3},
+			},
+		},
+		{
+			name:      "Nearest - Each Item On Its Own",
+			strategy:  StrategyNearest,
+			shipState: "sp",
+			demands: []Demand{
+				{Quantity: 1, Stock: map[uuid.UUID]int{saoPaulo.ID: 1, rio.ID: 1}},
+				{Quantity: 1, Stock: map[uuid.UUID]int{rio.ID: 1}},
+			},
+			expected: []Pick{
+				{Item: 0, LocationID: saoPaulo.ID, Quantity: 1},
+				{Item: 1, LocationID: rio.ID, Quantity: 1},
+			},
+		},
+		{
+			name:      "Fewest Shipments - One Location Holding Everything",
+			strategy:  StrategyFewestShipments,
+			shipState: "SP",
+			demands: []Demand{
+				{Quantity: 1, Stock: map[uuid.UUID]int{saoPaulo.ID: 1, rio.ID: 1}},
+				{Quantity: 1, Stock: map[uuid.UUID]int{rio.ID: 1, manaus.ID: 1}},
+			},
+			expected: []Pick{
+				{Item: 0, LocationID: rio.ID, Quantity: 1},
+				{Item: 1, LocationID: rio.ID, Quantity: 1},
+			},
+		},
+		{
+			name:      "Fewest Shipments - Nearest Among Equals",
+			strategy:  StrategyFewestShipments,
+			shipState: "AM",
+			demands:   []Demand{{Quantity: 5, Stock: map[uuid.UUID]int{saoPaulo.ID: 2, rio.ID: 10, manaus.ID: 10}}},
+			expected:  []Pick{{Item: 0, LocationID: manaus.ID, Quantity: 5}},
+		},
+		{
+			name:      "Fewest Shipments - Split When No Location Holds Enough",
+			strategy:  StrategyFewestShipments,
+			shipState: "SP",
+			demands:   []Demand{{Quantity: 8, Stock: map[uuid.UUID]int{saoPaulo.ID: 2, rio.ID: 4, manaus.ID: 4}}},
+			expected: []Pick{
+				{Item: 0, LocationID: rio.ID, Quantity: 4},
+				{Item: 0, LocationID: manaus.ID, Quantity: 4},
+			},
+		},
+		{
+			name:      "Short Items",
+			strategy:  StrategyNearest,
+			shipState: "SP",
+			demands: []Demand{
+				{Quantity: 1, Stock: map[uuid.UUID]int{rio.ID: 1}},
+				{Quantity: 3, Stock: map[uuid.UUID]int{rio.ID: 1, saoPaulo.ID: 1}},
+			},
+			short: []int{1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			picks, short := Allocate(tc.strategy, tc.shipState, locations, tc.demands)
+			assert.Equal(t, tc.expected, picks)
+			assert.Equal(t, tc.short, short)
+		})
+	}
+}
+
+func TestAllocate_GreedyWithManyLocations(t *testing.T) {
+	var locations []Location
+	stock := make(map[uuid.UUID]int)
+	for i := 0; i < maxExactLocations+3; i++ {
+		l := Location{ID: uuid.New(), Name: string(rune('A' + i)), State: "SP"}
+		locations = append(locations, l)
+		stock[l.ID] = 1
+	}
+	big := locations[len(locations)-1]
+	stock[big.ID] = 10
+
+	picks, short := Allocate(StrategyFewestShipments, "SP", locations, []Demand{{Quantity: 10, Stock: stock}})
+	require.Empty(t, short)
+	assert.Equal(t, []Pick{{Item: 0, LocationID: big.ID, Quantity: 10}}, picks)
+}
+
+func TestStateDistance(t *testing.T) {
+	assert.Zero(t, stateDistance("São Paulo", "sp"))
+	assert.Zero(t, stateDistance("Ontario", " ontario "))
+	assert.True(t, math.IsInf(stateDistance("Ontario", "SP"), 1))
+	assert.Less(t, stateDistance("SP", "RJ"), stateDistance("SP", "AM"))
+	assert.InDelta(t, 360, stateDistance("SP", "RJ"), 20) // About 360 km apart
+}
+
+func TestParseStrategy(t *testing.T) {
+	strategy, err := ParseStrategy("fewest_shipments")
+	require.NoError(t, err)
+	assert.Equal(t, StrategyFewestShipments, strategy)
+
+	_, err = ParseStrategy("cheapest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package fulfillment
+
+import (
+	"math"
+	"strings"
+)
+
+// coordinates is a point on Earth, in degrees.
+type coordinates struct {
+	lat, lon float64
+}
+
+// stateCapitals locates each Brazilian state by its capital, keyed by its abbreviation.
+var stateCapitals = map[string]coordinates{
+	"AC": {-9.97, -67.81},  // Rio Branco
+	"AL": {-9.67, -35.74},  // Maceió
+	"AP": {0.03, -51.07},   // Macapá
+	"AM": {-3.12, -60.02},  // Manaus
+	"BA": {-12.97, -38.50}, // Salvador
+	"CE": {-3.73, -38.52},  // Fortaleza
+	"DF": {-15.79, -47.88}, // Brasília
+	"ES": {-20.32, -40.34}, // Vitória
+	"GO": {-16.68, -49.25}, // Goiânia
+	"MA": {-2.53, -44.30},  // São Luís
+	"MT": {-15.60, -56.10}, // Cuiabá
+	"MS": {-20.47, -54.62}, // Campo Grande
+	"MG": {-19.92, -43.94}, // Belo Horizonte
+	"PA": {-1.46, -48.50},  // Belém
+	"PB": {-7.12, -34.86},  // João Pessoa
+	"PR": {-25.43, -49.27}, // Curitiba
+	"PE": {-8.05, -34.88},  // Recife
+	"PI": {-5.09, -42.80},  // Teresina
+	"RJ": {-22.91, -43.17}, // Rio de Janeiro
+	"RN": {-5.79, -35.21},  // Natal
+	"RS": {-30.03, -51.23}, // Porto Alegre
+	"RO": {-8.76, -63.90},  // Porto Velho
+	"RR": {2.82, -60.67},   // Boa Vista
+	"SC": {-27.60, -48.55}, // Florianópolis
+	"SP": {-23.55, -46.63}, // São Paulo
+	"SE": {-10.91, -37.07}, // Aracaju
+	"TO": {-10.18, -48.33}, // Palmas
+}
+
+// stateNames maps the unaccented, lowercase state names to their abbreviations.
+var stateNames = map[string]string{
+	"acre": "AC", "alagoas": "AL", "amapa": "AP", "amazonas": "AM", "bahia": "BA",
+	"ceara": "CE", "distrito federal": "DF", "espirito santo": "ES", "goias": "GO",
+	"maranhao": "MA", "mato grosso": "MT", "mato grosso do sul": "MS", "minas gerais": "MG",
+	"para": "PA", "paraiba": "PB", "parana": "PR", "pernambuco": "PE", "piaui": "PI",
+	"rio de janeiro": "RJ", "rio grande do norte": "RN", "rio grande do sul": "RS",
+	"rondonia": "RO", "roraima": "RR", "santa catarina": "SC", "sao paulo": "SP",
+	"sergipe": "SE", "tocantins": "TO",
+}
+
+var unaccent = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "é", "e", "ê", "e", "í", "i",
+	"ó", "o", "ô", "o", "õ", "o", "ú", "u", "ü", "u", "ç", "c",
+)
+
+// normalizeState reduces a state, written as its abbreviation ("SP") or name ("São Paulo"),
+// to a comparable key: the abbreviation of Brazilian states, else the lowercase text.
+func normalizeState(state string) string {
+	key := unaccent.Replace(strings.ToLower(strings.Join(strings.Fields(state), " ")))
+	if abbreviation, ok := stateNames[key]; ok {
+		return abbreviation
+	}
+	if _, ok := stateCapitals[strings.ToUpper(key)]; ok {
+		return strings.ToUpper(key)
+	}
+	return key
+}
+
+// stateDistance returns the distance in km between two states, measured between their
+// capitals. Unknown states are at distance 0 from themselves and +Inf from the others.
+func stateDistance(a, b string) float64 {
+	a, b = normalizeState(a), normalizeState(b)
+	if a == b && a != "" {
+		return 0
+	}
+	from, okFrom := stateCapitals[a]
+	to, okTo := stateCapitals[b]
+	if !okFrom || !okTo {
+		return math.Inf(1)
+	}
+
+	// Haversine formula
+	const earthRadiusKm = 6371
+	rad := math.Pi / 180
+	dLat := (to.lat - from.lat) * rad
+	dLon := (to.lon - from.lon) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(from.lat*rad)*math.Cos(to.lat*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/addresses"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/orders"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/webutils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Page size limits for GET /api/admin/users.
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// AdminUserHandler lets admins search customers and manage their accounts.
+type AdminUserHandler struct {
+	UserRepo    users.UserRepository
+	AddressRepo addresses.AddressRepository
+	OrderRepo   orders.OrderRepository
+	SessionRepo sessions.SessionRepository // A user's sessions end when they are disabled or must reset their password
+	ResetSender PasswordResetSender        // Sends the link for a forced password reset
+}
+
+// NewAdminUserHandler creates a new AdminUserHandler.
+func NewAdminUserHandler(userRepo users.UserRepository, addressRepo addresses.AddressRepository, orderRepo orders.OrderRepository, sessionRepo sessions.SessionRepository, resetSender PasswordResetSender) *AdminUserHandler {
+	return &AdminUserHandler{
+		UserRepo:    userRepo,
+		AddressRepo: addressRepo,
+		OrderRepo:   orderRepo,
+		SessionRepo: sessionRepo,
+		ResetSender: resetSender,
+	}
+}
+
+// --- Request/Response Structs ---
+
+type UserListResponse struct {
+	Users  []models.User `json:"users"`
+	Total  int           `json:"total"` // Number of users matching the filter, across all pages
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+type UpdateRoleRequest struct {
+	Role models.UserRole `json:"role"`
+}
+
+// --- Handlers ---
+
+// ListUsers handles GET /api/admin/users.
+// Query parameters: email and name (substring matches), role, created_from and created_to
+// (RFC 3339 timestamps or YYYY-MM-DD dates, created_to inclusive), limit and offset.
+func (h *AdminUserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	filter, fieldErrors := parseUserListFilter(r.URL.Query())
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return
+	}
+
+	list, total, err := h.UserRepo.List(r.Context(), filter)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to list users"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, UserListResponse{
+		Users:  list,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	})
+}
+
+// GetUser handles GET /api/admin/users/{id}.
+func (h *AdminUserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	user.PasswordHash = ""
+	webutils.WriteJSON(w, http.StatusOK, user)
+}
+
+// ListUserAddresses handles GET /api/admin/users/{id}/addresses.
+func (h *AdminUserHandler) ListUserAddresses(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	addressList, err := h.AddressRepo.FindByUserID(r.Context(), user.ID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve addresses"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, addressList)
+}
+
+// ListUserOrders handles GET /api/admin/users/{id}/orders.
+func (h *AdminUserHandler) ListUserOrders(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	orderList, err := h.OrderRepo.FindUserOrders(r.Context(), user.ID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve orders"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, orderList)
+}
+
+// UpdateRole handles PATCH /api/admin/users/{id}/role.
+// The new role applies to the user's next request, since the auth middleware reads it from the database.
+func (h *AdminUserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseOtherUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	if !req.Role.IsValid() {
+		webutils.ErrorJSON(w, fmt.Errorf("invalid role %q", req.Role), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.UserRepo.UpdateRole(r.Context(), targetID, req.Role); err != nil {
+		respondAdminUserError(w, err, "failed to update role")
+		return
+	}
+
+	h.writeUser(w, r, targetID)
+}
+
+// DisableUser handles POST /api/admin/users/{id}/disable.
+// The user can no longer log in, and their tokens and API keys stop working immediately.
+func (h *AdminUserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseOtherUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.UserRepo.SetDisabled(r.Context(), targetID, true); err != nil {
+		respondAdminUserError(w, err, "failed to disable user")
+		return
+	}
+
+	// The middleware already rejects the user; ending the sessions keeps them ended if re-enabled
+	if _, err := h.SessionRepo.RevokeAllExcept(r.Context(), targetID, uuid.Nil); err != nil {
+		log.Printf("Error revoking sessions of disabled user: %v", err)
+	}
+
+	h.writeUser(w, r, targetID)
+}
+
+// EnableUser handles POST /api/admin/users/{id}/enable.
+func (h *AdminUserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseOtherUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.UserRepo.SetDisabled(r.Context(), targetID, false); err != nil {
+		respondAdminUserError(w, err, "failed to enable user")
+		return
+	}
+
+	h.writeUser(w, r, targetID)
+}
+
+// ForcePasswordReset handles POST /api/admin/users/{id}/force-password-reset.
+// The current password stops working, every session ends and a reset link is emailed to the user.
+func (h *AdminUserHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseOtherUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.UserRepo.RequirePasswordReset(r.Context(), targetID); err != nil {
+		respondAdminUserError(w, err, "failed to force password reset")
+		return
+	}
+
+	// Sessions left active would keep the account usable by whoever knows the password
+	if _, err := h.SessionRepo.RevokeAllExcept(r.Context(), targetID, uuid.Nil); err != nil {
+		log.Printf("Error revoking sessions after forced password reset: %v", err)
+		webutils.ErrorJSON(w, errors.New("failed to force password reset"), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(r.Context(), targetID)
+	if err != nil {
+		respondAdminUserError(w, err, "failed to force password reset")
+		return
+	}
+	// The flag is set either way; the user can still ask for a new link through forgot-password
+	if err := h.ResetSender.SendPasswordResetLink(r.Context(), user); err != nil {
+		log.Printf("Error sending forced password reset link: %v", err)
+	}
+
+	user.PasswordHash = ""
+	webutils.WriteJSON(w, http.StatusOK, user)
+}
+
+// --- Helpers ---
+
+// findTargetUser loads the user named by the {id} URL variable.
+// On failure it writes the response and returns ok == false.
+func (h *AdminUserHandler) findTargetUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	targetID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid user ID in URL"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, err := h.UserRepo.FindByID(r.Context(), targetID)
+	if err != nil {
+		respondAdminUserError(w, err, "failed to retrieve user")
+		return nil, false
+	}
+	return user, true
+}
+
+// writeUser responds with the current state of a user after a change.
+func (h *AdminUserHandler) writeUser(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	user, err := h.UserRepo.FindByID(r.Context(), id)
+	if err != nil {
+		respondAdminUserError(w, err, "failed to retrieve user")
+		return
+	}
+
+	user.PasswordHash = ""
+	webutils.WriteJSON(w, http.StatusOK, user)
+}
+
+// parseOtherUserID parses the {id} URL variable of an account change.
+// Admins cannot change their own account this way, so they cannot lock themselves out.
+func parseOtherUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	authUserID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+
+	targetID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid user ID in URL"), http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	if targetID == authUserID {
+		webutils.ErrorJSON(w, errors.New("admins cannot change their own account"), http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return targetID, true
+}
+
+// respondAdminUserError writes the response for a failed user lookup or change.
+func respondAdminUserError(w http.ResponseWriter, err error, failureMessage string) {
+	if errors.Is(err, users.ErrUserNotFound) {
+		webutils.ErrorJSON(w, errors.New("user not found"), http.StatusNotFound)
+	} else {
+		webutils.ErrorJSON(w, errors.New(failureMessage), http.StatusInternalServerError)
+	}
+}
+
+// parseUserListFilter reads the ListUsers query parameters, reporting every invalid one.
+func parseUserListFilter(query url.Values) (users.ListFilter, []webutils.FieldError) {
+	filter := users.ListFilter{
+		Email: query.Get("email"),
+		Name:  query.Get("name"),
+		Role:  models.UserRole(query.Get("role")),
+		Limit: defaultUserPageSize,
+	}
+	var fieldErrors []webutils.FieldError
+	invalid := func(field, message string) {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: field, Code: "invalid", Message: message})
+	}
+
+	if filter.Role != "" && !filter.Role.IsValid() {
+		invalid("role", "unknown role")
+	}
+	if v := query.Get("created_from"); v != "" {
+		from, _, err := parseTimeOrDate(v)
+		if err != nil {
+			invalid("created_from", "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		} else {
+			filter.CreatedFrom = &from
+		}
+	}
+	if v := query.Get("created_to"); v != "" {
+		to, isDate, err := parseTimeOrDate(v)
+		if err != nil {
+			invalid("created_to", "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		} else {
+			if isDate {
+				to = to.AddDate(0, 0, 1) // A date includes the whole day
+			}
+			filter.CreatedTo = &to
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxUserPageSize {
+			invalid("limit", fmt.Sprintf("must be between 1 and %d", maxUserPageSize))
+		} else {
+			filter.Limit = limit
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			invalid("offset", "must be zero or more")
+		} else {
+			filter.Offset = offset
+		}
+	}
+	return filter, fieldErrors
+}
+
+// parseTimeOrDate parses an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC).
+func parseTimeOrDate(v string) (t time.Time, isDate bool, err error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, v)
+	return t, false, err
+}
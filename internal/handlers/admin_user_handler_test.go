@@ -0,0 +1,353 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/orders"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/users"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// adminUserTestMocks groups the mocks behind an AdminUserHandler.
+type adminUserTestMocks struct {
+	userRepo    *MockUserRepository
+	addressRepo *MockAddressRepository
+	orderRepo   *orders.MockOrderRepository
+	sessionRepo *sessions.MockSessionRepository
+	resetSender *MockPasswordResetSender
+}
+
+// setupAdminUserTest creates an AdminUserHandler behind the same middleware as in main.go.
+// It returns a token for an admin and one for a customer.
+func setupAdminUserTest(t *testing.T) (*adminUserTestMocks, *mux.Router, uuid.UUID, string, string) {
+	t.Helper()
+	m := &adminUserTestMocks{
+		userRepo:    new(MockUserRepository),
+		addressRepo: new(MockAddressRepository),
+		orderRepo:   new(orders.MockOrderRepository),
+		sessionRepo: new(sessions.MockSessionRepository),
+		resetSender: new(MockPasswordResetSender),
+	}
+	adminID := uuid.New()
+	customerID := uuid.New()
+	m.userRepo.On("FindByID", mock.Anything, adminID).Return(&models.User{ID: adminID, Role: models.RoleAdmin}, nil).Maybe()
+	m.userRepo.On("FindByID", mock.Anything, customerID).Return(&models.User{ID: customerID, Role: models.RoleCustomer}, nil).Maybe()
+
+	h := handlers.NewAdminUserHandler(m.userRepo, m.addressRepo, m.orderRepo, m.sessionRepo, m.resetSender)
+	mw := auth.NewMiddleware(testKeys, m.userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	admin := router.PathPrefix("/api/admin/users").Subrouter()
+	admin.Use(mw.Authenticate, mw.DenyAPIKeys, mw.RequireRole(models.RoleAdmin))
+	admin.HandleFunc("", h.ListUsers).Methods("GET")
+	admin.HandleFunc("/{id}", h.GetUser).Methods("GET")
+	admin.HandleFunc("/{id}/addresses", h.ListUserAddresses).Methods("GET")
+	admin.HandleFunc("/{id}/orders", h.ListUserOrders).Methods("GET")
+	admin.HandleFunc("/{id}/role", h.UpdateRole).Methods("PATCH")
+	admin.HandleFunc("/{id}/disable", h.DisableUser).Methods("POST")
+	admin.HandleFunc("/{id}/enable", h.EnableUser).Methods("POST")
+	admin.HandleFunc("/{id}/force-password-reset", h.ForcePasswordReset).Methods("POST")
+
+	adminToken, err := generateTestToken(adminID)
+	require.NoError(t, err)
+	customerToken, err := generateTestToken(customerID)
+	require.NoError(t, err)
+	return m, router, adminID, adminToken, customerToken
+}
+
+func newAdminRequest(method, path, token, body string) *http.Request {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestAdminUserHandler_ListUsers(t *testing.T) {
+	listed := []models.User{{ID: uuid.New(), Name: "Maria", Email: "maria@example.com", Role: models.RoleCustomer}}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	toTime := time.Date(2025, 1, 31, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedFilter *users.ListFilter // Nil when the repository must not be called
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Defaults",
+			query:          "",
+			expectedFilter: &users.ListFilter{Limit: 20},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "All Filters",
+			query:          "?email=MARIA&name=mar&role=customer&created_from=2025-01-01&created_to=2025-01-31&limit=5&offset=10",
+			expectedFilter: &users.ListFilter{Email: "MARIA", Name: "mar", Role: models.RoleCustomer, CreatedFrom: &from, CreatedTo: &toDay, Limit: 5, Offset: 10},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Timestamp Bound Is Exact",
+			query:          "?created_to=2025-01-31T12:30:00Z",
+			expectedFilter: &users.ListFilter{CreatedTo: &toTime, Limit: 20},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid Parameters",
+			query:          "?role=owner&created_from=yesterday&limit=500&offset=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"error":"validation failed","fields":[
+				{"field":"role","code":"invalid","message":"unknown role"},
+				{"field":"created_from","code":"invalid","message":"must be an RFC 3339 timestamp or a YYYY-MM-DD date"},
+				{"field":"limit","code":"invalid","message":"must be between 1 and 100"},
+				{"field":"offset","code":"invalid","message":"must be zero or more"}]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m, router, _, adminToken, _ := setupAdminUserTest(t)
+			if tc.expectedFilter != nil {
+				m.userRepo.On("List", mock.Anything, *tc.expectedFilter).Return(listed, 42, nil).Once()
+			}
+
+			rr := executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users"+tc.query, adminToken, ""), tc.expectedStatus, tc.expectedBody)
+
+			if tc.expectedStatus == http.StatusOK {
+				var resp handlers.UserListResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				assert.Equal(t, listed, resp.Users)
+				assert.Equal(t, 42, resp.Total)
+				assert.Equal(t, tc.expectedFilter.Limit, resp.Limit)
+				assert.Equal(t, tc.expectedFilter.Offset, resp.Offset)
+			}
+			m.userRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Repository Error", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("List", mock.Anything, mock.Anything).Return(nil, 0, assert.AnError).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users", adminToken, ""), http.StatusInternalServerError, `{"error":"failed to list users"}`)
+	})
+
+	t.Run("Customers Are Forbidden", func(t *testing.T) {
+		m, router, _, _, customerToken := setupAdminUserTest(t)
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users", customerToken, ""), http.StatusForbidden, `{"error":"forbidden"}`)
+		m.userRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminUserHandler_ViewUser(t *testing.T) {
+	targetID := uuid.New()
+	target := &models.User{ID: targetID, Name: "Maria", Email: "maria@example.com", PasswordHash: "secret-hash", Role: models.RoleCustomer}
+
+	t.Run("Get User", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(target, nil).Once()
+
+		rr := executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users/"+targetID.String(), adminToken, ""), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"email":"maria@example.com"`)
+		assert.NotContains(t, rr.Body.String(), "secret-hash")
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(nil, users.ErrUserNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users/"+targetID.String(), adminToken, ""), http.StatusNotFound, `{"error":"user not found"}`)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		_, router, _, adminToken, _ := setupAdminUserTest(t)
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users/not-a-uuid", adminToken, ""), http.StatusBadRequest, `{"error":"invalid user ID in URL"}`)
+	})
+
+	t.Run("Addresses", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(target, nil).Once()
+		addressList := []models.Address{{ID: uuid.New(), UserID: targetID, City: "Recife"}}
+		m.addressRepo.On("FindByUserID", mock.Anything, targetID).Return(addressList, nil).Once()
+
+		rr := executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users/"+targetID.String()+"/addresses", adminToken, ""), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"city":"Recife"`)
+		m.addressRepo.AssertExpectations(t)
+	})
+
+	t.Run("Orders", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(target, nil).Once()
+		orderID := uuid.New()
+		m.orderRepo.On("FindUserOrders", mock.Anything, targetID).Return([]models.Order{{ID: orderID, UserID: targetID}}, nil).Once()
+
+		rr := executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users/"+targetID.String()+"/orders", adminToken, ""), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), orderID.String())
+		m.orderRepo.AssertExpectations(t)
+	})
+
+	t.Run("Orders Error", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(target, nil).Once()
+		m.orderRepo.On("FindUserOrders", mock.Anything, targetID).Return(nil, assert.AnError).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/admin/users/"+targetID.String()+"/orders", adminToken, ""), http.StatusInternalServerError, `{"error":"failed to retrieve orders"}`)
+	})
+}
+
+func TestAdminUserHandler_UpdateRole(t *testing.T) {
+	targetID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("UpdateRole", mock.Anything, targetID, models.RoleAdmin).Return(nil).Once()
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(&models.User{ID: targetID, Role: models.RoleAdmin}, nil).Once()
+
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PATCH", "/api/admin/users/"+targetID.String()+"/role", adminToken, `{"role":"admin"}`), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"role":"admin"`)
+		m.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Role", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		executeRequestAndAssert(t, router, newAdminRequest("PATCH", "/api/admin/users/"+targetID.String()+"/role", adminToken, `{"role":"owner"}`), http.StatusBadRequest, `{"error":"invalid role \"owner\""}`)
+		m.userRepo.AssertNotCalled(t, "UpdateRole", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("UpdateRole", mock.Anything, targetID, models.RoleCustomer).Return(users.ErrUserNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("PATCH", "/api/admin/users/"+targetID.String()+"/role", adminToken, `{"role":"customer"}`), http.StatusNotFound, `{"error":"user not found"}`)
+	})
+
+	t.Run("Own Account", func(t *testing.T) {
+		m, router, adminID, adminToken, _ := setupAdminUserTest(t)
+		executeRequestAndAssert(t, router, newAdminRequest("PATCH", "/api/admin/users/"+adminID.String()+"/role", adminToken, `{"role":"customer"}`), http.StatusForbidden, `{"error":"admins cannot change their own account"}`)
+		m.userRepo.AssertNotCalled(t, "UpdateRole", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminUserHandler_DisableEnable(t *testing.T) {
+	targetID := uuid.New()
+	disabledAt := time.Now()
+
+	t.Run("Disable Ends Sessions", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("SetDisabled", mock.Anything, targetID, true).Return(nil).Once()
+		m.sessionRepo.On("RevokeAllExcept", mock.Anything, targetID, uuid.Nil).Return(int64(2), nil).Once()
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(&models.User{ID: targetID, DisabledAt: &disabledAt}, nil).Once()
+
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/disable", adminToken, ""), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"disabled_at"`)
+		m.userRepo.AssertExpectations(t)
+		m.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Session Revocation Failure Is Not Fatal", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("SetDisabled", mock.Anything, targetID, true).Return(nil).Once()
+		m.sessionRepo.On("RevokeAllExcept", mock.Anything, targetID, uuid.Nil).Return(int64(0), assert.AnError).Once()
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(&models.User{ID: targetID, DisabledAt: &disabledAt}, nil).Once()
+
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/disable", adminToken, ""), http.StatusOK, "")
+	})
+
+	t.Run("Enable", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("SetDisabled", mock.Anything, targetID, false).Return(nil).Once()
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(&models.User{ID: targetID}, nil).Once()
+
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/enable", adminToken, ""), http.StatusOK, "")
+		assert.NotContains(t, rr.Body.String(), `"disabled_at"`)
+		m.sessionRepo.AssertNotCalled(t, "RevokeAllExcept", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repository Error", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("SetDisabled", mock.Anything, targetID, true).Return(assert.AnError).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/disable", adminToken, ""), http.StatusInternalServerError, `{"error":"failed to disable user"}`)
+	})
+
+	t.Run("Own Account", func(t *testing.T) {
+		m, router, adminID, adminToken, _ := setupAdminUserTest(t)
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+adminID.String()+"/disable", adminToken, ""), http.StatusForbidden, `{"error":"admins cannot change their own account"}`)
+		m.userRepo.AssertNotCalled(t, "SetDisabled", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminUserHandler_ForcePasswordReset(t *testing.T) {
+	targetID := uuid.New()
+	target := &models.User{ID: targetID, Email: "maria@example.com", PasswordHash: "secret-hash", PasswordResetRequired: true}
+
+	t.Run("Success", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("RequirePasswordReset", mock.Anything, targetID).Return(nil).Once()
+		m.sessionRepo.On("RevokeAllExcept", mock.Anything, targetID, uuid.Nil).Return(int64(1), nil).Once()
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(target, nil).Once()
+		m.resetSender.On("SendPasswordResetLink", mock.Anything, target).Return(nil).Once()
+
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/force-password-reset", adminToken, ""), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"password_reset_required":true`)
+		assert.NotContains(t, rr.Body.String(), "secret-hash")
+		m.userRepo.AssertExpectations(t)
+		m.sessionRepo.AssertExpectations(t)
+		m.resetSender.AssertExpectations(t)
+	})
+
+	t.Run("Notification Failure Is Not Fatal", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("RequirePasswordReset", mock.Anything, targetID).Return(nil).Once()
+		m.sessionRepo.On("RevokeAllExcept", mock.Anything, targetID, uuid.Nil).Return(int64(0), nil).Once()
+		m.userRepo.On("FindByID", mock.Anything, targetID).Return(&models.User{ID: targetID, PasswordResetRequired: true}, nil).Once()
+		m.resetSender.On("SendPasswordResetLink", mock.Anything, mock.Anything).Return(assert.AnError).Once()
+
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/force-password-reset", adminToken, ""), http.StatusOK, "")
+	})
+
+	t.Run("Session Revocation Failure", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("RequirePasswordReset", mock.Anything, targetID).Return(nil).Once()
+		m.sessionRepo.On("RevokeAllExcept", mock.Anything, targetID, uuid.Nil).Return(int64(0), assert.AnError).Once()
+
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/force-password-reset", adminToken, ""), http.StatusInternalServerError, `{"error":"failed to force password reset"}`)
+		m.resetSender.AssertNotCalled(t, "SendPasswordResetLink", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		m, router, _, adminToken, _ := setupAdminUserTest(t)
+		m.userRepo.On("RequirePasswordReset", mock.Anything, targetID).Return(users.ErrUserNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/admin/users/"+targetID.String()+"/force-password-reset", adminToken, ""), http.StatusNotFound, `{"error":"user not found"}`)
+		m.resetSender.AssertNotCalled(t, "SendPasswordResetLink", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMiddleware_DisabledAccount(t *testing.T) {
+	userID := uuid.New()
+	disabledAt := time.Now().Add(-time.Minute)
+	token, err := generateTestToken(userID)
+	require.NoError(t, err)
+
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, Role: models.RoleAdmin, DisabledAt: &disabledAt}, nil).Once()
+	mw := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	router.Handle("/protected", mw.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Disabled users must not reach the handler")
+	}))).Methods("GET")
+
+	// The token is still valid and its session active; only the account state rejects it
+	executeRequestAndAssert(t, router, newAdminRequest("GET", "/protected", token, ""), http.StatusForbidden, `{"error":"account is disabled"}`)
+	mockUserRepo.AssertExpectations(t)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/webutils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxAPIKeyNameLength matches the api_keys.name column.
+const maxAPIKeyNameLength = 100
+
+// adminOnlyScopes can only be granted by admins, since the routes they open also require the admin role.
+var adminOnlyScopes = map[string]bool{
+	auth.ScopeProductsWrite:   true,
+	auth.ScopeCategoriesWrite: true,
+}
+
+// APIKeyHandler lets the authenticated user manage their personal API keys.
+type APIKeyHandler struct {
+	APIKeyRepo apikeys.APIKeyRepository
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(apiKeyRepo apikeys.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyRepo: apiKeyRepo,
+	}
+}
+
+// --- Request/Response Structs ---
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Optional; the key never expires if omitted
+}
+
+type CreateAPIKeyResponse struct {
+	APIKey *models.APIKey `json:"api_key"`
+	Key    string         `json:"key"` // Shown once; only its hash is stored
+}
+
+// --- Handlers ---
+
+// CreateAPIKey handles POST /api/users/me/api-keys.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 {
+		webutils.ErrorJSON(w, errors.New("name and scopes are required"), http.StatusBadRequest)
+		return
+	}
+	if len(req.Name) > maxAPIKeyNameLength {
+		webutils.ErrorJSON(w, fmt.Errorf("name must be at most %d characters", maxAPIKeyNameLength), http.StatusBadRequest)
+		return
+	}
+	scopes, err := validateAPIKeyScopes(req.Scopes, isAdmin(r))
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		webutils.ErrorJSON(w, errors.New("expires_at must be in the future"), http.StatusBadRequest)
+		return
+	}
+
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to create API key"), http.StatusInternalServerError)
+		return
+	}
+
+	created, err := h.APIKeyRepo.Create(r.Context(), &models.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to create API key"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: created, Key: key})
+}
+
+// ListAPIKeys handles GET /api/users/me/api-keys.
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	list, err := h.APIKeyRepo.ListByUser(r.Context(), userID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to list API keys"), http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []models.APIKey{}
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, list)
+}
+
+// DeleteAPIKey handles DELETE /api/users/me/api-keys/{id}.
+// The key stops working immediately.
+func (h *APIKeyHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	keyID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid API key ID format"), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.APIKeyRepo.Delete(r.Context(), keyID, userID); err != nil {
+		if errors.Is(err, apikeys.ErrAPIKeyNotFound) {
+			webutils.ErrorJSON(w, err, http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to delete API key"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateAPIKeyScopes checks the requested scopes and returns them without duplicates.
+func validateAPIKeyScopes(requested []string, admin bool) ([]string, error) {
+	seen := map[string]bool{}
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if !auth.IsValidScope(scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if adminOnlyScopes[scope] && !admin {
+			return nil, fmt.Errorf("scope %q requires the admin role", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
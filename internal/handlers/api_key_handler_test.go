@@ -0,0 +1,380 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAPIKeyTest creates an APIKeyHandler behind the auth middleware for an authenticated user.
+func setupAPIKeyTest(t *testing.T, user *models.User) (*apikeys.MockAPIKeyRepository, *mux.Router, string) {
+	t.Helper()
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+	mockAPIKeyRepo := new(apikeys.MockAPIKeyRepository)
+	h := handlers.NewAPIKeyHandler(mockAPIKeyRepo)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), mockAPIKeyRepo)
+
+	router := mux.NewRouter()
+	keyRoutes := router.PathPrefix("/api/users/me/api-keys").Subrouter()
+	keyRoutes.Use(authMiddleware.Authenticate, authMiddleware.DenyAPIKeys)
+	keyRoutes.HandleFunc("", h.ListAPIKeys).Methods("GET")
+	keyRoutes.HandleFunc("", h.CreateAPIKey).Methods("POST")
+	keyRoutes.HandleFunc("/{id}", h.DeleteAPIKey).Methods("DELETE")
+
+	testToken, err := generateTestToken(user.ID)
+	require.NoError(t, err)
+	return mockAPIKeyRepo, router, testToken
+}
+
+func TestAPIKeyHandler_CreateAPIKey(t *testing.T) {
+	customer := &models.User{ID: uuid.New(), Role: models.RoleCustomer}
+	admin := &models.User{ID: uuid.New(), Role: models.RoleAdmin}
+	expiresAt := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	// echoKey returns the stored key with an ID, as the database would.
+	echoKey := func(_ context.Context, key *models.APIKey) *models.APIKey {
+		created := *key
+		created.ID = uuid.New()
+		created.CreatedAt = time.Now()
+		return &created
+	}
+
+	tests := []struct {
+		name           string
+		user           *models.User
+		body           string
+		mockCreate     func(*apikeys.MockAPIKeyRepository, *models.User)
+		expectedStatus int
+		expectedBody   string
+		expectedScopes []string
+	}{
+		{
+			name: "Success",
+			user: customer,
+			body: fmt.Sprintf(`{"name":" ERP sync ","scopes":["orders:read","cart:read","orders:read"],"expires_at":"%s"}`, expiresAt.Format(time.RFC3339)),
+			mockCreate: func(repo *apikeys.MockAPIKeyRepository, user *models.User) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(k *models.APIKey) bool {
+					return k.UserID == user.ID && k.Name == "ERP sync" && k.ExpiresAt != nil && k.ExpiresAt.Equal(expiresAt)
+				})).Return(echoKey, nil).Once()
+			},
+			expectedStatus: http.StatusCreated,
+			expectedScopes: []string{"orders:read", "cart:read"},
+		},
+		{
+			name: "Admin Can Grant Product Scope",
+			user: admin,
+			body: `{"name":"Catalog import","scopes":["products:write"]}`,
+			mockCreate: func(repo *apikeys.MockAPIKeyRepository, user *models.User) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(echoKey, nil).Once()
+			},
+			expectedStatus: http.StatusCreated,
+			expectedScopes: []string{"products:write"},
+		},
+		{
+			name:           "Customer Cannot Grant Product Scope",
+			user:           customer,
+			body:           `{"name":"Catalog import","scopes":["products:write"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"scope \"products:write\" requires the admin role"}`,
+		},
+		{
+			name:           "Unknown Scope",
+			user:           customer,
+			body:           `{"name":"ERP sync","scopes":["everything"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"unknown scope \"everything\""}`,
+		},
+		{
+			name:           "Missing Scopes",
+			user:           customer,
+			body:           `{"name":"ERP sync","scopes":[]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"name and scopes are required"}`,
+		},
+		{
+			name:           "Name Too Long",
+			user:           customer,
+			body:           fmt.Sprintf(`{"name":"%s","scopes":["orders:read"]}`, strings.Repeat("a", 101)),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"name must be at most 100 characters"}`,
+		},
+		{
+			name:           "Expiry In The Past",
+			user:           customer,
+			body:           `{"name":"ERP sync","scopes":["orders:read"],"expires_at":"2020-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"expires_at must be in the future"}`,
+		},
+		{
+			name: "Repository Error",
+			user: customer,
+			body: `{"name":"ERP sync","scopes":["orders:read"]}`,
+			mockCreate: func(repo *apikeys.MockAPIKeyRepository, user *models.User) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to create API key"}`,
+		},
+		{
+			name:           "Invalid JSON",
+			user:           customer,
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid request body"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPIKeyRepo, router, testToken := setupAPIKeyTest(t, tc.user)
+			if tc.mockCreate != nil {
+				tc.mockCreate(mockAPIKeyRepo, tc.user)
+			}
+
+			req, _ := http.NewRequest("POST", "/api/users/me/api-keys", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			if tc.expectedStatus == http.StatusCreated {
+				var body handlers.CreateAPIKeyResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				assert.True(t, strings.HasPrefix(body.Key, auth.APIKeyPrefix))
+				assert.True(t, strings.HasPrefix(body.Key, body.APIKey.Prefix))
+				assert.Equal(t, tc.expectedScopes, body.APIKey.Scopes)
+				assert.NotContains(t, rr.Body.String(), "key_hash")
+
+				// Only the hash of the returned key is stored
+				stored := mockAPIKeyRepo.Calls[0].Arguments.Get(1).(*models.APIKey)
+				assert.Equal(t, auth.HashOpaqueToken(body.Key), stored.KeyHash)
+			}
+
+			mockAPIKeyRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAPIKeyHandler_ListAndDelete(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Role: models.RoleCustomer}
+	keyID := uuid.New()
+
+	t.Run("List", func(t *testing.T) {
+		mockAPIKeyRepo, router, testToken := setupAPIKeyTest(t, user)
+		mockAPIKeyRepo.On("ListByUser", mock.Anything, user.ID).Return([]models.APIKey{
+			{ID: keyID, UserID: user.ID, Name: "ERP sync", Prefix: "bca_abcdefgh", KeyHash: "secret-hash", Scopes: []string{"orders:read"}},
+		}, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/api-keys", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+
+		var body []models.APIKey
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Len(t, body, 1)
+		assert.Equal(t, "bca_abcdefgh", body[0].Prefix)
+		assert.NotContains(t, rr.Body.String(), "secret-hash")
+	})
+
+	t.Run("List Error", func(t *testing.T) {
+		mockAPIKeyRepo, router, testToken := setupAPIKeyTest(t, user)
+		mockAPIKeyRepo.On("ListByUser", mock.Anything, user.ID).Return(nil, assert.AnError).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/api-keys", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusInternalServerError, `{"error":"failed to list API keys"}`)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mockAPIKeyRepo, router, testToken := setupAPIKeyTest(t, user)
+		mockAPIKeyRepo.On("Delete", mock.Anything, keyID, user.ID).Return(nil).Once()
+
+		req, _ := http.NewRequest("DELETE", "/api/users/me/api-keys/"+keyID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusNoContent, "")
+		mockAPIKeyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Delete Unknown Or Another User's Key", func(t *testing.T) {
+		mockAPIKeyRepo, router, testToken := setupAPIKeyTest(t, user)
+		mockAPIKeyRepo.On("Delete", mock.Anything, keyID, user.ID).Return(apikeys.ErrAPIKeyNotFound).Once()
+
+		req, _ := http.NewRequest("DELETE", "/api/users/me/api-keys/"+keyID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusNotFound, `{"error":"API key not found"}`)
+	})
+
+	t.Run("Delete Invalid ID", func(t *testing.T) {
+		_, router, testToken := setupAPIKeyTest(t, user)
+
+		req, _ := http.NewRequest("DELETE", "/api/users/me/api-keys/not-a-uuid", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusBadRequest, `{"error":"invalid API key ID format"}`)
+	})
+
+	t.Run("API Keys Cannot Manage API Keys", func(t *testing.T) {
+		mockAPIKeyRepo, router, _ := setupAPIKeyTest(t, user)
+		mockAPIKeyRepo.On("FindByHash", mock.Anything, auth.HashOpaqueToken("bca_presented")).Return(&models.APIKey{ID: keyID, UserID: user.ID, Scopes: auth.APIKeyScopes}, nil).Once()
+		mockAPIKeyRepo.On("TouchLastUsed", mock.Anything, keyID).Return(nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/api-keys", nil)
+		req.Header.Set("X-API-Key", "bca_presented")
+		executeRequestAndAssert(t, router, req, http.StatusForbidden, `{"error":"API keys cannot access this resource"}`)
+		mockAPIKeyRepo.AssertExpectations(t)
+	})
+}
+
+func TestMiddleware_APIKeyAuthentication(t *testing.T) {
+	userID := uuid.New()
+	keyID := uuid.New()
+	presentedKey := "bca_presented-key"
+	presentedHash := auth.HashOpaqueToken(presentedKey)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	user := &models.User{ID: userID, Role: models.RoleAdmin}
+
+	tests := []struct {
+		name           string
+		setHeader      func(*http.Request)
+		mockRepo       func(*apikeys.MockAPIKeyRepository)
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "X-API-Key Header With Scope",
+			setHeader: func(req *http.Request) { req.Header.Set("X-API-Key", presentedKey) },
+			mockRepo: func(repo *apikeys.MockAPIKeyRepository) {
+				repo.On("FindByHash", mock.Anything, presentedHash).Return(&models.APIKey{ID: keyID, UserID: userID, Scopes: []string{auth.ScopeProductsWrite}, ExpiresAt: &future}, nil).Once()
+				repo.On("TouchLastUsed", mock.Anything, keyID).Return(nil).Once()
+			},
+			path:           "/products",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "Authorization ApiKey Header With Scope",
+			setHeader: func(req *http.Request) { req.Header.Set("Authorization", "ApiKey "+presentedKey) },
+			mockRepo: func(repo *apikeys.MockAPIKeyRepository) {
+				repo.On("FindByHash", mock.Anything, presentedHash).Return(&models.APIKey{ID: keyID, UserID: userID, Scopes: []string{auth.ScopeOrdersRead}}, nil).Once()
+				repo.On("TouchLastUsed", mock.Anything, keyID).Return(nil).Once()
+			},
+			path:           "/orders",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "Missing Scope",
+			setHeader: func(req *http.Request) { req.Header.Set("X-API-Key", presentedKey) },
+			mockRepo: func(repo *apikeys.MockAPIKeyRepository) {
+				repo.On("FindByHash", mock.Anything, presentedHash).Return(&models.APIKey{ID: keyID, UserID: userID, Scopes: []string{auth.ScopeOrdersRead}}, nil).Once()
+				repo.On("TouchLastUsed", mock.Anything, keyID).Return(nil).Once()
+			},
+			path:           "/products",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"insufficient scope"}`,
+		},
+		{
+			name:      "Last Used Update Error Is Not Fatal",
+			setHeader: func(req *http.Request) { req.Header.Set("X-API-Key", presentedKey) },
+			mockRepo: func(repo *apikeys.MockAPIKeyRepository) {
+				repo.On("FindByHash", mock.Anything, presentedHash).Return(&models.APIKey{ID: keyID, UserID: userID, Scopes: []string{auth.ScopeOrdersRead}}, nil).Once()
+				repo.On("TouchLastUsed", mock.Anything, keyID).Return(assert.AnError).Once()
+			},
+			path:           "/orders",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "Expired Key",
+			setHeader: func(req *http.Request) { req.Header.Set("X-API-Key", presentedKey) },
+			mockRepo: func(repo *apikeys.MockAPIKeyRepository) {
+				repo.On("FindByHash", mock.Anything, presentedHash).Return(&models.APIKey{ID: keyID, UserID: userID, Scopes: []string{auth.ScopeOrdersRead}, ExpiresAt: &past}, nil).Once()
+			},
+			path:           "/orders",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"API key has expired"}`,
+		},
+		{
+			name:      "Unknown Key",
+			setHeader: func(req *http.Request) { req.Header.Set("Authorization", "apikey "+presentedKey) },
+			mockRepo: func(repo *apikeys.MockAPIKeyRepository) {
+				repo.On("FindByHash", mock.Anything, presentedHash).Return(nil, apikeys.ErrAPIKeyNotFound).Once()
+			},
+			path:           "/orders",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid API key"}`,
+		},
+		{
+			name:      "Repository Error",
+			setHeader: func(req *http.Request) { req.Header.Set("X-API-Key", presentedKey) },
+			mockRepo: func(repo *apikeys.MockAPIKeyRepository) {
+				repo.On("FindByHash", mock.Anything, presentedHash).Return(nil, assert.AnError).Once()
+			},
+			path:           "/orders",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"error verifying API key"}`,
+		},
+		{
+			name: "JWT Is Not Limited By Scopes",
+			setHeader: func(req *http.Request) {
+				token, err := generateTestToken(userID)
+				require.NoError(t, err)
+				req.Header.Set("Authorization", "Bearer "+token)
+			},
+			mockRepo:       func(repo *apikeys.MockAPIKeyRepository) { /* Not called */ },
+			path:           "/products",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unknown Authorization Scheme",
+			setHeader:      func(req *http.Request) { req.Header.Set("Authorization", "Basic dXNlcjpwYXNz") },
+			mockRepo:       func(repo *apikeys.MockAPIKeyRepository) { /* Not called */ },
+			path:           "/orders",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid authorization header format"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockUserRepo.On("FindByID", mock.Anything, userID).Return(user, nil).Maybe()
+			mockAPIKeyRepo := new(apikeys.MockAPIKeyRepository)
+			tc.mockRepo(mockAPIKeyRepo)
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), mockAPIKeyRepo)
+
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, userID, r.Context().Value(auth.UserIDContextKey))
+				w.WriteHeader(http.StatusOK)
+			})
+			router := mux.NewRouter()
+			router.Handle("/products", authMiddleware.Authenticate(authMiddleware.RequireScope(auth.ScopeProductsWrite)(ok))).Methods("POST")
+			router.Handle("/orders", authMiddleware.Authenticate(authMiddleware.RequireScope(auth.ScopeOrdersRead)(ok))).Methods("GET")
+
+			method := "GET"
+			if tc.path == "/products" {
+				method = "POST"
+			}
+			req, _ := http.NewRequest(method, tc.path, nil)
+			tc.setHeader(req)
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockAPIKeyRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -2,61 +2,125 @@ package handlers
 
 import (
 	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/identities"
+	"bullet-cloud-api/internal/lockout"
+	"bullet-cloud-api/internal/mfa"
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/oidc"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/tokens"
 	"bullet-cloud-api/internal/users"
 	"bullet-cloud-api/internal/webutils"
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// maxUserAgentLength bounds the User-Agent stored with a session.
+const maxUserAgentLength = 512
+
+// mfaChallengeExpiry is how long a user has to submit the second factor after a correct password.
+const mfaChallengeExpiry = 5 * time.Minute
+
+// errPasswordResetRequired is returned by Login, LoginMFA and Refresh after an admin forced a password reset.
+var errPasswordResetRequired = errors.New("password reset required, use the link sent to your email")
+
 // AuthHandler handles authentication requests.
 type AuthHandler struct {
 	UserRepo            users.UserRepository
+	RefreshTokenRepo    tokens.RefreshTokenRepository
+	SessionRepo         sessions.SessionRepository // One session per login, shared by its access and refresh tokens
+	MFARepo             mfa.MFARepository          // Two-factor settings checked after the password step
 	Hasher              auth.PasswordHasher
-	JwtSecret           string
-	TokenExpiryDuration time.Duration
+	PasswordPolicy      *auth.PasswordPolicy // Rules for passwords chosen at registration
+	LoginLimiter        *lockout.Limiter     // Tracks failed logins per email and client IP
+	Verifier            VerificationSender   // Sends the email verification link after registration
+	Keys                *auth.KeySet         // Signs access tokens
+	JwtSecret           string               // Signs internal tokens such as the 2FA challenge
+	TokenExpiryDuration time.Duration        // Lifetime of access tokens
+	RefreshTokenExpiry  time.Duration        // Lifetime of each refresh token
+
+	// External sign-in; both are unset unless OIDC providers are configured (see WithOIDC)
+	IdentityRepo  identities.IdentityRepository // Links provider accounts to users
+	OIDCProviders map[string]*oidc.Provider     // Keyed by provider name
 }
 
 // NewAuthHandler creates a new AuthHandler.
-func NewAuthHandler(userRepo users.UserRepository, hasher auth.PasswordHasher, jwtSecret string, tokenExpiry time.Duration) *AuthHandler {
+func NewAuthHandler(userRepo users.UserRepository, refreshTokenRepo tokens.RefreshTokenRepository, sessionRepo sessions.SessionRepository, mfaRepo mfa.MFARepository, hasher auth.PasswordHasher, passwordPolicy *auth.PasswordPolicy, loginLimiter *lockout.Limiter, verifier VerificationSender, keys *auth.KeySet, jwtSecret string, tokenExpiry, refreshExpiry time.Duration) *AuthHandler {
 	return &AuthHandler{
 		UserRepo:            userRepo,
+		RefreshTokenRepo:    refreshTokenRepo,
+		SessionRepo:         sessionRepo,
+		MFARepo:             mfaRepo,
 		Hasher:              hasher,
+		PasswordPolicy:      passwordPolicy,
+		LoginLimiter:        loginLimiter,
+		Verifier:            verifier,
+		Keys:                keys,
 		JwtSecret:           jwtSecret,
 		TokenExpiryDuration: tokenExpiry,
+		RefreshTokenExpiry:  refreshExpiry,
 	}
 }
 
 // --- Request/Response Structs ---
 
+// RegisterRequest is the body of POST /api/auth/register.
 type RegisterRequest struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+// LoginRequest is the body of POST /api/auth/login.
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`         // Short-lived access token (JWT)
+	RefreshToken string `json:"refresh_token"` // Opaque token used to obtain new access tokens
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// MFAChallengeResponse is returned by Login instead of tokens when the account has 2FA enabled.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`  // Short-lived challenge, exchanged at /api/auth/login/2fa
+	ExpiresIn   int64  `json:"expires_in"` // Challenge lifetime in seconds
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"` // Current TOTP code or an unused recovery code
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LockoutResponse is returned when a login is refused because of too many failed attempts.
+type LockoutResponse struct {
+	Error       string    `json:"error"`
+	LockedUntil time.Time `json:"locked_until"`
 }
 
 // --- Handlers ---
 
 // Register handles new user registration.
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+	var req RegisterRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
@@ -71,6 +135,11 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if violations := h.PasswordPolicy.Validate(req.Password, req.Name, req.Email); len(violations) > 0 {
+		webutils.ValidationErrorJSON(w, passwordFieldErrors("password", violations))
+		return
+	}
+
 	// Check if email already exists
 	_, err := h.UserRepo.FindByEmail(context.Background(), req.Email)
 	if err == nil {
@@ -99,15 +168,17 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The account is usable right away; the user can ask for a new link if this one is lost
+	if err := h.Verifier.SendVerificationEmail(r.Context(), createdUser); err != nil {
+		log.Printf("Error sending verification email: %v", err)
+	}
+
 	webutils.WriteJSON(w, http.StatusCreated, createdUser)
 }
 
 // Login handles user login and JWT generation.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+	var req LoginRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
@@ -121,10 +192,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := clientIP(r)
+
+	// Refuse early while the account or the client is locked out
+	if err := h.LoginLimiter.Check(r.Context(), req.Email, ip); err != nil {
+		respondLoginBlocked(w, err)
+		return
+	}
+
 	user, err := h.UserRepo.FindByEmail(context.Background(), req.Email)
 	if err != nil {
 		if errors.Is(err, users.ErrUserNotFound) {
-			webutils.ErrorJSON(w, errors.New("invalid email or password"), http.StatusUnauthorized)
+			// Count unknown emails too, so lockouts don't reveal which accounts exist
+			h.loginFailed(w, r, req.Email, ip, errors.New("invalid email or password"))
 		} else {
 			webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
 		}
@@ -133,15 +213,400 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	err = h.Hasher.CheckPassword(user.PasswordHash, req.Password)
 	if err != nil {
-		webutils.ErrorJSON(w, errors.New("invalid email or password"), http.StatusUnauthorized)
+		h.loginFailed(w, r, req.Email, ip, errors.New("invalid email or password"))
+		return
+	}
+
+	if rejectDisabledAccount(w, user) {
+		return
+	}
+	// The password may be known to someone else; it only works again once it has been reset
+	if rejectPasswordResetRequired(w, user) {
+		return
+	}
+
+	h.upgradePasswordHash(r.Context(), user, req.Password)
+
+	h.completeLogin(w, r, user)
+}
+
+// LoginMFA handles POST /api/auth/login/2fa.
+// It exchanges the challenge token returned by Login plus a valid second factor for real tokens.
+// Wrong codes count as failed logins, so the code cannot be brute-forced.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFARequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if req.MFAToken == "" || strings.TrimSpace(req.Code) == "" {
+		webutils.ErrorJSON(w, errors.New("mfa_token and code are required"), http.StatusBadRequest)
+		return
+	}
+
+	claims, err := auth.ValidateMFAChallengeToken(req.MFAToken, h.JwtSecret)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid or expired two-factor challenge"), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("invalid or expired two-factor challenge"), http.StatusUnauthorized)
+		} else {
+			webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+		}
+		return
+	}
+	if rejectDisabledAccount(w, user) || rejectPasswordResetRequired(w, user) {
+		return
+	}
+
+	ip := clientIP(r)
+	if err := h.LoginLimiter.Check(r.Context(), user.Email, ip); err != nil {
+		respondLoginBlocked(w, err)
+		return
+	}
+
+	settings, err := h.MFARepo.FindByUserID(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, mfa.ErrMFANotFound) {
+		webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+		return
+	}
+	if !settings.IsEnabled() {
+		// 2FA was turned off after the challenge was issued; make the client log in again
+		webutils.ErrorJSON(w, errors.New("invalid or expired two-factor challenge"), http.StatusUnauthorized)
+		return
+	}
+
+	if err := verifySecondFactor(r.Context(), h.MFARepo, settings, req.Code); err != nil {
+		if errors.Is(err, errInvalidSecondFactor) {
+			h.loginFailed(w, r, user.Email, ip, err)
+		} else {
+			webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.LoginLimiter.RecordSuccess(r.Context(), user.Email); err != nil {
+		log.Printf("Error resetting login attempts: %v", err)
+	}
+
+	resp, err := h.issueTokens(r, user)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Refresh handles POST /api/auth/refresh.
+// The presented refresh token is consumed and replaced (rotation); reusing an old
+// token revokes every token issued from the same login.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		webutils.ErrorJSON(w, errors.New("refresh_token is required"), http.StatusBadRequest)
+		return
+	}
+
+	refreshToken, refreshHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to refresh token"), http.StatusInternalServerError)
+		return
+	}
+
+	rotated, err := h.RefreshTokenRepo.Rotate(r.Context(), auth.HashOpaqueToken(req.RefreshToken), refreshHash, time.Now().Add(h.RefreshTokenExpiry))
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrRefreshTokenReused):
+			log.Printf("WARNING: refresh token reuse detected, token family revoked")
+			webutils.ErrorJSON(w, errors.New("invalid refresh token"), http.StatusUnauthorized)
+		case errors.Is(err, tokens.ErrRefreshTokenNotFound), errors.Is(err, tokens.ErrRefreshTokenExpired):
+			webutils.ErrorJSON(w, errors.New("invalid refresh token"), http.StatusUnauthorized)
+		default:
+			webutils.ErrorJSON(w, errors.New("failed to refresh token"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Make sure the account still exists before handing out a new access token
+	user, err := h.UserRepo.FindByID(r.Context(), rotated.UserID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("invalid refresh token"), http.StatusUnauthorized)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to refresh token"), http.StatusInternalServerError)
+		}
+		return
+	}
+	// A forced reset ends the account's tokens too, not only its password
+	if rejectDisabledAccount(w, user) || rejectPasswordResetRequired(w, user) {
+		return
+	}
+
+	// The refresh token family is the login's session; it must not have been revoked
+	session, err := h.SessionRepo.FindByID(r.Context(), rotated.FamilyID)
+	switch {
+	case errors.Is(err, sessions.ErrSessionNotFound):
+		// Logged in before sessions were tracked: adopt the token family as a session
+		_, err = h.SessionRepo.Create(r.Context(), newSession(r, user.ID, rotated.FamilyID))
+		if err != nil {
+			webutils.ErrorJSON(w, errors.New("failed to refresh token"), http.StatusInternalServerError)
+			return
+		}
+	case err != nil:
+		webutils.ErrorJSON(w, errors.New("failed to refresh token"), http.StatusInternalServerError)
+		return
+	case !session.IsActive():
+		webutils.ErrorJSON(w, errors.New("invalid refresh token"), http.StatusUnauthorized)
+		return
+	}
+
+	accessToken, err := auth.GenerateToken(user.ID, string(user.Role), rotated.FamilyID, h.Keys, h.TokenExpiryDuration)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to refresh token"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, h.tokenResponse(accessToken, refreshToken))
+}
+
+// Logout handles POST /api/auth/logout.
+// It revokes the presented refresh token, every token rotated from the same login and
+// the login's session, so its access tokens stop working too.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		webutils.ErrorJSON(w, errors.New("refresh_token is required"), http.StatusBadRequest)
+		return
+	}
+
+	familyID, err := h.RefreshTokenRepo.RevokeFamily(r.Context(), auth.HashOpaqueToken(req.RefreshToken))
+	if err != nil && !errors.Is(err, tokens.ErrRefreshTokenNotFound) {
+		webutils.ErrorJSON(w, errors.New("failed to logout"), http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		err = h.SessionRepo.Revoke(r.Context(), familyID)
+		if err != nil && !errors.Is(err, sessions.ErrSessionNotFound) {
+			webutils.ErrorJSON(w, errors.New("failed to logout"), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Unknown tokens are treated as already logged out
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// upgradePasswordHash re-hashes a just-verified password if its stored hash uses an outdated
+// algorithm or cost. Failures are only logged, since the old hash still works.
+func (h *AuthHandler) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+	if !h.Hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	newHash, err := h.Hasher.HashPassword(password)
+	if err != nil {
+		log.Printf("Error re-hashing password: %v", err)
 		return
 	}
+	if err := h.UserRepo.UpdatePassword(ctx, user.ID, newHash); err != nil {
+		log.Printf("Error upgrading password hash: %v", err)
+		return
+	}
+	user.PasswordHash = newHash
+}
 
-	token, err := auth.GenerateToken(user.ID, h.JwtSecret, h.TokenExpiryDuration)
+// completeLogin finishes a login whose first factor (password or external identity) succeeded.
+// With 2FA enabled this only earns a challenge token, and failed attempts are not reset
+// until the second factor is verified.
+func (h *AuthHandler) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User) {
+	settings, err := h.MFARepo.FindByUserID(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, mfa.ErrMFANotFound) {
+		webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+		return
+	}
+	if settings.IsEnabled() {
+		challenge, err := auth.GenerateMFAChallengeToken(user.ID, h.JwtSecret, mfaChallengeExpiry)
+		if err != nil {
+			webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    challenge,
+			ExpiresIn:   int64(mfaChallengeExpiry.Seconds()),
+		})
+		return
+	}
+
+	if err := h.LoginLimiter.RecordSuccess(r.Context(), user.Email); err != nil {
+		log.Printf("Error resetting login attempts: %v", err)
+	}
+
+	resp, err := h.issueTokens(r, user)
 	if err != nil {
 		webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
 		return
 	}
 
-	webutils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+	webutils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// rejectDisabledAccount writes a 403 response and returns true if an admin disabled the user's account.
+func rejectDisabledAccount(w http.ResponseWriter, user *models.User) bool {
+	if !user.IsDisabled() {
+		return false
+	}
+	webutils.ErrorJSON(w, auth.ErrAccountDisabled, http.StatusForbidden)
+	return true
+}
+
+// rejectPasswordResetRequired writes a 403 response and returns true if an admin forced a password reset
+// that the user has not completed yet.
+func rejectPasswordResetRequired(w http.ResponseWriter, user *models.User) bool {
+	if !user.PasswordResetRequired {
+		return false
+	}
+	webutils.ErrorJSON(w, errPasswordResetRequired, http.StatusForbidden)
+	return true
+}
+
+// loginFailed records a failed login and writes the matching response:
+// a lockout if this attempt reached the threshold, otherwise 401 with failure.
+func (h *AuthHandler) loginFailed(w http.ResponseWriter, r *http.Request, email, ip string, failure error) {
+	credentialFailed(w, r, h.LoginLimiter, email, ip, failure, http.StatusUnauthorized)
+}
+
+// credentialFailed records a wrong password or second factor against the login limiter, so
+// guesses made with an access token count like failed logins, and writes the matching
+// response: a lockout if this attempt reached the threshold, otherwise status with failure.
+func credentialFailed(w http.ResponseWriter, r *http.Request, limiter *lockout.Limiter, email, ip string, failure error, status int) {
+	if err := limiter.RecordFailure(r.Context(), email, ip); err != nil {
+		respondLoginBlocked(w, err)
+		return
+	}
+	webutils.ErrorJSON(w, failure, status)
+}
+
+// respondLoginBlocked writes the response for an error returned by the login limiter.
+// Account lockouts use 423 Locked; client (IP) lockouts use 429 Too Many Requests.
+func respondLoginBlocked(w http.ResponseWriter, err error) {
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) {
+		log.Printf("Error checking login attempts: %v", err)
+		webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusLocked
+	if locked.Scope == lockout.ScopeIP {
+		status = http.StatusTooManyRequests
+	}
+	retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	webutils.WriteJSON(w, status, LockoutResponse{
+		Error:       "too many failed login attempts",
+		LockedUntil: locked.Until.UTC(),
+	})
+}
+
+// respondRequestsLimited writes the response for an error returned by a limiter of requests
+// other than logins: 429 Too Many Requests for both the email and the client.
+func respondRequestsLimited(w http.ResponseWriter, err error, message string) {
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) {
+		log.Printf("Error checking request limit: %v", err)
+		webutils.ErrorJSON(w, errors.New("request failed"), http.StatusInternalServerError)
+		return
+	}
+	retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	webutils.WriteJSON(w, http.StatusTooManyRequests, LockoutResponse{Error: message, LockedUntil: locked.Until.UTC()})
+}
+
+// passwordFieldErrors reports password policy violations as errors on the given field.
+func passwordFieldErrors(field string, violations []auth.PasswordViolation) []webutils.FieldError {
+	fields := make([]webutils.FieldError, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, webutils.FieldError{Field: field, Code: v.Code, Message: v.Message})
+	}
+	return fields
+}
+
+// clientIP returns the IP address of the client that sent the request. Behind reverse
+// proxies it relies on webutils.RealIP to have resolved it from X-Forwarded-For.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// newSession describes a session for the client that sent the request.
+func newSession(r *http.Request, userID, sessionID uuid.UUID) *models.Session {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return &models.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		UserAgent: userAgent,
+		IPAddress: clientIP(r),
+	}
+}
+
+// issueTokens starts a new session for the user and issues its access token and first refresh token.
+func (h *AuthHandler) issueTokens(r *http.Request, user *models.User) (*LoginResponse, error) {
+	session, err := h.SessionRepo.Create(r.Context(), newSession(r, user.ID, uuid.Nil))
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := auth.GenerateToken(user.ID, string(user.Role), session.ID, h.Keys, h.TokenExpiryDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = h.RefreshTokenRepo.Create(r.Context(), &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  session.ID, // The refresh token family shares the session's ID
+		TokenHash: refreshHash,
+		ExpiresAt: time.Now().Add(h.RefreshTokenExpiry),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h.tokenResponse(accessToken, refreshToken), nil
+}
+
+// tokenResponse builds the response body returned whenever tokens are issued.
+func (h *AuthHandler) tokenResponse(accessToken, refreshToken string) *LoginResponse {
+	return &LoginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(h.TokenExpiryDuration.Seconds()),
+	}
 }
@@ -1,13 +1,19 @@
 package handlers_test
 
 import (
+	"bullet-cloud-api/internal/auth"
 	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/mfa"
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/tokens"
 	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/webutils"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"testing"
 	"time"
 
@@ -49,7 +55,7 @@ func TestAuthHandler_Register(t *testing.T) {
 	userEmail := "test@example.com"
 	userPassword := "password123"
 	hashedPassword := "hashed_password_string"
-	createdUser := &models.User{ID: uuid.New(), Name: userName, Email: userEmail}
+	createdUser := &models.User{ID: uuid.New(), Name: userName, Email: userEmail, Role: models.RoleCustomer}
 
 	tests := []struct {
 		name string
@@ -58,6 +64,7 @@ func TestAuthHandler_Register(t *testing.T) {
 		mockHashPassword    func(*MockPasswordHasher)
 		mockUserCreate      func(*MockUserRepository)
 		mockUserFindByEmail func(*MockUserRepository)
+		mockSendVerify      func(*MockVerificationSender) // Optional: only set when registration succeeds
 		expectedStatus      int
 		expectedBodyRegexp  string // Use regexp for ID matching
 	}{
@@ -76,8 +83,29 @@ func TestAuthHandler_Register(t *testing.T) {
 			mockUserFindByEmail: func(repo *MockUserRepository) {
 				repo.On("FindByEmail", mock.Anything, userEmail).Return(nil, users.ErrUserNotFound).Once()
 			},
+			mockSendVerify: func(sender *MockVerificationSender) {
+				sender.On("SendVerificationEmail", mock.Anything, createdUser).Return(nil).Once()
+			},
+			expectedStatus:     http.StatusCreated,
+			expectedBodyRegexp: fmt.Sprintf(`{"id":"%s","name":"%s","email":"%s","role":"customer","email_verified_at":null,"created_at":".*","updated_at":".*"}`, createdUser.ID, userName, userEmail),
+		},
+		{
+			name: "Success - Verification Email Failure Does Not Block Registration",
+			body: fmt.Sprintf(`{"name":"%s", "email":"%s", "password":"%s"}`, userName, userEmail, userPassword),
+			mockHashPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("HashPassword", userPassword).Return(hashedPassword, nil).Once()
+			},
+			mockUserCreate: func(repo *MockUserRepository) {
+				repo.On("Create", mock.Anything, userName, userEmail, hashedPassword).Return(createdUser, nil).Once()
+			},
+			mockUserFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(nil, users.ErrUserNotFound).Once()
+			},
+			mockSendVerify: func(sender *MockVerificationSender) {
+				sender.On("SendVerificationEmail", mock.Anything, createdUser).Return(assert.AnError).Once()
+			},
 			expectedStatus:     http.StatusCreated,
-			expectedBodyRegexp: fmt.Sprintf(`{"id":"%s","name":"%s","email":"%s","created_at":".*","updated_at":".*"}`, createdUser.ID, userName, userEmail),
+			expectedBodyRegexp: fmt.Sprintf(`"id":"%s"`, createdUser.ID),
 		},
 		{
 			name: "Duplicate Email",
@@ -141,6 +169,35 @@ func TestAuthHandler_Register(t *testing.T) {
 			expectedStatus:      http.StatusBadRequest,
 			expectedBodyRegexp:  `{"error":"name, email, and password are required"}`,
 		},
+		{
+			name:                "Weak Password",
+			body:                fmt.Sprintf(`{"name":"%s", "email":"%s", "password":"short"}`, userName, userEmail),
+			mockHashPassword:    func(hasher *MockPasswordHasher) { /* Not called */ },
+			mockUserCreate:      func(repo *MockUserRepository) { /* Not called */ },
+			mockUserFindByEmail: func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:      http.StatusBadRequest,
+			expectedBodyRegexp: regexp.QuoteMeta(`{"error":"validation failed","fields":[` +
+				`{"field":"password","code":"too_short","message":"password must be at least 8 characters long"},` +
+				`{"field":"password","code":"too_few_character_classes","message":"password must mix at least 2 of: lowercase letters, uppercase letters, digits, symbols"}]}`),
+		},
+		{
+			name:                "Password Contains Email",
+			body:                fmt.Sprintf(`{"name":"%s", "email":"%s", "password":"Test@Example.com1"}`, userName, userEmail),
+			mockHashPassword:    func(hasher *MockPasswordHasher) { /* Not called */ },
+			mockUserCreate:      func(repo *MockUserRepository) { /* Not called */ },
+			mockUserFindByEmail: func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:      http.StatusBadRequest,
+			expectedBodyRegexp:  `"code":"contains_personal_info"`,
+		},
+		{
+			name:                "Breached Password",
+			body:                fmt.Sprintf(`{"name":"%s", "email":"%s", "password":"breached-password-1"}`, userName, userEmail),
+			mockHashPassword:    func(hasher *MockPasswordHasher) { /* Not called */ },
+			mockUserCreate:      func(repo *MockUserRepository) { /* Not called */ },
+			mockUserFindByEmail: func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:      http.StatusBadRequest,
+			expectedBodyRegexp:  `"code":"breached"`,
+		},
 		{
 			name:             "FindByEmail DB Error",
 			body:             fmt.Sprintf(`{"name":"%s", "email":"%s", "password":"%s"}`, userName, userEmail, userPassword),
@@ -160,14 +217,18 @@ func TestAuthHandler_Register(t *testing.T) {
 			// Create mocks for subtest
 			mockUserRepo := new(MockUserRepository)
 			mockHasher := new(MockPasswordHasher)
+			mockVerifier := new(MockVerificationSender)
 
 			// Create a NEW AuthHandler INSIDE t.Run using subtest mocks
-			authHandler := handlers.NewAuthHandler(mockUserRepo, mockHasher, testJwtSecret, time.Hour*1)
+			authHandler := handlers.NewAuthHandler(mockUserRepo, new(tokens.MockRefreshTokenRepository), new(sessions.MockSessionRepository), new(mfa.MockMFARepository), mockHasher, testPasswordPolicy, newTestLoginLimiter(), mockVerifier, testKeys, testJwtSecret, time.Hour*1, time.Hour*24)
 
 			// Setup mocks for the specific test case by passing the subtest mocks
 			tc.mockUserFindByEmail(mockUserRepo)
 			tc.mockHashPassword(mockHasher)
 			tc.mockUserCreate(mockUserRepo)
+			if tc.mockSendVerify != nil {
+				tc.mockSendVerify(mockVerifier)
+			}
 
 			// Create router and register handler INSIDE t.Run for isolation
 			router := mux.NewRouter()
@@ -182,6 +243,7 @@ func TestAuthHandler_Register(t *testing.T) {
 
 			mockUserRepo.AssertExpectations(t)
 			mockHasher.AssertExpectations(t)
+			mockVerifier.AssertExpectations(t)
 		})
 	}
 }
@@ -193,6 +255,7 @@ func TestAuthHandler_Login(t *testing.T) {
 	storedHash := "correct_hashed_password"
 	fakeUserID := uuid.New()
 	foundUser := &models.User{ID: fakeUserID, Email: userEmail, PasswordHash: storedHash}
+	enabledAt := time.Now().Add(-24 * time.Hour)
 
 	tests := []struct {
 		name string
@@ -200,9 +263,13 @@ func TestAuthHandler_Login(t *testing.T) {
 		// Pass mocks created inside t.Run
 		mockFindByEmail   func(*MockUserRepository)
 		mockCheckPassword func(*MockPasswordHasher)
+		mockMFARepo       func(*mfa.MockMFARepository)
+		mockSessionRepo   func(*sessions.MockSessionRepository)
+		mockRefreshRepo   func(*tokens.MockRefreshTokenRepository)
 		expectedStatus    int
 		expectedBodyJSON  map[string]interface{} // Check specific fields
 		expectedBodyError string                 // For error cases
+		expectChallenge   bool                   // Expect an MFA challenge instead of tokens
 	}{
 		{
 			name: "Success",
@@ -212,11 +279,71 @@ func TestAuthHandler_Login(t *testing.T) {
 			},
 			mockCheckPassword: func(hasher *MockPasswordHasher) {
 				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(false).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+					return s.UserID == fakeUserID
+				})).Return(createdSession, nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(rt *models.RefreshToken) bool {
+					return rt.UserID == fakeUserID && rt.TokenHash != "" && rt.FamilyID != uuid.Nil
+				})).Return(&models.RefreshToken{ID: uuid.New(), UserID: fakeUserID}, nil).Once()
 			},
 			expectedStatus:    http.StatusOK,
 			expectedBodyJSON:  map[string]interface{}{"token": mock.AnythingOfType("string")},
 			expectedBodyError: "",
 		},
+		{
+			name: "Outdated Hash Is Upgraded",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(&models.User{ID: fakeUserID, Email: userEmail, PasswordHash: storedHash}, nil).Once()
+				repo.On("UpdatePassword", mock.Anything, fakeUserID, "upgraded_hash").Return(nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(true).Once()
+				hasher.On("HashPassword", userPassword).Return("upgraded_hash", nil).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(createdSession, nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{ID: uuid.New(), UserID: fakeUserID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Hash Upgrade Failure Does Not Block Login",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(&models.User{ID: fakeUserID, Email: userEmail, PasswordHash: storedHash}, nil).Once()
+				repo.On("UpdatePassword", mock.Anything, fakeUserID, "upgraded_hash").Return(assert.AnError).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(true).Once()
+				hasher.On("HashPassword", userPassword).Return("upgraded_hash", nil).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(createdSession, nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{ID: uuid.New(), UserID: fakeUserID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
 		{
 			name: "User Not Found",
 			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
@@ -241,6 +368,30 @@ func TestAuthHandler_Login(t *testing.T) {
 			expectedBodyJSON:  nil,
 			expectedBodyError: `{"error":"invalid email or password"}`,
 		},
+		{
+			name: "Disabled Account",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(&models.User{ID: fakeUserID, Email: userEmail, PasswordHash: storedHash, DisabledAt: &enabledAt, PasswordResetRequired: true}, nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+			},
+			expectedStatus:    http.StatusForbidden,
+			expectedBodyError: `{"error":"account is disabled"}`,
+		},
+		{
+			name: "Password Reset Required",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(&models.User{ID: fakeUserID, Email: userEmail, PasswordHash: storedHash, PasswordResetRequired: true}, nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+			},
+			expectedStatus:    http.StatusForbidden,
+			expectedBodyError: `{"error":"password reset required, use the link sent to your email"}`,
+		},
 		{
 			name: "Find User DB Error",
 			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
@@ -274,12 +425,126 @@ func TestAuthHandler_Login(t *testing.T) {
 			},
 			mockCheckPassword: func(hasher *MockPasswordHasher) {
 				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(false).Once()
 				// Assume GenerateToken succeeds if CheckPassword is nil.
 			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+					return s.UserID == fakeUserID
+				})).Return(createdSession, nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{ID: uuid.New(), UserID: fakeUserID}, nil).Once()
+			},
 			expectedStatus:    http.StatusOK,
 			expectedBodyJSON:  map[string]interface{}{"token": mock.AnythingOfType("string")},
 			expectedBodyError: "",
 		},
+		{
+			name: "Refresh Token Store Error",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(false).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+					return s.UserID == fakeUserID
+				})).Return(createdSession, nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBodyJSON:  nil,
+			expectedBodyError: `{"error":"login failed"}`,
+		},
+		{
+			name: "Session Store Error",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(false).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+			},
+			mockRefreshRepo:   func(repo *tokens.MockRefreshTokenRepository) { /* Not called */ },
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBodyJSON:  nil,
+			expectedBodyError: `{"error":"login failed"}`,
+		},
+		{
+			name: "MFA Enabled Returns Challenge",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(false).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(&models.UserMFA{UserID: fakeUserID, EnabledAt: &enabledAt}, nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) { /* Not called: no tokens before the second factor */ },
+			expectedStatus:  http.StatusOK,
+			expectChallenge: true,
+		},
+		{
+			name: "MFA Pending Enrollment Is Ignored",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(false).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(&models.UserMFA{UserID: fakeUserID}, nil).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+					return s.UserID == fakeUserID
+				})).Return(createdSession, nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{ID: uuid.New(), UserID: fakeUserID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "MFA Lookup Error",
+			body: fmt.Sprintf(`{"email":"%s", "password":"%s"}`, userEmail, userPassword),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Once()
+			},
+			mockCheckPassword: func(hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+				hasher.On("NeedsRehash", storedHash).Return(false).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, fakeUserID).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBodyError: `{"error":"login failed"}`,
+		},
 		{
 			name:              "Invalid JSON",
 			body:              `{"email":"bad}`, // Malformed
@@ -306,13 +571,25 @@ func TestAuthHandler_Login(t *testing.T) {
 			// Create mocks for subtest
 			mockUserRepo := new(MockUserRepository)
 			mockHasher := new(MockPasswordHasher)
+			mockRefreshRepo := new(tokens.MockRefreshTokenRepository)
+			mockSessionRepo := new(sessions.MockSessionRepository)
+			mockMFARepo := new(mfa.MockMFARepository)
 
 			// Create a NEW AuthHandler INSIDE t.Run using subtest mocks
-			authHandler := handlers.NewAuthHandler(mockUserRepo, mockHasher, testJwtSecret, time.Hour*1)
+			authHandler := handlers.NewAuthHandler(mockUserRepo, mockRefreshRepo, mockSessionRepo, mockMFARepo, mockHasher, testPasswordPolicy, newTestLoginLimiter(), new(MockVerificationSender), testKeys, testJwtSecret, time.Hour*1, time.Hour*24)
 
 			// Setup mock expectations on the subtest mocks
 			tc.mockFindByEmail(mockUserRepo)
 			tc.mockCheckPassword(mockHasher)
+			if tc.mockMFARepo != nil {
+				tc.mockMFARepo(mockMFARepo)
+			}
+			if tc.mockSessionRepo != nil {
+				tc.mockSessionRepo(mockSessionRepo)
+			}
+			if tc.mockRefreshRepo != nil {
+				tc.mockRefreshRepo(mockRefreshRepo)
+			}
 
 			// Create router and register handler INSIDE t.Run for isolation
 			router := mux.NewRouter()
@@ -325,6 +602,18 @@ func TestAuthHandler_Login(t *testing.T) {
 
 			if tc.expectedBodyError != "" {
 				require.JSONEq(t, tc.expectedBodyError, rr.Body.String(), "Handler returned wrong error body")
+			} else if tc.expectChallenge {
+				var respBody handlers.MFAChallengeResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &respBody))
+				assert.True(t, respBody.MFARequired)
+				assert.NotContains(t, rr.Body.String(), `"token"`, "No access token may be issued before the second factor")
+
+				// The challenge only works at the 2FA endpoint, not as an access token
+				claims, err := auth.ValidateMFAChallengeToken(respBody.MFAToken, testJwtSecret)
+				require.NoError(t, err)
+				assert.Equal(t, fakeUserID, claims.UserID)
+				_, err = auth.ValidateToken(respBody.MFAToken, testKeys)
+				assert.Error(t, err)
 			} else {
 				// Check for token presence and type
 				var respBody map[string]interface{}
@@ -332,11 +621,606 @@ func TestAuthHandler_Login(t *testing.T) {
 				require.NoError(t, err, "Failed to unmarshal response body")
 				require.Contains(t, respBody, "token", "Response body should contain token")
 				require.IsType(t, "string", respBody["token"], "Token should be a string")
+				require.NotEmpty(t, respBody["refresh_token"], "Response body should contain a refresh token")
 				// Optionally, validate the token structure/payload here if needed
 			}
 
 			mockUserRepo.AssertExpectations(t)
 			mockHasher.AssertExpectations(t)
+			mockRefreshRepo.AssertExpectations(t)
+			mockSessionRepo.AssertExpectations(t)
+			mockMFARepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthHandler_LoginLockout(t *testing.T) {
+	userEmail := "test@example.com"
+	userPassword := "password123"
+	storedHash := "correct_hashed_password"
+	foundUser := &models.User{ID: uuid.New(), Email: userEmail, PasswordHash: storedHash}
+
+	// newLoginRequest builds a login request from a fixed client address.
+	newLoginRequest := func(email, password, remoteAddr string) *http.Request {
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(fmt.Sprintf(`{"email":"%s", "password":"%s"}`, email, password)))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		return req
+	}
+
+	// setup creates a handler with a fresh in-memory lockout store.
+	setup := func() (*MockUserRepository, *MockPasswordHasher, *tokens.MockRefreshTokenRepository, *mux.Router) {
+		mockUserRepo := new(MockUserRepository)
+		mockHasher := new(MockPasswordHasher)
+		mockRefreshRepo := new(tokens.MockRefreshTokenRepository)
+		mockMFARepo := new(mfa.MockMFARepository)
+		mockMFARepo.On("FindByUserID", mock.Anything, mock.Anything).Return(nil, mfa.ErrMFANotFound).Maybe()
+		mockSessionRepo := new(sessions.MockSessionRepository)
+		mockSessionRepo.On("Create", mock.Anything, mock.Anything).Return(createdSession, nil).Maybe()
+		authHandler := handlers.NewAuthHandler(mockUserRepo, mockRefreshRepo, mockSessionRepo, mockMFARepo, mockHasher, testPasswordPolicy, newTestLoginLimiter(), new(MockVerificationSender), testKeys, testJwtSecret, time.Hour*1, time.Hour*24)
+
+		router := mux.NewRouter()
+		router.HandleFunc("/login", authHandler.Login).Methods("POST")
+		return mockUserRepo, mockHasher, mockRefreshRepo, router
+	}
+
+	t.Run("Account Locked After Max Attempts", func(t *testing.T) {
+		mockUserRepo, mockHasher, _, router := setup()
+		mockUserRepo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Times(testMaxLoginAttempts)
+		mockHasher.On("CheckPassword", storedHash, "wrongpassword").Return(bcrypt.ErrMismatchedHashAndPassword).Times(testMaxLoginAttempts)
+
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, newLoginRequest(userEmail, "wrongpassword", "198.51.100.1:1234"), http.StatusUnauthorized, `{"error":"invalid email or password"}`)
+		}
+
+		// The attempt that reaches the threshold is answered with the lockout
+		rr := executeRequestAndAssert(t, router, newLoginRequest(userEmail, "wrongpassword", "198.51.100.1:1234"), http.StatusLocked, "")
+		var body handlers.LockoutResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "too many failed login attempts", body.Error)
+		assert.WithinDuration(t, time.Now().Add(testLockoutDuration), body.LockedUntil, time.Minute)
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+		// Even the correct password is refused while locked, from any client, without touching the repository
+		executeRequestAndAssert(t, router, newLoginRequest(userEmail, userPassword, "203.0.113.9:4321"), http.StatusLocked, "too many failed login attempts")
+
+		mockUserRepo.AssertExpectations(t)
+		mockHasher.AssertExpectations(t)
+	})
+
+	t.Run("Client IP Locked Across Accounts", func(t *testing.T) {
+		mockUserRepo, _, _, router := setup()
+		mockUserRepo.On("FindByEmail", mock.Anything, mock.Anything).Return(nil, users.ErrUserNotFound).Times(testMaxLoginAttempts)
+
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			email := fmt.Sprintf("user%d@example.com", i)
+			executeRequestAndAssert(t, router, newLoginRequest(email, userPassword, "198.51.100.2:1234"), http.StatusUnauthorized, `{"error":"invalid email or password"}`)
+		}
+		executeRequestAndAssert(t, router, newLoginRequest("last@example.com", userPassword, "198.51.100.2:1234"), http.StatusTooManyRequests, "too many failed login attempts")
+
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Clients Behind A Trusted Proxy Are Told Apart", func(t *testing.T) {
+		mockUserRepo, _, _, router := setup()
+		router.Use(webutils.RealIP(1))
+		mockUserRepo.On("FindByEmail", mock.Anything, mock.Anything).Return(nil, users.ErrUserNotFound)
+
+		// Every request arrives from the proxy; it appends the client's address
+		forwarded := func(email, forwardedFor string) *http.Request {
+			req := newLoginRequest(email, userPassword, "10.0.0.1:5555")
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			return req
+		}
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, forwarded(fmt.Sprintf("user%d@example.com", i), "203.0.113.7"), http.StatusUnauthorized, `{"error":"invalid email or password"}`)
+		}
+		executeRequestAndAssert(t, router, forwarded("last@example.com", "203.0.113.7"), http.StatusTooManyRequests, "too many failed login attempts")
+
+		// A forged entry before the proxy's does not get the client out of its lockout
+		executeRequestAndAssert(t, router, forwarded("other@example.com", "192.0.2.99, 203.0.113.7"), http.StatusTooManyRequests, "too many failed login attempts")
+		// Other clients behind the same proxy can still log in
+		executeRequestAndAssert(t, router, forwarded("other@example.com", "203.0.113.8"), http.StatusUnauthorized, `{"error":"invalid email or password"}`)
+	})
+
+	t.Run("Successful Login Resets Counter", func(t *testing.T) {
+		mockUserRepo, mockHasher, mockRefreshRepo, router := setup()
+		mockUserRepo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil)
+		mockHasher.On("CheckPassword", storedHash, "wrongpassword").Return(bcrypt.ErrMismatchedHashAndPassword)
+		mockHasher.On("CheckPassword", storedHash, userPassword).Return(nil).Once()
+		mockHasher.On("NeedsRehash", storedHash).Return(false).Once()
+		mockRefreshRepo.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{ID: uuid.New(), UserID: foundUser.ID}, nil).Once()
+
+		// Each client stays below the IP threshold so only the account counter matters
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, newLoginRequest(userEmail, "wrongpassword", fmt.Sprintf("198.51.100.%d:1234", 10+i)), http.StatusUnauthorized, `{"error":"invalid email or password"}`)
+		}
+		executeRequestAndAssert(t, router, newLoginRequest(userEmail, userPassword, "198.51.100.20:1234"), http.StatusOK, "refresh_token")
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, newLoginRequest(userEmail, "wrongpassword", fmt.Sprintf("198.51.100.%d:1234", 20+i)), http.StatusUnauthorized, `{"error":"invalid email or password"}`)
+		}
+
+		mockUserRepo.AssertExpectations(t)
+		mockHasher.AssertExpectations(t)
+		mockRefreshRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuthHandler_Refresh(t *testing.T) {
+	presentedToken := "presented-refresh-token"
+	presentedHash := auth.HashOpaqueToken(presentedToken)
+	userID := uuid.New()
+	rotated := &models.RefreshToken{ID: uuid.New(), UserID: userID, FamilyID: uuid.New()}
+	revokedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name              string
+		body              string
+		mockRotate        func(*tokens.MockRefreshTokenRepository)
+		mockFindByID      func(*MockUserRepository)
+		mockSessionRepo   func(*sessions.MockSessionRepository)
+		expectedStatus    int
+		expectedBodyError string
+	}{
+		{
+			name: "Success",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(rotated, nil).Once()
+			},
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, rotated.FamilyID).Return(&models.Session{ID: rotated.FamilyID, UserID: userID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Success - Session Adopted For Legacy Login",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(rotated, nil).Once()
+			},
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, rotated.FamilyID).Return(nil, sessions.ErrSessionNotFound).Once()
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+					return s.ID == rotated.FamilyID && s.UserID == userID
+				})).Return(&models.Session{ID: rotated.FamilyID, UserID: userID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Session Revoked",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(rotated, nil).Once()
+			},
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, rotated.FamilyID).Return(&models.Session{ID: rotated.FamilyID, UserID: userID, RevokedAt: &revokedAt}, nil).Once()
+			},
+			expectedStatus:    http.StatusUnauthorized,
+			expectedBodyError: `{"error":"invalid refresh token"}`,
+		},
+		{
+			name: "User Disabled",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(rotated, nil).Once()
+			},
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, DisabledAt: &revokedAt}, nil).Once()
+			},
+			expectedStatus:    http.StatusForbidden,
+			expectedBodyError: `{"error":"account is disabled"}`,
+		},
+		{
+			name: "Password Reset Required",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(rotated, nil).Once()
+			},
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, PasswordResetRequired: true}, nil).Once()
+			},
+			expectedStatus:    http.StatusForbidden,
+			expectedBodyError: `{"error":"password reset required, use the link sent to your email"}`,
+		},
+		{
+			name: "Session Lookup Error",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(rotated, nil).Once()
+			},
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil).Once()
+			},
+			mockSessionRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, rotated.FamilyID).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBodyError: `{"error":"failed to refresh token"}`,
+		},
+		{
+			name: "Reused Token",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(nil, tokens.ErrRefreshTokenReused).Once()
+			},
+			mockFindByID:      func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:    http.StatusUnauthorized,
+			expectedBodyError: `{"error":"invalid refresh token"}`,
+		},
+		{
+			name: "Expired Token",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(nil, tokens.ErrRefreshTokenExpired).Once()
+			},
+			mockFindByID:      func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:    http.StatusUnauthorized,
+			expectedBodyError: `{"error":"invalid refresh token"}`,
+		},
+		{
+			name: "Unknown Token",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(nil, tokens.ErrRefreshTokenNotFound).Once()
+			},
+			mockFindByID:      func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:    http.StatusUnauthorized,
+			expectedBodyError: `{"error":"invalid refresh token"}`,
+		},
+		{
+			name: "User Deleted",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(rotated, nil).Once()
+			},
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(nil, users.ErrUserNotFound).Once()
+			},
+			expectedStatus:    http.StatusUnauthorized,
+			expectedBodyError: `{"error":"invalid refresh token"}`,
+		},
+		{
+			name: "Repository Error",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRotate: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Rotate", mock.Anything, presentedHash, mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+			},
+			mockFindByID:      func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBodyError: `{"error":"failed to refresh token"}`,
+		},
+		{
+			name:              "Missing Token",
+			body:              `{}`,
+			mockRotate:        func(repo *tokens.MockRefreshTokenRepository) { /* Not called */ },
+			mockFindByID:      func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:    http.StatusBadRequest,
+			expectedBodyError: `{"error":"refresh_token is required"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockRefreshRepo := new(tokens.MockRefreshTokenRepository)
+			mockSessionRepo := new(sessions.MockSessionRepository)
+			authHandler := handlers.NewAuthHandler(mockUserRepo, mockRefreshRepo, mockSessionRepo, new(mfa.MockMFARepository), new(MockPasswordHasher), testPasswordPolicy, newTestLoginLimiter(), new(MockVerificationSender), testKeys, testJwtSecret, time.Hour*1, time.Hour*24)
+
+			tc.mockRotate(mockRefreshRepo)
+			tc.mockFindByID(mockUserRepo)
+			if tc.mockSessionRepo != nil {
+				tc.mockSessionRepo(mockSessionRepo)
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/refresh", authHandler.Refresh).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/refresh", bytes.NewBufferString(tc.body))
+			rr := executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBodyError)
+
+			if tc.expectedStatus == http.StatusOK {
+				var respBody handlers.LoginResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &respBody))
+				assert.NotEmpty(t, respBody.Token)
+				assert.NotEmpty(t, respBody.RefreshToken)
+				assert.NotEqual(t, presentedToken, respBody.RefreshToken, "Refresh token must be rotated")
+
+				claims, err := auth.ValidateToken(respBody.Token, testKeys)
+				require.NoError(t, err)
+				assert.Equal(t, userID, claims.UserID)
+				assert.Equal(t, rotated.FamilyID.String(), claims.ID, "Refreshed tokens stay in the same session")
+			}
+
+			mockUserRepo.AssertExpectations(t)
+			mockRefreshRepo.AssertExpectations(t)
+			mockSessionRepo.AssertExpectations(t)
 		})
 	}
 }
+
+func TestAuthHandler_Logout(t *testing.T) {
+	presentedToken := "presented-refresh-token"
+	presentedHash := auth.HashOpaqueToken(presentedToken)
+	familyID := uuid.New()
+
+	tests := []struct {
+		name              string
+		body              string
+		mockRevoke        func(*tokens.MockRefreshTokenRepository)
+		mockRevokeSession func(*sessions.MockSessionRepository)
+		expectedStatus    int
+		expectedBodyError string
+	}{
+		{
+			name: "Success",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRevoke: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("RevokeFamily", mock.Anything, presentedHash).Return(familyID, nil).Once()
+			},
+			mockRevokeSession: func(repo *sessions.MockSessionRepository) {
+				repo.On("Revoke", mock.Anything, familyID).Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Success - Legacy Login Without Session",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRevoke: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("RevokeFamily", mock.Anything, presentedHash).Return(familyID, nil).Once()
+			},
+			mockRevokeSession: func(repo *sessions.MockSessionRepository) {
+				repo.On("Revoke", mock.Anything, familyID).Return(sessions.ErrSessionNotFound).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Unknown Token Is Ignored",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRevoke: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("RevokeFamily", mock.Anything, presentedHash).Return(uuid.Nil, tokens.ErrRefreshTokenNotFound).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Repository Error",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRevoke: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("RevokeFamily", mock.Anything, presentedHash).Return(uuid.Nil, assert.AnError).Once()
+			},
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBodyError: `{"error":"failed to logout"}`,
+		},
+		{
+			name: "Session Store Error",
+			body: fmt.Sprintf(`{"refresh_token":"%s"}`, presentedToken),
+			mockRevoke: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("RevokeFamily", mock.Anything, presentedHash).Return(familyID, nil).Once()
+			},
+			mockRevokeSession: func(repo *sessions.MockSessionRepository) {
+				repo.On("Revoke", mock.Anything, familyID).Return(assert.AnError).Once()
+			},
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBodyError: `{"error":"failed to logout"}`,
+		},
+		{
+			name:              "Invalid JSON",
+			body:              `{"refresh_token":`,
+			mockRevoke:        func(repo *tokens.MockRefreshTokenRepository) { /* Not called */ },
+			expectedStatus:    http.StatusBadRequest,
+			expectedBodyError: `{"error":"invalid request body"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockRefreshRepo := new(tokens.MockRefreshTokenRepository)
+			mockSessionRepo := new(sessions.MockSessionRepository)
+			authHandler := handlers.NewAuthHandler(new(MockUserRepository), mockRefreshRepo, mockSessionRepo, new(mfa.MockMFARepository), new(MockPasswordHasher), testPasswordPolicy, newTestLoginLimiter(), new(MockVerificationSender), testKeys, testJwtSecret, time.Hour*1, time.Hour*24)
+
+			tc.mockRevoke(mockRefreshRepo)
+			if tc.mockRevokeSession != nil {
+				tc.mockRevokeSession(mockSessionRepo)
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/logout", bytes.NewBufferString(tc.body))
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBodyError)
+
+			mockRefreshRepo.AssertExpectations(t)
+			mockSessionRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthHandler_LoginMFA(t *testing.T) {
+	userID := uuid.New()
+	userEmail := "test@example.com"
+	user := &models.User{ID: userID, Email: userEmail}
+	secret, err := auth.GenerateTOTPSecret()
+	require.NoError(t, err)
+	validCode, err := auth.GenerateTOTPCode(secret, time.Now())
+	require.NoError(t, err)
+	enabledAt := time.Now().Add(-time.Hour)
+	enabled := &models.UserMFA{UserID: userID, Secret: secret, EnabledAt: &enabledAt}
+
+	challenge, err := auth.GenerateMFAChallengeToken(userID, testJwtSecret, time.Minute)
+	require.NoError(t, err)
+	expiredChallenge, err := auth.GenerateMFAChallengeToken(userID, testJwtSecret, -time.Minute)
+	require.NoError(t, err)
+	accessToken, err := generateTestToken(userID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		body            string
+		mockFindByID    func(*MockUserRepository)
+		mockMFARepo     func(*mfa.MockMFARepository)
+		mockRefreshRepo func(*tokens.MockRefreshTokenRepository)
+		expectedStatus  int
+		expectedBody    string
+	}{
+		{
+			name: "Success With TOTP Code",
+			body: fmt.Sprintf(`{"mfa_token":"%s","code":"%s"}`, challenge, validCode),
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(user, nil).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("RecordStep", mock.Anything, userID, mock.AnythingOfType("int64")).Return(nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{ID: uuid.New(), UserID: userID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "refresh_token",
+		},
+		{
+			name: "Success With Recovery Code",
+			body: fmt.Sprintf(`{"mfa_token":"%s","code":"abcde-fghij"}`, challenge),
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(user, nil).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("UseRecoveryCode", mock.Anything, userID, auth.HashRecoveryCode("abcde-fghij")).Return(nil).Once()
+			},
+			mockRefreshRepo: func(repo *tokens.MockRefreshTokenRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{ID: uuid.New(), UserID: userID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "refresh_token",
+		},
+		{
+			name: "Reused TOTP Code",
+			body: fmt.Sprintf(`{"mfa_token":"%s","code":"%s"}`, challenge, validCode),
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(user, nil).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("RecordStep", mock.Anything, userID, mock.AnythingOfType("int64")).Return(mfa.ErrTOTPCodeReused).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid two-factor code"}`,
+		},
+		{
+			name: "Wrong Code",
+			body: fmt.Sprintf(`{"mfa_token":"%s","code":"not-a-code"}`, challenge),
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(user, nil).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("UseRecoveryCode", mock.Anything, userID, mock.Anything).Return(mfa.ErrRecoveryCodeNotFound).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid two-factor code"}`,
+		},
+		{
+			name: "MFA Disabled Since Challenge",
+			body: fmt.Sprintf(`{"mfa_token":"%s","code":"%s"}`, challenge, validCode),
+			mockFindByID: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(user, nil).Once()
+			},
+			mockMFARepo: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid or expired two-factor challenge"}`,
+		},
+		{
+			name:           "Expired Challenge",
+			body:           fmt.Sprintf(`{"mfa_token":"%s","code":"%s"}`, expiredChallenge, validCode),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid or expired two-factor challenge"}`,
+		},
+		{
+			name:           "Access Token Is Not A Challenge",
+			body:           fmt.Sprintf(`{"mfa_token":"%s","code":"%s"}`, accessToken, validCode),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid or expired two-factor challenge"}`,
+		},
+		{
+			name:           "Missing Code",
+			body:           fmt.Sprintf(`{"mfa_token":"%s"}`, challenge),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"mfa_token and code are required"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockRefreshRepo := new(tokens.MockRefreshTokenRepository)
+			mockMFARepo := new(mfa.MockMFARepository)
+			mockSessionRepo := new(sessions.MockSessionRepository)
+			mockSessionRepo.On("Create", mock.Anything, mock.Anything).Return(createdSession, nil).Maybe()
+			authHandler := handlers.NewAuthHandler(mockUserRepo, mockRefreshRepo, mockSessionRepo, mockMFARepo, new(MockPasswordHasher), testPasswordPolicy, newTestLoginLimiter(), new(MockVerificationSender), testKeys, testJwtSecret, time.Hour*1, time.Hour*24)
+
+			if tc.mockFindByID != nil {
+				tc.mockFindByID(mockUserRepo)
+			}
+			if tc.mockMFARepo != nil {
+				tc.mockMFARepo(mockMFARepo)
+			}
+			if tc.mockRefreshRepo != nil {
+				tc.mockRefreshRepo(mockRefreshRepo)
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/login/2fa", authHandler.LoginMFA).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/login/2fa", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockUserRepo.AssertExpectations(t)
+			mockMFARepo.AssertExpectations(t)
+			mockRefreshRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Wrong Codes Lock The Account", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockMFARepo := new(mfa.MockMFARepository)
+		authHandler := handlers.NewAuthHandler(mockUserRepo, new(tokens.MockRefreshTokenRepository), new(sessions.MockSessionRepository), mockMFARepo, new(MockPasswordHasher), testPasswordPolicy, newTestLoginLimiter(), new(MockVerificationSender), testKeys, testJwtSecret, time.Hour*1, time.Hour*24)
+		mockUserRepo.On("FindByID", mock.Anything, userID).Return(user, nil)
+		mockMFARepo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Times(testMaxLoginAttempts)
+		mockMFARepo.On("UseRecoveryCode", mock.Anything, userID, mock.Anything).Return(mfa.ErrRecoveryCodeNotFound).Times(testMaxLoginAttempts)
+
+		router := mux.NewRouter()
+		router.HandleFunc("/login/2fa", authHandler.LoginMFA).Methods("POST")
+		newRequest := func(remoteAddr string) *http.Request {
+			req, _ := http.NewRequest("POST", "/login/2fa", bytes.NewBufferString(fmt.Sprintf(`{"mfa_token":"%s","code":"not-a-code"}`, challenge)))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = remoteAddr
+			return req
+		}
+
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, newRequest(fmt.Sprintf("198.51.100.%d:1234", i)), http.StatusUnauthorized, `{"error":"invalid two-factor code"}`)
+		}
+		executeRequestAndAssert(t, router, newRequest("198.51.100.50:1234"), http.StatusLocked, "too many failed login attempts")
+
+		// Further attempts are refused before any code is checked
+		executeRequestAndAssert(t, router, newRequest("198.51.100.51:1234"), http.StatusLocked, "too many failed login attempts")
+
+		mockMFARepo.AssertExpectations(t)
+	})
+}
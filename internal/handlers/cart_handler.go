@@ -4,9 +4,13 @@ import (
 	// For UserIDContextKey
 	"bullet-cloud-api/internal/cart" // Cart Repository
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/pricing"
 	"bullet-cloud-api/internal/products" // Product Repository
+	"bullet-cloud-api/internal/variants"
 	"bullet-cloud-api/internal/webutils" // JSON Helpers
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -17,21 +21,26 @@ import (
 type CartHandler struct {
 	CartRepo    cart.CartRepository
 	ProductRepo products.ProductRepository // Needed to get current price on add
+	VariantRepo variants.VariantRepository // Products sold in variants are added by variant
+	PricingRepo pricing.PricingRepository  // Prices in the currency a response is asked in
 }
 
 // NewCartHandler creates a new CartHandler.
-func NewCartHandler(cartRepo cart.CartRepository, productRepo products.ProductRepository) *CartHandler {
+func NewCartHandler(cartRepo cart.CartRepository, productRepo products.ProductRepository, variantRepo variants.VariantRepository, pricingRepo pricing.PricingRepository) *CartHandler {
 	return &CartHandler{
 		CartRepo:    cartRepo,
 		ProductRepo: productRepo,
+		VariantRepo: variantRepo,
+		PricingRepo: pricingRepo,
 	}
 }
 
 // --- Request/Response Structs ---
 
 type AddCartItemRequest struct {
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int       `json:"quantity"`
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id"` // Required for products sold in variants
+	Quantity  int        `json:"quantity"`
 }
 
 type UpdateCartItemRequest struct {
@@ -42,7 +51,7 @@ type UpdateCartItemRequest struct {
 type CartResponse struct {
 	Cart  models.Cart       `json:"cart"`
 	Items []models.CartItem `json:"items"`
-	Total float64           `json:"total"` // Calculated total price
+	Total money.Money       `json:"total"` // Calculated total price
 }
 
 // --- Handlers ---
@@ -64,6 +73,7 @@ func (h *CartHandler) getOrCreateUserCart(w http.ResponseWriter, r *http.Request
 }
 
 // GetCart handles GET /api/cart
+// The prices and total are in the currency the request asks for (see requestedCurrency).
 func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 	userCart, ok := h.getOrCreateUserCart(w, r)
 	if !ok {
@@ -76,10 +86,26 @@ func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quote, ok := quoteRequest(w, r, h.PricingRepo, cartProductIDs(items))
+	if !ok {
+		return
+	}
+	if err := quote.PriceCartItems(items); err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to convert prices"), http.StatusInternalServerError)
+		return
+	}
+
 	// Calculate total
-	var total float64
+	total := money.New(0, quote.Currency())
 	for _, item := range items {
-		total += item.Price * float64(item.Quantity)
+		line, err := item.Price.Mul(item.Quantity)
+		if err == nil {
+			total, err = total.Add(line)
+		}
+		if err != nil {
+			webutils.ErrorJSON(w, errors.New("failed to calculate cart total"), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	resp := CartResponse{
@@ -120,18 +146,52 @@ func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	terms, ok := h.itemPriceAndStock(w, r, product, req.VariantID)
+	if !ok {
+		return
+	}
+	quote, ok := quoteRequest(w, r, h.PricingRepo, []uuid.UUID{req.ProductID})
+	if !ok {
+		return
+	}
+
+	// A tracked stock must cover what is already in the cart plus the added quantity.
+	// Checkout takes the stock, so it may still run short by then.
+	if terms.stock != nil {
+		inCart := 0
+		existing, err := h.CartRepo.FindCartItem(r.Context(), userCart.ID, req.ProductID, req.VariantID)
+		if err == nil {
+			inCart = existing.Quantity
+		} else if !errors.Is(err, cart.ErrProductNotInCart) {
+			webutils.ErrorJSON(w, errors.New("failed to retrieve cart item"), http.StatusInternalServerError)
+			return
+		}
+		if inCart+req.Quantity > *terms.stock {
+			webutils.ErrorJSON(w, fmt.Errorf("insufficient stock, %d available", *terms.stock), http.StatusConflict)
+			return
+		}
+	}
+
 	// Add or update the item in the repository
-	cartItem, err := h.CartRepo.AddItem(r.Context(), userCart.ID, req.ProductID, req.Quantity, product.Price)
+	cartItem, err := h.CartRepo.AddItem(r.Context(), userCart.ID, req.ProductID, req.VariantID, req.Quantity, terms.price)
 	if err != nil {
 		webutils.ErrorJSON(w, errors.New("failed to add item to cart"), http.StatusInternalServerError)
 		return
 	}
+	// The cart keeps the price in the store currency; the response shows the requested one
+	cartItem.VariantPriced = terms.variantPriced
+	priced := []models.CartItem{*cartItem}
+	if err := quote.PriceCartItems(priced); err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to convert prices"), http.StatusInternalServerError)
+		return
+	}
 
 	// Return 201 Created on success
-	webutils.WriteJSON(w, http.StatusCreated, cartItem)
+	webutils.WriteJSON(w, http.StatusCreated, priced[0])
 }
 
 // UpdateItem handles PUT /api/cart/items/{productId}
+// The variant of products sold in variants goes in the variant_id query parameter.
 func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 	userCart, ok := h.getOrCreateUserCart(w, r)
 	if !ok {
@@ -145,6 +205,10 @@ func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 		webutils.ErrorJSON(w, errors.New("invalid product ID format"), http.StatusBadRequest)
 		return
 	}
+	variantID, ok := parseVariantIDQuery(w, r)
+	if !ok {
+		return
+	}
 
 	var req UpdateCartItemRequest
 	if err := webutils.ReadJSON(r, &req); err != nil {
@@ -158,7 +222,26 @@ func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.CartRepo.UpdateItemQuantity(r.Context(), userCart.ID, productID, req.Quantity)
+	// As in AddItem, a tracked stock must cover the new quantity
+	product, err := h.ProductRepo.FindByID(r.Context(), productID)
+	if err != nil {
+		if errors.Is(err, products.ErrProductNotFound) {
+			webutils.ErrorJSON(w, errors.New("product not found"), http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to validate product"), http.StatusInternalServerError)
+		}
+		return
+	}
+	terms, ok := h.itemPriceAndStock(w, r, product, variantID)
+	if !ok {
+		return
+	}
+	if terms.stock != nil && req.Quantity > *terms.stock {
+		webutils.ErrorJSON(w, fmt.Errorf("insufficient stock, %d available", *terms.stock), http.StatusConflict)
+		return
+	}
+
+	_, err = h.CartRepo.UpdateItemQuantity(r.Context(), userCart.ID, productID, variantID, req.Quantity)
 	if err != nil {
 		if errors.Is(err, cart.ErrProductNotInCart) {
 			webutils.ErrorJSON(w, err, http.StatusNotFound)
@@ -173,6 +256,7 @@ func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteItem handles DELETE /api/cart/items/{productId}
+// The variant of products sold in variants goes in the variant_id query parameter.
 func (h *CartHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 	userCart, ok := h.getOrCreateUserCart(w, r)
 	if !ok {
@@ -186,8 +270,12 @@ func (h *CartHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 		webutils.ErrorJSON(w, errors.New("invalid product ID format"), http.StatusBadRequest)
 		return
 	}
+	variantID, ok := parseVariantIDQuery(w, r)
+	if !ok {
+		return
+	}
 
-	err = h.CartRepo.RemoveItem(r.Context(), userCart.ID, productID)
+	err = h.CartRepo.RemoveItem(r.Context(), userCart.ID, productID, variantID)
 	if err != nil {
 		if errors.Is(err, cart.ErrProductNotInCart) {
 			webutils.ErrorJSON(w, err, http.StatusNotFound)
@@ -216,3 +304,65 @@ func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
+
+// --- Helpers ---
+
+// itemTerms are the current price and stock of a product, or of its variant, in a cart.
+type itemTerms struct {
+	price         money.Money
+	variantPriced bool // The variant overrides the product price
+	stock         *int // nil when it is not tracked
+}
+
+// itemPriceAndStock returns the current price and stock of the product, or of its variant for
+// products sold in variants: those need a variantID of the product, others must not have one.
+// On failure it writes the response and returns ok == false.
+func (h *CartHandler) itemPriceAndStock(w http.ResponseWriter, r *http.Request, product *models.Product, variantID *uuid.UUID) (itemTerms, bool) {
+	if variantID == nil {
+		productVariants, err := h.VariantRepo.FindByProductID(r.Context(), product.ID)
+		if err != nil {
+			webutils.ErrorJSON(w, errors.New("failed to validate product"), http.StatusInternalServerError)
+			return itemTerms{}, false
+		}
+		if len(productVariants) > 0 {
+			webutils.ErrorJSON(w, errors.New("variant_id is required, the product is sold in variants"), http.StatusBadRequest)
+			return itemTerms{}, false
+		}
+		return itemTerms{price: product.Price, stock: product.Stock}, true
+	}
+
+	variant, err := h.VariantRepo.FindByID(r.Context(), *variantID)
+	if err != nil && !errors.Is(err, variants.ErrVariantNotFound) {
+		webutils.ErrorJSON(w, errors.New("failed to validate variant"), http.StatusInternalServerError)
+		return itemTerms{}, false
+	}
+	if err != nil || variant.ProductID != product.ID {
+		webutils.ErrorJSON(w, errors.New("variant not found"), http.StatusNotFound)
+		return itemTerms{}, false
+	}
+	return itemTerms{price: variant.EffectivePrice(product.Price), variantPriced: variant.Price != nil, stock: variant.Stock}, true
+}
+
+// cartProductIDs returns the products of the cart items.
+func cartProductIDs(items []models.CartItem) []uuid.UUID {
+	productIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		productIDs[i] = item.ProductID
+	}
+	return productIDs
+}
+
+// parseVariantIDQuery reads the optional variant_id query parameter naming a cart item.
+// On failure it writes the response and returns ok == false.
+func parseVariantIDQuery(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+	v := r.URL.Query().Get("variant_id")
+	if v == "" {
+		return nil, true
+	}
+	variantID, err := uuid.Parse(v)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid variant ID format"), http.StatusBadRequest)
+		return nil, false
+	}
+	return &variantID, true
+}
@@ -1,11 +1,16 @@
 package handlers_test
 
 import (
+	"bullet-cloud-api/internal/apikeys"
 	"bullet-cloud-api/internal/auth"
 	"bullet-cloud-api/internal/cart"
 	"bullet-cloud-api/internal/handlers"
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/pricing"
 	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/variants"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -24,10 +29,10 @@ func setupCartTest(t *testing.T) (*MockCartRepository, *MockUserRepository, *Moc
 	// Call the base setup - Capture necessary mocks and router, ignore cart repo from base
 	_, _, router, mockUserRepo, mockProductRepo, _, _, _, _ := setupBaseTest(t)
 
-	cartHandler := handlers.NewCartHandler(mockCartRepo, mockProductRepo)
+	cartHandler := handlers.NewCartHandler(mockCartRepo, mockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
 
 	// Need authMiddleware instance for protected routes
-	authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 
 	apiV1 := router.PathPrefix("/api").Subrouter()
 	apiV1.Use(authMiddleware.Authenticate)
@@ -45,8 +50,8 @@ func TestCartHandler_GetCart(t *testing.T) {
 	testUserID := uuid.New()
 	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
 	testItems := []models.CartItem{
-		{CartID: testCart.ID, ProductID: uuid.New(), Quantity: 2, Price: 10.50},
-		{CartID: testCart.ID, ProductID: uuid.New(), Quantity: 1, Price: 25.00},
+		{CartID: testCart.ID, ProductID: uuid.New(), Quantity: 2, Price: brl("10.50")},
+		{CartID: testCart.ID, ProductID: uuid.New(), Quantity: 1, Price: brl("25.00")},
 	}
 
 	tests := []struct {
@@ -62,7 +67,7 @@ func TestCartHandler_GetCart(t *testing.T) {
 				mockGetCartItemsSuccess(mockCartRepo, testCart.ID, testItems)
 			},
 			expectedStatus:       http.StatusOK,
-			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","quantity":%d,"price":%.2f,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","quantity":%d,"price":%.2f,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}],"total":%.2f}`, testCart.ID, testUserID, testItems[0].CartID, testItems[0].ProductID, testItems[0].Quantity, testItems[0].Price, testItems[1].CartID, testItems[1].ProductID, testItems[1].Quantity, testItems[1].Price, (testItems[0].Price*float64(testItems[0].Quantity))+(testItems[1].Price*float64(testItems[1].Quantity))),
+			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","variant_id":null,"quantity":%d,"price":%s,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","variant_id":null,"quantity":%d,"price":%s,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}],"total":%s}`, testCart.ID, testUserID, testItems[0].CartID, testItems[0].ProductID, testItems[0].Quantity, moneyJSON(testItems[0].Price), testItems[1].CartID, testItems[1].ProductID, testItems[1].Quantity, moneyJSON(testItems[1].Price), moneyJSON(brl("46.00"))),
 		},
 		{
 			name: "Success - New Cart (Empty)",
@@ -71,7 +76,7 @@ func TestCartHandler_GetCart(t *testing.T) {
 				mockGetCartItemsSuccess(mockCartRepo, testCart.ID, []models.CartItem{})
 			},
 			expectedStatus:       http.StatusOK,
-			expectedBodyContains: fmt.Sprintf(`{"cart": {"id":"%s", "user_id":"%s", "created_at":"0001-01-01T00:00:00Z", "updated_at":"0001-01-01T00:00:00Z"}, "items": [], "total": 0.00}`, testCart.ID, testUserID),
+			expectedBodyContains: fmt.Sprintf(`{"cart": {"id":"%s", "user_id":"%s", "created_at":"0001-01-01T00:00:00Z", "updated_at":"0001-01-01T00:00:00Z"}, "items": [], "total": {"amount":"0.00","currency":"BRL"}}`, testCart.ID, testUserID),
 		},
 		{
 			name: "Error - GetOrCreateCart Fails",
@@ -113,8 +118,8 @@ func TestCartHandler_GetCart(t *testing.T) {
 			mockUserRepo := new(MockUserRepository)
 			mockCartRepo := new(MockCartRepository)
 			mockProductRepo := new(MockProductRepository) // Needed for handler instantiation
-			cartHandler := handlers.NewCartHandler(mockCartRepo, mockProductRepo)
-			authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+			cartHandler := handlers.NewCartHandler(mockCartRepo, mockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 			router := mux.NewRouter()
 			router.Handle("/api/cart", authMiddleware.Authenticate(http.HandlerFunc(cartHandler.GetCart))).Methods("GET")
 
@@ -150,37 +155,149 @@ func TestCartHandler_GetCart(t *testing.T) {
 	}
 }
 
+// TestCartHandler_GetCart_InCurrency tests GET /api/cart?currency=... with a price list
+func TestCartHandler_GetCart_InCurrency(t *testing.T) {
+	testUserID := uuid.New()
+	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
+	listedID, convertedID := uuid.New(), uuid.New()
+	testItems := []models.CartItem{
+		{CartID: testCart.ID, ProductID: listedID, Quantity: 2, Price: brl("10.50")},
+		{CartID: testCart.ID, ProductID: convertedID, Quantity: 1, Price: brl("25.00")},
+	}
+
+	mockUserRepo := new(MockUserRepository)
+	mockCartRepo := new(MockCartRepository)
+	mockPricingRepo := new(pricing.MockPricingRepository)
+	mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Maybe()
+	mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
+	mockGetCartItemsSuccess(mockCartRepo, testCart.ID, testItems)
+	mockPricingRepo.On("FindRate", mock.Anything, "USD").Return(&models.ExchangeRate{Currency: "USD", Rate: money.MustParseRate("0.2")}, nil).Once()
+	mockPricingRepo.On("FindPrices", mock.Anything, "USD", []uuid.UUID{listedID, convertedID}).
+		Return([]models.ProductPrice{{ProductID: listedID, Price: money.MustParse("1.99", "USD")}}, nil).Once()
+
+	cartHandler := handlers.NewCartHandler(mockCartRepo, new(MockProductRepository), new(variants.MockVariantRepository), mockPricingRepo)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+	router := mux.NewRouter()
+	router.Handle("/api/cart", authMiddleware.Authenticate(http.HandlerFunc(cartHandler.GetCart))).Methods("GET")
+
+	token, err := generateTestToken(testUserID)
+	require.NoError(t, err)
+	rr := executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/cart?currency=USD", token, ""), http.StatusOK, "")
+
+	var resp handlers.CartResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 2)
+	assert.Equal(t, money.MustParse("1.99", "USD"), resp.Items[0].Price, "From the price list")
+	assert.Equal(t, money.MustParse("5.00", "USD"), resp.Items[1].Price, "Converted at the current rate")
+	assert.Equal(t, money.MustParse("8.98", "USD"), resp.Total)
+	mockCartRepo.AssertExpectations(t)
+	mockPricingRepo.AssertExpectations(t)
+}
+
 // TestCartHandler_AddItem tests the POST /api/cart/items endpoint
 func TestCartHandler_AddItem(t *testing.T) {
 	testUserID := uuid.New()
 	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
 	productID := uuid.New()
-	testProduct := &models.Product{ID: productID, Name: "Test Item", Price: 19.99}
+	testProduct := &models.Product{ID: productID, Name: "Test Item", Price: brl("19.99")}
 	testQuantity := 2
 	testCartItem := &models.CartItem{CartID: testCart.ID, ProductID: productID, Quantity: testQuantity, Price: testProduct.Price}
+	variantID := uuid.New()
+	variantPrice := brl("24.99")
+	testVariant := &models.ProductVariant{ID: variantID, ProductID: productID, SKU: "TEST-G", Options: map[string]string{"size": "G"}, Price: &variantPrice}
 
 	tests := []struct {
 		name                 string
 		body                 string
-		mocksSetup           func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository)
+		mocksSetup           func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository)
 		expectedStatus       int
 		expectedBodyContains string
 	}{
 		{
 			name: "Success - Add New Item",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) {
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
 				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
 				mockFindProductSuccess(mockProductRepo, testProduct)
+				mockNoVariants(mockVariantRepo, productID)
 				mockAddItemSuccess(mockCartRepo, testCart.ID, productID, testQuantity, testProduct.Price, testCartItem)
 			},
 			expectedStatus:       http.StatusCreated,
-			expectedBodyContains: fmt.Sprintf(`{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","quantity":%d,"price":%.2f,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, testCart.ID, productID, testQuantity, testProduct.Price),
+			expectedBodyContains: fmt.Sprintf(`{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","variant_id":null,"quantity":%d,"price":%s,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, testCart.ID, productID, testQuantity, moneyJSON(testProduct.Price)),
+		},
+		{
+			name: "Success - Add Variant",
+			body: fmt.Sprintf(`{"product_id":"%s", "variant_id":"%s", "quantity":%d}`, productID, variantID, testQuantity),
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
+				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
+				mockFindProductSuccess(mockProductRepo, testProduct)
+				mockVariantRepo.On("FindByID", mock.Anything, variantID).Return(testVariant, nil).Once()
+				variantItem := &models.CartItem{CartID: testCart.ID, ProductID: productID, VariantID: &variantID, Quantity: testQuantity, Price: *testVariant.Price}
+				mockCartRepo.On("AddItem", mock.Anything, testCart.ID, productID, &variantID, testQuantity, *testVariant.Price).Return(variantItem, nil).Once()
+			},
+			expectedStatus:       http.StatusCreated,
+			expectedBodyContains: fmt.Sprintf(`"variant_id":"%s","quantity":%d,"price":%s`, variantID, testQuantity, moneyJSON(*testVariant.Price)),
+		},
+		{
+			name: "Error - Variant Required",
+			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
+				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
+				mockFindProductSuccess(mockProductRepo, testProduct)
+				mockVariantRepo.On("FindByProductID", mock.Anything, productID).Return([]models.ProductVariant{*testVariant}, nil).Once()
+			},
+			expectedStatus:       http.StatusBadRequest,
+			expectedBodyContains: `{"error":"variant_id is required, the product is sold in variants"}`,
+		},
+		{
+			name: "Error - Variant Of Another Product",
+			body: fmt.Sprintf(`{"product_id":"%s", "variant_id":"%s", "quantity":%d}`, productID, variantID, testQuantity),
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
+				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
+				mockFindProductSuccess(mockProductRepo, testProduct)
+				otherVariant := *testVariant
+				otherVariant.ProductID = uuid.New()
+				mockVariantRepo.On("FindByID", mock.Anything, variantID).Return(&otherVariant, nil).Once()
+			},
+			expectedStatus:       http.StatusNotFound,
+			expectedBodyContains: `{"error":"variant not found"}`,
+		},
+		{
+			name: "Success - Within Stock",
+			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
+				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
+				stock := 4
+				stocked := *testProduct
+				stocked.Stock = &stock
+				mockFindProductSuccess(mockProductRepo, &stocked)
+				mockNoVariants(mockVariantRepo, productID)
+				mockCartRepo.On("FindCartItem", mock.Anything, testCart.ID, productID, noVariant).Return(nil, cart.ErrProductNotInCart).Once()
+				mockAddItemSuccess(mockCartRepo, testCart.ID, productID, testQuantity, testProduct.Price, testCartItem)
+			},
+			expectedStatus:       http.StatusCreated,
+			expectedBodyContains: fmt.Sprintf(`"quantity":%d`, testQuantity),
+		},
+		{
+			name: "Error - Insufficient Stock Counting The Cart",
+			body: fmt.Sprintf(`{"product_id":"%s", "variant_id":"%s", "quantity":%d}`, productID, variantID, testQuantity),
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
+				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
+				mockFindProductSuccess(mockProductRepo, testProduct)
+				stock := 3
+				stocked := *testVariant
+				stocked.Stock = &stock
+				mockVariantRepo.On("FindByID", mock.Anything, variantID).Return(&stocked, nil).Once()
+				inCart := &models.CartItem{CartID: testCart.ID, ProductID: productID, VariantID: &variantID, Quantity: 2}
+				mockCartRepo.On("FindCartItem", mock.Anything, testCart.ID, productID, &variantID).Return(inCart, nil).Once()
+			},
+			expectedStatus:       http.StatusConflict,
+			expectedBodyContains: `{"error":"insufficient stock, 3 available"}`,
 		},
 		{
 			name: "Error - Invalid Quantity (Zero)",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":0}`, productID),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) {
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
 				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
 				// No product or add item mock needed
 			},
@@ -190,7 +307,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 		{
 			name: "Error - Invalid Quantity (Negative)",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":-1}`, productID),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) {
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
 				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
 			},
 			expectedStatus:       http.StatusBadRequest,
@@ -199,7 +316,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 		{
 			name: "Error - Product Not Found",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) {
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
 				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
 				mockFindProductNotFound(mockProductRepo, productID)
 			},
@@ -209,7 +326,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 		{
 			name: "Error - FindProductByID Fails (Internal Error)",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) {
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
 				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
 				mockFindProductError(mockProductRepo, productID)
 			},
@@ -219,9 +336,10 @@ func TestCartHandler_AddItem(t *testing.T) {
 		{
 			name: "Error - AddItem Fails",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) {
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
 				mockGetOrCreateCartSuccess(mockCartRepo, testUserID, testCart)
 				mockFindProductSuccess(mockProductRepo, testProduct)
+				mockNoVariants(mockVariantRepo, productID)
 				mockAddItemError(mockCartRepo, testCart.ID, productID, testQuantity, testProduct.Price)
 			},
 			expectedStatus:       http.StatusInternalServerError,
@@ -230,7 +348,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 		{
 			name: "Error - Invalid JSON Body",
 			body: `{"product_id": invalid}`, // Malformed JSON
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) {
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
 				// May or may not call GetOrCreateCart depending on when body is parsed
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Maybe()
 			},
@@ -240,7 +358,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 		{
 			name: "Error - Middleware User Check Fails",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) { /* No cart/product mocks needed */
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) { /* No cart/product mocks needed */
 			},
 			expectedStatus:       http.StatusUnauthorized,
 			expectedBodyContains: `{"error":"user associated with token not found"}`,
@@ -248,7 +366,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 		{
 			name: "Error - No Auth Token",
 			body: fmt.Sprintf(`{"product_id":"%s", "quantity":%d}`, productID, testQuantity),
-			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository) { /* No cart/product mocks needed */
+			mocksSetup: func(mockCartRepo *MockCartRepository, mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) { /* No cart/product mocks needed */
 			},
 			expectedStatus:       http.StatusUnauthorized,
 			expectedBodyContains: `{"error":"authorization header required"}`,
@@ -262,8 +380,9 @@ func TestCartHandler_AddItem(t *testing.T) {
 			mockUserRepo := new(MockUserRepository)
 			mockCartRepo := new(MockCartRepository)
 			mockProductRepo := new(MockProductRepository)
-			cartHandler := handlers.NewCartHandler(mockCartRepo, mockProductRepo)
-			authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+			mockVariantRepo := new(variants.MockVariantRepository)
+			cartHandler := handlers.NewCartHandler(mockCartRepo, mockProductRepo, mockVariantRepo, new(pricing.MockPricingRepository))
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 			router := mux.NewRouter()
 			router.Handle("/api/cart/items", authMiddleware.Authenticate(http.HandlerFunc(cartHandler.AddItem))).Methods("POST")
 
@@ -275,7 +394,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 			}
 
 			// Setup CartRepo and ProductRepo mocks
-			tc.mocksSetup(mockCartRepo, mockProductRepo)
+			tc.mocksSetup(mockCartRepo, mockProductRepo, mockVariantRepo)
 
 			// Generate token (or not)
 			var currentToken string
@@ -303,6 +422,7 @@ func TestCartHandler_AddItem(t *testing.T) {
 			// Assert mocks
 			mockCartRepo.AssertExpectations(t)
 			mockProductRepo.AssertExpectations(t)
+			mockVariantRepo.AssertExpectations(t)
 			mockUserRepo.AssertExpectations(t)
 		})
 	}
@@ -314,17 +434,18 @@ func TestCartHandler_DeleteItem(t *testing.T) {
 	_, _, router, baseMockUserRepo, baseMockProductRepo, _, _, baseMockCartRepo, token := setupBaseTest(t)
 
 	// Handler created once
-	cartHandler := handlers.NewCartHandler(baseMockCartRepo, baseMockProductRepo)
+	cartHandler := handlers.NewCartHandler(baseMockCartRepo, baseMockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
 
-	claims, err := auth.ValidateToken(token, testJwtSecret)
+	claims, err := auth.ValidateToken(token, testKeys)
 	require.NoError(t, err)
 	testUserID := claims.UserID
 
 	productID := uuid.New()
 	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
+	variantID := uuid.New()
 
 	// Route registered once
-	router.Handle("/api/cart/items/{productId}", auth.NewMiddleware(testJwtSecret, baseMockUserRepo).Authenticate(http.HandlerFunc(cartHandler.DeleteItem))).Methods("DELETE")
+	router.Handle("/api/cart/items/{productId}", auth.NewMiddleware(testKeys, baseMockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository)).Authenticate(http.HandlerFunc(cartHandler.DeleteItem))).Methods("DELETE")
 
 	tests := []struct {
 		name                 string
@@ -343,14 +464,40 @@ func TestCartHandler_DeleteItem(t *testing.T) {
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Twice()
 			},
 			mockRemoveItem: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID).Return(nil).Once()
+				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID, noVariant).Return(nil).Once()
 			},
 			mockGetCartItems: func(mockCartRepo *MockCartRepository) {
 				// Simulate cart being empty after removal
 				mockCartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return([]models.CartItem{}, nil).Once()
 			},
 			expectedStatus:       http.StatusOK,
-			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[],"total":0}`, testCart.ID, testUserID),
+			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[],"total":{"amount":"0.00","currency":"BRL"}}`, testCart.ID, testUserID),
+		},
+		{
+			name:           "Success - Variant",
+			productIDParam: productID.String() + "?variant_id=" + variantID.String(),
+			mockGetOrCreateCart: func(mockCartRepo *MockCartRepository) {
+				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Twice()
+			},
+			mockRemoveItem: func(mockCartRepo *MockCartRepository) {
+				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID, &variantID).Return(nil).Once()
+			},
+			mockGetCartItems: func(mockCartRepo *MockCartRepository) {
+				mockCartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return([]models.CartItem{}, nil).Once()
+			},
+			expectedStatus:       http.StatusOK,
+			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[],"total":{"amount":"0.00","currency":"BRL"}}`, testCart.ID, testUserID),
+		},
+		{
+			name:           "Invalid Variant ID Format",
+			productIDParam: productID.String() + "?variant_id=invalid-uuid",
+			mockGetOrCreateCart: func(mockCartRepo *MockCartRepository) {
+				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
+			},
+			mockRemoveItem:       func(mockCartRepo *MockCartRepository) { /* Not called */ },
+			mockGetCartItems:     func(mockCartRepo *MockCartRepository) { /* Not called */ },
+			expectedStatus:       http.StatusBadRequest,
+			expectedBodyContains: `{"error":"invalid variant ID format"}`,
 		},
 		{
 			name:           "Product Not Found in Cart",
@@ -359,7 +506,7 @@ func TestCartHandler_DeleteItem(t *testing.T) {
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
 			},
 			mockRemoveItem: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID).Return(cart.ErrProductNotInCart).Once()
+				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID, noVariant).Return(cart.ErrProductNotInCart).Once()
 			},
 			mockGetCartItems:     func(mockCartRepo *MockCartRepository) { /* Not called */ },
 			expectedStatus:       http.StatusNotFound,
@@ -394,7 +541,7 @@ func TestCartHandler_DeleteItem(t *testing.T) {
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
 			},
 			mockRemoveItem: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID).Return(assert.AnError).Once()
+				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID, noVariant).Return(assert.AnError).Once()
 			},
 			mockGetCartItems:     func(mockCartRepo *MockCartRepository) { /* Not called */ },
 			expectedStatus:       http.StatusInternalServerError,
@@ -408,7 +555,7 @@ func TestCartHandler_DeleteItem(t *testing.T) {
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Twice()
 			},
 			mockRemoveItem: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID).Return(nil).Once()
+				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID, noVariant).Return(nil).Once()
 			},
 			mockGetCartItems: func(mockCartRepo *MockCartRepository) {
 				mockCartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return(nil, assert.AnError).Once()
@@ -427,7 +574,7 @@ func TestCartHandler_DeleteItem(t *testing.T) {
 			cartHandler.CartRepo = mockCartRepo // Update handler repo
 
 			// New middleware
-			authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Maybe()
 
 			// Setup specific mocks
@@ -457,25 +604,34 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 	_, _, router, baseMockUserRepo, baseMockProductRepo, _, _, baseMockCartRepo, token := setupBaseTest(t)
 
 	// Handler created once
-	cartHandler := handlers.NewCartHandler(baseMockCartRepo, baseMockProductRepo)
+	cartHandler := handlers.NewCartHandler(baseMockCartRepo, baseMockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
 
-	claims, err := auth.ValidateToken(token, testJwtSecret)
+	claims, err := auth.ValidateToken(token, testKeys)
 	require.NoError(t, err)
 	testUserID := claims.UserID
 
 	productID := uuid.New()
 	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
 	updatedQuantity := 5
-	updatedItem := &models.CartItem{CartID: testCart.ID, ProductID: productID, Quantity: updatedQuantity, Price: 15.00}
+	updatedItem := &models.CartItem{CartID: testCart.ID, ProductID: productID, Quantity: updatedQuantity, Price: brl("15.00")}
+	// productWithStock sets up the product lookup of the stock check; nil stock is untracked
+	productWithStock := func(stock *int) func(*MockProductRepository, *variants.MockVariantRepository) {
+		return func(mockProductRepo *MockProductRepository, mockVariantRepo *variants.MockVariantRepository) {
+			mockFindProductSuccess(mockProductRepo, &models.Product{ID: productID, Price: brl("15.00"), Stock: stock})
+			mockNoVariants(mockVariantRepo, productID)
+		}
+	}
+	lowStock := 4
 
 	// Route registered once
-	router.Handle("/api/cart/items/{productId}", auth.NewMiddleware(testJwtSecret, baseMockUserRepo).Authenticate(http.HandlerFunc(cartHandler.UpdateItem))).Methods("PUT")
+	router.Handle("/api/cart/items/{productId}", auth.NewMiddleware(testKeys, baseMockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository)).Authenticate(http.HandlerFunc(cartHandler.UpdateItem))).Methods("PUT")
 
 	tests := []struct {
 		name                 string
 		productIDParam       string
 		body                 string
 		mockGetOrCreateCart  func(*MockCartRepository)
+		mockProduct          func(*MockProductRepository, *variants.MockVariantRepository) // nil when the stock is not checked
 		mockUpdateQuantity   func(*MockCartRepository)
 		mockRemoveItem       func(*MockCartRepository) // For quantity <= 0 case
 		mockGetCartItems     func(*MockCartRepository) // For the final GetCart call
@@ -490,8 +646,9 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 				// Expect two calls: one at the start, one inside the final GetCart call
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Twice()
 			},
+			mockProduct: productWithStock(nil),
 			mockUpdateQuantity: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("UpdateItemQuantity", mock.Anything, testCart.ID, productID, updatedQuantity).Return(updatedItem, nil).Once()
+				mockCartRepo.On("UpdateItemQuantity", mock.Anything, testCart.ID, productID, noVariant, updatedQuantity).Return(updatedItem, nil).Once()
 			},
 			mockRemoveItem: func(mockCartRepo *MockCartRepository) {}, // Not called
 			mockGetCartItems: func(mockCartRepo *MockCartRepository) {
@@ -499,7 +656,7 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 				mockCartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return([]models.CartItem{*updatedItem}, nil).Once()
 			},
 			expectedStatus:       http.StatusOK,
-			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[%s],"total":%.2f}`, testCart.ID, testUserID, fmt.Sprintf(`{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","quantity":%d,"price":%.2f,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, updatedItem.CartID, updatedItem.ProductID, updatedItem.Quantity, updatedItem.Price), float64(updatedItem.Quantity)*updatedItem.Price),
+			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[%s],"total":%s}`, testCart.ID, testUserID, fmt.Sprintf(`{"id":"00000000-0000-0000-0000-000000000000","cart_id":"%s","product_id":"%s","variant_id":null,"quantity":%d,"price":%s,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, updatedItem.CartID, updatedItem.ProductID, updatedItem.Quantity, moneyJSON(updatedItem.Price)), moneyJSON(money.New(updatedItem.Price.Amount*int64(updatedItem.Quantity), updatedItem.Price.Currency))),
 		},
 		{
 			name:           "Quantity Zero (Triggers Delete)",
@@ -511,13 +668,13 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 			},
 			mockUpdateQuantity: func(mockCartRepo *MockCartRepository) {}, // Not called directly
 			mockRemoveItem: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID).Return(nil).Once()
+				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID, noVariant).Return(nil).Once()
 			},
 			mockGetCartItems: func(mockCartRepo *MockCartRepository) {
 				mockCartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return([]models.CartItem{}, nil).Once()
 			},
 			expectedStatus:       http.StatusOK,
-			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[],"total":0}`, testCart.ID, testUserID),
+			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[],"total":{"amount":"0.00","currency":"BRL"}}`, testCart.ID, testUserID),
 		},
 		{
 			name:           "Product Not Found in Cart",
@@ -526,14 +683,29 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 			mockGetOrCreateCart: func(mockCartRepo *MockCartRepository) {
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
 			},
+			mockProduct: productWithStock(nil),
 			mockUpdateQuantity: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("UpdateItemQuantity", mock.Anything, testCart.ID, productID, updatedQuantity).Return(nil, cart.ErrProductNotInCart).Once()
+				mockCartRepo.On("UpdateItemQuantity", mock.Anything, testCart.ID, productID, noVariant, updatedQuantity).Return(nil, cart.ErrProductNotInCart).Once()
 			},
 			mockRemoveItem:       func(mockCartRepo *MockCartRepository) {}, // Not called
 			mockGetCartItems:     func(mockCartRepo *MockCartRepository) {}, // Not called
 			expectedStatus:       http.StatusNotFound,
 			expectedBodyContains: `{"error":"product not found in cart"}`,
 		},
+		{
+			name:           "Insufficient Stock",
+			productIDParam: productID.String(),
+			body:           fmt.Sprintf(`{"quantity": %d}`, updatedQuantity),
+			mockGetOrCreateCart: func(mockCartRepo *MockCartRepository) {
+				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
+			},
+			mockProduct:          productWithStock(&lowStock),
+			mockUpdateQuantity:   func(mockCartRepo *MockCartRepository) {}, // Not called
+			mockRemoveItem:       func(mockCartRepo *MockCartRepository) {}, // Not called
+			mockGetCartItems:     func(mockCartRepo *MockCartRepository) {}, // Not called
+			expectedStatus:       http.StatusConflict,
+			expectedBodyContains: `{"error":"insufficient stock, 4 available"}`,
+		},
 		{
 			name:           "Invalid Quantity (Negative Triggers Delete)",
 			productIDParam: productID.String(),
@@ -544,13 +716,13 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 			},
 			mockUpdateQuantity: func(mockCartRepo *MockCartRepository) {}, // Not called directly
 			mockRemoveItem: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID).Return(nil).Once()
+				mockCartRepo.On("RemoveItem", mock.Anything, testCart.ID, productID, noVariant).Return(nil).Once()
 			},
 			mockGetCartItems: func(mockCartRepo *MockCartRepository) {
 				mockCartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return([]models.CartItem{}, nil).Once()
 			},
 			expectedStatus:       http.StatusOK,
-			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[],"total":0}`, testCart.ID, testUserID),
+			expectedBodyContains: fmt.Sprintf(`{"cart":{"id":"%s","user_id":"%s","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},"items":[],"total":{"amount":"0.00","currency":"BRL"}}`, testCart.ID, testUserID),
 		},
 		{
 			name:           "Invalid JSON Body",
@@ -598,8 +770,9 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 			mockGetOrCreateCart: func(mockCartRepo *MockCartRepository) {
 				mockCartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
 			},
+			mockProduct: productWithStock(&updatedQuantity), // Exactly covers the new quantity
 			mockUpdateQuantity: func(mockCartRepo *MockCartRepository) {
-				mockCartRepo.On("UpdateItemQuantity", mock.Anything, testCart.ID, productID, updatedQuantity).Return(nil, assert.AnError).Once()
+				mockCartRepo.On("UpdateItemQuantity", mock.Anything, testCart.ID, productID, noVariant, updatedQuantity).Return(nil, assert.AnError).Once()
 			},
 			mockRemoveItem:       func(mockCartRepo *MockCartRepository) {}, // Not called
 			mockGetCartItems:     func(mockCartRepo *MockCartRepository) {}, // Not called
@@ -614,14 +787,21 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 			// Fresh mocks
 			mockUserRepo := new(MockUserRepository)
 			mockCartRepo := new(MockCartRepository)
-			cartHandler.CartRepo = mockCartRepo // Update handler repo
+			mockProductRepo := new(MockProductRepository)
+			mockVariantRepo := new(variants.MockVariantRepository)
+			cartHandler.CartRepo = mockCartRepo // Update handler repos
+			cartHandler.ProductRepo = mockProductRepo
+			cartHandler.VariantRepo = mockVariantRepo
 
 			// New middleware
-			authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Maybe()
 
 			// Setup specific mocks
 			tc.mockGetOrCreateCart(mockCartRepo)
+			if tc.mockProduct != nil {
+				tc.mockProduct(mockProductRepo, mockVariantRepo)
+			}
 			tc.mockUpdateQuantity(mockCartRepo)
 			tc.mockRemoveItem(mockCartRepo)
 			tc.mockGetCartItems(mockCartRepo)
@@ -638,6 +818,8 @@ func TestCartHandler_UpdateItem(t *testing.T) {
 			executeRequestAndAssert(t, subRouter, req, tc.expectedStatus, tc.expectedBodyContains)
 
 			mockCartRepo.AssertExpectations(t)
+			mockProductRepo.AssertExpectations(t)
+			mockVariantRepo.AssertExpectations(t)
 			mockUserRepo.AssertExpectations(t)
 		})
 	}
@@ -649,15 +831,15 @@ func TestCartHandler_ClearCart(t *testing.T) {
 	_, _, router, baseMockUserRepo, baseMockProductRepo, _, _, baseMockCartRepo, token := setupBaseTest(t)
 
 	// Handler created once
-	cartHandler := handlers.NewCartHandler(baseMockCartRepo, baseMockProductRepo)
+	cartHandler := handlers.NewCartHandler(baseMockCartRepo, baseMockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
 
-	claims, err := auth.ValidateToken(token, testJwtSecret)
+	claims, err := auth.ValidateToken(token, testKeys)
 	require.NoError(t, err)
 	testUserID := claims.UserID
 	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
 
 	// Route registered once
-	router.Handle("/api/cart", auth.NewMiddleware(testJwtSecret, baseMockUserRepo).Authenticate(http.HandlerFunc(cartHandler.ClearCart))).Methods("DELETE")
+	router.Handle("/api/cart", auth.NewMiddleware(testKeys, baseMockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository)).Authenticate(http.HandlerFunc(cartHandler.ClearCart))).Methods("DELETE")
 
 	tests := []struct {
 		name                 string
@@ -708,7 +890,7 @@ func TestCartHandler_ClearCart(t *testing.T) {
 			cartHandler.CartRepo = mockCartRepo // Update handler repo
 
 			// New middleware
-			authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Maybe()
 
 			// Setup specific mocks
@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"bullet-cloud-api/internal/apikeys"
 	"bullet-cloud-api/internal/auth"
 	"bullet-cloud-api/internal/categories"
 	"bullet-cloud-api/internal/handlers"
@@ -30,7 +31,7 @@ func setupCategoryTest(t *testing.T) (*categories.MockCategoryRepository, *MockU
 	categoryHandler := handlers.NewCategoryHandler(mockCategoryRepo)
 
 	// Need authMiddleware instance for protected routes
-	authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 
 	apiV1 := router.PathPrefix("/api").Subrouter()
 
@@ -40,7 +41,7 @@ func setupCategoryTest(t *testing.T) (*categories.MockCategoryRepository, *MockU
 
 	// Protected category routes
 	protectedCategoryRoutes := apiV1.PathPrefix("/categories").Subrouter()
-	protectedCategoryRoutes.Use(authMiddleware.Authenticate) // Apply middleware
+	protectedCategoryRoutes.Use(authMiddleware.Authenticate, authMiddleware.RequireRole(models.RoleAdmin)) // Apply middleware
 	protectedCategoryRoutes.HandleFunc("", categoryHandler.CreateCategory).Methods("POST")
 	protectedCategoryRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", categoryHandler.UpdateCategory).Methods("PUT")
 	protectedCategoryRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", categoryHandler.DeleteCategory).Methods("DELETE")
@@ -193,7 +194,7 @@ func TestCategoryHandler_CreateCategory(t *testing.T) {
 		{
 			name:           "Success",
 			body:           `{"name":"New Category"}`,
-			mockUserReturn: &models.User{ID: testUserID}, // Simulate user exists
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin}, // Simulate user exists
 			mockUserErr:    nil,
 			mockCreateErr:  nil,
 			expectedStatus: http.StatusCreated,
@@ -202,7 +203,7 @@ func TestCategoryHandler_CreateCategory(t *testing.T) {
 		{
 			name:           "Failure - Invalid JSON",
 			body:           `{"name":"Category",}`, // Invalid JSON
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockCreateErr:  nil,
 			expectedStatus: http.StatusBadRequest,
@@ -211,7 +212,7 @@ func TestCategoryHandler_CreateCategory(t *testing.T) {
 		{
 			name:           "Failure - Missing Name",
 			body:           `{}`, // Empty body
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockCreateErr:  nil,
 			expectedStatus: http.StatusBadRequest,
@@ -220,7 +221,7 @@ func TestCategoryHandler_CreateCategory(t *testing.T) {
 		{
 			name:           "Failure - Name Already Exists",
 			body:           `{"name":"Existing Category"}`,
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockCreateErr:  categories.ErrCategoryNameExists,
 			expectedStatus: http.StatusConflict,
@@ -229,12 +230,21 @@ func TestCategoryHandler_CreateCategory(t *testing.T) {
 		{
 			name:           "Failure - Repo Create Error",
 			body:           `{"name":"Good Category"}`,
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockCreateErr:  errors.New("db create failed"),
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   `{"error":"failed to create category"}`,
 		},
+		{
+			name:           "Failure - Not Admin",
+			body:           `{"name":"Customer Category"}`,
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleCustomer},
+			mockUserErr:    nil,
+			mockCreateErr:  nil,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"forbidden"}`,
+		},
 		{
 			name:           "Failure - Middleware User Check Fails",
 			body:           `{"name":"Another Category"}`,
@@ -267,7 +277,7 @@ func TestCategoryHandler_CreateCategory(t *testing.T) {
 			}
 
 			// Mock category repo create (only if middleware check passes and validation passes)
-			if tc.mockUserErr == nil && tc.expectedStatus != http.StatusBadRequest && tc.expectedStatus != http.StatusUnauthorized {
+			if tc.mockUserErr == nil && tc.expectedStatus != http.StatusBadRequest && tc.expectedStatus != http.StatusUnauthorized && tc.expectedStatus != http.StatusForbidden {
 				mockCategoryRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Category")).
 					Return(func(ctx context.Context, c *models.Category) *models.Category {
 						if tc.mockCreateErr != nil {
@@ -305,7 +315,7 @@ func TestCategoryHandler_UpdateCategory(t *testing.T) {
 	// Corrected generateTestToken call
 	testToken, err := generateTestToken(testUserID)
 	require.NoError(t, err, "Failed to generate test token")
-	userForToken := &models.User{ID: testUserID} // Define user for token once
+	userForToken := &models.User{ID: testUserID, Role: models.RoleAdmin} // Define user for token once
 
 	tests := []struct {
 		name              string
@@ -489,7 +499,7 @@ func TestCategoryHandler_DeleteCategory(t *testing.T) {
 	// Corrected generateTestToken call
 	testToken, err := generateTestToken(testUserID)
 	require.NoError(t, err, "Failed to generate test token")
-	userForToken := &models.User{ID: testUserID} // Define user for token once
+	userForToken := &models.User{ID: testUserID, Role: models.RoleAdmin} // Define user for token once
 
 	tests := []struct {
 		name              string
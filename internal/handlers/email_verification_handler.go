@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/notify"
+	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/webutils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// resendVerificationMessage is returned whether or not the email is registered,
+// so the endpoint cannot be used to discover accounts.
+const resendVerificationMessage = "if the email is registered and not yet verified, a verification link has been sent"
+
+// VerificationSender sends email verification links to users, and tells them when their
+// email changes.
+type VerificationSender interface {
+	SendVerificationEmail(ctx context.Context, user *models.User) error
+	SendEmailChangedNotice(ctx context.Context, user *models.User, previousEmail string) error
+}
+
+// EmailVerificationHandler handles email verification requests and implements VerificationSender.
+type EmailVerificationHandler struct {
+	UserRepo    users.UserRepository
+	Notifier    notify.Notifier
+	JwtSecret   string        // Verification tokens are signed with a key derived from it
+	TokenExpiry time.Duration // Lifetime of verification links
+	AppBaseURL  string        // Frontend base URL for the verification link
+}
+
+// NewEmailVerificationHandler creates a new EmailVerificationHandler.
+func NewEmailVerificationHandler(userRepo users.UserRepository, notifier notify.Notifier, jwtSecret string, tokenExpiry time.Duration, appBaseURL string) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		UserRepo:    userRepo,
+		Notifier:    notifier,
+		JwtSecret:   jwtSecret,
+		TokenExpiry: tokenExpiry,
+		AppBaseURL:  appBaseURL,
+	}
+}
+
+// --- Request/Response Structs ---
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// --- Handlers ---
+
+// SendVerificationEmail issues a signed verification token for the user and sends them the link.
+func (h *EmailVerificationHandler) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := auth.GenerateEmailVerificationToken(user.ID, user.Email, user.EmailChangedAt, h.JwtSecret, h.TokenExpiry)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", strings.TrimRight(h.AppBaseURL, "/"), url.QueryEscape(token))
+	return h.Notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n\nIf you did not create an account, you can ignore this message.",
+			user.Name, h.TokenExpiry, link),
+	})
+}
+
+// SendEmailChangedNotice tells the previous address of the user that their email was changed,
+// so the owner notices if someone else did it.
+func (h *EmailVerificationHandler) SendEmailChangedNotice(ctx context.Context, user *models.User, previousEmail string) error {
+	return h.Notifier.Send(ctx, notify.Message{
+		To:      previousEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s.\n\nIf you did not make this change, reset your password and contact support right away.",
+			user.Name, user.Email),
+	})
+}
+
+// VerifyEmail handles POST /api/auth/verify-email.
+func (h *EmailVerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		webutils.ErrorJSON(w, errors.New("token is required"), http.StatusBadRequest)
+		return
+	}
+
+	claims, err := auth.ValidateEmailVerificationToken(req.Token, h.JwtSecret)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid or expired verification token"), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("invalid or expired verification token"), http.StatusBadRequest)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to verify email"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// A link sent before the email last changed must not verify it, even for the same address
+	if !strings.EqualFold(user.Email, claims.Email) || claims.EmailVersion != user.EmailChangedAt.UnixMicro() {
+		webutils.ErrorJSON(w, errors.New("invalid or expired verification token"), http.StatusBadRequest)
+		return
+	}
+
+	if !user.IsEmailVerified() {
+		if err := h.UserRepo.MarkEmailVerified(r.Context(), user.ID); err != nil {
+			webutils.ErrorJSON(w, errors.New("failed to verify email"), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, MessageResponse{Message: "email verified"})
+}
+
+// ResendVerification handles POST /api/auth/resend-verification.
+// It always answers 202 with the same body; a link is only sent to unverified accounts.
+func (h *EmailVerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req ResendVerificationRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		webutils.ErrorJSON(w, errors.New("email is required"), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.UserRepo.FindByEmail(r.Context(), req.Email)
+	switch {
+	case err == nil && !user.IsEmailVerified():
+		if err := h.SendVerificationEmail(r.Context(), user); err != nil {
+			log.Printf("Error sending verification email: %v", err)
+		}
+	case err != nil && !errors.Is(err, users.ErrUserNotFound):
+		// Logged only: failing loudly here would reveal that the account exists
+		log.Printf("Error looking up user for verification resend: %v", err)
+	}
+
+	webutils.WriteJSON(w, http.StatusAccepted, MessageResponse{Message: resendVerificationMessage})
+}
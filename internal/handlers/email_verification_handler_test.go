@@ -0,0 +1,252 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/notify"
+	"bullet-cloud-api/internal/users"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationHandler_VerifyEmail(t *testing.T) {
+	userID := uuid.New()
+	userEmail := "test@example.com"
+	verifiedAt := time.Now().Add(-time.Hour)
+	emailChangedAt := time.Now().Add(-2 * time.Hour)
+	unverifiedUser := &models.User{ID: userID, Email: userEmail, EmailChangedAt: emailChangedAt}
+	verifiedUser := &models.User{ID: userID, Email: userEmail, EmailVerifiedAt: &verifiedAt, EmailChangedAt: emailChangedAt}
+
+	validToken, err := auth.GenerateEmailVerificationToken(userID, userEmail, emailChangedAt, testJwtSecret, time.Hour)
+	require.NoError(t, err)
+	expiredToken, err := auth.GenerateEmailVerificationToken(userID, userEmail, emailChangedAt, testJwtSecret, -time.Minute)
+	require.NoError(t, err)
+	accessToken, err := auth.GenerateToken(userID, string(models.RoleCustomer), userID, testKeys, time.Hour)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockUserRepo   func(*MockUserRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			body: fmt.Sprintf(`{"token":"%s"}`, validToken),
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(unverifiedUser, nil).Once()
+				repo.On("MarkEmailVerified", mock.Anything, userID).Return(nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"email verified"}`,
+		},
+		{
+			name: "Success - Already Verified",
+			body: fmt.Sprintf(`{"token":"%s"}`, validToken),
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(verifiedUser, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"email verified"}`,
+		},
+		{
+			name:           "Expired Token",
+			body:           fmt.Sprintf(`{"token":"%s"}`, expiredToken),
+			mockUserRepo:   func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired verification token"}`,
+		},
+		{
+			name:           "Access Token Is Rejected",
+			body:           fmt.Sprintf(`{"token":"%s"}`, accessToken),
+			mockUserRepo:   func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired verification token"}`,
+		},
+		{
+			name: "Email Changed Since Token Was Issued",
+			body: fmt.Sprintf(`{"token":"%s"}`, validToken),
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, Email: "new@example.com", EmailChangedAt: time.Now()}, nil).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired verification token"}`,
+		},
+		{
+			name: "Email Changed And Changed Back Since Token Was Issued",
+			body: fmt.Sprintf(`{"token":"%s"}`, validToken),
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, Email: userEmail, EmailChangedAt: time.Now()}, nil).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired verification token"}`,
+		},
+		{
+			name: "User Not Found",
+			body: fmt.Sprintf(`{"token":"%s"}`, validToken),
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(nil, users.ErrUserNotFound).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired verification token"}`,
+		},
+		{
+			name: "Mark Verified Error",
+			body: fmt.Sprintf(`{"token":"%s"}`, validToken),
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(unverifiedUser, nil).Once()
+				repo.On("MarkEmailVerified", mock.Anything, userID).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to verify email"}`,
+		},
+		{
+			name:           "Missing Token",
+			body:           `{}`,
+			mockUserRepo:   func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"token is required"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			h := handlers.NewEmailVerificationHandler(mockUserRepo, notify.NewLogNotifier(), testJwtSecret, time.Hour, "http://localhost:3000")
+			tc.mockUserRepo(mockUserRepo)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/auth/verify-email", h.VerifyEmail).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/api/auth/verify-email", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEmailVerificationHandler_ResendVerification(t *testing.T) {
+	userEmail := "test@example.com"
+	verifiedAt := time.Now()
+	unverifiedUser := &models.User{ID: uuid.New(), Name: "Test User", Email: userEmail}
+	verifiedUser := &models.User{ID: uuid.New(), Name: "Test User", Email: userEmail, EmailVerifiedAt: &verifiedAt}
+	genericBody := `{"message":"if the email is registered and not yet verified, a verification link has been sent"}`
+
+	tests := []struct {
+		name             string
+		body             string
+		mockFindByEmail  func(*MockUserRepository)
+		expectedStatus   int
+		expectedBody     string
+		expectedMessages int
+	}{
+		{
+			name: "Success - Unverified Account",
+			body: fmt.Sprintf(`{"email":"%s"}`, userEmail),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(unverifiedUser, nil).Once()
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedBody:     genericBody,
+			expectedMessages: 1,
+		},
+		{
+			name: "Already Verified Account",
+			body: fmt.Sprintf(`{"email":"%s"}`, userEmail),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(verifiedUser, nil).Once()
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedBody:     genericBody,
+			expectedMessages: 0,
+		},
+		{
+			name: "Unknown Email",
+			body: `{"email":"nobody@example.com"}`,
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, users.ErrUserNotFound).Once()
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedBody:     genericBody,
+			expectedMessages: 0,
+		},
+		{
+			name:             "Missing Email",
+			body:             `{}`,
+			mockFindByEmail:  func(repo *MockUserRepository) { /* Not called */ },
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"error":"email is required"}`,
+			expectedMessages: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			outboxDir := t.TempDir()
+			notifier, err := notify.NewOutboxNotifier(outboxDir)
+			require.NoError(t, err)
+
+			h := handlers.NewEmailVerificationHandler(mockUserRepo, notifier, testJwtSecret, time.Hour, "https://shop.example.com")
+			tc.mockFindByEmail(mockUserRepo)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/auth/resend-verification", h.ResendVerification).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/api/auth/resend-verification", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			msgs := readOutbox(t, outboxDir)
+			require.Len(t, msgs, tc.expectedMessages)
+			if tc.expectedMessages > 0 {
+				assert.Equal(t, userEmail, msgs[0].To)
+
+				// The link must carry a token that verifies this user's email
+				link := msgs[0].Body[strings.Index(msgs[0].Body, "https://shop.example.com/verify-email?token="):]
+				link = link[:strings.IndexByte(link, '\n')]
+				parsed, err := url.Parse(link)
+				require.NoError(t, err)
+				claims, err := auth.ValidateEmailVerificationToken(parsed.Query().Get("token"), testJwtSecret)
+				require.NoError(t, err)
+				assert.Equal(t, unverifiedUser.ID, claims.UserID)
+				assert.Equal(t, userEmail, claims.Email)
+			}
+
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEmailVerificationHandler_SendEmailChangedNotice(t *testing.T) {
+	outboxDir := t.TempDir()
+	notifier, err := notify.NewOutboxNotifier(outboxDir)
+	require.NoError(t, err)
+	h := handlers.NewEmailVerificationHandler(new(MockUserRepository), notifier, testJwtSecret, time.Hour, "https://shop.example.com")
+
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "new@example.com"}
+	require.NoError(t, h.SendEmailChangedNotice(context.Background(), user, "old@example.com"))
+
+	msgs := readOutbox(t, outboxDir)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "old@example.com", msgs[0].To)
+	assert.Contains(t, msgs[0].Body, "new@example.com")
+}
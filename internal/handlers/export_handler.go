@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/exports"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/webutils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ExportScheduler generates data exports in the background. It is implemented by exports.Runner.
+type ExportScheduler interface {
+	Enqueue(export *models.DataExport)
+}
+
+// ExportDeleter deletes a user's data exports and their files. It is implemented by exports.Runner.
+type ExportDeleter interface {
+	DeleteForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// ExportHandler handles personal data export requests (LGPD/GDPR).
+type ExportHandler struct {
+	Collector     *exports.Collector
+	ExportRepo    exports.ExportRepository
+	Scheduler     ExportScheduler
+	JwtSecret     string // Download links are signed with a key derived from it
+	SyncMaxOrders int    // Accounts with more orders are exported in the background
+}
+
+// NewExportHandler creates a new ExportHandler.
+func NewExportHandler(collector *exports.Collector, exportRepo exports.ExportRepository, scheduler ExportScheduler, jwtSecret string, syncMaxOrders int) *ExportHandler {
+	return &ExportHandler{
+		Collector:     collector,
+		ExportRepo:    exportRepo,
+		Scheduler:     scheduler,
+		JwtSecret:     jwtSecret,
+		SyncMaxOrders: syncMaxOrders,
+	}
+}
+
+// --- Request/Response Structs ---
+
+// DataExportResponse describes a background export. DownloadURL is set once the export is ready.
+type DataExportResponse struct {
+	models.DataExport
+	StatusURL   string `json:"status_url"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// --- Handlers ---
+
+// ExportMe handles GET /api/users/me/export?format=json|zip.
+// Small accounts get the export in the response. Large accounts, or requests with async=true,
+// get 202 and an export generated in the background, whose status URL gives the download link.
+func (h *ExportHandler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	authUserID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = exports.FormatJSON
+	}
+	if !exports.IsValidFormat(format) {
+		webutils.ErrorJSON(w, errors.New("format must be json or zip"), http.StatusBadRequest)
+		return
+	}
+	async, _ := strconv.ParseBool(query.Get("async"))
+
+	if !async {
+		orderCount, err := h.Collector.CountOrders(r.Context(), authUserID)
+		if err != nil {
+			webutils.ErrorJSON(w, errors.New("failed to export data"), http.StatusInternalServerError)
+			return
+		}
+		async = orderCount > h.SyncMaxOrders
+	}
+	if async {
+		h.startBackgroundExport(w, r, authUserID, format)
+		return
+	}
+
+	bundle, err := h.Collector.Collect(r.Context(), authUserID)
+	if err != nil {
+		log.Printf("Error collecting data export for user %s: %v", authUserID, err)
+		webutils.ErrorJSON(w, errors.New("failed to export data"), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", exports.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exports.FileName(format, bundle.ExportedAt)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := exports.Write(w, bundle, format); err != nil {
+		log.Printf("Error writing data export for user %s: %v", authUserID, err) // Headers are already sent
+	}
+}
+
+// GetExport handles GET /api/users/me/exports/{id}.
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	authUserID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	exportID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid export ID"), http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.ExportRepo.FindByID(r.Context(), exportID)
+	if err != nil || export.UserID != authUserID {
+		if err == nil || errors.Is(err, exports.ErrExportNotFound) {
+			webutils.ErrorJSON(w, errors.New("export not found"), http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to retrieve export"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response, err := h.newDataExportResponse(export)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve export"), http.StatusInternalServerError)
+		return
+	}
+	webutils.WriteJSON(w, http.StatusOK, response)
+}
+
+// DownloadExport handles GET /api/exports/{id}/download?token=...
+// The signed token in the link is the only credential, so the link can be opened in a browser.
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid export ID"), http.StatusBadRequest)
+		return
+	}
+
+	claims, err := auth.ValidateDataExportToken(r.URL.Query().Get("token"), h.JwtSecret)
+	if err != nil || claims.ExportID != exportID {
+		webutils.ErrorJSON(w, errors.New("invalid or expired download link"), http.StatusForbidden)
+		return
+	}
+
+	export, err := h.ExportRepo.FindByID(r.Context(), exportID)
+	if err != nil && !errors.Is(err, exports.ErrExportNotFound) {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve export"), http.StatusInternalServerError)
+		return
+	}
+	if err != nil || export.UserID != claims.UserID || !export.IsDownloadable(time.Now()) {
+		webutils.ErrorJSON(w, errors.New("export is no longer available"), http.StatusGone)
+		return
+	}
+	// Links issued before the account was deleted must not serve its data
+	if _, err := h.Collector.UserRepo.FindByID(r.Context(), claims.UserID); err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("export is no longer available"), http.StatusGone)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to retrieve export"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	file, err := os.Open(export.FilePath)
+	if err != nil {
+		log.Printf("Error opening data export %s: %v", export.ID, err)
+		webutils.ErrorJSON(w, errors.New("export is no longer available"), http.StatusGone)
+		return
+	}
+	defer file.Close()
+
+	modTime := export.CreatedAt
+	if export.CompletedAt != nil {
+		modTime = *export.CompletedAt
+	}
+	name := exports.FileName(export.Format, export.CreatedAt)
+	w.Header().Set("Content-Type", exports.ContentType(export.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, name, modTime, file) // Supports resuming interrupted downloads
+}
+
+// startBackgroundExport queues an export, or reuses the one the user already has pending.
+func (h *ExportHandler) startBackgroundExport(w http.ResponseWriter, r *http.Request, userID uuid.UUID, format string) {
+	export, err := h.ExportRepo.FindPendingByUser(r.Context(), userID)
+	if errors.Is(err, exports.ErrExportNotFound) {
+		export, err = h.ExportRepo.Create(r.Context(), userID, format)
+		if err == nil {
+			h.Scheduler.Enqueue(export)
+		}
+	}
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to start export"), http.StatusInternalServerError)
+		return
+	}
+
+	response, err := h.newDataExportResponse(export)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to start export"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", response.StatusURL)
+	webutils.WriteJSON(w, http.StatusAccepted, response)
+}
+
+// newDataExportResponse adds the status URL and, for downloadable exports, a signed download link
+// that expires with the export.
+func (h *ExportHandler) newDataExportResponse(export *models.DataExport) (DataExportResponse, error) {
+	response := DataExportResponse{
+		DataExport: *export,
+		StatusURL:  "/api/users/me/exports/" + export.ID.String(),
+	}
+	if export.IsDownloadable(time.Now()) {
+		token, err := auth.GenerateDataExportToken(export.ID, export.UserID, h.JwtSecret, *export.ExpiresAt)
+		if err != nil {
+			return response, err
+		}
+		response.DownloadURL = fmt.Sprintf("/api/exports/%s/download?token=%s", export.ID, url.QueryEscape(token))
+	}
+	return response, nil
+}
@@ -0,0 +1,308 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/cart"
+	"bullet-cloud-api/internal/exports"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/identities"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/orders"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/users"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockExportScheduler records the exports queued for background generation.
+type MockExportScheduler struct {
+	mock.Mock
+}
+
+func (m *MockExportScheduler) Enqueue(export *models.DataExport) {
+	m.Called(export)
+}
+
+// exportTestMocks holds the repositories read by an export.
+type exportTestMocks struct {
+	userRepo     *MockUserRepository
+	addressRepo  *MockAddressRepository
+	cartRepo     *MockCartRepository
+	orderRepo    *orders.MockOrderRepository
+	sessionRepo  *sessions.MockSessionRepository
+	apiKeyRepo   *apikeys.MockAPIKeyRepository
+	identityRepo *identities.MockIdentityRepository
+	exportRepo   *exports.MockExportRepository
+	scheduler    *MockExportScheduler
+}
+
+// setupExportTest wires the export routes for an authenticated user. Accounts with more than
+// one order are exported in the background.
+func setupExportTest(t *testing.T, user *models.User) (*exportTestMocks, *mux.Router, string) {
+	t.Helper()
+	m := &exportTestMocks{
+		userRepo:     new(MockUserRepository),
+		addressRepo:  new(MockAddressRepository),
+		cartRepo:     new(MockCartRepository),
+		orderRepo:    new(orders.MockOrderRepository),
+		sessionRepo:  newTestSessionRepo(),
+		apiKeyRepo:   new(apikeys.MockAPIKeyRepository),
+		identityRepo: new(identities.MockIdentityRepository),
+		exportRepo:   new(exports.MockExportRepository),
+		scheduler:    new(MockExportScheduler),
+	}
+	m.userRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+
+	collector := exports.NewCollector(m.userRepo, m.addressRepo, m.cartRepo, m.orderRepo, m.sessionRepo, m.apiKeyRepo, m.identityRepo)
+	h := handlers.NewExportHandler(collector, m.exportRepo, m.scheduler, testJwtSecret, 1)
+	authMiddleware := auth.NewMiddleware(testKeys, m.userRepo, m.sessionRepo, new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/exports/{id}/download", h.DownloadExport).Methods("GET")
+	accountRoutes := router.PathPrefix("/api/users/me").Subrouter()
+	accountRoutes.Use(authMiddleware.Authenticate)
+	accountRoutes.HandleFunc("/export", h.ExportMe).Methods("GET")
+	accountRoutes.HandleFunc("/exports/{id}", h.GetExport).Methods("GET")
+
+	testToken, err := generateTestToken(user.ID)
+	require.NoError(t, err)
+	return m, router, testToken
+}
+
+// expectCollect sets up the reads of a complete export with one address, one cart item and one order.
+func (m *exportTestMocks) expectCollect(userID uuid.UUID, order models.Order) {
+	cartID := uuid.New()
+	m.addressRepo.On("FindByUserID", mock.Anything, userID).Return([]models.Address{{ID: uuid.New(), UserID: userID, Street: "Rua A, 1", City: "São Paulo"}}, nil).Once()
+	m.cartRepo.On("FindCartByUserID", mock.Anything, userID).Return(&models.Cart{ID: cartID, UserID: userID}, nil).Once()
+	m.cartRepo.On("GetCartItems", mock.Anything, cartID).Return([]models.CartItem{{CartID: cartID, ProductID: uuid.New(), Quantity: 2}}, nil).Once()
+	m.orderRepo.On("FindOrderByID", mock.Anything, order.ID).Return(&order, []models.OrderItem{{OrderID: order.ID, ProductID: uuid.New(), Quantity: 1, Price: brl("9.90")}}, nil).Once()
+	m.sessionRepo.On("ListByUser", mock.Anything, userID).Return([]models.Session{{ID: userID, UserID: userID, UserAgent: "curl/8.0"}}, nil).Once()
+	m.apiKeyRepo.On("ListByUser", mock.Anything, userID).Return([]models.APIKey{{ID: uuid.New(), UserID: userID, Name: "ci", KeyHash: "secret-hash"}}, nil).Once()
+	m.identityRepo.On("ListByUser", mock.Anything, userID).Return([]models.UserIdentity{}, nil).Once()
+}
+
+func TestExportHandler_ExportMe(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", PasswordHash: "hash", Role: models.RoleCustomer}
+	order := models.Order{ID: uuid.New(), UserID: user.ID, Status: models.StatusDelivered, Total: brl("9.90"), Currency: money.DefaultCurrency, ExchangeRate: money.OneRate}
+
+	t.Run("JSON In Response", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		m.orderRepo.On("FindUserOrders", mock.Anything, user.ID).Return([]models.Order{order}, nil).Twice() // Size check + export
+		m.expectCollect(user.ID, order)
+
+		req, _ := http.NewRequest("GET", "/api/users/me/export", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.Regexp(t, `^attachment; filename="bullet-cloud-export-\d{8}\.json"$`, rr.Header().Get("Content-Disposition"))
+		assert.NotContains(t, rr.Body.String(), "hash", "Password and API key hashes are never exported")
+
+		var bundle exports.Bundle
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &bundle))
+		assert.Equal(t, "ana@example.com", bundle.User.Email)
+		assert.Len(t, bundle.Addresses, 1)
+		assert.Len(t, bundle.Cart.Items, 1)
+		require.Len(t, bundle.Orders, 1)
+		assert.Equal(t, order.ID, bundle.Orders[0].Order.ID)
+		assert.Len(t, bundle.Orders[0].Items, 1)
+		assert.Len(t, bundle.Sessions, 1)
+		assert.Len(t, bundle.APIKeys, 1)
+
+		m.exportRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ZIP In Response", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		m.orderRepo.On("FindUserOrders", mock.Anything, user.ID).Return([]models.Order{order}, nil).Twice()
+		m.expectCollect(user.ID, order)
+
+		req, _ := http.NewRequest("GET", "/api/users/me/export?format=zip", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+
+		archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		require.NoError(t, err)
+		var names []string
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		assert.Equal(t, []string{"addresses.json", "api_keys.json", "cart.json", "identities.json", "orders.json", "sessions.json", "user.json"}, names)
+	})
+
+	t.Run("Without Cart", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		m.orderRepo.On("FindUserOrders", mock.Anything, user.ID).Return([]models.Order{}, nil).Twice()
+		m.addressRepo.On("FindByUserID", mock.Anything, user.ID).Return([]models.Address{}, nil).Once()
+		m.cartRepo.On("FindCartByUserID", mock.Anything, user.ID).Return(nil, cart.ErrCartNotFound).Once()
+		m.sessionRepo.On("ListByUser", mock.Anything, user.ID).Return([]models.Session{}, nil).Once()
+		m.apiKeyRepo.On("ListByUser", mock.Anything, user.ID).Return([]models.APIKey{}, nil).Once()
+		m.identityRepo.On("ListByUser", mock.Anything, user.ID).Return([]models.UserIdentity{}, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/export", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+		var bundle exports.Bundle
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &bundle))
+		assert.Nil(t, bundle.Cart.Cart)
+		assert.Empty(t, bundle.Cart.Items)
+		m.cartRepo.AssertNotCalled(t, "GetOrCreateCartByUserID", mock.Anything, mock.Anything) // Exporting never creates a cart
+	})
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		_, router, testToken := setupExportTest(t, user)
+
+		req, _ := http.NewRequest("GET", "/api/users/me/export?format=csv", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusBadRequest, `{"error":"format must be json or zip"}`)
+	})
+
+	t.Run("Large Account Runs In Background", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		m.orderRepo.On("FindUserOrders", mock.Anything, user.ID).Return([]models.Order{order, order}, nil).Once()
+		created := &models.DataExport{ID: uuid.New(), UserID: user.ID, Format: "zip", Status: models.ExportPending}
+		m.exportRepo.On("FindPendingByUser", mock.Anything, user.ID).Return(nil, exports.ErrExportNotFound).Once()
+		m.exportRepo.On("Create", mock.Anything, user.ID, "zip").Return(created, nil).Once()
+		m.scheduler.On("Enqueue", created).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/export?format=zip", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequestAndAssert(t, router, req, http.StatusAccepted, "")
+
+		statusURL := "/api/users/me/exports/" + created.ID.String()
+		assert.Equal(t, statusURL, rr.Header().Get("Location"))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "pending", body["status"])
+		assert.Equal(t, statusURL, body["status_url"])
+		assert.NotContains(t, body, "download_url")
+
+		m.exportRepo.AssertExpectations(t)
+		m.scheduler.AssertExpectations(t)
+	})
+
+	t.Run("Async Reuses Pending Export", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		pending := &models.DataExport{ID: uuid.New(), UserID: user.ID, Format: "json", Status: models.ExportPending}
+		m.exportRepo.On("FindPendingByUser", mock.Anything, user.ID).Return(pending, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/export?async=true", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusAccepted, "")
+
+		m.orderRepo.AssertNotCalled(t, "FindUserOrders", mock.Anything, mock.Anything)
+		m.exportRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+		m.scheduler.AssertNotCalled(t, "Enqueue", mock.Anything)
+	})
+
+	t.Run("Collect Error", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		m.orderRepo.On("FindUserOrders", mock.Anything, user.ID).Return([]models.Order{}, nil).Once()
+		m.addressRepo.On("FindByUserID", mock.Anything, user.ID).Return(nil, assert.AnError).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/export", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusInternalServerError, `{"error":"failed to export data"}`)
+	})
+}
+
+func TestExportHandler_GetExportAndDownload(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Role: models.RoleCustomer}
+	completedAt := time.Now().Add(-time.Minute)
+	expiresAt := time.Now().Add(time.Hour)
+
+	filePath := filepath.Join(t.TempDir(), "export.json")
+	require.NoError(t, os.WriteFile(filePath, []byte(`{"user":{}}`), 0o600))
+	ready := &models.DataExport{ID: uuid.New(), UserID: user.ID, Format: "json", Status: models.ExportReady, FilePath: filePath, CompletedAt: &completedAt, ExpiresAt: &expiresAt}
+
+	t.Run("Ready Export Has Download Link", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		m.exportRepo.On("FindByID", mock.Anything, ready.ID).Return(ready, nil)
+
+		req, _ := http.NewRequest("GET", "/api/users/me/exports/"+ready.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+
+		var body handlers.DataExportResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, models.ExportReady, body.Status)
+		require.NotEmpty(t, body.DownloadURL)
+		assert.NotContains(t, rr.Body.String(), filePath, "The server path is never exposed")
+
+		// The link works without the bearer token
+		download, _ := http.NewRequest("GET", body.DownloadURL, nil)
+		rr = executeRequestAndAssert(t, router, download, http.StatusOK, "")
+		assert.Equal(t, `{"user":{}}`, rr.Body.String())
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+	})
+
+	t.Run("Export Of Another User", func(t *testing.T) {
+		m, router, testToken := setupExportTest(t, user)
+		other := *ready
+		other.UserID = uuid.New()
+		m.exportRepo.On("FindByID", mock.Anything, other.ID).Return(&other, nil)
+
+		req, _ := http.NewRequest("GET", "/api/users/me/exports/"+other.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusNotFound, `{"error":"export not found"}`)
+	})
+
+	t.Run("Download With Invalid Token", func(t *testing.T) {
+		_, router, _ := setupExportTest(t, user)
+
+		req, _ := http.NewRequest("GET", "/api/exports/"+ready.ID.String()+"/download?token=forged", nil)
+		executeRequestAndAssert(t, router, req, http.StatusForbidden, `{"error":"invalid or expired download link"}`)
+	})
+
+	t.Run("Download Token For Another Export", func(t *testing.T) {
+		_, router, _ := setupExportTest(t, user)
+		token, err := auth.GenerateDataExportToken(uuid.New(), user.ID, testJwtSecret, expiresAt)
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("GET", "/api/exports/"+ready.ID.String()+"/download?token="+token, nil)
+		executeRequestAndAssert(t, router, req, http.StatusForbidden, `{"error":"invalid or expired download link"}`)
+	})
+
+	t.Run("Download Of Removed Export", func(t *testing.T) {
+		m, router, _ := setupExportTest(t, user)
+		m.exportRepo.On("FindByID", mock.Anything, ready.ID).Return(nil, exports.ErrExportNotFound)
+		token, err := auth.GenerateDataExportToken(ready.ID, user.ID, testJwtSecret, expiresAt)
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("GET", "/api/exports/"+ready.ID.String()+"/download?token="+token, nil)
+		executeRequestAndAssert(t, router, req, http.StatusGone, `{"error":"export is no longer available"}`)
+	})
+
+	t.Run("Download After Account Deletion", func(t *testing.T) {
+		m, router, _ := setupExportTest(t, user)
+		deletedID := uuid.New()
+		m.userRepo.On("FindByID", mock.Anything, deletedID).Return(nil, users.ErrUserNotFound).Once()
+		orphan := *ready
+		orphan.UserID = deletedID
+		m.exportRepo.On("FindByID", mock.Anything, orphan.ID).Return(&orphan, nil).Once()
+		token, err := auth.GenerateDataExportToken(orphan.ID, deletedID, testJwtSecret, expiresAt)
+		require.NoError(t, err)
+
+		// A link issued before the deletion no longer serves the file
+		req, _ := http.NewRequest("GET", "/api/exports/"+orphan.ID.String()+"/download?token="+token, nil)
+		executeRequestAndAssert(t, router, req, http.StatusGone, `{"error":"export is no longer available"}`)
+		m.userRepo.AssertCalled(t, "FindByID", mock.Anything, deletedID)
+	})
+}
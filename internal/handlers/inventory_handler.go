@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/inventory"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/webutils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Limits of the stock location fields, of transfer listings and of the sales window of
+// low-stock listings.
+const (
+	maxLocationNameLength = 100 // As in the stock_locations table
+	defaultTransferLimit  = 50
+	maxTransferLimit      = 200
+	maxSalesWindowDays    = 365
+)
+
+// InventoryHandler manages stock locations, the stock of products and variants kept at
+// them, and the transfers between them. Its stock routes serve both /api/products/{id}/stock
+// and /api/products/{id}/variants/{variantId}/stock.
+type InventoryHandler struct {
+	InventoryRepo inventory.InventoryRepository
+	SalesWindow   time.Duration // Default sales used to estimate the days of cover of low-stock products
+}
+
+// NewInventoryHandler creates a new InventoryHandler.
+func NewInventoryHandler(inventoryRepo inventory.InventoryRepository, salesWindow time.Duration) *InventoryHandler {
+	return &InventoryHandler{InventoryRepo: inventoryRepo, SalesWindow: salesWindow}
+}
+
+// --- Request Structs ---
+
+// StockLocationRequest is the body of the stock location create and update requests.
+type StockLocationRequest struct {
+	Name       string `json:"name"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	State      string `json:"state"` // Abbreviation ("SP") or name of the state; orders ship from the nearest
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+// SetStockRequest is the body of SetStock. Stock is required: a number of units, or null
+// to stop tracking the stock at the location.
+type SetStockRequest struct {
+	LocationID uuid.UUID       `json:"location_id"`
+	Stock      json.RawMessage `json:"stock"`
+}
+
+// AdjustStockRequest is the body of AdjustStock.
+type AdjustStockRequest struct {
+	LocationID uuid.UUID `json:"location_id"`
+	Delta      int       `json:"delta"` // Units received, or negative for units lost or removed
+}
+
+// ReorderThresholdRequest is the body of SetReorderThreshold. ReorderThreshold is required:
+// a number of units, or null to disable the low-stock alerts of the product.
+type ReorderThresholdRequest struct {
+	ReorderThreshold json.RawMessage `json:"reorder_threshold"`
+}
+
+// StockTransferRequest is the body of CreateTransfer.
+type StockTransferRequest struct {
+	FromLocationID uuid.UUID  `json:"from_location_id"`
+	ToLocationID   uuid.UUID  `json:"to_location_id"`
+	ProductID      uuid.UUID  `json:"product_id"`
+	VariantID      *uuid.UUID `json:"variant_id"` // Required for products sold in variants
+	Quantity       int        `json:"quantity"`
+	Note           string     `json:"note"` // Optional
+}
+
+// --- Location Handlers ---
+
+// ListLocations handles GET /api/stock-locations.
+func (h *InventoryHandler) ListLocations(w http.ResponseWriter, r *http.Request) {
+	locations, err := h.InventoryRepo.ListLocations(r.Context())
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve stock locations"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, locations)
+}
+
+// CreateLocation handles POST /api/stock-locations.
+func (h *InventoryHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	location, ok := readStockLocationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.InventoryRepo.CreateLocation(r.Context(), location)
+	if err != nil {
+		respondStockError(w, err, "failed to create stock location")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// GetLocation handles GET /api/stock-locations/{id}.
+func (h *InventoryHandler) GetLocation(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := parseLocationURL(w, r)
+	if !ok {
+		return
+	}
+
+	location, err := h.InventoryRepo.FindLocationByID(r.Context(), locationID)
+	if err != nil {
+		respondStockError(w, err, "failed to retrieve stock location")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, location)
+}
+
+// UpdateLocation handles PUT /api/stock-locations/{id}.
+func (h *InventoryHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := parseLocationURL(w, r)
+	if !ok {
+		return
+	}
+	location, ok := readStockLocationRequest(w, r)
+	if !ok {
+		return
+	}
+	location.ID = locationID
+
+	updated, err := h.InventoryRepo.UpdateLocation(r.Context(), location)
+	if err != nil {
+		respondStockError(w, err, "failed to update stock location")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, updated)
+}
+
+// DeleteLocation handles DELETE /api/stock-locations/{id}.
+// Locations still holding units, or that order items shipped from, cannot be deleted.
+func (h *InventoryHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := parseLocationURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.InventoryRepo.DeleteLocation(r.Context(), locationID); err != nil {
+		respondStockError(w, err, "failed to delete stock location")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPickingList handles GET /api/stock-locations/{id}/picking-list: the items of pending
+// and processing orders to collect at the location, the oldest orders first.
+func (h *InventoryHandler) GetPickingList(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := parseLocationURL(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.InventoryRepo.PickingList(r.Context(), locationID)
+	if err != nil {
+		respondStockError(w, err, "failed to retrieve picking list")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, items)
+}
+
+// --- Stock Handlers ---
+
+// GetStock handles GET /api/products/{id}/stock and /api/products/{id}/variants/{variantId}/stock:
+// the units at each location tracking the stock.
+func (h *InventoryHandler) GetStock(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := parseStockURL(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := h.InventoryRepo.FindStock(r.Context(), productID, variantID)
+	if err != nil {
+		respondStockError(w, err, "failed to retrieve stock")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, summary)
+}
+
+// SetStock handles PUT /api/products/{id}/stock and /api/products/{id}/variants/{variantId}/stock,
+// replacing the units at one location.
+func (h *InventoryHandler) SetStock(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := parseStockURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetStockRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	var fieldErrors []webutils.FieldError
+	if req.LocationID == uuid.Nil {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "location_id", Code: "required", Message: "is required"})
+	}
+	var stock *int
+	if len(req.Stock) == 0 {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "stock", Code: "required", Message: "is required, null stops tracking the stock at the location"})
+	} else if err := json.Unmarshal(req.Stock, &stock); err != nil || (stock != nil && *stock < 0) {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "stock", Code: "invalid", Message: "must be a whole number of units, at least 0, or null"})
+	}
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return
+	}
+
+	summary, err := h.InventoryRepo.SetStock(r.Context(), req.LocationID, productID, variantID, stock)
+	if err != nil {
+		respondStockError(w, err, "failed to set stock")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, summary)
+}
+
+// AdjustStock handles POST /api/products/{id}/stock/adjustments and
+// /api/products/{id}/variants/{variantId}/stock/adjustments.
+// Adjusting is safe while customers check out, unlike setting the stock.
+func (h *InventoryHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := parseStockURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req AdjustStockRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	var fieldErrors []webutils.FieldError
+	if req.LocationID == uuid.Nil {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "location_id", Code: "required", Message: "is required"})
+	}
+	if req.Delta == 0 {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "delta", Code: "invalid", Message: "must not be zero"})
+	}
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return
+	}
+
+	summary, err := h.InventoryRepo.AdjustStock(r.Context(), req.LocationID, productID, variantID, req.Delta)
+	if err != nil {
+		respondStockError(w, err, "failed to adjust stock")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, summary)
+}
+
+// SetReorderThreshold handles PUT /api/products/{id}/reorder-threshold. Purchasing is told
+// when the units of the product at all locations fall below the threshold.
+func (h *InventoryHandler) SetReorderThreshold(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid product ID format"), http.StatusBadRequest)
+		return
+	}
+
+	var req ReorderThresholdRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	var threshold *int
+	if len(req.ReorderThreshold) == 0 {
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "reorder_threshold", Code: "required", Message: "is required, null disables the low-stock alerts"}})
+		return
+	}
+	if err := json.Unmarshal(req.ReorderThreshold, &threshold); err != nil || (threshold != nil && *threshold < 0) {
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "reorder_threshold", Code: "invalid", Message: "must be a whole number of units, at least 0, or null"}})
+		return
+	}
+
+	summary, err := h.InventoryRepo.SetReorderThreshold(r.Context(), productID, threshold)
+	if err != nil {
+		respondStockError(w, err, "failed to set reorder threshold")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, summary)
+}
+
+// ListLowStock handles GET /api/products/low-stock: the tracked products below their reorder
+// threshold, the fewest days of cover first. The days parameter sets how many days of recent
+// sales estimate the days of cover (default SalesWindow).
+func (h *InventoryHandler) ListLowStock(w http.ResponseWriter, r *http.Request) {
+	window := h.SalesWindow
+	if v := r.URL.Query().Get("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 || days > maxSalesWindowDays {
+			webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "days", Code: "invalid", Message: fmt.Sprintf("must be between 1 and %d", maxSalesWindowDays)}})
+			return
+		}
+		window = time.Duration(days) * 24 * time.Hour
+	}
+
+	lowStock, err := h.InventoryRepo.ListLowStock(r.Context(), window)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve low-stock products"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, lowStock)
+}
+
+// --- Transfer Handlers ---
+
+// CreateTransfer handles POST /api/stock-transfers, moving units between two locations.
+func (h *InventoryHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	authUserID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var req StockTransferRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	var fieldErrors []webutils.FieldError
+	if req.FromLocationID == uuid.Nil {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "from_location_id", Code: "required", Message: "is required"})
+	}
+	if req.ToLocationID == uuid.Nil {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "to_location_id", Code: "required", Message: "is required"})
+	} else if req.ToLocationID == req.FromLocationID {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "to_location_id", Code: "invalid", Message: "must differ from from_location_id"})
+	}
+	if req.ProductID == uuid.Nil {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "product_id", Code: "required", Message: "is required"})
+	}
+	if req.Quantity <= 0 {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "quantity", Code: "invalid", Message: "must be at least 1"})
+	}
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return
+	}
+
+	transfer := &models.StockTransfer{
+		FromLocationID: req.FromLocationID,
+		ToLocationID:   req.ToLocationID,
+		ProductID:      req.ProductID,
+		VariantID:      req.VariantID,
+		Quantity:       req.Quantity,
+		Note:           strings.TrimSpace(req.Note),
+		CreatedBy:      &authUserID,
+	}
+	created, err := h.InventoryRepo.Transfer(r.Context(), transfer)
+	if err != nil {
+		respondStockError(w, err, "failed to transfer stock")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// ListTransfers handles GET /api/stock-transfers, the latest first. The location_id
+// parameter keeps the transfers from or to a location; limit defaults to 50, at most 200.
+func (h *InventoryHandler) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var fieldErrors []webutils.FieldError
+	var locationID *uuid.UUID
+	if v := query.Get("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			fieldErrors = append(fieldErrors, webutils.FieldError{Field: "location_id", Code: "invalid", Message: "must be a UUID"})
+		}
+		locationID = &id
+	}
+	limit := defaultTransferLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTransferLimit {
+			fieldErrors = append(fieldErrors, webutils.FieldError{Field: "limit", Code: "invalid", Message: fmt.Sprintf("must be between 1 and %d", maxTransferLimit)})
+		}
+		limit = n
+	}
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return
+	}
+
+	transfers, err := h.InventoryRepo.ListTransfers(r.Context(), locationID, limit)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve stock transfers"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, transfers)
+}
+
+// --- Helpers ---
+
+// readStockLocationRequest decodes and validates a stock location request.
+// On failure it writes the response and returns ok == false.
+func readStockLocationRequest(w http.ResponseWriter, r *http.Request) (*models.StockLocation, bool) {
+	var req StockLocationRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	location := &models.StockLocation{
+		Name:       strings.TrimSpace(req.Name),
+		Street:     strings.TrimSpace(req.Street),
+		City:       strings.TrimSpace(req.City),
+		State:      strings.TrimSpace(req.State),
+		PostalCode: strings.TrimSpace(req.PostalCode),
+		Country:    strings.TrimSpace(req.Country),
+	}
+	var fieldErrors []webutils.FieldError
+	switch {
+	case location.Name == "":
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "name", Code: "required", Message: "is required"})
+	case len(location.Name) > maxLocationNameLength:
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "name", Code: "too_long", Message: fmt.Sprintf("must be at most %d characters", maxLocationNameLength)})
+	}
+	if location.State == "" {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: "state", Code: "required", Message: "is required, orders ship from the locations nearest to them"})
+	}
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return nil, false
+	}
+	return location, true
+}
+
+// parseLocationURL parses the {id} URL variable of the stock location routes.
+// On failure it writes the response and returns ok == false.
+func parseLocationURL(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	locationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid stock location ID format"), http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return locationID, true
+}
+
+// parseStockURL parses the {id} URL variable, and {variantId} on variant routes.
+// On failure it writes the response and returns ok == false.
+func parseStockURL(w http.ResponseWriter, r *http.Request) (uuid.UUID, *uuid.UUID, bool) {
+	if _, ok := mux.Vars(r)["variantId"]; ok {
+		productID, variantID, ok := parseVariantURL(w, r)
+		return productID, &variantID, ok
+	}
+	productID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid product ID format"), http.StatusBadRequest)
+		return uuid.Nil, nil, false
+	}
+	return productID, nil, true
+}
+
+// respondStockError writes the response for a failed stock or stock location operation.
+func respondStockError(w http.ResponseWriter, err error, failureMessage string) {
+	switch {
+	case errors.Is(err, inventory.ErrLocationNotFound), errors.Is(err, inventory.ErrStockItemNotFound):
+		webutils.ErrorJSON(w, err, http.StatusNotFound)
+	case errors.Is(err, inventory.ErrLocationNameExists), errors.Is(err, inventory.ErrLocationHasStock),
+		errors.Is(err, inventory.ErrLocationHasOrders), errors.Is(err, inventory.ErrNegativeStock),
+		errors.Is(err, inventory.ErrInsufficientTransferStock), errors.Is(err, inventory.ErrNegativeThreshold):
+		webutils.ErrorJSON(w, err, http.StatusConflict)
+	default:
+		webutils.ErrorJSON(w, errors.New(failureMessage), http.StatusInternalServerError)
+	}
+}
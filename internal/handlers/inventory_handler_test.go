@@ -0,0 +1,345 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/inventory"
+	"bullet-cloud-api/internal/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testSalesWindow is the default sales window of the low-stock listing in the tests.
+const testSalesWindow = 30 * 24 * time.Hour
+
+// setupInventoryTest creates an InventoryHandler behind the same middleware as in main.go.
+// It returns a token for an admin and the admin's ID.
+func setupInventoryTest(t *testing.T) (*inventory.MockInventoryRepository, *mux.Router, string, uuid.UUID) {
+	t.Helper()
+	inventoryRepo := new(inventory.MockInventoryRepository)
+	userRepo := new(MockUserRepository)
+	adminID := uuid.New()
+	userRepo.On("FindByID", mock.Anything, adminID).Return(&models.User{ID: adminID, Role: models.RoleAdmin}, nil).Maybe()
+
+	h := handlers.NewInventoryHandler(inventoryRepo, testSalesWindow)
+	mw := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	admin := router.PathPrefix("/api/products").Subrouter()
+	admin.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin))
+	admin.HandleFunc("/low-stock", h.ListLowStock).Methods("GET")
+	admin.HandleFunc("/{id}/stock", h.GetStock).Methods("GET")
+	admin.HandleFunc("/{id}/stock", h.SetStock).Methods("PUT")
+	admin.HandleFunc("/{id}/stock/adjustments", h.AdjustStock).Methods("POST")
+	admin.HandleFunc("/{id}/reorder-threshold", h.SetReorderThreshold).Methods("PUT")
+	admin.HandleFunc("/{id}/variants/{variantId}/stock", h.SetStock).Methods("PUT")
+	admin.HandleFunc("/{id}/variants/{variantId}/stock/adjustments", h.AdjustStock).Methods("POST")
+
+	locations := router.PathPrefix("/api/stock-locations").Subrouter()
+	locations.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin))
+	locations.HandleFunc("", h.CreateLocation).Methods("POST")
+	locations.HandleFunc("/{id}", h.DeleteLocation).Methods("DELETE")
+	locations.HandleFunc("/{id}/picking-list", h.GetPickingList).Methods("GET")
+
+	transfers := router.PathPrefix("/api/stock-transfers").Subrouter()
+	transfers.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin))
+	transfers.HandleFunc("", h.ListTransfers).Methods("GET")
+	transfers.HandleFunc("", h.CreateTransfer).Methods("POST")
+
+	token, err := generateTestToken(adminID)
+	require.NoError(t, err)
+	return inventoryRepo, router, token, adminID
+}
+
+func TestInventoryHandler_CreateLocation(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+
+	t.Run("Success", func(t *testing.T) {
+		expected := &models.StockLocation{Name: "CD Recife", City: "Recife", State: "PE"}
+		inventoryRepo.On("CreateLocation", mock.Anything, expected).Return(expected, nil).Once()
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/stock-locations", token, `{"name":" CD Recife ","city":"Recife","state":"PE"}`), http.StatusCreated, "")
+		assert.Contains(t, rr.Body.String(), `"name":"CD Recife"`)
+	})
+
+	t.Run("Validation Errors", func(t *testing.T) {
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/stock-locations", token, `{"name":"","state":" "}`), http.StatusBadRequest, "")
+		body := rr.Body.String()
+		assert.Contains(t, body, `"field":"name"`)
+		assert.Contains(t, body, `"field":"state"`)
+	})
+
+	t.Run("Duplicate Name", func(t *testing.T) {
+		inventoryRepo.On("CreateLocation", mock.Anything, mock.Anything).Return(nil, inventory.ErrLocationNameExists).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/stock-locations", token, `{"name":"CD Recife","state":"PE"}`), http.StatusConflict, `{"error":"stock location name already exists"}`)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_DeleteLocation(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	locationID := uuid.New()
+	path := fmt.Sprintf("/api/stock-locations/%s", locationID)
+
+	t.Run("Success", func(t *testing.T) {
+		inventoryRepo.On("DeleteLocation", mock.Anything, locationID).Return(nil).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("DELETE", path, token, ""), http.StatusNoContent, "")
+	})
+
+	t.Run("Still Holds Units", func(t *testing.T) {
+		inventoryRepo.On("DeleteLocation", mock.Anything, locationID).Return(inventory.ErrLocationHasStock).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("DELETE", path, token, ""), http.StatusConflict, `{"error":"stock location still holds units, transfer or remove them first"}`)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		inventoryRepo.On("DeleteLocation", mock.Anything, locationID).Return(inventory.ErrLocationNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("DELETE", path, token, ""), http.StatusNotFound, `{"error":"stock location not found"}`)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_GetStock(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	productID := uuid.New()
+	locationID := uuid.New()
+	stock := 4
+	summary := &models.StockSummary{
+		ProductID: productID,
+		Stock:     &stock,
+		Levels:    []models.StockLevel{{LocationID: locationID, LocationName: "CD São Paulo", Quantity: 4}},
+	}
+	inventoryRepo.On("FindStock", mock.Anything, productID, noVariant).Return(summary, nil).Once()
+
+	rr := executeRequestAndAssert(t, router, newAdminRequest("GET", fmt.Sprintf("/api/products/%s/stock", productID), token, ""), http.StatusOK, "")
+	assert.Contains(t, rr.Body.String(), fmt.Sprintf(`"levels":[{"location_id":"%s","location_name":"CD São Paulo","quantity":4`, locationID))
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_SetStock(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	productID := uuid.New()
+	variantID := uuid.New()
+	locationID := uuid.New()
+	productPath := fmt.Sprintf("/api/products/%s/stock", productID)
+	variantPath := fmt.Sprintf("/api/products/%s/variants/%s/stock", productID, variantID)
+	stock := 12
+	nilStock := (*int)(nil)
+
+	t.Run("Success - Product", func(t *testing.T) {
+		inventoryRepo.On("SetStock", mock.Anything, locationID, productID, noVariant, &stock).
+			Return(&models.StockSummary{ProductID: productID, Stock: &stock, Levels: []models.StockLevel{}}, nil).Once()
+		expected := fmt.Sprintf(`{"product_id":"%s","variant_id":null,"stock":12,"levels":[]}`, productID)
+		body := fmt.Sprintf(`{"location_id":"%s","stock":12}`, locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", productPath, token, body), http.StatusOK, expected)
+	})
+
+	t.Run("Success - Stop Tracking Variant", func(t *testing.T) {
+		inventoryRepo.On("SetStock", mock.Anything, locationID, productID, &variantID, nilStock).
+			Return(&models.StockSummary{ProductID: productID, VariantID: &variantID, Levels: []models.StockLevel{}}, nil).Once()
+		expected := fmt.Sprintf(`{"product_id":"%s","variant_id":"%s","stock":null,"levels":[]}`, productID, variantID)
+		body := fmt.Sprintf(`{"location_id":"%s","stock":null}`, locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", variantPath, token, body), http.StatusOK, expected)
+	})
+
+	t.Run("Missing Fields", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[` +
+			`{"field":"location_id","code":"required","message":"is required"},` +
+			`{"field":"stock","code":"required","message":"is required, null stops tracking the stock at the location"}]}`
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", productPath, token, `{}`), http.StatusBadRequest, expected)
+	})
+
+	t.Run("Negative Stock", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[{"field":"stock","code":"invalid","message":"must be a whole number of units, at least 0, or null"}]}`
+		body := fmt.Sprintf(`{"location_id":"%s","stock":-1}`, locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", productPath, token, body), http.StatusBadRequest, expected)
+	})
+
+	t.Run("Location Not Found", func(t *testing.T) {
+		inventoryRepo.On("SetStock", mock.Anything, locationID, productID, &variantID, &stock).Return(nil, inventory.ErrLocationNotFound).Once()
+		body := fmt.Sprintf(`{"location_id":"%s","stock":12}`, locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", variantPath, token, body), http.StatusNotFound, `{"error":"stock location not found"}`)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_AdjustStock(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	productID := uuid.New()
+	locationID := uuid.New()
+	path := fmt.Sprintf("/api/products/%s/stock/adjustments", productID)
+
+	t.Run("Success", func(t *testing.T) {
+		stock := 7
+		inventoryRepo.On("AdjustStock", mock.Anything, locationID, productID, noVariant, -3).
+			Return(&models.StockSummary{ProductID: productID, Stock: &stock, Levels: []models.StockLevel{}}, nil).Once()
+		expected := fmt.Sprintf(`{"product_id":"%s","variant_id":null,"stock":7,"levels":[]}`, productID)
+		body := fmt.Sprintf(`{"location_id":"%s","delta":-3}`, locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("POST", path, token, body), http.StatusOK, expected)
+	})
+
+	t.Run("Zero Delta", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[{"field":"delta","code":"invalid","message":"must not be zero"}]}`
+		body := fmt.Sprintf(`{"location_id":"%s","delta":0}`, locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("POST", path, token, body), http.StatusBadRequest, expected)
+	})
+
+	t.Run("Below Zero", func(t *testing.T) {
+		inventoryRepo.On("AdjustStock", mock.Anything, locationID, productID, noVariant, -50).Return(nil, inventory.ErrNegativeStock).Once()
+		body := fmt.Sprintf(`{"location_id":"%s","delta":-50}`, locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("POST", path, token, body), http.StatusConflict, `{"error":"stock cannot go below zero"}`)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_SetReorderThreshold(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	productID := uuid.New()
+	path := fmt.Sprintf("/api/products/%s/reorder-threshold", productID)
+
+	t.Run("Success", func(t *testing.T) {
+		threshold := 10
+		inventoryRepo.On("SetReorderThreshold", mock.Anything, productID, &threshold).
+			Return(&models.StockSummary{ProductID: productID, Levels: []models.StockLevel{}, ReorderThreshold: &threshold}, nil).Once()
+		expected := fmt.Sprintf(`{"product_id":"%s","variant_id":null,"stock":null,"levels":[],"reorder_threshold":10}`, productID)
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"reorder_threshold":10}`), http.StatusOK, expected)
+	})
+
+	t.Run("Success - Disable Alerts", func(t *testing.T) {
+		inventoryRepo.On("SetReorderThreshold", mock.Anything, productID, (*int)(nil)).
+			Return(&models.StockSummary{ProductID: productID, Levels: []models.StockLevel{}}, nil).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"reorder_threshold":null}`), http.StatusOK, "")
+	})
+
+	t.Run("Missing Threshold", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[{"field":"reorder_threshold","code":"required","message":"is required, null disables the low-stock alerts"}]}`
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{}`), http.StatusBadRequest, expected)
+	})
+
+	t.Run("Negative Threshold", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[{"field":"reorder_threshold","code":"invalid","message":"must be a whole number of units, at least 0, or null"}]}`
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"reorder_threshold":-2}`), http.StatusBadRequest, expected)
+	})
+
+	t.Run("Product Not Found", func(t *testing.T) {
+		inventoryRepo.On("SetReorderThreshold", mock.Anything, productID, mock.Anything).Return(nil, inventory.ErrStockItemNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"reorder_threshold":5}`), http.StatusNotFound, `{"error":"product or variant not found"}`)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_ListLowStock(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	productID := uuid.New()
+
+	t.Run("Success - Default Window", func(t *testing.T) {
+		cover := 2.5
+		lowStock := []models.LowStockProduct{{ProductID: productID, Name: "Camiseta", Stock: 5, ReorderThreshold: 10, UnitsSold: 60, DailySales: 2, DaysOfCover: &cover}}
+		inventoryRepo.On("ListLowStock", mock.Anything, testSalesWindow).Return(lowStock, nil).Once()
+		expected := fmt.Sprintf(`[{"product_id":"%s","name":"Camiseta","stock":5,"reorder_threshold":10,"units_sold":60,"daily_sales":2,"days_of_cover":2.5,"alerted_at":null}]`, productID)
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/products/low-stock", token, ""), http.StatusOK, expected)
+	})
+
+	t.Run("Success - Last Week", func(t *testing.T) {
+		inventoryRepo.On("ListLowStock", mock.Anything, 7*24*time.Hour).Return([]models.LowStockProduct{}, nil).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/products/low-stock?days=7", token, ""), http.StatusOK, `[]`)
+	})
+
+	t.Run("Invalid Days", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[{"field":"days","code":"invalid","message":"must be between 1 and 365"}]}`
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/products/low-stock?days=0", token, ""), http.StatusBadRequest, expected)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_CreateTransfer(t *testing.T) {
+	inventoryRepo, router, token, adminID := setupInventoryTest(t)
+	fromID := uuid.New()
+	toID := uuid.New()
+	productID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		expected := &models.StockTransfer{
+			FromLocationID: fromID,
+			ToLocationID:   toID,
+			ProductID:      productID,
+			Quantity:       5,
+			Note:           "Reposição",
+			CreatedBy:      &adminID,
+		}
+		inventoryRepo.On("Transfer", mock.Anything, expected).Return(expected, nil).Once()
+		body := fmt.Sprintf(`{"from_location_id":"%s","to_location_id":"%s","product_id":"%s","quantity":5,"note":" Reposição "}`, fromID, toID, productID)
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/stock-transfers", token, body), http.StatusCreated, "")
+		assert.Contains(t, rr.Body.String(), fmt.Sprintf(`"created_by":"%s"`, adminID))
+	})
+
+	t.Run("Same Location", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[` +
+			`{"field":"to_location_id","code":"invalid","message":"must differ from from_location_id"},` +
+			`{"field":"quantity","code":"invalid","message":"must be at least 1"}]}`
+		body := fmt.Sprintf(`{"from_location_id":"%s","to_location_id":"%s","product_id":"%s","quantity":0}`, fromID, fromID, productID)
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/stock-transfers", token, body), http.StatusBadRequest, expected)
+	})
+
+	t.Run("Not Enough Units", func(t *testing.T) {
+		inventoryRepo.On("Transfer", mock.Anything, mock.Anything).Return(nil, inventory.ErrInsufficientTransferStock).Once()
+		body := fmt.Sprintf(`{"from_location_id":"%s","to_location_id":"%s","product_id":"%s","quantity":500}`, fromID, toID, productID)
+		executeRequestAndAssert(t, router, newAdminRequest("POST", "/api/stock-transfers", token, body), http.StatusConflict, `{"error":"the source location does not hold enough units"}`)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_ListTransfers(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	locationID := uuid.New()
+
+	t.Run("Success - Filtered By Location", func(t *testing.T) {
+		inventoryRepo.On("ListTransfers", mock.Anything, &locationID, 10).Return([]models.StockTransfer{}, nil).Once()
+		path := fmt.Sprintf("/api/stock-transfers?location_id=%s&limit=10", locationID)
+		executeRequestAndAssert(t, router, newAdminRequest("GET", path, token, ""), http.StatusOK, `[]`)
+	})
+
+	t.Run("Invalid Limit", func(t *testing.T) {
+		expected := `{"error":"validation failed","fields":[{"field":"limit","code":"invalid","message":"must be between 1 and 200"}]}`
+		executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/stock-transfers?limit=500", token, ""), http.StatusBadRequest, expected)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
+
+func TestInventoryHandler_GetPickingList(t *testing.T) {
+	inventoryRepo, router, token, _ := setupInventoryTest(t)
+	locationID := uuid.New()
+	path := fmt.Sprintf("/api/stock-locations/%s/picking-list", locationID)
+
+	t.Run("Success", func(t *testing.T) {
+		orderID := uuid.New()
+		productID := uuid.New()
+		sku := "CAM-M"
+		items := []models.PickingListItem{{OrderID: orderID, OrderStatus: models.StatusPending, ProductID: productID, Name: "Camiseta", SKU: &sku, Quantity: 2}}
+		inventoryRepo.On("PickingList", mock.Anything, locationID).Return(items, nil).Once()
+		rr := executeRequestAndAssert(t, router, newAdminRequest("GET", path, token, ""), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"name":"Camiseta","sku":"CAM-M","quantity":2`)
+	})
+
+	t.Run("Location Not Found", func(t *testing.T) {
+		inventoryRepo.On("PickingList", mock.Anything, locationID).Return(nil, inventory.ErrLocationNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("GET", path, token, ""), http.StatusNotFound, `{"error":"stock location not found"}`)
+	})
+
+	inventoryRepo.AssertExpectations(t)
+}
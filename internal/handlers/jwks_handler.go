@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/webutils"
+	"net/http"
+)
+
+// jwksCacheControl lets other services cache the key set briefly; after a rotation the new
+// key is picked up within this window, while the old one stays published until removed.
+const jwksCacheControl = "public, max-age=300"
+
+// JWKSHandler publishes the public keys used to verify access tokens.
+type JWKSHandler struct {
+	Keys *auth.KeySet
+}
+
+// NewJWKSHandler creates a new JWKSHandler.
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{Keys: keys}
+}
+
+// GetJWKS handles GET /.well-known/jwks.json.
+// The set is empty with the default HS256 algorithm, since shared secrets are never published.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", jwksCacheControl)
+	webutils.WriteJSON(w, http.StatusOK, h.Keys.JWKS())
+}
@@ -0,0 +1,198 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM writes a DER-encoded key to a PEM file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func getJWKS(t *testing.T, keys *auth.KeySet) auth.JWKSet {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/.well-known/jwks.json", handlers.NewJWKSHandler(keys).GetJWKS).Methods("GET")
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+	assert.Equal(t, "public, max-age=300", rr.Header().Get("Cache-Control"))
+
+	var set auth.JWKSet
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &set))
+	return set
+}
+
+func TestJWKSHandler_GetJWKS(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	require.NoError(t, err)
+	rsaPrivatePath := writePEM(t, dir, "rsa.pem", "PRIVATE KEY", rsaPKCS8)
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.NoError(t, err)
+	edPrivatePath := writePEM(t, dir, "ed25519.pem", "PRIVATE KEY", edPKCS8)
+	edPKIX, err := x509.MarshalPKIXPublicKey(edPublic)
+	require.NoError(t, err)
+	edPublicPath := writePEM(t, dir, "ed25519.pub", "PUBLIC KEY", edPKIX)
+
+	t.Run("HS256 Publishes No Keys", func(t *testing.T) {
+		keys, err := auth.LoadKeySet("", testJwtSecret, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, auth.AlgorithmHS256, keys.Algorithm())
+
+		set := getJWKS(t, keys)
+		assert.NotNil(t, set.Keys)
+		assert.Empty(t, set.Keys)
+	})
+
+	t.Run("RS256 With Rotated Key", func(t *testing.T) {
+		keys, err := auth.LoadKeySet(auth.AlgorithmRS256, testJwtSecret, rsaPrivatePath, []string{edPublicPath})
+		require.NoError(t, err)
+		assert.Equal(t, auth.AlgorithmRS256, keys.Algorithm())
+
+		set := getJWKS(t, keys)
+		require.Len(t, set.Keys, 2)
+		assert.Equal(t, "RSA", set.Keys[0].KeyType)
+		assert.Equal(t, "RS256", set.Keys[0].Algorithm)
+		assert.Equal(t, "sig", set.Keys[0].Use)
+		assert.Equal(t, "AQAB", set.Keys[0].E)
+		assert.NotEmpty(t, set.Keys[0].N)
+		assert.Equal(t, "OKP", set.Keys[1].KeyType)
+		assert.Equal(t, "Ed25519", set.Keys[1].Curve)
+		assert.NotEqual(t, set.Keys[0].KeyID, set.Keys[1].KeyID)
+
+		// New tokens carry the kid of the current signing key
+		token, err := auth.GenerateToken(uuid.New(), string(models.RoleCustomer), uuid.New(), keys, time.Hour)
+		require.NoError(t, err)
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", parsed.Method.Alg())
+		assert.Equal(t, set.Keys[0].KeyID, parsed.Header["kid"])
+	})
+
+	t.Run("EdDSA", func(t *testing.T) {
+		keys, err := auth.LoadKeySet(auth.AlgorithmEdDSA, testJwtSecret, edPrivatePath, nil)
+		require.NoError(t, err)
+
+		set := getJWKS(t, keys)
+		require.Len(t, set.Keys, 1)
+		assert.Equal(t, "EdDSA", set.Keys[0].Algorithm)
+		assert.NotEmpty(t, set.Keys[0].X)
+	})
+
+	t.Run("Invalid Configuration", func(t *testing.T) {
+		_, err := auth.LoadKeySet("none", testJwtSecret, "", nil)
+		assert.Error(t, err)
+		_, err = auth.LoadKeySet(auth.AlgorithmRS256, testJwtSecret, "", nil)
+		assert.Error(t, err, "RS256 requires a private key")
+		_, err = auth.LoadKeySet(auth.AlgorithmRS256, testJwtSecret, edPrivatePath, nil)
+		assert.Error(t, err, "key type must match the algorithm")
+		_, err = auth.LoadKeySet(auth.AlgorithmEdDSA, testJwtSecret, edPublicPath, nil)
+		assert.Error(t, err, "a public key cannot sign")
+		_, err = auth.LoadKeySet(auth.AlgorithmEdDSA, testJwtSecret, filepath.Join(dir, "missing.pem"), nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestMiddleware_AsymmetricKeyRotation(t *testing.T) {
+	userID := uuid.New()
+
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, strangerKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	oldKeys, err := auth.NewKeySet(oldKey)
+	require.NoError(t, err)
+	rotatedKeys, err := auth.NewKeySet(newKey, oldKey.Public()) // New signing key, old key still accepted
+	require.NoError(t, err)
+	retiredKeys, err := auth.NewKeySet(newKey) // Old key removed after its tokens expired
+	require.NoError(t, err)
+	strangerKeys, err := auth.NewKeySet(strangerKey)
+	require.NoError(t, err)
+
+	oldToken, err := auth.GenerateToken(userID, string(models.RoleCustomer), userID, oldKeys, time.Hour)
+	require.NoError(t, err)
+	newToken, err := auth.GenerateToken(userID, string(models.RoleCustomer), userID, rotatedKeys, time.Hour)
+	require.NoError(t, err)
+	strangerToken, err := auth.GenerateToken(userID, string(models.RoleCustomer), userID, strangerKeys, time.Hour)
+	require.NoError(t, err)
+	hmacToken, err := auth.GenerateToken(userID, string(models.RoleCustomer), userID, testKeys, time.Hour)
+	require.NoError(t, err)
+
+	// An HS256 token "signed" with the published RSA public key and claiming its kid
+	oldKID := oldKeys.JWKS().Keys[0].KeyID
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
+		UserID:           userID,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	})
+	forged.Header["kid"] = oldKID
+	publicDER, err := x509.MarshalPKIXPublicKey(oldKey.Public())
+	require.NoError(t, err)
+	forgedToken, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		keys           *auth.KeySet
+		token          string
+		expectedStatus int
+	}{
+		{name: "Token From Previous Key Accepted During Rotation", keys: rotatedKeys, token: oldToken, expectedStatus: http.StatusOK},
+		{name: "Token From New Key Accepted", keys: rotatedKeys, token: newToken, expectedStatus: http.StatusOK},
+		{name: "Token From Retired Key Rejected", keys: retiredKeys, token: oldToken, expectedStatus: http.StatusUnauthorized},
+		{name: "Token From Unknown Key Rejected", keys: rotatedKeys, token: strangerToken, expectedStatus: http.StatusUnauthorized},
+		{name: "HS256 Token Rejected When Using Asymmetric Keys", keys: rotatedKeys, token: hmacToken, expectedStatus: http.StatusUnauthorized},
+		{name: "Algorithm Confusion Rejected", keys: rotatedKeys, token: forgedToken, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockUserRepo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, Role: models.RoleCustomer}, nil).Maybe()
+			authMiddleware := auth.NewMiddleware(tc.keys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+			router := mux.NewRouter()
+			router.Handle("/protected", authMiddleware.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))).Methods("GET")
+
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, "")
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/lockout"
+	"bullet-cloud-api/internal/mfa"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/webutils"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount is the number of recovery codes issued when 2FA is enabled.
+const recoveryCodeCount = 10
+
+// errInvalidSecondFactor is returned by verifySecondFactor when the code is wrong or was already used.
+var errInvalidSecondFactor = errors.New("invalid two-factor code")
+
+// MFAHandler handles TOTP two-factor enrollment for the authenticated user.
+type MFAHandler struct {
+	UserRepo     users.UserRepository
+	MFARepo      mfa.MFARepository
+	LoginLimiter *lockout.Limiter // Wrong codes count as failed logins
+	Issuer       string           // Shown as the account issuer in authenticator apps
+}
+
+// NewMFAHandler creates a new MFAHandler.
+func NewMFAHandler(userRepo users.UserRepository, mfaRepo mfa.MFARepository, loginLimiter *lockout.Limiter, issuer string) *MFAHandler {
+	return &MFAHandler{
+		UserRepo:     userRepo,
+		MFARepo:      mfaRepo,
+		LoginLimiter: loginLimiter,
+		Issuer:       issuer,
+	}
+}
+
+// --- Request/Response Structs ---
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`      // Base32 secret for manual entry
+	OTPAuthURL string `json:"otpauth_url"` // otpauth:// URI, usually rendered as a QR code
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Shown once; only their hashes are stored
+}
+
+// --- Handlers ---
+
+// Enroll handles POST /api/users/me/2fa/enroll.
+// It generates a new secret that only becomes active once confirmed with a valid code.
+func (h *MFAHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(r.Context(), userID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve user"), http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to start two-factor enrollment"), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.MFARepo.UpsertPending(r.Context(), userID, secret); err != nil {
+		if errors.Is(err, mfa.ErrMFAAlreadyEnabled) {
+			webutils.ErrorJSON(w, errors.New("two-factor authentication already enabled"), http.StatusConflict)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to start two-factor enrollment"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURL: auth.TOTPProvisioningURI(secret, h.Issuer, user.Email),
+	})
+}
+
+// Confirm handles POST /api/users/me/2fa/confirm.
+// A valid code from the pending secret enables 2FA and returns the recovery codes.
+func (h *MFAHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		webutils.ErrorJSON(w, errors.New("code is required"), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.MFARepo.FindByUserID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, mfa.ErrMFANotFound) {
+			webutils.ErrorJSON(w, errors.New("two-factor enrollment not started"), http.StatusBadRequest)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to enable two-factor authentication"), http.StatusInternalServerError)
+		}
+		return
+	}
+	if settings.IsEnabled() {
+		webutils.ErrorJSON(w, errors.New("two-factor authentication already enabled"), http.StatusConflict)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(settings.Secret, req.Code, time.Now())
+	if !ok {
+		webutils.ErrorJSON(w, errInvalidSecondFactor, http.StatusBadRequest)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to enable two-factor authentication"), http.StatusInternalServerError)
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+
+	if err := h.MFARepo.Enable(r.Context(), userID, step, hashes); err != nil {
+		switch {
+		case errors.Is(err, mfa.ErrMFAAlreadyEnabled):
+			webutils.ErrorJSON(w, errors.New("two-factor authentication already enabled"), http.StatusConflict)
+		case errors.Is(err, mfa.ErrMFANotFound):
+			webutils.ErrorJSON(w, errors.New("two-factor enrollment not started"), http.StatusBadRequest)
+		default:
+			webutils.ErrorJSON(w, errors.New("failed to enable two-factor authentication"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable handles POST /api/users/me/2fa/disable.
+// A current TOTP code or an unused recovery code is required, so a stolen access token alone cannot turn 2FA off.
+// Wrong codes count as failed logins, as in AuthHandler.LoginMFA, so they cannot be brute-forced here either.
+func (h *MFAHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		webutils.ErrorJSON(w, errors.New("code is required"), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.MFARepo.FindByUserID(r.Context(), userID)
+	if err != nil && !errors.Is(err, mfa.ErrMFANotFound) {
+		webutils.ErrorJSON(w, errors.New("failed to disable two-factor authentication"), http.StatusInternalServerError)
+		return
+	}
+	if !settings.IsEnabled() {
+		webutils.ErrorJSON(w, errors.New("two-factor authentication is not enabled"), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("user not found"), http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to disable two-factor authentication"), http.StatusInternalServerError)
+		}
+		return
+	}
+	ip := clientIP(r)
+	if err := h.LoginLimiter.Check(r.Context(), user.Email, ip); err != nil {
+		respondLoginBlocked(w, err)
+		return
+	}
+
+	if err := verifySecondFactor(r.Context(), h.MFARepo, settings, req.Code); err != nil {
+		if errors.Is(err, errInvalidSecondFactor) {
+			credentialFailed(w, r, h.LoginLimiter, user.Email, ip, err, http.StatusBadRequest)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to disable two-factor authentication"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.MFARepo.Disable(r.Context(), userID); err != nil && !errors.Is(err, mfa.ErrMFANotFound) {
+		webutils.ErrorJSON(w, errors.New("failed to disable two-factor authentication"), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code.
+// Accepted codes are burned, so the same code cannot be replayed. It returns
+// errInvalidSecondFactor for wrong or reused codes.
+func verifySecondFactor(ctx context.Context, repo mfa.MFARepository, settings *models.UserMFA, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := auth.ValidateTOTP(settings.Secret, code, time.Now()); ok {
+		err := repo.RecordStep(ctx, settings.UserID, step)
+		if errors.Is(err, mfa.ErrTOTPCodeReused) {
+			return errInvalidSecondFactor
+		}
+		return err
+	}
+
+	err := repo.UseRecoveryCode(ctx, settings.UserID, auth.HashRecoveryCode(code))
+	if errors.Is(err, mfa.ErrRecoveryCodeNotFound) {
+		return errInvalidSecondFactor
+	}
+	return err
+}
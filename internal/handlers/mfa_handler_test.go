@@ -0,0 +1,336 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/mfa"
+	"bullet-cloud-api/internal/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMFATest creates an MFAHandler behind the auth middleware for an authenticated user.
+func setupMFATest(t *testing.T, userID uuid.UUID) (*MockUserRepository, *mfa.MockMFARepository, *mux.Router, string) {
+	t.Helper()
+	mockUserRepo := new(MockUserRepository)
+	mockMFARepo := new(mfa.MockMFARepository)
+	h := handlers.NewMFAHandler(mockUserRepo, mockMFARepo, newTestLoginLimiter(), "Bullet Cloud API")
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	router.Handle("/api/users/me/2fa/enroll", authMiddleware.Authenticate(http.HandlerFunc(h.Enroll))).Methods("POST")
+	router.Handle("/api/users/me/2fa/confirm", authMiddleware.Authenticate(http.HandlerFunc(h.Confirm))).Methods("POST")
+	router.Handle("/api/users/me/2fa/disable", authMiddleware.Authenticate(http.HandlerFunc(h.Disable))).Methods("POST")
+
+	testToken, err := generateTestToken(userID)
+	require.NoError(t, err)
+	return mockUserRepo, mockMFARepo, router, testToken
+}
+
+func TestMFAHandler_Enroll(t *testing.T) {
+	userID := uuid.New()
+	user := &models.User{ID: userID, Email: "test@example.com"}
+
+	tests := []struct {
+		name           string
+		mockUpsert     func(*mfa.MockMFARepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			mockUpsert: func(repo *mfa.MockMFARepository) {
+				repo.On("UpsertPending", mock.Anything, userID, mock.AnythingOfType("string")).Return(&models.UserMFA{UserID: userID}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Already Enabled",
+			mockUpsert: func(repo *mfa.MockMFARepository) {
+				repo.On("UpsertPending", mock.Anything, userID, mock.Anything).Return(nil, mfa.ErrMFAAlreadyEnabled).Once()
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"two-factor authentication already enabled"}`,
+		},
+		{
+			name: "Repository Error",
+			mockUpsert: func(repo *mfa.MockMFARepository) {
+				repo.On("UpsertPending", mock.Anything, userID, mock.Anything).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to start two-factor enrollment"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo, mockMFARepo, router, testToken := setupMFATest(t, userID)
+			mockUserRepo.On("FindByID", mock.Anything, userID).Return(user, nil)
+			tc.mockUpsert(mockMFARepo)
+
+			req, _ := http.NewRequest("POST", "/api/users/me/2fa/enroll", nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			if tc.expectedStatus == http.StatusOK {
+				var resp handlers.MFAEnrollResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+				// The returned secret is the one stored as pending, and the URI carries it
+				stored := mockMFARepo.Calls[0].Arguments.String(2)
+				assert.Equal(t, stored, resp.Secret)
+				parsed, err := url.Parse(resp.OTPAuthURL)
+				require.NoError(t, err)
+				assert.Equal(t, "otpauth", parsed.Scheme)
+				assert.Equal(t, "totp", parsed.Host)
+				assert.Equal(t, resp.Secret, parsed.Query().Get("secret"))
+				assert.Equal(t, "Bullet Cloud API", parsed.Query().Get("issuer"))
+				assert.Contains(t, parsed.Path, "test@example.com")
+			}
+
+			mockMFARepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMFAHandler_Confirm(t *testing.T) {
+	userID := uuid.New()
+	secret, err := auth.GenerateTOTPSecret()
+	require.NoError(t, err)
+	validCode, err := auth.GenerateTOTPCode(secret, time.Now())
+	require.NoError(t, err)
+	enabledAt := time.Now().Add(-time.Hour)
+	pending := &models.UserMFA{UserID: userID, Secret: secret}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockMFA        func(*mfa.MockMFARepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(pending, nil).Once()
+				repo.On("Enable", mock.Anything, userID, mock.AnythingOfType("int64"), mock.MatchedBy(func(hashes []string) bool {
+					return len(hashes) == 10
+				})).Return(nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Wrong Code",
+			body: `{"code":"12345"}`,
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(pending, nil).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid two-factor code"}`,
+		},
+		{
+			name: "Enrollment Not Started",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"two-factor enrollment not started"}`,
+		},
+		{
+			name: "Already Enabled",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(&models.UserMFA{UserID: userID, Secret: secret, EnabledAt: &enabledAt}, nil).Once()
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"two-factor authentication already enabled"}`,
+		},
+		{
+			name: "Enable Error",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(pending, nil).Once()
+				repo.On("Enable", mock.Anything, userID, mock.Anything, mock.Anything).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to enable two-factor authentication"}`,
+		},
+		{
+			name:           "Missing Code",
+			body:           `{}`,
+			mockMFA:        func(repo *mfa.MockMFARepository) { /* Not called */ },
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"code is required"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo, mockMFARepo, router, testToken := setupMFATest(t, userID)
+			mockUserRepo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil)
+			tc.mockMFA(mockMFARepo)
+
+			req, _ := http.NewRequest("POST", "/api/users/me/2fa/confirm", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			if tc.expectedStatus == http.StatusOK {
+				var resp handlers.MFARecoveryCodesResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Len(t, resp.RecoveryCodes, 10)
+
+				// Only hashes of the returned codes are stored
+				stored := mockMFARepo.Calls[1].Arguments.Get(3).([]string)
+				for i, code := range resp.RecoveryCodes {
+					assert.Equal(t, auth.HashRecoveryCode(code), stored[i])
+					assert.NotEqual(t, code, stored[i])
+				}
+			}
+
+			mockMFARepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMFAHandler_Disable(t *testing.T) {
+	userID := uuid.New()
+	secret, err := auth.GenerateTOTPSecret()
+	require.NoError(t, err)
+	validCode, err := auth.GenerateTOTPCode(secret, time.Now())
+	require.NoError(t, err)
+	enabledAt := time.Now().Add(-time.Hour)
+	enabled := &models.UserMFA{UserID: userID, Secret: secret, EnabledAt: &enabledAt}
+	recoveryCode := "abcde-fghij"
+
+	tests := []struct {
+		name           string
+		body           string
+		mockMFA        func(*mfa.MockMFARepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success With TOTP Code",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("RecordStep", mock.Anything, userID, mock.AnythingOfType("int64")).Return(nil).Once()
+				repo.On("Disable", mock.Anything, userID).Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Success With Recovery Code",
+			body: `{"code":"ABCDEFGHIJ"}`, // Case and dash are ignored
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("UseRecoveryCode", mock.Anything, userID, auth.HashRecoveryCode(recoveryCode)).Return(nil).Once()
+				repo.On("Disable", mock.Anything, userID).Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Reused TOTP Code",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("RecordStep", mock.Anything, userID, mock.AnythingOfType("int64")).Return(mfa.ErrTOTPCodeReused).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid two-factor code"}`,
+		},
+		{
+			name: "Unknown Recovery Code",
+			body: `{"code":"zzzzz-zzzzz"}`,
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("UseRecoveryCode", mock.Anything, userID, mock.Anything).Return(mfa.ErrRecoveryCodeNotFound).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid two-factor code"}`,
+		},
+		{
+			name: "Not Enabled",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"two-factor authentication is not enabled"}`,
+		},
+		{
+			name: "Pending Enrollment Is Not Enabled",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(&models.UserMFA{UserID: userID, Secret: secret}, nil).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"two-factor authentication is not enabled"}`,
+		},
+		{
+			name: "Disable Error",
+			body: fmt.Sprintf(`{"code":"%s"}`, validCode),
+			mockMFA: func(repo *mfa.MockMFARepository) {
+				repo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil).Once()
+				repo.On("RecordStep", mock.Anything, userID, mock.AnythingOfType("int64")).Return(nil).Once()
+				repo.On("Disable", mock.Anything, userID).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to disable two-factor authentication"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo, mockMFARepo, router, testToken := setupMFATest(t, userID)
+			mockUserRepo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil)
+			tc.mockMFA(mockMFARepo)
+
+			req, _ := http.NewRequest("POST", "/api/users/me/2fa/disable", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockMFARepo.AssertExpectations(t)
+		})
+	}
+	t.Run("Locked After Max Wrong Codes", func(t *testing.T) {
+		mockUserRepo, mockMFARepo, router, testToken := setupMFATest(t, userID)
+		mockUserRepo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, Email: "test@example.com"}, nil)
+		mockMFARepo.On("FindByUserID", mock.Anything, userID).Return(enabled, nil)
+		mockMFARepo.On("UseRecoveryCode", mock.Anything, userID, mock.Anything).Return(mfa.ErrRecoveryCodeNotFound).Times(testMaxLoginAttempts)
+		disable := func(code string) *http.Request {
+			req, _ := http.NewRequest("POST", "/api/users/me/2fa/disable", bytes.NewBufferString(fmt.Sprintf(`{"code":"%s"}`, code)))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			return req
+		}
+
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, disable("zzzzz-zzzzz"), http.StatusBadRequest, `{"error":"invalid two-factor code"}`)
+		}
+		executeRequestAndAssert(t, router, disable("zzzzz-zzzzz"), http.StatusLocked, "too many failed login attempts")
+
+		// Even a valid code is refused while locked, without being checked
+		executeRequestAndAssert(t, router, disable(validCode), http.StatusLocked, "too many failed login attempts")
+		mockMFARepo.AssertExpectations(t)
+		mockMFARepo.AssertNotCalled(t, "Disable", mock.Anything, userID)
+	})
+}
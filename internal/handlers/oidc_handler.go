@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/identities"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/oidc"
+	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/webutils"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcFlowExpiry is how long a user has to complete the sign-in at the identity provider.
+const oidcFlowExpiry = 10 * time.Minute
+
+// oidcFlowCookie holds the signed state, nonce and PKCE verifier between the redirect and the callback.
+const (
+	oidcFlowCookie     = "oidc_flow"
+	oidcFlowCookiePath = "/api/auth/oidc"
+)
+
+// errUnverifiedExternalEmail is returned when a new external identity has no verified email to link by.
+var errUnverifiedExternalEmail = errors.New("identity provider did not return a verified email")
+
+// WithOIDC enables sign-in through the given OpenID Connect providers.
+func (h *AuthHandler) WithOIDC(identityRepo identities.IdentityRepository, providers ...*oidc.Provider) *AuthHandler {
+	h.IdentityRepo = identityRepo
+	h.OIDCProviders = make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		h.OIDCProviders[provider.Name()] = provider
+	}
+	return h
+}
+
+// --- Handlers ---
+
+// OIDCLogin handles GET /api/auth/oidc/{provider}/login.
+// It redirects the browser to the identity provider, using the authorization code flow with PKCE.
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.OIDCProviders[mux.Vars(r)["provider"]]
+	if !ok {
+		webutils.ErrorJSON(w, errors.New("unknown identity provider"), http.StatusNotFound)
+		return
+	}
+
+	flow, err := oidc.NewFlow(provider.Name())
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to start login"), http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), flow)
+	if err != nil {
+		log.Printf("Error contacting identity provider %s: %v", provider.Name(), err)
+		webutils.ErrorJSON(w, errors.New("identity provider unavailable"), http.StatusBadGateway)
+		return
+	}
+
+	flowToken, err := auth.GenerateOIDCFlowToken(flow, h.JwtSecret, oidcFlowExpiry)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to start login"), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    flowToken,
+		Path:     oidcFlowCookiePath,
+		MaxAge:   int(oidcFlowExpiry.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode, // Sent on the provider's top-level redirect back to the callback
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback handles GET /api/auth/oidc/{provider}/callback.
+// It redeems the authorization code, links the external identity to a user by verified
+// email (creating the user if needed) and then responds exactly like Login.
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.OIDCProviders[mux.Vars(r)["provider"]]
+	if !ok {
+		webutils.ErrorJSON(w, errors.New("unknown identity provider"), http.StatusNotFound)
+		return
+	}
+
+	// The flow cookie is single use
+	http.SetCookie(w, &http.Cookie{Name: oidcFlowCookie, Path: oidcFlowCookiePath, MaxAge: -1, HttpOnly: true, Secure: true})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		log.Printf("Identity provider %s refused the login: %s %s", provider.Name(), providerErr, query.Get("error_description"))
+		webutils.ErrorJSON(w, errors.New("login was cancelled or refused by the identity provider"), http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("login expired or was not started, please try again"), http.StatusBadRequest)
+		return
+	}
+	flow, err := auth.ValidateOIDCFlowToken(cookie.Value, h.JwtSecret)
+	if err != nil || flow.Provider != provider.Name() {
+		webutils.ErrorJSON(w, errors.New("login expired or was not started, please try again"), http.StatusBadRequest)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(flow.State)) != 1 {
+		webutils.ErrorJSON(w, errors.New("invalid login state"), http.StatusBadRequest)
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		webutils.ErrorJSON(w, errors.New("authorization code is required"), http.StatusBadRequest)
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), code, &flow.Flow)
+	if err != nil {
+		log.Printf("Error completing login with identity provider %s: %v", provider.Name(), err)
+		webutils.ErrorJSON(w, errors.New("identity provider login failed"), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.resolveExternalUser(r.Context(), provider.Name(), claims)
+	if err != nil {
+		if errors.Is(err, errUnverifiedExternalEmail) {
+			webutils.ErrorJSON(w, err, http.StatusForbidden)
+		} else {
+			log.Printf("Error linking identity from %s: %v", provider.Name(), err)
+			webutils.ErrorJSON(w, errors.New("login failed"), http.StatusInternalServerError)
+		}
+		return
+	}
+	if rejectDisabledAccount(w, user) {
+		return
+	}
+
+	h.completeLogin(w, r, user)
+}
+
+// resolveExternalUser returns the user linked to the external identity. An identity seen for the
+// first time is linked to the user with the same (provider-verified) email, or to a new user.
+func (h *AuthHandler) resolveExternalUser(ctx context.Context, providerName string, claims *oidc.Claims) (*models.User, error) {
+	identity, err := h.IdentityRepo.FindByProviderSubject(ctx, providerName, claims.Subject)
+	if err == nil {
+		return h.UserRepo.FindByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, identities.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return nil, errUnverifiedExternalEmail
+	}
+
+	user, err := h.UserRepo.FindByEmail(ctx, email)
+	if errors.Is(err, users.ErrUserNotFound) {
+		user, err = h.createExternalUser(ctx, email, claims.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := h.UserRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	_, err = h.IdentityRepo.Create(ctx, &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createExternalUser registers a user who signed in through an identity provider.
+// The account gets a random password; the user can set one through the password reset flow.
+func (h *AuthHandler) createExternalUser(ctx context.Context, email, name string) (*models.User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		// Fall back to the local part of the address
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	password, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := h.Hasher.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return h.UserRepo.Create(ctx, name, email, hashedPassword)
+}
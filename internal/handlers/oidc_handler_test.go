@@ -0,0 +1,411 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/identities"
+	"bullet-cloud-api/internal/mfa"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/oidc"
+	"bullet-cloud-api/internal/tokens"
+	"bullet-cloud-api/internal/users"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testOIDCProvider     = "acme"
+	testOIDCClientID     = "bullet-cloud"
+	testOIDCClientSecret = "acme-secret"
+	testOIDCRedirectURL  = "http://localhost:4445/api/auth/oidc/acme/callback"
+	testOIDCKeyID        = "acme-key-1"
+)
+
+// fakeOIDCProvider is an in-process OpenID Connect provider serving discovery, JWKS and
+// the token endpoint. Tests play the browser: they call authorize to obtain a code.
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthCode
+}
+
+// fakeAuthCode is what the provider remembers about an issued authorization code.
+type fakeAuthCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &fakeOIDCProvider{key: key, codes: map[string]fakeAuthCode{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testOIDCKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize simulates the user signing in at the provider: it checks the authorization
+// request and returns a code whose ID token carries the given claims (merged over defaults).
+func (p *fakeOIDCProvider) authorize(t *testing.T, authURL *url.URL, claims jwt.MapClaims) string {
+	t.Helper()
+	q := authURL.Query()
+	require.Equal(t, "code", q.Get("response_type"))
+	require.Equal(t, testOIDCClientID, q.Get("client_id"))
+	require.Equal(t, testOIDCRedirectURL, q.Get("redirect_uri"))
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+
+	idClaims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            testOIDCClientID,
+		"sub":            "acme-user-1",
+		"email":          "Ext.User@Example.com",
+		"email_verified": true,
+		"name":           "External User",
+		"nonce":          q.Get("nonce"),
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+
+	code := uuid.NewString()
+	p.mu.Lock()
+	p.codes[code] = fakeAuthCode{challenge: q.Get("code_challenge"), claims: idClaims}
+	p.mu.Unlock()
+	return code
+}
+
+// token implements the token endpoint, enforcing client authentication and PKCE.
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testOIDCClientID || secret != testOIDCClientSecret {
+		writeTestJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	code, found := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !found || r.PostFormValue("grant_type") != "authorization_code" ||
+		r.PostFormValue("redirect_uri") != testOIDCRedirectURL ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, code.claims)
+	token.Header["kid"] = testOIDCKeyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeTestJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeTestJSON(w, http.StatusOK, map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": idToken})
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// oidcTestMocks are the repositories touched by an external login.
+type oidcTestMocks struct {
+	users      *MockUserRepository
+	identities *identities.MockIdentityRepository
+	mfa        *mfa.MockMFARepository
+	refresh    *tokens.MockRefreshTokenRepository
+	hasher     *MockPasswordHasher
+}
+
+// setupOIDCTest wires an AuthHandler with OIDC enabled against a fake provider.
+func setupOIDCTest(t *testing.T) (*fakeOIDCProvider, *oidcTestMocks, *mux.Router) {
+	t.Helper()
+	provider := newFakeOIDCProvider(t)
+	m := &oidcTestMocks{
+		users:      new(MockUserRepository),
+		identities: new(identities.MockIdentityRepository),
+		mfa:        new(mfa.MockMFARepository),
+		refresh:    new(tokens.MockRefreshTokenRepository),
+		hasher:     new(MockPasswordHasher),
+	}
+	sessionRepo := newTestSessionRepo()
+	sessionRepo.On("Create", mock.Anything, mock.Anything).Return(createdSession, nil).Maybe()
+	m.refresh.On("Create", mock.Anything, mock.Anything).Return(&models.RefreshToken{}, nil).Maybe()
+
+	authHandler := handlers.NewAuthHandler(m.users, m.refresh, sessionRepo, m.mfa, m.hasher, testPasswordPolicy, newTestLoginLimiter(), new(MockVerificationSender), testKeys, testJwtSecret, time.Hour*1, time.Hour*24).
+		WithOIDC(m.identities, oidc.NewProvider(oidc.Config{
+			Name:         testOIDCProvider,
+			Issuer:       provider.server.URL,
+			ClientID:     testOIDCClientID,
+			ClientSecret: testOIDCClientSecret,
+			Scopes:       []string{"email", "profile"},
+			RedirectURL:  testOIDCRedirectURL,
+		}, provider.server.Client()))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/oidc/{provider}/login", authHandler.OIDCLogin).Methods("GET")
+	router.HandleFunc("/api/auth/oidc/{provider}/callback", authHandler.OIDCCallback).Methods("GET")
+	return provider, m, router
+}
+
+// startOIDCLogin calls the login endpoint and returns the provider URL and the flow cookie.
+func startOIDCLogin(t *testing.T, router *mux.Router) (*url.URL, *http.Cookie) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/api/auth/oidc/"+testOIDCProvider+"/login", nil)
+	rr := executeRequestAndAssert(t, router, req, http.StatusFound, "")
+
+	authURL, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	return authURL, cookies[0]
+}
+
+func TestAuthHandler_OIDCLogin(t *testing.T) {
+	provider, _, router := setupOIDCTest(t)
+
+	authURL, cookie := startOIDCLogin(t, router)
+
+	assert.Equal(t, provider.server.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	q := authURL.Query()
+	assert.Equal(t, "openid email profile", q.Get("scope"))
+	assert.NotEmpty(t, q.Get("state"))
+	assert.NotEmpty(t, q.Get("nonce"))
+	assert.NotEmpty(t, q.Get("code_challenge"))
+
+	assert.Equal(t, "oidc_flow", cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	assert.NotContains(t, authURL.String(), cookie.Value, "the flow token must not be sent to the provider")
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/auth/oidc/unknown/login", nil)
+		executeRequestAndAssert(t, router, req, http.StatusNotFound, `{"error":"unknown identity provider"}`)
+	})
+}
+
+func TestAuthHandler_OIDCCallback(t *testing.T) {
+	userID := uuid.New()
+	verifiedAt := time.Now()
+	existingUser := &models.User{ID: userID, Name: "Existing", Email: "ext.user@example.com", Role: models.RoleCustomer, EmailVerifiedAt: &verifiedAt}
+	linkedIdentity := &models.UserIdentity{ID: uuid.New(), UserID: userID, Provider: testOIDCProvider, Subject: "acme-user-1"}
+	enabledAt := time.Now()
+
+	tests := []struct {
+		name           string
+		claims         jwt.MapClaims                                                      // Overrides of the default ID token claims
+		tamper         func(q url.Values, cookie *http.Cookie) (url.Values, *http.Cookie) // Optional: alters the callback request
+		otherLogin     bool                                                               // Redeem the code with the state and cookie of a second login
+		setupMocks     func(m *oidcTestMocks)
+		expectedStatus int
+		expectedBody   string // Empty when tokens are returned
+		expectTokens   bool
+	}{
+		{
+			name: "Success - Identity already linked",
+			setupMocks: func(m *oidcTestMocks) {
+				m.identities.On("FindByProviderSubject", mock.Anything, testOIDCProvider, "acme-user-1").Return(linkedIdentity, nil).Once()
+				m.users.On("FindByID", mock.Anything, userID).Return(existingUser, nil).Once()
+				m.mfa.On("FindByUserID", mock.Anything, userID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectTokens:   true,
+		},
+		{
+			name: "Success - Links existing user by verified email",
+			setupMocks: func(m *oidcTestMocks) {
+				m.identities.On("FindByProviderSubject", mock.Anything, testOIDCProvider, "acme-user-1").Return(nil, identities.ErrIdentityNotFound).Once()
+				m.users.On("FindByEmail", mock.Anything, "ext.user@example.com").Return(existingUser, nil).Once()
+				m.identities.On("Create", mock.Anything, mock.MatchedBy(func(i *models.UserIdentity) bool {
+					return i.UserID == userID && i.Provider == testOIDCProvider && i.Subject == "acme-user-1" && i.Email == "ext.user@example.com"
+				})).Return(linkedIdentity, nil).Once()
+				m.mfa.On("FindByUserID", mock.Anything, userID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectTokens:   true,
+		},
+		{
+			name: "Success - Creates a new verified user",
+			setupMocks: func(m *oidcTestMocks) {
+				newUser := &models.User{ID: userID, Name: "External User", Email: "ext.user@example.com", Role: models.RoleCustomer}
+				m.identities.On("FindByProviderSubject", mock.Anything, testOIDCProvider, "acme-user-1").Return(nil, identities.ErrIdentityNotFound).Once()
+				m.users.On("FindByEmail", mock.Anything, "ext.user@example.com").Return(nil, users.ErrUserNotFound).Once()
+				m.hasher.On("HashPassword", mock.AnythingOfType("string")).Return("random_hash", nil).Once()
+				m.users.On("Create", mock.Anything, "External User", "ext.user@example.com", "random_hash").Return(newUser, nil).Once()
+				m.users.On("MarkEmailVerified", mock.Anything, userID).Return(nil).Once()
+				m.identities.On("Create", mock.Anything, mock.Anything).Return(linkedIdentity, nil).Once()
+				m.mfa.On("FindByUserID", mock.Anything, userID).Return(nil, mfa.ErrMFANotFound).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectTokens:   true,
+		},
+		{
+			name: "Two-factor challenge when 2FA is enabled",
+			setupMocks: func(m *oidcTestMocks) {
+				m.identities.On("FindByProviderSubject", mock.Anything, testOIDCProvider, "acme-user-1").Return(linkedIdentity, nil).Once()
+				m.users.On("FindByID", mock.Anything, userID).Return(existingUser, nil).Once()
+				m.mfa.On("FindByUserID", mock.Anything, userID).Return(&models.UserMFA{UserID: userID, EnabledAt: &enabledAt}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"mfa_required":true`,
+		},
+		{
+			name:   "Fail - Email not verified by provider",
+			claims: jwt.MapClaims{"email_verified": false},
+			setupMocks: func(m *oidcTestMocks) {
+				m.identities.On("FindByProviderSubject", mock.Anything, testOIDCProvider, "acme-user-1").Return(nil, identities.ErrIdentityNotFound).Once()
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"identity provider did not return a verified email"}`,
+		},
+		{
+			name:           "Fail - Nonce mismatch",
+			claims:         jwt.MapClaims{"nonce": "replayed"},
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"identity provider login failed"}`,
+		},
+		{
+			name:           "Fail - Wrong audience",
+			claims:         jwt.MapClaims{"aud": "another-client"},
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"identity provider login failed"}`,
+		},
+		{
+			name:           "Fail - Expired ID token",
+			claims:         jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()},
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"identity provider login failed"}`,
+		},
+		{
+			name: "Fail - State mismatch",
+			tamper: func(q url.Values, cookie *http.Cookie) (url.Values, *http.Cookie) {
+				q.Set("state", "forged")
+				return q, cookie
+			},
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid login state"}`,
+		},
+		{
+			name: "Fail - Missing flow cookie",
+			tamper: func(q url.Values, cookie *http.Cookie) (url.Values, *http.Cookie) {
+				return q, nil
+			},
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"login expired or was not started, please try again"}`,
+		},
+		{
+			name: "Fail - Tampered flow cookie",
+			tamper: func(q url.Values, cookie *http.Cookie) (url.Values, *http.Cookie) {
+				cookie.Value += "x"
+				return q, cookie
+			},
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"login expired or was not started, please try again"}`,
+		},
+		{
+			name:           "Fail - Code from another login (PKCE)",
+			otherLogin:     true, // The second login's verifier does not match the code's challenge
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"identity provider login failed"}`,
+		},
+		{
+			name: "Fail - Provider refused the login",
+			tamper: func(q url.Values, cookie *http.Cookie) (url.Values, *http.Cookie) {
+				return url.Values{"error": {"access_denied"}, "state": {q.Get("state")}}, cookie
+			},
+			setupMocks:     func(m *oidcTestMocks) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"login was cancelled or refused by the identity provider"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, m, router := setupOIDCTest(t)
+			tc.setupMocks(m)
+
+			authURL, cookie := startOIDCLogin(t, router)
+			code := provider.authorize(t, authURL, tc.claims)
+			q := url.Values{"code": {code}, "state": {authURL.Query().Get("state")}}
+
+			if tc.otherLogin {
+				otherURL, otherCookie := startOIDCLogin(t, router)
+				q.Set("state", otherURL.Query().Get("state"))
+				cookie = otherCookie
+			}
+			if tc.tamper != nil {
+				q, cookie = tc.tamper(q, cookie)
+			}
+
+			req, _ := http.NewRequest("GET", "/api/auth/oidc/"+testOIDCProvider+"/callback?"+q.Encode(), nil)
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			rr := executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			if tc.expectTokens {
+				var resp handlers.LoginResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				assert.NotEmpty(t, resp.Token)
+				assert.NotEmpty(t, resp.RefreshToken)
+				assert.Equal(t, "Bearer", resp.TokenType)
+			}
+
+			m.users.AssertExpectations(t)
+			m.identities.AssertExpectations(t)
+			m.mfa.AssertExpectations(t)
+			m.hasher.AssertExpectations(t)
+		})
+	}
+}
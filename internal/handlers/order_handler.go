@@ -5,6 +5,8 @@ import (
 	"bullet-cloud-api/internal/cart"
 	"bullet-cloud-api/internal/models"
 	"bullet-cloud-api/internal/orders"
+	"bullet-cloud-api/internal/pricing"
+	"bullet-cloud-api/internal/users"
 	"bullet-cloud-api/internal/webutils"
 	"errors"
 	"log"
@@ -16,17 +18,23 @@ import (
 
 // OrderHandler handles order-related requests.
 type OrderHandler struct {
-	OrderRepo   orders.OrderRepository
-	CartRepo    cart.CartRepository
-	AddressRepo addresses.AddressRepository // To validate shipping address
+	OrderRepo            orders.OrderRepository
+	CartRepo             cart.CartRepository
+	AddressRepo          addresses.AddressRepository // To validate shipping address
+	UserRepo             users.UserRepository        // To check email verification at checkout
+	PricingRepo          pricing.PricingRepository   // The currency and exchange rate of checkout
+	RequireVerifiedEmail bool                        // Block checkout for accounts with an unverified email
 }
 
 // NewOrderHandler creates a new OrderHandler.
-func NewOrderHandler(orderRepo orders.OrderRepository, cartRepo cart.CartRepository, addressRepo addresses.AddressRepository) *OrderHandler {
+func NewOrderHandler(orderRepo orders.OrderRepository, cartRepo cart.CartRepository, addressRepo addresses.AddressRepository, userRepo users.UserRepository, pricingRepo pricing.PricingRepository, requireVerifiedEmail bool) *OrderHandler {
 	return &OrderHandler{
-		OrderRepo:   orderRepo,
-		CartRepo:    cartRepo,
-		AddressRepo: addressRepo,
+		OrderRepo:            orderRepo,
+		CartRepo:             cartRepo,
+		AddressRepo:          addressRepo,
+		UserRepo:             userRepo,
+		PricingRepo:          pricingRepo,
+		RequireVerifiedEmail: requireVerifiedEmail,
 	}
 }
 
@@ -36,14 +44,26 @@ type CreateOrderRequest struct {
 	ShippingAddressID uuid.UUID `json:"shipping_address_id"`
 }
 
+type UpdateOrderStatusRequest struct {
+	Status models.OrderStatus `json:"status"`
+}
+
 type OrderResponse struct {
 	Order models.Order       `json:"order"`
 	Items []models.OrderItem `json:"items"`
 }
 
+// InsufficientStockResponse is the 409 body for an order whose items are short of stock.
+type InsufficientStockResponse struct {
+	Error string                 `json:"error"`
+	Items []orders.StockShortage `json:"items"`
+}
+
 // --- Handlers ---
 
 // CreateOrder handles POST /api/orders
+// The order is paid in the currency the request asks for (see requestedCurrency), the store
+// currency by default, and records the exchange rate of the moment.
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	authUserID, err := getAuthenticatedUserID(r) // Use helper from user_handler
 	if err != nil {
@@ -51,6 +71,18 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.RequireVerifiedEmail {
+		user, err := h.UserRepo.FindByID(r.Context(), authUserID)
+		if err != nil {
+			webutils.ErrorJSON(w, errors.New("failed to retrieve user"), http.StatusInternalServerError)
+			return
+		}
+		if !user.IsEmailVerified() {
+			webutils.ErrorJSON(w, errors.New("email address must be verified before checkout"), http.StatusForbidden)
+			return
+		}
+	}
+
 	var req CreateOrderRequest
 	if err := webutils.ReadJSON(r, &req); err != nil {
 		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
@@ -87,9 +119,21 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quote, ok := quoteRequest(w, r, h.PricingRepo, cartProductIDs(cartItems))
+	if !ok {
+		return
+	}
+	if err := quote.PriceCartItems(cartItems); err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to convert prices"), http.StatusInternalServerError)
+		return
+	}
+
 	// Create order using the repository (which handles transaction and cart clearing)
-	newOrder, err := h.OrderRepo.CreateOrderFromCart(r.Context(), authUserID, userCart.ID, req.ShippingAddressID, cartItems)
+	newOrder, err := h.OrderRepo.CreateOrderFromCart(r.Context(), authUserID, userCart.ID, req.ShippingAddressID, cartItems, quote.Rate)
 	if err != nil {
+		if respondInsufficientStock(w, err) {
+			return
+		}
 		log.Printf("ERROR creating order for user %s: %v", authUserID, err)
 		webutils.ErrorJSON(w, errors.New("failed to create order"), http.StatusInternalServerError)
 		return
@@ -112,6 +156,7 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 }
 
 // ListOrders handles GET /api/orders
+// Orders show the amounts they were paid, unless a currency is requested (see priceOrder).
 func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
 	authUserID, err := getAuthenticatedUserID(r)
 	if err != nil {
@@ -124,6 +169,18 @@ func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
 		webutils.ErrorJSON(w, errors.New("failed to retrieve orders"), http.StatusInternalServerError)
 		return
 	}
+	quote, ok := h.requestedQuote(w, r)
+	if !ok {
+		return
+	}
+	if quote != nil {
+		for i := range orderList {
+			if err := quote.PriceOrder(&orderList[i], nil); err != nil {
+				webutils.ErrorJSON(w, errors.New("failed to convert prices"), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
 
 	webutils.WriteJSON(w, http.StatusOK, orderList)
 }
@@ -154,11 +211,14 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Authorization check: Ensure the fetched order belongs to the authenticated user
-	if order.UserID != authUserID {
+	// Authorization check: Ensure the fetched order belongs to the authenticated user (or an admin)
+	if order.UserID != authUserID && !isAdmin(r) {
 		webutils.ErrorJSON(w, errors.New("forbidden"), http.StatusForbidden)
 		return
 	}
+	if !h.priceOrder(w, r, order, items) {
+		return
+	}
 
 	resp := OrderResponse{
 		Order: *order,
@@ -215,5 +275,98 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK) // Return 200 OK or 204 No Content
 }
 
+// UpdateOrderStatus handles PATCH /api/orders/{id}/status
+// Restricted to admins by the router.
+func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderIDStr := vars["id"]
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid order ID format"), http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateOrderStatusRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if !req.Status.IsValid() {
+		webutils.ErrorJSON(w, errors.New("invalid order status"), http.StatusBadRequest)
+		return
+	}
+
+	err = h.OrderRepo.UpdateOrderStatus(r.Context(), orderID, req.Status)
+	if err != nil {
+		if respondInsufficientStock(w, err) {
+			return
+		}
+		if errors.Is(err, orders.ErrOrderNotFound) {
+			webutils.ErrorJSON(w, err, http.StatusNotFound)
+		} else if errors.Is(err, orders.ErrOrderCannotBeCancelled) || errors.Is(err, orders.ErrInvalidStatusTransition) {
+			webutils.ErrorJSON(w, err, http.StatusConflict)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to update order status"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	order, items, err := h.OrderRepo.FindOrderByID(r.Context(), orderID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve order"), http.StatusInternalServerError)
+		return
+	}
+	if !h.priceOrder(w, r, order, items) {
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, OrderResponse{Order: *order, Items: items})
+}
+
+// priceOrder shows an order, and its items, in the currency the request asks for, if any.
+// The amounts are converted through the exchange rate recorded at checkout, so the currency
+// and exchange_rate of the order still tell what was paid.
+// On failure it writes the response and returns false.
+func (h *OrderHandler) priceOrder(w http.ResponseWriter, r *http.Request, order *models.Order, items []models.OrderItem) bool {
+	quote, ok := h.requestedQuote(w, r)
+	if !ok || quote == nil {
+		return ok
+	}
+	if err := quote.PriceOrder(order, items); err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to convert prices"), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// requestedQuote returns the quote of the currency the request asks for, or nil when it asks
+// for none. On failure it writes the response and returns ok == false.
+func (h *OrderHandler) requestedQuote(w http.ResponseWriter, r *http.Request) (*pricing.Quote, bool) {
+	currency, err := requestedCurrency(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusBadRequest)
+		return nil, false
+	}
+	if currency == "" {
+		return nil, true
+	}
+	return quoteRequest(w, r, h.PricingRepo, nil)
+}
+
+// respondInsufficientStock writes a 409 listing the short items when err is an
+// *orders.InsufficientStockError, and reports whether it did.
+func respondInsufficientStock(w http.ResponseWriter, err error) bool {
+	var stockErr *orders.InsufficientStockError
+	if !errors.As(err, &stockErr) {
+		return false
+	}
+	webutils.WriteJSON(w, http.StatusConflict, InsufficientStockResponse{
+		Error: "insufficient stock",
+		Items: stockErr.Items,
+	})
+	return true
+}
+
 // TODO: Implement public TrackOrder handler (GET /api/orders/tracking/{trackingNumber})
 // This would likely need a different repository method FindOrderByTrackingNumber
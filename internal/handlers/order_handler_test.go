@@ -0,0 +1,336 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/addresses"
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/orders"
+	"bullet-cloud-api/internal/pricing"
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderHandler_CreateOrder_EmailVerification(t *testing.T) {
+	testUserID := uuid.New()
+	addressID := uuid.New()
+	testToken, err := generateTestToken(testUserID)
+	require.NoError(t, err, "Failed to generate test token")
+
+	verifiedAt := time.Now()
+	unverifiedUser := &models.User{ID: testUserID}
+	verifiedUser := &models.User{ID: testUserID, EmailVerifiedAt: &verifiedAt}
+
+	tests := []struct {
+		name                 string
+		requireVerifiedEmail bool
+		mockUserRepo         func(*MockUserRepository)
+		expectAddressLookup  bool // Checkout continues past the verification gate
+		expectedStatus       int
+		expectedBody         string
+	}{
+		{
+			name:                 "Blocked - Unverified Email",
+			requireVerifiedEmail: true,
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, testUserID).Return(unverifiedUser, nil).Twice() // Middleware + handler
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"email address must be verified before checkout"}`,
+		},
+		{
+			name:                 "Allowed - Verified Email",
+			requireVerifiedEmail: true,
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, testUserID).Return(verifiedUser, nil).Twice()
+			},
+			expectAddressLookup: true,
+			expectedStatus:      http.StatusBadRequest,
+			expectedBody:        `{"error":"shipping address not found or does not belong to user"}`,
+		},
+		{
+			name:                 "Allowed - Verification Not Required",
+			requireVerifiedEmail: false,
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, testUserID).Return(unverifiedUser, nil).Once() // Middleware only
+			},
+			expectAddressLookup: true,
+			expectedStatus:      http.StatusBadRequest,
+			expectedBody:        `{"error":"shipping address not found or does not belong to user"}`,
+		},
+		{
+			name:                 "User Lookup Error",
+			requireVerifiedEmail: true,
+			mockUserRepo: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, testUserID).Return(unverifiedUser, nil).Once()
+				repo.On("FindByID", mock.Anything, testUserID).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to retrieve user"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockAddressRepo := new(MockAddressRepository)
+			orderHandler := handlers.NewOrderHandler(nil, nil, mockAddressRepo, mockUserRepo, new(pricing.MockPricingRepository), tc.requireVerifiedEmail)
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+			tc.mockUserRepo(mockUserRepo)
+			if tc.expectAddressLookup {
+				mockAddressRepo.On("FindByUserAndID", mock.Anything, testUserID, addressID).Return(nil, addresses.ErrAddressNotFound).Once()
+			}
+
+			router := mux.NewRouter()
+			router.Handle("/api/orders", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.CreateOrder))).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/api/orders", bytes.NewBufferString(fmt.Sprintf(`{"shipping_address_id":"%s"}`, addressID)))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockUserRepo.AssertExpectations(t)
+			mockAddressRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderHandler_CreateOrder_InsufficientStock(t *testing.T) {
+	testUserID := uuid.New()
+	addressID := uuid.New()
+	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
+	productID := uuid.New()
+	variantID := uuid.New()
+	cartItems := []models.CartItem{
+		{CartID: testCart.ID, ProductID: productID, Quantity: 1, Price: brl("10")},
+		{CartID: testCart.ID, ProductID: productID, VariantID: &variantID, Quantity: 3, Price: brl("12")},
+	}
+	token, err := generateTestToken(testUserID)
+	require.NoError(t, err)
+
+	userRepo := new(MockUserRepository)
+	addressRepo := new(MockAddressRepository)
+	cartRepo := new(MockCartRepository)
+	orderRepo := new(orders.MockOrderRepository)
+	userRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Once()
+	addressRepo.On("FindByUserAndID", mock.Anything, testUserID, addressID).Return(&models.Address{ID: addressID}, nil).Once()
+	cartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
+	cartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return(cartItems, nil).Once()
+	shortage := orders.StockShortage{ProductID: productID, VariantID: &variantID, Name: "Camiseta", Requested: 3, Available: 1}
+	orderRepo.On("CreateOrderFromCart", mock.Anything, testUserID, testCart.ID, addressID, cartItems, models.ExchangeRate{Currency: money.DefaultCurrency, Rate: money.OneRate}).
+		Return(nil, &orders.InsufficientStockError{Items: []orders.StockShortage{shortage}}).Once()
+
+	orderHandler := handlers.NewOrderHandler(orderRepo, cartRepo, addressRepo, userRepo, new(pricing.MockPricingRepository), false)
+	authMiddleware := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+	router := mux.NewRouter()
+	router.Handle("/api/orders", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.CreateOrder))).Methods("POST")
+
+	req := newAdminRequest("POST", "/api/orders", token, fmt.Sprintf(`{"shipping_address_id":"%s"}`, addressID))
+	expected := fmt.Sprintf(`{"error":"insufficient stock","items":[{"product_id":"%s","variant_id":"%s","name":"Camiseta","requested":3,"available":1}]}`, productID, variantID)
+	executeRequestAndAssert(t, router, req, http.StatusConflict, expected)
+
+	userRepo.AssertExpectations(t)
+	addressRepo.AssertExpectations(t)
+	cartRepo.AssertExpectations(t)
+	orderRepo.AssertExpectations(t)
+}
+
+func TestOrderHandler_CancelOrder(t *testing.T) {
+	testUserID := uuid.New()
+	orderID := uuid.New()
+	token, err := generateTestToken(testUserID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		cancelErr      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Success - Stock Restored By Repository", expectedStatus: http.StatusOK},
+		{name: "Already Shipped", cancelErr: orders.ErrOrderCannotBeCancelled, expectedStatus: http.StatusConflict, expectedBody: `{"error":"order cannot be cancelled in its current status"}`},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := new(MockUserRepository)
+			orderRepo := new(orders.MockOrderRepository)
+			userRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Once()
+			orderRepo.On("FindOrderByID", mock.Anything, orderID).Return(&models.Order{ID: orderID, UserID: testUserID}, []models.OrderItem{}, nil).Once()
+			orderRepo.On("UpdateOrderStatus", mock.Anything, orderID, models.StatusCancelled).Return(tc.cancelErr).Once()
+
+			orderHandler := handlers.NewOrderHandler(orderRepo, nil, nil, userRepo, new(pricing.MockPricingRepository), false)
+			authMiddleware := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+			router := mux.NewRouter()
+			router.Handle("/api/orders/{id}/cancel", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.CancelOrder))).Methods("PATCH")
+
+			req := newAdminRequest("PATCH", fmt.Sprintf("/api/orders/%s/cancel", orderID), token, "")
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			userRepo.AssertExpectations(t)
+			orderRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderHandler_UpdateOrderStatus(t *testing.T) {
+	adminID := uuid.New()
+	orderID := uuid.New()
+	token, err := generateTestToken(adminID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		status         models.OrderStatus
+		updateErr      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Success", status: models.StatusShipped, expectedStatus: http.StatusOK},
+		{name: "Transition Not Allowed", status: models.StatusPending, updateErr: orders.ErrInvalidStatusTransition, expectedStatus: http.StatusConflict, expectedBody: `{"error":"order cannot move to this status from its current status"}`},
+		{name: "Shipped Order Cancelled", status: models.StatusCancelled, updateErr: orders.ErrOrderCannotBeCancelled, expectedStatus: http.StatusConflict, expectedBody: `{"error":"order cannot be cancelled in its current status"}`},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := new(MockUserRepository)
+			orderRepo := new(orders.MockOrderRepository)
+			userRepo.On("FindByID", mock.Anything, adminID).Return(&models.User{ID: adminID, Role: models.RoleAdmin}, nil).Once()
+			orderRepo.On("UpdateOrderStatus", mock.Anything, orderID, tc.status).Return(tc.updateErr).Once()
+			if tc.updateErr == nil {
+				orderRepo.On("FindOrderByID", mock.Anything, orderID).Return(&models.Order{ID: orderID, Status: tc.status}, []models.OrderItem{}, nil).Once()
+			}
+
+			orderHandler := handlers.NewOrderHandler(orderRepo, nil, nil, userRepo, new(pricing.MockPricingRepository), false)
+			authMiddleware := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+			router := mux.NewRouter()
+			router.Handle("/api/orders/{id}/status", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.UpdateOrderStatus))).Methods("PATCH")
+
+			req := newAdminRequest("PATCH", fmt.Sprintf("/api/orders/%s/status", orderID), token, fmt.Sprintf(`{"status":"%s"}`, tc.status))
+			rr := executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+			if tc.updateErr == nil {
+				assert.Contains(t, rr.Body.String(), `"status":"shipped"`)
+			}
+
+			userRepo.AssertExpectations(t)
+			orderRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderHandler_CreateOrder_InCurrency(t *testing.T) {
+	testUserID := uuid.New()
+	addressID := uuid.New()
+	testCart := &models.Cart{ID: uuid.New(), UserID: testUserID}
+	listedID, convertedID := uuid.New(), uuid.New()
+	cartItems := []models.CartItem{
+		{CartID: testCart.ID, ProductID: listedID, Quantity: 2, Price: brl("10")},
+		{CartID: testCart.ID, ProductID: convertedID, Quantity: 1, Price: brl("12")},
+	}
+	usdRate := models.ExchangeRate{Currency: "USD", Rate: money.MustParseRate("0.2")}
+	token, err := generateTestToken(testUserID)
+	require.NoError(t, err)
+
+	addressRepo := new(MockAddressRepository)
+	cartRepo := new(MockCartRepository)
+	orderRepo := new(orders.MockOrderRepository)
+	pricingRepo := new(pricing.MockPricingRepository)
+	userRepo := new(MockUserRepository)
+	userRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Once()
+	addressRepo.On("FindByUserAndID", mock.Anything, testUserID, addressID).Return(&models.Address{ID: addressID}, nil).Once()
+	cartRepo.On("GetOrCreateCartByUserID", mock.Anything, testUserID).Return(testCart, nil).Once()
+	cartRepo.On("GetCartItems", mock.Anything, testCart.ID).Return(cartItems, nil).Once()
+	pricingRepo.On("FindRate", mock.Anything, "USD").Return(&usdRate, nil).Once()
+	pricingRepo.On("FindPrices", mock.Anything, "USD", []uuid.UUID{listedID, convertedID}).
+		Return([]models.ProductPrice{{ProductID: listedID, Price: money.MustParse("2.50", "USD")}}, nil).Once()
+
+	// The items reach the order priced in dollars: from the price list, or converted
+	pricedItems := []models.CartItem{
+		{CartID: testCart.ID, ProductID: listedID, Quantity: 2, Price: money.MustParse("2.50", "USD")},
+		{CartID: testCart.ID, ProductID: convertedID, Quantity: 1, Price: money.MustParse("2.40", "USD")},
+	}
+	created := &models.Order{ID: uuid.New(), UserID: testUserID, Status: models.StatusPending, Total: money.MustParse("7.40", "USD"), Currency: "USD", ExchangeRate: usdRate.Rate}
+	orderRepo.On("CreateOrderFromCart", mock.Anything, testUserID, testCart.ID, addressID, pricedItems, usdRate).Return(created, nil).Once()
+	orderRepo.On("FindOrderByID", mock.Anything, created.ID).Return(created, []models.OrderItem{}, nil).Once()
+
+	orderHandler := handlers.NewOrderHandler(orderRepo, cartRepo, addressRepo, userRepo, pricingRepo, false)
+	authMiddleware := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+	router := mux.NewRouter()
+	router.Handle("/api/orders", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.CreateOrder))).Methods("POST")
+
+	req := newAdminRequest("POST", "/api/orders?currency=usd", token, fmt.Sprintf(`{"shipping_address_id":"%s"}`, addressID))
+	rr := executeRequestAndAssert(t, router, req, http.StatusCreated, "")
+	assert.Contains(t, rr.Body.String(), `"total":{"amount":"7.40","currency":"USD"},"currency":"USD","exchange_rate":"0.2"`)
+
+	cartRepo.AssertExpectations(t)
+	orderRepo.AssertExpectations(t)
+	pricingRepo.AssertExpectations(t)
+}
+
+func TestOrderHandler_GetOrder_InCurrency(t *testing.T) {
+	testUserID := uuid.New()
+	token, err := generateTestToken(testUserID)
+	require.NoError(t, err)
+	// Paid in dollars at 0.2; the rate is 0.25 today
+	paid := func() (*models.Order, []models.OrderItem) {
+		order := &models.Order{ID: uuid.New(), UserID: testUserID, Total: money.MustParse("30.00", "USD"), Currency: "USD", ExchangeRate: money.MustParseRate("0.2")}
+		return order, []models.OrderItem{{OrderID: order.ID, Quantity: 2, Price: money.MustParse("15.00", "USD")}}
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockRate       *models.ExchangeRate
+		expectedStatus int
+		expectedTotal  string
+		expectedPrice  string
+	}{
+		{name: "As Paid", expectedStatus: http.StatusOK, expectedTotal: `"total":{"amount":"30.00","currency":"USD"}`, expectedPrice: `"price":{"amount":"15.00","currency":"USD"}`},
+		{name: "Store Currency At The Checkout Rate", query: "?currency=BRL", expectedStatus: http.StatusOK, expectedTotal: `"total":{"amount":"150.00","currency":"BRL"}`, expectedPrice: `"price":{"amount":"75.00","currency":"BRL"}`},
+		{name: "Another Currency Through The Store Currency", query: "?currency=EUR", mockRate: &models.ExchangeRate{Currency: "EUR", Rate: money.MustParseRate("0.18")}, expectedStatus: http.StatusOK, expectedTotal: `"total":{"amount":"27.00","currency":"EUR"}`, expectedPrice: `"price":{"amount":"13.50","currency":"EUR"}`},
+		{name: "Invalid Currency", query: "?currency=euro", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			order, items := paid()
+			orderRepo := new(orders.MockOrderRepository)
+			pricingRepo := new(pricing.MockPricingRepository)
+			userRepo := new(MockUserRepository)
+			userRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Maybe()
+			orderRepo.On("FindOrderByID", mock.Anything, order.ID).Return(order, items, nil).Once()
+			if tc.mockRate != nil {
+				pricingRepo.On("FindRate", mock.Anything, tc.mockRate.Currency).Return(tc.mockRate, nil).Once()
+			}
+
+			orderHandler := handlers.NewOrderHandler(orderRepo, nil, nil, userRepo, pricingRepo, false)
+			authMiddleware := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+			router := mux.NewRouter()
+			router.Handle("/api/orders/{id}", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.GetOrder))).Methods("GET")
+
+			rr := executeRequestAndAssert(t, router, newAdminRequest("GET", "/api/orders/"+order.ID.String()+tc.query, token, ""), tc.expectedStatus, "")
+			if tc.expectedStatus == http.StatusOK {
+				body := rr.Body.String()
+				assert.Contains(t, body, tc.expectedTotal)
+				assert.Contains(t, body, tc.expectedPrice)
+				assert.Contains(t, body, `"currency":"USD","exchange_rate":"0.2"`, "The order still records what was paid")
+			}
+			pricingRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/lockout"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/notify"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/tokens"
+	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/webutils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// forgotPasswordMessage is returned whether or not the email is registered,
+// so the endpoint cannot be used to discover accounts.
+const forgotPasswordMessage = "if the email is registered, a password reset link has been sent"
+
+// resetLinkTimeout bounds sending a reset link in the background, after the response.
+const resetLinkTimeout = time.Minute
+
+// PasswordResetSender sends password reset links to users.
+type PasswordResetSender interface {
+	SendPasswordResetLink(ctx context.Context, user *models.User) error
+}
+
+// PasswordResetHandler handles the forgot/reset password flow and implements PasswordResetSender.
+type PasswordResetHandler struct {
+	UserRepo       users.UserRepository
+	ResetTokenRepo tokens.PasswordResetRepository
+	SessionRepo    sessions.SessionRepository
+	Hasher         auth.PasswordHasher
+	PasswordPolicy *auth.PasswordPolicy // Rules the new password must satisfy
+	Notifier       notify.Notifier
+	RequestLimiter *lockout.Limiter // Limits forgot password requests per email and client IP
+	TokenExpiry    time.Duration    // Lifetime of reset tokens
+	AppBaseURL     string           // Frontend base URL for the reset link
+
+	sending sync.WaitGroup // Reset links still being sent in the background
+}
+
+// NewPasswordResetHandler creates a new PasswordResetHandler.
+func NewPasswordResetHandler(userRepo users.UserRepository, resetTokenRepo tokens.PasswordResetRepository, sessionRepo sessions.SessionRepository, hasher auth.PasswordHasher, passwordPolicy *auth.PasswordPolicy, notifier notify.Notifier, requestLimiter *lockout.Limiter, tokenExpiry time.Duration, appBaseURL string) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		UserRepo:       userRepo,
+		ResetTokenRepo: resetTokenRepo,
+		SessionRepo:    sessionRepo,
+		Hasher:         hasher,
+		PasswordPolicy: passwordPolicy,
+		Notifier:       notifier,
+		RequestLimiter: requestLimiter,
+		TokenExpiry:    tokenExpiry,
+		AppBaseURL:     appBaseURL,
+	}
+}
+
+// --- Request/Response Structs ---
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// --- Handlers ---
+
+// ForgotPassword handles POST /api/auth/forgot-password.
+// It always answers 202 with the same body, right away; a reset link is then sent in the
+// background if the account exists. Requests are limited per email and per client IP.
+func (h *PasswordResetHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		webutils.ErrorJSON(w, errors.New("email is required"), http.StatusBadRequest)
+		return
+	}
+
+	// Unknown emails count too, so the limit doesn't reveal which accounts exist
+	ip := clientIP(r)
+	if err := h.RequestLimiter.Check(r.Context(), req.Email, ip); err != nil {
+		respondRequestsLimited(w, err, "too many password reset requests")
+		return
+	}
+	var locked *lockout.LockedError
+	if err := h.RequestLimiter.RecordFailure(r.Context(), req.Email, ip); err != nil && !errors.As(err, &locked) {
+		log.Printf("Error counting password reset requests: %v", err)
+	}
+
+	// Answer before looking the account up: how long that and the delivery take must not tell
+	// registered emails apart
+	h.sending.Add(1)
+	go func() {
+		defer h.sending.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), resetLinkTimeout)
+		defer cancel()
+		if err := h.sendResetLink(ctx, req.Email); err != nil {
+			log.Printf("Error sending password reset link: %v", err)
+		}
+	}()
+
+	webutils.WriteJSON(w, http.StatusAccepted, MessageResponse{Message: forgotPasswordMessage})
+}
+
+// Wait blocks until the reset links being sent in the background have been sent.
+func (h *PasswordResetHandler) Wait() {
+	h.sending.Wait()
+}
+
+// sendResetLink sends a reset link to the account with the given email.
+// Unknown emails are silently ignored.
+func (h *PasswordResetHandler) sendResetLink(ctx context.Context, email string) error {
+	user, err := h.UserRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	return h.SendPasswordResetLink(ctx, user)
+}
+
+// SendPasswordResetLink creates a reset token for the user and notifies them.
+func (h *PasswordResetHandler) SendPasswordResetLink(ctx context.Context, user *models.User) error {
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = h.ResetTokenRepo.Create(ctx, &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(h.TokenExpiry),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", strings.TrimRight(h.AppBaseURL, "/"), url.QueryEscape(token))
+	return h.Notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not request this, you can ignore this message.",
+			user.Name, h.TokenExpiry, link),
+	})
+}
+
+// ResetPassword handles POST /api/auth/reset-password.
+// A valid token sets the new password and logs the user out of every session.
+func (h *PasswordResetHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		webutils.ErrorJSON(w, errors.New("token and password are required"), http.StatusBadRequest)
+		return
+	}
+
+	tokenHash := auth.HashOpaqueToken(req.Token)
+
+	// Check the new password before burning the token, so a rejected password can be corrected
+	resetToken, err := h.ResetTokenRepo.FindValid(r.Context(), tokenHash)
+	if err != nil {
+		respondResetTokenError(w, err)
+		return
+	}
+	user, err := h.UserRepo.FindByID(r.Context(), resetToken.UserID)
+	if err != nil {
+		respondResetTokenError(w, err)
+		return
+	}
+	if violations := h.PasswordPolicy.Validate(req.Password, user.Name, user.Email); len(violations) > 0 {
+		webutils.ValidationErrorJSON(w, passwordFieldErrors("password", violations))
+		return
+	}
+
+	resetToken, err = h.ResetTokenRepo.Consume(r.Context(), tokenHash)
+	if err != nil {
+		respondResetTokenError(w, err)
+		return
+	}
+
+	hashedPassword, err := h.Hasher.HashPassword(req.Password)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to reset password"), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.UserRepo.UpdatePassword(r.Context(), resetToken.UserID, hashedPassword); err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to reset password"), http.StatusInternalServerError)
+		return
+	}
+
+	// Whoever knew the old password must not keep a working session
+	if _, err := h.SessionRepo.RevokeAllExcept(r.Context(), resetToken.UserID, uuid.Nil); err != nil {
+		log.Printf("Error revoking sessions after password reset: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondResetTokenError writes the response for a reset token that could not be used.
+func respondResetTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tokens.ErrResetTokenNotFound), errors.Is(err, tokens.ErrResetTokenExpired), errors.Is(err, tokens.ErrResetTokenUsed),
+		errors.Is(err, users.ErrUserNotFound):
+		webutils.ErrorJSON(w, errors.New("invalid or expired reset token"), http.StatusBadRequest)
+	default:
+		webutils.ErrorJSON(w, errors.New("failed to reset password"), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,401 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/notify"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/tokens"
+	"bullet-cloud-api/internal/users"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// readOutbox returns every message written to an outbox directory.
+func readOutbox(t *testing.T, dir string) []notify.Message {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+
+	var msgs []notify.Message
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		var msg notify.Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestPasswordResetHandler_ForgotPassword(t *testing.T) {
+	userEmail := "test@example.com"
+	foundUser := &models.User{ID: uuid.New(), Name: "Test User", Email: userEmail}
+	genericBody := `{"message":"if the email is registered, a password reset link has been sent"}`
+
+	tests := []struct {
+		name             string
+		body             string
+		mockFindByEmail  func(*MockUserRepository)
+		mockCreate       func(*tokens.MockPasswordResetRepository)
+		expectedStatus   int
+		expectedBody     string
+		expectedMessages int
+	}{
+		{
+			name: "Success - Registered Email",
+			body: fmt.Sprintf(`{"email":"%s"}`, userEmail),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Once()
+			},
+			mockCreate: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(rt *models.PasswordResetToken) bool {
+					return rt.UserID == foundUser.ID && rt.TokenHash != "" && rt.ExpiresAt.After(time.Now())
+				})).Return(&models.PasswordResetToken{ID: uuid.New(), UserID: foundUser.ID}, nil).Once()
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedBody:     genericBody,
+			expectedMessages: 1,
+		},
+		{
+			name: "Success - Unknown Email Looks The Same",
+			body: `{"email":"nobody@example.com"}`,
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, users.ErrUserNotFound).Once()
+			},
+			mockCreate:       func(repo *tokens.MockPasswordResetRepository) { /* Not called */ },
+			expectedStatus:   http.StatusAccepted,
+			expectedBody:     genericBody,
+			expectedMessages: 0,
+		},
+		{
+			name: "Token Store Error Is Not Revealed",
+			body: fmt.Sprintf(`{"email":"%s"}`, userEmail),
+			mockFindByEmail: func(repo *MockUserRepository) {
+				repo.On("FindByEmail", mock.Anything, userEmail).Return(foundUser, nil).Once()
+			},
+			mockCreate: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedBody:     genericBody,
+			expectedMessages: 0,
+		},
+		{
+			name:             "Missing Email",
+			body:             `{"email":"  "}`,
+			mockFindByEmail:  func(repo *MockUserRepository) { /* Not called */ },
+			mockCreate:       func(repo *tokens.MockPasswordResetRepository) { /* Not called */ },
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"error":"email is required"}`,
+			expectedMessages: 0,
+		},
+		{
+			name:             "Invalid JSON",
+			body:             `{"email":`,
+			mockFindByEmail:  func(repo *MockUserRepository) { /* Not called */ },
+			mockCreate:       func(repo *tokens.MockPasswordResetRepository) { /* Not called */ },
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"error":"invalid request body"}`,
+			expectedMessages: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockResetRepo := new(tokens.MockPasswordResetRepository)
+			outboxDir := t.TempDir()
+			notifier, err := notify.NewOutboxNotifier(outboxDir)
+			require.NoError(t, err)
+
+			h := handlers.NewPasswordResetHandler(mockUserRepo, mockResetRepo, new(sessions.MockSessionRepository), new(MockPasswordHasher), testPasswordPolicy, notifier, newTestLoginLimiter(), time.Hour, "https://shop.example.com/")
+			tc.mockFindByEmail(mockUserRepo)
+			tc.mockCreate(mockResetRepo)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/auth/forgot-password", h.ForgotPassword).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/api/auth/forgot-password", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+			h.Wait() // The link is sent after the response
+
+			msgs := readOutbox(t, outboxDir)
+			require.Len(t, msgs, tc.expectedMessages)
+			if tc.expectedMessages > 0 {
+				assert.Equal(t, userEmail, msgs[0].To)
+				assert.Contains(t, msgs[0].Body, "https://shop.example.com/reset-password?token=")
+
+				// The hash stored by the repository must match the token that was sent
+				createdToken := mockResetRepo.Calls[0].Arguments.Get(1).(*models.PasswordResetToken)
+				sent := msgs[0].Body[strings.Index(msgs[0].Body, "token=")+len("token="):]
+				sent = sent[:strings.IndexByte(sent, '\n')]
+				assert.Equal(t, createdToken.TokenHash, auth.HashOpaqueToken(sent))
+			}
+
+			mockUserRepo.AssertExpectations(t)
+			mockResetRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Answered Before The Account Is Looked Up", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockResetRepo := new(tokens.MockPasswordResetRepository)
+		release := make(chan time.Time)
+		mockUserRepo.On("FindByEmail", mock.Anything, userEmail).WaitUntil(release).Return(foundUser, nil).Once()
+		mockResetRepo.On("Create", mock.Anything, mock.Anything).Return(&models.PasswordResetToken{ID: uuid.New(), UserID: foundUser.ID}, nil).Once()
+		outboxDir := t.TempDir()
+		notifier, err := notify.NewOutboxNotifier(outboxDir)
+		require.NoError(t, err)
+		h := handlers.NewPasswordResetHandler(mockUserRepo, mockResetRepo, new(sessions.MockSessionRepository), new(MockPasswordHasher), testPasswordPolicy, notifier, newTestLoginLimiter(), time.Hour, "https://shop.example.com/")
+
+		req, _ := http.NewRequest("POST", "/api/auth/forgot-password", bytes.NewBufferString(fmt.Sprintf(`{"email":"%s"}`, userEmail)))
+		rr := httptest.NewRecorder()
+		h.ForgotPassword(rr, req) // Returns while the lookup is still blocked
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.JSONEq(t, genericBody, rr.Body.String())
+
+		close(release)
+		h.Wait()
+		assert.Len(t, readOutbox(t, outboxDir), 1)
+		mockUserRepo.AssertExpectations(t)
+		mockResetRepo.AssertExpectations(t)
+	})
+
+	t.Run("Requests Are Limited Without Locking Logins", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockUserRepo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, users.ErrUserNotFound)
+		loginLimiter := newTestLoginLimiter()
+		h := handlers.NewPasswordResetHandler(mockUserRepo, new(tokens.MockPasswordResetRepository), new(sessions.MockSessionRepository), new(MockPasswordHasher), testPasswordPolicy, notify.NewLogNotifier(), loginLimiter.Named("password-reset"), time.Hour, "https://shop.example.com/")
+		router := mux.NewRouter()
+		router.HandleFunc("/api/auth/forgot-password", h.ForgotPassword).Methods("POST")
+		forgot := func() *http.Request {
+			req, _ := http.NewRequest("POST", "/api/auth/forgot-password", bytes.NewBufferString(`{"email":"nobody@example.com"}`))
+			req.RemoteAddr = "198.51.100.1:1234"
+			return req
+		}
+
+		for i := 0; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, forgot(), http.StatusAccepted, genericBody)
+		}
+		rr := executeRequestAndAssert(t, router, forgot(), http.StatusTooManyRequests, "too many password reset requests")
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+		h.Wait()
+
+		// Counted apart from failed logins
+		assert.NoError(t, loginLimiter.Check(context.Background(), "nobody@example.com", "198.51.100.1"))
+	})
+}
+
+func TestPasswordResetHandler_ResetPassword(t *testing.T) {
+	presentedToken := "presented-reset-token"
+	presentedHash := auth.HashOpaqueToken(presentedToken)
+	userID := uuid.New()
+	newPassword := "new-password-123"
+	newHash := "new_hashed_password"
+	validBody := fmt.Sprintf(`{"token":"%s","password":"%s"}`, presentedToken, newPassword)
+	validToken := &models.PasswordResetToken{ID: uuid.New(), UserID: userID}
+	tokenUser := &models.User{ID: userID, Name: "Maria Silva", Email: "maria@example.com"}
+	findValid := func(repo *tokens.MockPasswordResetRepository) {
+		repo.On("FindValid", mock.Anything, presentedHash).Return(validToken, nil).Once()
+	}
+	findUser := func(repo *MockUserRepository) {
+		repo.On("FindByID", mock.Anything, userID).Return(tokenUser, nil).Once()
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockFindValid  func(*tokens.MockPasswordResetRepository)
+		mockFindUser   func(*MockUserRepository)
+		mockConsume    func(*tokens.MockPasswordResetRepository)
+		mockHash       func(*MockPasswordHasher)
+		mockUpdate     func(*MockUserRepository)
+		mockRevoke     func(*sessions.MockSessionRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:          "Success",
+			body:          validBody,
+			mockFindValid: findValid,
+			mockFindUser:  findUser,
+			mockConsume: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("Consume", mock.Anything, presentedHash).Return(validToken, nil).Once()
+			},
+			mockHash: func(hasher *MockPasswordHasher) {
+				hasher.On("HashPassword", newPassword).Return(newHash, nil).Once()
+			},
+			mockUpdate: func(repo *MockUserRepository) {
+				repo.On("UpdatePassword", mock.Anything, userID, newHash).Return(nil).Once()
+			},
+			mockRevoke: func(repo *sessions.MockSessionRepository) {
+				repo.On("RevokeAllExcept", mock.Anything, userID, uuid.Nil).Return(int64(2), nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedBody:   "",
+		},
+		{
+			name: "Unknown Token",
+			body: validBody,
+			mockFindValid: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("FindValid", mock.Anything, presentedHash).Return(nil, tokens.ErrResetTokenNotFound).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired reset token"}`,
+		},
+		{
+			name: "Expired Token",
+			body: validBody,
+			mockFindValid: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("FindValid", mock.Anything, presentedHash).Return(nil, tokens.ErrResetTokenExpired).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired reset token"}`,
+		},
+		{
+			name: "Already Used Token",
+			body: validBody,
+			mockFindValid: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("FindValid", mock.Anything, presentedHash).Return(nil, tokens.ErrResetTokenUsed).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired reset token"}`,
+		},
+		{
+			name: "Token Store Error",
+			body: validBody,
+			mockFindValid: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("FindValid", mock.Anything, presentedHash).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to reset password"}`,
+		},
+		{
+			name:          "Token Used Concurrently",
+			body:          validBody,
+			mockFindValid: findValid,
+			mockFindUser:  findUser,
+			mockConsume: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("Consume", mock.Anything, presentedHash).Return(nil, tokens.ErrResetTokenUsed).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired reset token"}`,
+		},
+		{
+			name:          "User Deleted",
+			body:          validBody,
+			mockFindValid: findValid,
+			mockFindUser: func(repo *MockUserRepository) {
+				repo.On("FindByID", mock.Anything, userID).Return(nil, users.ErrUserNotFound).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid or expired reset token"}`,
+		},
+		{
+			name:           "Password Violates Policy (token not consumed)",
+			body:           fmt.Sprintf(`{"token":"%s","password":"maria-2024"}`, presentedToken),
+			mockFindValid:  findValid,
+			mockFindUser:   findUser,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"validation failed","fields":[{"field":"password","code":"contains_personal_info","message":"password must not contain your name or email"}]}`,
+		},
+		{
+			name:           "Breached Password",
+			body:           fmt.Sprintf(`{"token":"%s","password":"breached-password-1"}`, presentedToken),
+			mockFindValid:  findValid,
+			mockFindUser:   findUser,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"validation failed","fields":[{"field":"password","code":"breached","message":"password is too common or has appeared in a data breach"}]}`,
+		},
+		{
+			name:          "Update Password Error",
+			body:          validBody,
+			mockFindValid: findValid,
+			mockFindUser:  findUser,
+			mockConsume: func(repo *tokens.MockPasswordResetRepository) {
+				repo.On("Consume", mock.Anything, presentedHash).Return(validToken, nil).Once()
+			},
+			mockHash: func(hasher *MockPasswordHasher) {
+				hasher.On("HashPassword", newPassword).Return(newHash, nil).Once()
+			},
+			mockUpdate: func(repo *MockUserRepository) {
+				repo.On("UpdatePassword", mock.Anything, userID, newHash).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to reset password"}`,
+		},
+		{
+			name:           "Missing Password",
+			body:           fmt.Sprintf(`{"token":"%s"}`, presentedToken),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"token and password are required"}`,
+		},
+		{
+			name:           "Invalid JSON",
+			body:           `{"token":}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid request body"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockResetRepo := new(tokens.MockPasswordResetRepository)
+			mockSessionRepo := new(sessions.MockSessionRepository)
+			mockHasher := new(MockPasswordHasher)
+
+			h := handlers.NewPasswordResetHandler(mockUserRepo, mockResetRepo, mockSessionRepo, mockHasher, testPasswordPolicy, notify.NewLogNotifier(), newTestLoginLimiter(), time.Hour, "http://localhost:3000")
+			if tc.mockFindValid != nil {
+				tc.mockFindValid(mockResetRepo)
+			}
+			if tc.mockFindUser != nil {
+				tc.mockFindUser(mockUserRepo)
+			}
+			if tc.mockConsume != nil {
+				tc.mockConsume(mockResetRepo)
+			}
+			if tc.mockHash != nil {
+				tc.mockHash(mockHasher)
+			}
+			if tc.mockUpdate != nil {
+				tc.mockUpdate(mockUserRepo)
+			}
+			if tc.mockRevoke != nil {
+				tc.mockRevoke(mockSessionRepo)
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/auth/reset-password", h.ResetPassword).Methods("POST")
+
+			req, _ := http.NewRequest("POST", "/api/auth/reset-password", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockUserRepo.AssertExpectations(t)
+			mockResetRepo.AssertExpectations(t)
+			mockSessionRepo.AssertExpectations(t)
+			mockHasher.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/pricing"
+	"bullet-cloud-api/internal/products"
+	"bullet-cloud-api/internal/webutils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// acceptCurrencyHeader asks for the amounts of a response in a currency, like the currency
+// query parameter.
+const acceptCurrencyHeader = "Accept-Currency"
+
+// PricingHandler manages the exchange rates of the currencies the store prices in, and the
+// prices of products and variants set in those currencies (price lists). Its price routes
+// serve both /api/products/{id}/prices/{currency} and
+// /api/products/{id}/variants/{variantId}/prices/{currency}.
+type PricingHandler struct {
+	PricingRepo pricing.PricingRepository
+	ProductRepo products.ProductRepository // To tell missing products from ones without prices
+}
+
+// NewPricingHandler creates a new PricingHandler.
+func NewPricingHandler(pricingRepo pricing.PricingRepository, productRepo products.ProductRepository) *PricingHandler {
+	return &PricingHandler{PricingRepo: pricingRepo, ProductRepo: productRepo}
+}
+
+// --- Request Structs ---
+
+// SetExchangeRateRequest is the body of SetRate.
+type SetExchangeRateRequest struct {
+	Rate money.Rate `json:"rate"` // Units of the currency worth one unit of the store currency
+}
+
+// SetProductPriceRequest is the body of SetPrice. The price is in the currency of the URL.
+type SetProductPriceRequest struct {
+	Price money.Money `json:"price"`
+}
+
+// --- Exchange Rate Handlers ---
+
+// ListRates handles GET /api/exchange-rates: the currencies prices can be shown in besides
+// the store currency.
+func (h *PricingHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.PricingRepo.ListRates(r.Context())
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve exchange rates"), http.StatusInternalServerError)
+		return
+	}
+	webutils.WriteJSON(w, http.StatusOK, rates)
+}
+
+// SetRate handles PUT /api/exchange-rates/{currency}, creating or replacing its rate.
+// Orders keep the rate they were placed at.
+func (h *PricingHandler) SetRate(w http.ResponseWriter, r *http.Request) {
+	currency, ok := parseCurrencyURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetExchangeRateRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "rate", Code: "invalid", Message: "must be a positive decimal, such as \"0.19\""}})
+		return
+	}
+	if req.Rate <= 0 {
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "rate", Code: "required", Message: "is required"}})
+		return
+	}
+
+	rate, err := h.PricingRepo.SetRate(r.Context(), currency, req.Rate)
+	if err != nil {
+		respondPricingError(w, err, "failed to set exchange rate")
+		return
+	}
+	webutils.WriteJSON(w, http.StatusOK, rate)
+}
+
+// DeleteRate handles DELETE /api/exchange-rates/{currency}. Prices are no longer shown in
+// the currency; its price lists are kept for when it comes back.
+func (h *PricingHandler) DeleteRate(w http.ResponseWriter, r *http.Request) {
+	currency, ok := parseCurrencyURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.PricingRepo.DeleteRate(r.Context(), currency); err != nil {
+		respondPricingError(w, err, "failed to delete exchange rate")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Price List Handlers ---
+
+// ListPrices handles GET /api/products/{id}/prices: the prices of the product, and of its
+// variants, set in other currencies.
+func (h *PricingHandler) ListPrices(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid product ID format"), http.StatusBadRequest)
+		return
+	}
+	if _, err := h.ProductRepo.FindByID(r.Context(), productID); err != nil {
+		if errors.Is(err, products.ErrProductNotFound) {
+			webutils.ErrorJSON(w, err, http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to retrieve product"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	prices, err := h.PricingRepo.ListPrices(r.Context(), productID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve prices"), http.StatusInternalServerError)
+		return
+	}
+	webutils.WriteJSON(w, http.StatusOK, prices)
+}
+
+// SetPrice handles PUT /api/products/{id}/prices/{currency} and
+// /api/products/{id}/variants/{variantId}/prices/{currency}, replacing the conversion of the
+// price in the store currency. A product's price also applies to its variants without one.
+func (h *PricingHandler) SetPrice(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := parseStockURL(w, r)
+	if !ok {
+		return
+	}
+	currency, ok := parseCurrencyURL(w, r)
+	if !ok {
+		return
+	}
+
+	req := SetProductPriceRequest{Price: money.Money{Currency: currency}} // Bare amounts are in the URL's currency
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "price", Code: "invalid", Message: "must be a decimal amount, such as \"19.90\""}})
+		return
+	}
+	switch {
+	case !req.Price.IsPositive():
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "price", Code: "invalid", Message: "must be positive"}})
+		return
+	case req.Price.Currency != currency:
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "price", Code: "invalid", Message: "must be in " + currency}})
+		return
+	}
+
+	price, err := h.PricingRepo.SetPrice(r.Context(), productID, variantID, req.Price)
+	if err != nil {
+		respondPricingError(w, err, "failed to set price")
+		return
+	}
+	webutils.WriteJSON(w, http.StatusOK, price)
+}
+
+// DeletePrice handles DELETE /api/products/{id}/prices/{currency} and
+// /api/products/{id}/variants/{variantId}/prices/{currency}; the price is then converted again.
+func (h *PricingHandler) DeletePrice(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := parseStockURL(w, r)
+	if !ok {
+		return
+	}
+	currency, ok := parseCurrencyURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.PricingRepo.DeletePrice(r.Context(), productID, variantID, currency); err != nil {
+		respondPricingError(w, err, "failed to delete price")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Helpers ---
+
+// parseCurrencyURL reads the currency code of the URL.
+// On failure it writes the response and returns ok == false.
+func parseCurrencyURL(w http.ResponseWriter, r *http.Request) (string, bool) {
+	currency, err := money.ParseCurrency(mux.Vars(r)["currency"])
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusBadRequest)
+		return "", false
+	}
+	return currency, true
+}
+
+// requestedCurrency returns the currency a response is asked in, from the currency query
+// parameter or else the Accept-Currency header; "" when neither is given.
+func requestedCurrency(r *http.Request) (string, error) {
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		currency = r.Header.Get(acceptCurrencyHeader)
+	}
+	if currency == "" {
+		return "", nil
+	}
+	return money.ParseCurrency(currency)
+}
+
+// quoteRequest prices a response in the requested currency, or in the store currency when
+// none is requested. productIDs are the products priced in the response.
+// On failure it writes the response and returns ok == false.
+func quoteRequest(w http.ResponseWriter, r *http.Request, repo pricing.PricingRepository, productIDs []uuid.UUID) (*pricing.Quote, bool) {
+	currency, err := requestedCurrency(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusBadRequest)
+		return nil, false
+	}
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	quote, err := pricing.NewQuote(r.Context(), repo, currency, productIDs)
+	if err != nil {
+		if errors.Is(err, pricing.ErrRateNotFound) {
+			webutils.ErrorJSON(w, fmt.Errorf("prices are not available in %s", currency), http.StatusBadRequest)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to retrieve exchange rates"), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return quote, true
+}
+
+// respondPricingError writes the response for a failed exchange rate or price operation.
+func respondPricingError(w http.ResponseWriter, err error, failureMessage string) {
+	switch {
+	case errors.Is(err, pricing.ErrRateNotFound), errors.Is(err, pricing.ErrPriceNotFound),
+		errors.Is(err, pricing.ErrPriceItemNotFound):
+		webutils.ErrorJSON(w, err, http.StatusNotFound)
+	case errors.Is(err, pricing.ErrStoreCurrency):
+		webutils.ErrorJSON(w, err, http.StatusBadRequest)
+	default:
+		webutils.ErrorJSON(w, errors.New(failureMessage), http.StatusInternalServerError)
+	}
+}
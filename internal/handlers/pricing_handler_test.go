@@ -0,0 +1,176 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/pricing"
+	"bullet-cloud-api/internal/products"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPricingTest creates a PricingHandler behind the same routes as in main.go.
+// It returns a token for an admin.
+func setupPricingTest(t *testing.T) (*pricing.MockPricingRepository, *MockProductRepository, *mux.Router, string) {
+	t.Helper()
+	pricingRepo := new(pricing.MockPricingRepository)
+	productRepo := new(MockProductRepository)
+	userRepo := new(MockUserRepository)
+	adminID := uuid.New()
+	userRepo.On("FindByID", mock.Anything, adminID).Return(&models.User{ID: adminID, Role: models.RoleAdmin}, nil).Maybe()
+
+	h := handlers.NewPricingHandler(pricingRepo, productRepo)
+	mw := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/exchange-rates", h.ListRates).Methods("GET")
+	router.HandleFunc("/api/products/{id}/prices", h.ListPrices).Methods("GET")
+
+	rates := router.PathPrefix("/api/exchange-rates").Subrouter()
+	rates.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin))
+	rates.HandleFunc("/{currency}", h.SetRate).Methods("PUT")
+	rates.HandleFunc("/{currency}", h.DeleteRate).Methods("DELETE")
+
+	admin := router.PathPrefix("/api/products").Subrouter()
+	admin.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin))
+	admin.HandleFunc("/{id}/prices/{currency}", h.SetPrice).Methods("PUT")
+	admin.HandleFunc("/{id}/prices/{currency}", h.DeletePrice).Methods("DELETE")
+	admin.HandleFunc("/{id}/variants/{variantId}/prices/{currency}", h.SetPrice).Methods("PUT")
+
+	token, err := generateTestToken(adminID)
+	require.NoError(t, err)
+	return pricingRepo, productRepo, router, token
+}
+
+func TestPricingHandler_SetRate(t *testing.T) {
+	pricingRepo, _, router, token := setupPricingTest(t)
+
+	t.Run("Success", func(t *testing.T) {
+		rate := money.MustParseRate("0.1875")
+		pricingRepo.On("SetRate", mock.Anything, "USD", rate).Return(&models.ExchangeRate{Currency: "USD", Rate: rate}, nil).Once()
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PUT", "/api/exchange-rates/usd", token, `{"rate":"0.1875"}`), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"currency":"USD","rate":"0.1875"`)
+	})
+
+	t.Run("Rate Is Required", func(t *testing.T) {
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PUT", "/api/exchange-rates/USD", token, `{}`), http.StatusBadRequest, "")
+		assert.Contains(t, rr.Body.String(), `"field":"rate"`)
+	})
+
+	t.Run("Invalid Rate", func(t *testing.T) {
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PUT", "/api/exchange-rates/USD", token, `{"rate":"-1"}`), http.StatusBadRequest, "")
+		assert.Contains(t, rr.Body.String(), `"field":"rate"`)
+	})
+
+	t.Run("Invalid Currency", func(t *testing.T) {
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", "/api/exchange-rates/U5D", token, `{"rate":"0.2"}`), http.StatusBadRequest, "invalid currency")
+	})
+
+	t.Run("Currency With Three Decimals", func(t *testing.T) {
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", "/api/exchange-rates/KWD", token, `{"rate":"0.06"}`), http.StatusBadRequest, "unsupported currency")
+	})
+
+	t.Run("Store Currency", func(t *testing.T) {
+		pricingRepo.On("SetRate", mock.Anything, "BRL", money.OneRate).Return(nil, pricing.ErrStoreCurrency).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", "/api/exchange-rates/BRL", token, `{"rate":"1"}`), http.StatusBadRequest, "")
+	})
+
+	pricingRepo.AssertExpectations(t)
+}
+
+func TestPricingHandler_DeleteRate(t *testing.T) {
+	pricingRepo, _, router, token := setupPricingTest(t)
+
+	pricingRepo.On("DeleteRate", mock.Anything, "USD").Return(nil).Once()
+	executeRequestAndAssert(t, router, newAdminRequest("DELETE", "/api/exchange-rates/USD", token, ""), http.StatusNoContent, "")
+
+	pricingRepo.On("DeleteRate", mock.Anything, "EUR").Return(pricing.ErrRateNotFound).Once()
+	executeRequestAndAssert(t, router, newAdminRequest("DELETE", "/api/exchange-rates/EUR", token, ""), http.StatusNotFound, `{"error":"exchange rate not found"}`)
+
+	pricingRepo.AssertExpectations(t)
+}
+
+func TestPricingHandler_ListPrices(t *testing.T) {
+	pricingRepo, productRepo, router, _ := setupPricingTest(t)
+	productID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		productRepo.On("FindByID", mock.Anything, productID).Return(&models.Product{ID: productID}, nil).Once()
+		pricingRepo.On("ListPrices", mock.Anything, productID).Return([]models.ProductPrice{{ProductID: productID, Price: money.MustParse("9.99", "USD")}}, nil).Once()
+		req, _ := http.NewRequest("GET", "/api/products/"+productID.String()+"/prices", nil)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"price":{"amount":"9.99","currency":"USD"}`)
+	})
+
+	t.Run("Product Not Found", func(t *testing.T) {
+		missingID := uuid.New()
+		productRepo.On("FindByID", mock.Anything, missingID).Return(nil, products.ErrProductNotFound).Once()
+		req, _ := http.NewRequest("GET", "/api/products/"+missingID.String()+"/prices", nil)
+		executeRequestAndAssert(t, router, req, http.StatusNotFound, `{"error":"product not found"}`)
+	})
+
+	pricingRepo.AssertExpectations(t)
+	productRepo.AssertExpectations(t)
+}
+
+func TestPricingHandler_SetPrice(t *testing.T) {
+	pricingRepo, _, router, token := setupPricingTest(t)
+	productID, variantID := uuid.New(), uuid.New()
+	productPath := "/api/products/" + productID.String() + "/prices/"
+
+	t.Run("Amount In The Currency Of The URL", func(t *testing.T) {
+		price := money.MustParse("1500", "JPY")
+		pricingRepo.On("SetPrice", mock.Anything, productID, (*uuid.UUID)(nil), price).
+			Return(&models.ProductPrice{ProductID: productID, Price: price}, nil).Once()
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PUT", productPath+"jpy", token, `{"price":"1500"}`), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"price":{"amount":"1500","currency":"JPY"}`)
+	})
+
+	t.Run("Variant", func(t *testing.T) {
+		price := money.MustParse("12.50", "USD")
+		pricingRepo.On("SetPrice", mock.Anything, productID, &variantID, price).
+			Return(&models.ProductPrice{ProductID: productID, VariantID: &variantID, Price: price}, nil).Once()
+		path := "/api/products/" + productID.String() + "/variants/" + variantID.String() + "/prices/USD"
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"price":{"amount":"12.50","currency":"USD"}}`), http.StatusOK, "")
+	})
+
+	t.Run("Currency Differs From The URL", func(t *testing.T) {
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PUT", productPath+"USD", token, `{"price":{"amount":"12.50","currency":"EUR"}}`), http.StatusBadRequest, "")
+		assert.Contains(t, rr.Body.String(), `"message":"must be in USD"`)
+	})
+
+	t.Run("Price Must Be Positive", func(t *testing.T) {
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PUT", productPath+"USD", token, `{"price":"0"}`), http.StatusBadRequest, "")
+		assert.Contains(t, rr.Body.String(), `"message":"must be positive"`)
+	})
+
+	t.Run("Product Not Found", func(t *testing.T) {
+		price := money.MustParse("12.50", "USD")
+		pricingRepo.On("SetPrice", mock.Anything, productID, (*uuid.UUID)(nil), price).Return(nil, pricing.ErrPriceItemNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", productPath+"USD", token, `{"price":"12.50"}`), http.StatusNotFound, `{"error":"product or variant not found"}`)
+	})
+
+	pricingRepo.AssertExpectations(t)
+}
+
+func TestPricingHandler_DeletePrice(t *testing.T) {
+	pricingRepo, _, router, token := setupPricingTest(t)
+	productID := uuid.New()
+
+	pricingRepo.On("DeletePrice", mock.Anything, productID, (*uuid.UUID)(nil), "USD").Return(nil).Once()
+	executeRequestAndAssert(t, router, newAdminRequest("DELETE", "/api/products/"+productID.String()+"/prices/usd", token, ""), http.StatusNoContent, "")
+
+	pricingRepo.On("DeletePrice", mock.Anything, productID, (*uuid.UUID)(nil), "EUR").Return(pricing.ErrPriceNotFound).Once()
+	executeRequestAndAssert(t, router, newAdminRequest("DELETE", "/api/products/"+productID.String()+"/prices/EUR", token, ""), http.StatusNotFound, `{"error":"price not found"}`)
+
+	pricingRepo.AssertExpectations(t)
+}
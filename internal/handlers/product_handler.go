@@ -2,10 +2,17 @@ package handlers
 
 import (
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/pricing"
 	"bullet-cloud-api/internal/products" // Product Repository
+	"bullet-cloud-api/internal/variants"
 	"bullet-cloud-api/internal/webutils" // JSON Helpers
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -14,27 +21,43 @@ import (
 // ProductHandler handles product-related requests.
 type ProductHandler struct {
 	ProductRepo products.ProductRepository
+	VariantRepo variants.VariantRepository // The product detail lists its variants
+	PricingRepo pricing.PricingRepository  // Prices in the currency a response is asked in
 }
 
 // NewProductHandler creates a new ProductHandler.
-func NewProductHandler(productRepo products.ProductRepository) *ProductHandler {
-	return &ProductHandler{ProductRepo: productRepo}
+func NewProductHandler(productRepo products.ProductRepository, variantRepo variants.VariantRepository, pricingRepo pricing.PricingRepository) *ProductHandler {
+	return &ProductHandler{ProductRepo: productRepo, VariantRepo: variantRepo, PricingRepo: pricingRepo}
 }
 
 // --- Request Structs (for Create/Update) ---
 
 type CreateProductRequest struct {
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Price       float64    `json:"price"`
-	CategoryID  *uuid.UUID `json:"category_id"` // Optional
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`       // {"amount": "59.90", "currency": "BRL"}, or just the amount
+	CategoryID  *uuid.UUID  `json:"category_id"` // Optional
 }
 
 type UpdateProductRequest struct {
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Price       float64    `json:"price"`
-	CategoryID  *uuid.UUID `json:"category_id"` // Optional
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`       // {"amount": "59.90", "currency": "BRL"}, or just the amount
+	CategoryID  *uuid.UUID  `json:"category_id"` // Optional
+}
+
+// ProductListResponse is one page of a product listing or search.
+type ProductListResponse struct {
+	Products   []models.Product `json:"products"`
+	NextCursor *string          `json:"next_cursor"`      // Pass as cursor to get the next page; null on the last page
+	Total      int              `json:"total"`            // Products matching the filters, across all pages
+	Fuzzy      bool             `json:"fuzzy,omitempty"`  // Search results matched by similar names, as nothing matched the query exactly
+	Facets     *products.Facets `json:"facets,omitempty"` // Search only: result counts per category and price range
+}
+
+// SuggestionListResponse lists the type-ahead suggestions for a search prefix.
+type SuggestionListResponse struct {
+	Suggestions []products.Suggestion `json:"suggestions"`
 }
 
 // --- Handlers ---
@@ -54,11 +77,15 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		webutils.ErrorJSON(w, errors.New("product name is required"), http.StatusBadRequest)
 		return
 	}
-	// Validate Price (must be positive)
-	if req.Price <= 0 {
+	// Validate Price (must be positive, in the store currency)
+	if !req.Price.IsPositive() {
 		webutils.ErrorJSON(w, errors.New("product price must be positive"), http.StatusBadRequest)
 		return
 	}
+	if req.Price.Currency != money.DefaultCurrency {
+		webutils.ErrorJSON(w, fmt.Errorf("product price must be in %s", money.DefaultCurrency), http.StatusBadRequest)
+		return
+	}
 	// TODO: Add more validations if needed (e.g., description length, category exists)
 	// --- End Validations ---
 
@@ -79,21 +106,32 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	webutils.WriteJSON(w, http.StatusCreated, createdProduct)
 }
 
-// GetAllProducts handles GET requests to list all products.
-// This is often a public endpoint.
+// GetAllProducts handles GET requests to list products, one page at a time.
+// This is often a public endpoint. See parseProductListOptions for the query parameters.
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	// TODO: Add pagination and filtering based on query parameters (r.URL.Query())
-	productList, err := h.ProductRepo.FindAll(r.Context())
+	opts, fieldErrors := parseProductListOptions(r.URL.Query(), false)
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return
+	}
+
+	page, err := h.ProductRepo.FindAll(r.Context(), opts)
 	if err != nil {
-		webutils.ErrorJSON(w, errors.New("failed to retrieve products"), http.StatusInternalServerError)
+		respondProductListError(w, err, "failed to retrieve products")
+		return
+	}
+	if !h.priceProducts(w, r, page.Products) {
 		return
 	}
 
-	webutils.WriteJSON(w, http.StatusOK, productList)
+	webutils.WriteJSON(w, http.StatusOK, newProductListResponse(page))
 }
 
-// SearchProducts handles GET requests to search products by query.
-// This is often a public endpoint.
+// SearchProducts handles GET requests to search products by query (full-text, web search syntax).
+// This is often a public endpoint. It accepts the same options as GetAllProducts, and
+// each result carries its highlighted snippets. When nothing matches, products with a
+// similar name are returned instead (typos such as "notbook"), flagged as fuzzy.
+// The response also counts the results per category and price range (facets).
 func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
@@ -101,17 +139,67 @@ func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	productList, err := h.ProductRepo.Search(r.Context(), query)
+	opts, fieldErrors := parseProductListOptions(r.URL.Query(), true)
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return
+	}
+
+	page, err := h.ProductRepo.Search(r.Context(), query, opts)
+	if err != nil {
+		respondProductListError(w, err, "failed to search products")
+		return
+	}
+
+	fuzzy := page.Total == 0
+	if fuzzy {
+		page, err = h.ProductRepo.SearchSimilar(r.Context(), query, opts)
+		if err != nil {
+			respondProductListError(w, err, "failed to search products")
+			return
+		}
+	}
+	if !h.priceProducts(w, r, page.Products) {
+		return
+	}
+
+	resp := newProductListResponse(page)
+	resp.Fuzzy = fuzzy && page.Total > 0
+	webutils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// SuggestProducts handles GET /api/products/suggest, the type-ahead of the search box.
+// It returns product and category names starting with the q parameter, or resembling it,
+// the most ordered first. limit defaults to 8, at most 20.
+func (h *ProductHandler) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimSpace(r.URL.Query().Get("q"))
+	if prefix == "" {
+		webutils.ErrorJSON(w, errors.New("query parameter 'q' is required"), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSuggestionLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSuggestionLimit {
+			webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "limit", Code: "invalid", Message: fmt.Sprintf("must be between 1 and %d", maxSuggestionLimit)}})
+			return
+		}
+		limit = n
+	}
+
+	suggestions, err := h.ProductRepo.Suggest(r.Context(), prefix, limit)
 	if err != nil {
-		webutils.ErrorJSON(w, errors.New("failed to search products"), http.StatusInternalServerError)
+		webutils.ErrorJSON(w, errors.New("failed to retrieve suggestions"), http.StatusInternalServerError)
 		return
 	}
 
-	webutils.WriteJSON(w, http.StatusOK, productList)
+	webutils.WriteJSON(w, http.StatusOK, SuggestionListResponse{Suggestions: suggestions})
 }
 
 // GetProduct handles GET requests for a specific product by ID.
-// This is often a public endpoint.
+// This is often a public endpoint. Products sold in variants come with their variants and
+// the values each option takes (e.g. every size and color).
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
@@ -136,7 +224,21 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	webutils.WriteJSON(w, http.StatusOK, product)
+	productVariants, err := h.VariantRepo.FindByProductID(r.Context(), productID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve product variants"), http.StatusInternalServerError)
+		return
+	}
+	if len(productVariants) > 0 {
+		product.Variants = productVariants
+		product.Options = models.VariantOptionMatrix(productVariants)
+	}
+	priced := []models.Product{*product}
+	if !h.priceProducts(w, r, priced) {
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, priced[0])
 }
 
 // UpdateProduct handles PUT requests to update an existing product.
@@ -162,10 +264,14 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Basic Validation
-	if req.Name == "" || req.Price < 0 {
+	if req.Name == "" || req.Price.IsNegative() {
 		webutils.ErrorJSON(w, errors.New("product name is required and price must be non-negative"), http.StatusBadRequest)
 		return
 	}
+	if req.Price.Currency != "" && req.Price.Currency != money.DefaultCurrency {
+		webutils.ErrorJSON(w, fmt.Errorf("product price must be in %s", money.DefaultCurrency), http.StatusBadRequest)
+		return
+	}
 
 	productToUpdate := &models.Product{
 		// ID is set by the repository based on the URL param
@@ -221,3 +327,138 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 // TODO: Implement handlers for other product endpoints:
 // GetFeaturedProducts (GET /api/products/featured)
 // GetProductsByCategory (GET /api/products/category/{categoryId})
+
+// --- Listing Helpers ---
+
+// Page size limits for product listings and searches.
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+)
+
+// Limits for the number of search suggestions.
+const (
+	defaultSuggestionLimit = 8
+	maxSuggestionLimit     = 20
+)
+
+// parseProductListOptions reads the listing query parameters, reporting every invalid one:
+// category_id, min_price, max_price, created_from and created_to (RFC 3339 timestamps or
+// YYYY-MM-DD dates, created_to inclusive), sort (newest, price_asc, price_desc, name, and
+// relevance for searches), limit and cursor.
+func parseProductListOptions(query url.Values, isSearch bool) (products.ListOptions, []webutils.FieldError) {
+	opts := products.ListOptions{
+		Sort:  products.Sort(query.Get("sort")),
+		Limit: defaultProductPageSize,
+	}
+	var fieldErrors []webutils.FieldError
+	invalid := func(field, message string) {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: field, Code: "invalid", Message: message})
+	}
+
+	if v := query.Get("category_id"); v != "" {
+		categoryID, err := uuid.Parse(v)
+		if err != nil {
+			invalid("category_id", "must be a UUID")
+		} else {
+			opts.CategoryID = &categoryID
+		}
+	}
+	parsePrice := func(field string) *money.Money {
+		v := query.Get(field)
+		if v == "" {
+			return nil
+		}
+		price, err := money.Parse(v, money.DefaultCurrency)
+		if err != nil || price.IsNegative() {
+			invalid(field, "must be a non-negative number")
+			return nil
+		}
+		return &price
+	}
+	opts.MinPrice = parsePrice("min_price")
+	opts.MaxPrice = parsePrice("max_price")
+	if opts.MinPrice != nil && opts.MaxPrice != nil && opts.MinPrice.Amount > opts.MaxPrice.Amount {
+		invalid("max_price", "must not be less than min_price")
+	}
+	if v := query.Get("created_from"); v != "" {
+		from, _, err := parseTimeOrDate(v)
+		if err != nil {
+			invalid("created_from", "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		} else {
+			opts.CreatedFrom = &from
+		}
+	}
+	if v := query.Get("created_to"); v != "" {
+		to, isDate, err := parseTimeOrDate(v)
+		if err != nil {
+			invalid("created_to", "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		} else {
+			if isDate {
+				to = to.AddDate(0, 0, 1) // A date includes the whole day
+			}
+			opts.CreatedTo = &to
+		}
+	}
+	if opts.Sort != "" && (!opts.Sort.IsValid() || (opts.Sort == products.SortRelevance && !isSearch)) {
+		invalid("sort", "unknown sort order")
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxProductPageSize {
+			invalid("limit", fmt.Sprintf("must be between 1 and %d", maxProductPageSize))
+		} else {
+			opts.Limit = limit
+		}
+	}
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := products.DecodeCursor(v)
+		if err != nil {
+			invalid("cursor", "must be a next_cursor returned by a previous page")
+		} else {
+			opts.After = cursor
+		}
+	}
+	return opts, fieldErrors
+}
+
+// priceProducts shows the prices of the products, and of their variants, in the currency
+// the request asks for (see requestedCurrency); they are in the store currency otherwise.
+// On failure it writes the response and returns false.
+func (h *ProductHandler) priceProducts(w http.ResponseWriter, r *http.Request, list []models.Product) bool {
+	productIDs := make([]uuid.UUID, len(list))
+	for i, p := range list {
+		productIDs[i] = p.ID
+	}
+	quote, ok := quoteRequest(w, r, h.PricingRepo, productIDs)
+	if !ok {
+		return false
+	}
+	for i := range list {
+		if err := quote.PriceProduct(&list[i]); err != nil {
+			webutils.ErrorJSON(w, errors.New("failed to convert prices"), http.StatusInternalServerError)
+			return false
+		}
+	}
+	return true
+}
+
+// newProductListResponse converts a page for the response body.
+func newProductListResponse(page *products.Page) ProductListResponse {
+	resp := ProductListResponse{Products: page.Products, Total: page.Total, Facets: page.Facets}
+	if page.Next != nil {
+		next := page.Next.Encode()
+		resp.NextCursor = &next
+	}
+	return resp
+}
+
+// respondProductListError writes the response for a failed listing or search.
+func respondProductListError(w http.ResponseWriter, err error, failureMessage string) {
+	if errors.Is(err, products.ErrInvalidCursor) {
+		// The cursor was issued for another sort order
+		webutils.ValidationErrorJSON(w, []webutils.FieldError{{Field: "cursor", Code: "invalid", Message: "does not match the sort order"}})
+		return
+	}
+	webutils.ErrorJSON(w, errors.New(failureMessage), http.StatusInternalServerError)
+}
@@ -1,13 +1,18 @@
 package handlers_test
 
 import (
+	"bullet-cloud-api/internal/apikeys"
 	"bullet-cloud-api/internal/auth" // For middleware and context key
 	"bullet-cloud-api/internal/handlers"
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/pricing"
 	"bullet-cloud-api/internal/products"
 	"bullet-cloud-api/internal/users" // For user mock
+	"bullet-cloud-api/internal/variants"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -29,10 +34,10 @@ func setupProductTest(t *testing.T) (*products.MockProductRepository, *MockUserR
 	// Call the base setup - Capture necessary mocks and router, ignore others
 	_, _, router, mockUserRepo, _, _, _, _, _ := setupBaseTest(t)
 
-	productHandler := handlers.NewProductHandler(mockProductRepo)
+	productHandler := handlers.NewProductHandler(mockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
 
 	// Need authMiddleware instance for protected routes
-	authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 
 	apiV1 := router.PathPrefix("/api").Subrouter()
 
@@ -42,7 +47,7 @@ func setupProductTest(t *testing.T) (*products.MockProductRepository, *MockUserR
 
 	// Protected routes
 	protectedProductRoutes := apiV1.PathPrefix("/products").Subrouter()
-	protectedProductRoutes.Use(authMiddleware.Authenticate) // Apply middleware
+	protectedProductRoutes.Use(authMiddleware.Authenticate, authMiddleware.RequireRole(models.RoleAdmin)) // Apply middleware
 	protectedProductRoutes.HandleFunc("", productHandler.CreateProduct).Methods("POST")
 	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", productHandler.UpdateProduct).Methods("PUT")
 	protectedProductRoutes.HandleFunc("/{id:[0-9a-fA-F-]+}", productHandler.DeleteProduct).Methods("DELETE")
@@ -55,42 +60,84 @@ func setupProductTest(t *testing.T) (*products.MockProductRepository, *MockUserR
 func TestProductHandler_GetAllProducts(t *testing.T) {
 	// Corrected setupBaseTest call
 	_, _, router, _, baseMockProductRepo, _, _, _, _ := setupBaseTest(t)
-	productHandler := handlers.NewProductHandler(baseMockProductRepo)
+	productHandler := handlers.NewProductHandler(baseMockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
 
 	router.HandleFunc("/api/products", productHandler.GetAllProducts).Methods("GET")
 
 	testProducts := []models.Product{
-		{ID: uuid.New(), Name: "Product A", Price: 10.99},
-		{ID: uuid.New(), Name: "Product B", Price: 25.50},
+		{ID: uuid.New(), Name: "Product A", Price: brl("10.99")},
+		{ID: uuid.New(), Name: "Product B", Price: brl("25.50")},
 	}
+	productsJSON := fmt.Sprintf(`[{"id":"%s","name":"%s","description":"","price":%s,"category_id":null,"stock":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},{"id":"%s","name":"%s","description":"","price":%s,"category_id":null,"stock":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}]`, testProducts[0].ID, testProducts[0].Name, moneyJSON(testProducts[0].Price), testProducts[1].ID, testProducts[1].Name, moneyJSON(testProducts[1].Price))
+	nextCursor := &products.Cursor{Sort: products.SortPriceAsc, Keys: []string{"25.50", "2025-01-01 00:00:00+00", testProducts[1].ID.String()}}
+	categoryID := uuid.New()
+	minPrice, maxPrice := brl("10"), brl("99.90")
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
 
 	tests := []struct {
-		name              string
-		mockFindAllReturn []models.Product
-		mockFindAllError  error
-		expectedStatus    int
-		expectedBody      string // Expect JSON list
+		name             string
+		query            string
+		expectedOpts     *products.ListOptions // Nil when the repository must not be called
+		mockFindAllPage  *products.Page
+		mockFindAllError error
+		expectedStatus   int
+		expectedBody     string
 	}{
 		{
-			name:              "Success",
-			mockFindAllReturn: testProducts,
-			mockFindAllError:  nil,
-			expectedStatus:    http.StatusOK,
-			expectedBody:      fmt.Sprintf(`[{"id":"%s","name":"%s","description":"","price":%.2f,"category_id":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},{"id":"%s","name":"%s","description":"","price":%.2f,"category_id":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}]`, testProducts[0].ID, testProducts[0].Name, testProducts[0].Price, testProducts[1].ID, testProducts[1].Name, testProducts[1].Price),
+			name:            "Success - Defaults",
+			expectedOpts:    &products.ListOptions{Limit: 20},
+			mockFindAllPage: &products.Page{Products: testProducts, Total: 2},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    fmt.Sprintf(`{"products":%s,"next_cursor":null,"total":2}`, productsJSON),
+		},
+		{
+			name:            "Success - Filters, Sort And Next Page",
+			query:           fmt.Sprintf("?category_id=%s&min_price=10&max_price=99.9&created_from=2025-01-01&created_to=2025-01-31&sort=price_asc&limit=2", categoryID),
+			expectedOpts:    &products.ListOptions{CategoryID: &categoryID, MinPrice: &minPrice, MaxPrice: &maxPrice, CreatedFrom: &from, CreatedTo: &to, Sort: products.SortPriceAsc, Limit: 2},
+			mockFindAllPage: &products.Page{Products: testProducts, Total: 5, Next: nextCursor},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    fmt.Sprintf(`{"products":%s,"next_cursor":"%s","total":5}`, productsJSON, nextCursor.Encode()),
+		},
+		{
+			name:            "Success - Cursor Is Passed Back",
+			query:           "?sort=price_asc&cursor=" + nextCursor.Encode(),
+			expectedOpts:    &products.ListOptions{Sort: products.SortPriceAsc, Limit: 20, After: nextCursor},
+			mockFindAllPage: &products.Page{Products: []models.Product{}, Total: 5},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    `{"products":[],"next_cursor":null,"total":5}`,
+		},
+		{
+			name:             "Cursor Of Another Sort Order",
+			query:            "?sort=name&cursor=" + nextCursor.Encode(),
+			expectedOpts:     &products.ListOptions{Sort: products.SortName, Limit: 20, After: nextCursor},
+			mockFindAllError: products.ErrInvalidCursor,
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"error":"validation failed","fields":[{"field":"cursor","code":"invalid","message":"does not match the sort order"}]}`,
+		},
+		{
+			name:           "Invalid Parameters",
+			query:          "?category_id=abc&min_price=-1&sort=relevance&limit=0&cursor=garbage",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"error":"validation failed","fields":[
+				{"field":"category_id","code":"invalid","message":"must be a UUID"},
+				{"field":"min_price","code":"invalid","message":"must be a non-negative number"},
+				{"field":"sort","code":"invalid","message":"unknown sort order"},
+				{"field":"limit","code":"invalid","message":"must be between 1 and 100"},
+				{"field":"cursor","code":"invalid","message":"must be a next_cursor returned by a previous page"}]}`,
 		},
 		{
-			name:              "Success - No Products",
-			mockFindAllReturn: []models.Product{},
-			mockFindAllError:  nil,
-			expectedStatus:    http.StatusOK,
-			expectedBody:      `[]`,
+			name:           "Inverted Price Range",
+			query:          "?min_price=50&max_price=10",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"validation failed","fields":[{"field":"max_price","code":"invalid","message":"must not be less than min_price"}]}`,
 		},
 		{
-			name:              "Error - Repository Failure",
-			mockFindAllReturn: nil,
-			mockFindAllError:  assert.AnError,
-			expectedStatus:    http.StatusInternalServerError,
-			expectedBody:      `{"error":"failed to retrieve products"}`,
+			name:             "Error - Repository Failure",
+			expectedOpts:     &products.ListOptions{Limit: 20},
+			mockFindAllError: assert.AnError,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedBody:     `{"error":"failed to retrieve products"}`,
 		},
 	}
 
@@ -100,30 +147,153 @@ func TestProductHandler_GetAllProducts(t *testing.T) {
 			mockProductRepo := new(MockProductRepository)
 			productHandler.ProductRepo = mockProductRepo
 
-			mockProductRepo.On("FindAll", mock.Anything).Return(tc.mockFindAllReturn, tc.mockFindAllError).Once()
+			if tc.expectedOpts != nil {
+				mockProductRepo.On("FindAll", mock.Anything, *tc.expectedOpts).Return(tc.mockFindAllPage, tc.mockFindAllError).Once()
+			}
 
-			req, _ := http.NewRequest("GET", "/api/products", nil)
+			req, _ := http.NewRequest("GET", "/api/products"+tc.query, nil)
 			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
 			mockProductRepo.AssertExpectations(t)
 		})
 	}
 }
 
+func TestProductHandler_SearchProducts(t *testing.T) {
+	mockProductRepo := new(MockProductRepository)
+	productHandler := handlers.NewProductHandler(mockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
+	router := mux.NewRouter()
+	router.HandleFunc("/api/products/search", productHandler.SearchProducts).Methods("GET")
+
+	found := []models.Product{{
+		ID:        uuid.New(),
+		Name:      "Camisa",
+		Price:     brl("49.90"),
+		Highlight: &models.ProductHighlight{Name: "<mark>Camisa</mark>", Description: "Algodão, ideal para <mark>camisas</mark> sociais"},
+	}}
+
+	t.Run("Success With Options", func(t *testing.T) {
+		maxPrice := brl("100")
+		categoryID := uuid.New()
+		facets := &products.Facets{
+			Categories:  []products.CategoryFacet{{ID: &categoryID, Name: "Roupas", Count: 1}},
+			PriceRanges: []products.PriceRangeFacet{{Min: brl("0"), Max: &maxPrice, Count: 1}, {Min: brl("100"), Count: 0}},
+		}
+		mockProductRepo.On("Search", mock.Anything, "camisa", products.ListOptions{MaxPrice: &maxPrice, Sort: products.SortRelevance, Limit: 10}).
+			Return(&products.Page{Products: found, Total: 1, Facets: facets}, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/products/search?q=camisa&max_price=100&sort=relevance&limit=10", nil)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+		var resp handlers.ProductListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.Total)
+		assert.Nil(t, resp.NextCursor)
+		assert.Equal(t, found, resp.Products, "Results include the highlighted snippets")
+		assert.Equal(t, facets, resp.Facets)
+	})
+
+	t.Run("Falls Back To Similar Names", func(t *testing.T) {
+		similar := []models.Product{{ID: uuid.New(), Name: "Notebook", Price: brl("3500")}}
+		opts := products.ListOptions{Limit: 20}
+		mockProductRepo.On("Search", mock.Anything, "notbook", opts).Return(&products.Page{Products: []models.Product{}}, nil).Once()
+		mockProductRepo.On("SearchSimilar", mock.Anything, "notbook", opts).Return(&products.Page{Products: similar, Total: 1}, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/products/search?q=notbook", nil)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+		var resp handlers.ProductListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.True(t, resp.Fuzzy)
+		assert.Equal(t, similar, resp.Products)
+	})
+
+	t.Run("No Match At All", func(t *testing.T) {
+		empty := &products.Page{Products: []models.Product{}}
+		mockProductRepo.On("Search", mock.Anything, "xyzzy", mock.Anything).Return(empty, nil).Once()
+		mockProductRepo.On("SearchSimilar", mock.Anything, "xyzzy", mock.Anything).Return(empty, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/products/search?q=xyzzy", nil)
+		executeRequestAndAssert(t, router, req, http.StatusOK, `{"products":[],"next_cursor":null,"total":0}`)
+	})
+
+	t.Run("Missing Query", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/products/search", nil)
+		executeRequestAndAssert(t, router, req, http.StatusBadRequest, `{"error":"search query parameter 'q' is required"}`)
+	})
+
+	t.Run("Repository Failure", func(t *testing.T) {
+		mockProductRepo.On("Search", mock.Anything, "erro", mock.Anything).Return(nil, assert.AnError).Once()
+		req, _ := http.NewRequest("GET", "/api/products/search?q=erro", nil)
+		executeRequestAndAssert(t, router, req, http.StatusInternalServerError, `{"error":"failed to search products"}`)
+	})
+
+	mockProductRepo.AssertExpectations(t)
+}
+
+func TestProductHandler_SuggestProducts(t *testing.T) {
+	mockProductRepo := new(MockProductRepository)
+	productHandler := handlers.NewProductHandler(mockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
+	router := mux.NewRouter()
+	router.HandleFunc("/api/products/suggest", productHandler.SuggestProducts).Methods("GET")
+
+	t.Run("Success", func(t *testing.T) {
+		suggestions := []products.Suggestion{
+			{Type: products.SuggestionProduct, ID: uuid.New(), Name: "Notebook Gamer", OrderCount: 12},
+			{Type: products.SuggestionCategory, ID: uuid.New(), Name: "Notebooks", OrderCount: 40},
+		}
+		mockProductRepo.On("Suggest", mock.Anything, "note", 8).Return(suggestions, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/products/suggest?q=%20note%20", nil)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+		var resp handlers.SuggestionListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, suggestions, resp.Suggestions)
+	})
+
+	t.Run("Custom Limit", func(t *testing.T) {
+		mockProductRepo.On("Suggest", mock.Anything, "cam", 3).Return([]products.Suggestion{}, nil).Once()
+		req, _ := http.NewRequest("GET", "/api/products/suggest?q=cam&limit=3", nil)
+		executeRequestAndAssert(t, router, req, http.StatusOK, `{"suggestions":[]}`)
+	})
+
+	t.Run("Missing Query", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/products/suggest?q=%20", nil)
+		executeRequestAndAssert(t, router, req, http.StatusBadRequest, `{"error":"query parameter 'q' is required"}`)
+	})
+
+	t.Run("Invalid Limit", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/products/suggest?q=cam&limit=50", nil)
+		rr := executeRequestAndAssert(t, router, req, http.StatusBadRequest, "")
+		assert.Contains(t, rr.Body.String(), `"field":"limit"`)
+	})
+
+	t.Run("Repository Failure", func(t *testing.T) {
+		mockProductRepo.On("Suggest", mock.Anything, "erro", 8).Return(nil, assert.AnError).Once()
+		req, _ := http.NewRequest("GET", "/api/products/suggest?q=erro", nil)
+		executeRequestAndAssert(t, router, req, http.StatusInternalServerError, `{"error":"failed to retrieve suggestions"}`)
+	})
+
+	mockProductRepo.AssertExpectations(t)
+}
+
 func TestProductHandler_GetProduct(t *testing.T) {
 	// Corrected setupBaseTest call
 	_, _, router, _, baseMockProductRepo, _, _, _, _ := setupBaseTest(t)
-	productHandler := handlers.NewProductHandler(baseMockProductRepo)
+	productHandler := handlers.NewProductHandler(baseMockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
 
 	router.HandleFunc("/api/products/{id}", productHandler.GetProduct).Methods("GET")
 
 	testID := uuid.New()
-	testProduct := &models.Product{ID: testID, Name: "Found Product", Price: 50.0}
+	testProduct := &models.Product{ID: testID, Name: "Found Product", Price: brl("50")}
+	variantID := uuid.New()
+	testVariants := []models.ProductVariant{
+		{ID: variantID, ProductID: testID, SKU: "FP-P-AZUL", Options: map[string]string{"size": "P", "color": "Azul"}},
+	}
 
 	tests := []struct {
 		name               string
 		productID          string
 		mockFindByIDReturn *models.Product
 		mockFindByIDError  error
+		mockVariants       []models.ProductVariant // Variants of the product found
 		expectedStatus     int
 		expectedBody       string
 	}{
@@ -133,7 +303,15 @@ func TestProductHandler_GetProduct(t *testing.T) {
 			mockFindByIDReturn: testProduct,
 			mockFindByIDError:  nil,
 			expectedStatus:     http.StatusOK,
-			expectedBody:       fmt.Sprintf(`{"id":"%s","name":"%s","description":"","price":%.2f,"category_id":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, testID, testProduct.Name, testProduct.Price),
+			expectedBody:       fmt.Sprintf(`{"id":"%s","name":"%s","description":"","price":%s,"category_id":null,"stock":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, testID, testProduct.Name, moneyJSON(testProduct.Price)),
+		},
+		{
+			name:               "Success With Variants",
+			productID:          testID.String(),
+			mockFindByIDReturn: &models.Product{ID: testID, Name: testProduct.Name, Price: testProduct.Price},
+			mockVariants:       testVariants,
+			expectedStatus:     http.StatusOK,
+			expectedBody:       fmt.Sprintf(`{"id":"%s","name":"%s","description":"","price":%s,"category_id":null,"stock":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","variants":[{"id":"%s","product_id":"%s","sku":"FP-P-AZUL","options":{"color":"Azul","size":"P"},"price":null,"barcode":null,"stock":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}],"options":{"color":["Azul"],"size":["P"]}}`, testID, testProduct.Name, moneyJSON(testProduct.Price), variantID, testID),
 		},
 		{
 			name:               "Not Found",
@@ -165,38 +343,91 @@ func TestProductHandler_GetProduct(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			mockProductRepo := new(MockProductRepository)
+			mockVariantRepo := new(variants.MockVariantRepository)
 			productHandler.ProductRepo = mockProductRepo
+			productHandler.VariantRepo = mockVariantRepo
 
 			if tc.productID != "not-a-valid-uuid" {
 				uuidToFind, _ := uuid.Parse(tc.productID)
 				mockProductRepo.On("FindByID", mock.Anything, uuidToFind).Return(tc.mockFindByIDReturn, tc.mockFindByIDError).Once()
+				if tc.mockFindByIDReturn != nil {
+					mockVariantRepo.On("FindByProductID", mock.Anything, uuidToFind).Return(tc.mockVariants, nil).Once()
+				}
 			}
 
 			url := fmt.Sprintf("/api/products/%s", tc.productID)
 			req, _ := http.NewRequest("GET", url, nil)
 			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
 			mockProductRepo.AssertExpectations(t)
+			mockVariantRepo.AssertExpectations(t)
 		})
 	}
 }
 
 // --- Tests for Protected Routes (Require Authentication) ---
 
+func TestProductHandler_GetProduct_InCurrency(t *testing.T) {
+	productID, listedVariant, plainVariant := uuid.New(), uuid.New(), uuid.New()
+	product := func() *models.Product {
+		return &models.Product{ID: productID, Name: "Camiseta", Price: brl("50")}
+	}
+	productVariants := []models.ProductVariant{
+		{ID: listedVariant, ProductID: productID, SKU: "CAM-P", Options: map[string]string{"size": "P"}},
+		{ID: plainVariant, ProductID: productID, SKU: "CAM-G", Options: map[string]string{"size": "G"}},
+	}
+	usdRate := &models.ExchangeRate{Currency: "USD", Rate: money.MustParseRate("0.2")}
+
+	t.Run("Price List And Conversion", func(t *testing.T) {
+		productRepo, variantRepo, pricingRepo := new(MockProductRepository), new(variants.MockVariantRepository), new(pricing.MockPricingRepository)
+		productRepo.On("FindByID", mock.Anything, productID).Return(product(), nil).Once()
+		variantRepo.On("FindByProductID", mock.Anything, productID).Return(productVariants, nil).Once()
+		pricingRepo.On("FindRate", mock.Anything, "USD").Return(usdRate, nil).Once()
+		pricingRepo.On("FindPrices", mock.Anything, "USD", []uuid.UUID{productID}).
+			Return([]models.ProductPrice{{ProductID: productID, VariantID: &listedVariant, Price: money.MustParse("11.99", "USD")}}, nil).Once()
+
+		router := mux.NewRouter()
+		router.HandleFunc("/api/products/{id}", handlers.NewProductHandler(productRepo, variantRepo, pricingRepo).GetProduct).Methods("GET")
+		req, _ := http.NewRequest("GET", "/api/products/"+productID.String(), nil)
+		req.Header.Set("Accept-Currency", "USD")
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+
+		var got models.Product
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Equal(t, money.MustParse("10.00", "USD"), got.Price, "Converted at the current rate")
+		require.Len(t, got.Variants, 2)
+		assert.Equal(t, money.MustParse("11.99", "USD"), *got.Variants[0].Price, "From the price list")
+		assert.Nil(t, got.Variants[1].Price, "Still follows the product price")
+		pricingRepo.AssertExpectations(t)
+	})
+
+	t.Run("Currency Without Exchange Rate", func(t *testing.T) {
+		productRepo, variantRepo, pricingRepo := new(MockProductRepository), new(variants.MockVariantRepository), new(pricing.MockPricingRepository)
+		productRepo.On("FindByID", mock.Anything, productID).Return(product(), nil).Once()
+		variantRepo.On("FindByProductID", mock.Anything, productID).Return([]models.ProductVariant{}, nil).Once()
+		pricingRepo.On("FindRate", mock.Anything, "CHF").Return(nil, pricing.ErrRateNotFound).Once()
+
+		router := mux.NewRouter()
+		router.HandleFunc("/api/products/{id}", handlers.NewProductHandler(productRepo, variantRepo, pricingRepo).GetProduct).Methods("GET")
+		req, _ := http.NewRequest("GET", "/api/products/"+productID.String()+"?currency=chf", nil)
+		executeRequestAndAssert(t, router, req, http.StatusBadRequest, `{"error":"prices are not available in CHF"}`)
+	})
+}
+
 func TestProductHandler_CreateProduct(t *testing.T) {
 	// Corrected setupBaseTest call
 	_, _, router, baseMockUserRepo, baseMockProductRepo, _, _, _, token := setupBaseTest(t)
-	productHandler := handlers.NewProductHandler(baseMockProductRepo)
-	authMiddleware := auth.NewMiddleware(testJwtSecret, baseMockUserRepo)
+	productHandler := handlers.NewProductHandler(baseMockProductRepo, new(variants.MockVariantRepository), new(pricing.MockPricingRepository))
+	authMiddleware := auth.NewMiddleware(testKeys, baseMockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 
 	// Extract UserID from token for mock setup
-	claims, err := auth.ValidateToken(token, testJwtSecret)
+	claims, err := auth.ValidateToken(token, testKeys)
 	require.NoError(t, err)
 	testUserID := claims.UserID
 
-	router.Handle("/api/products", authMiddleware.Authenticate(http.HandlerFunc(productHandler.CreateProduct))).Methods("POST")
+	router.Handle("/api/products", authMiddleware.Authenticate(authMiddleware.RequireRole(models.RoleAdmin)(http.HandlerFunc(productHandler.CreateProduct)))).Methods("POST")
 
 	productName := "New Gadget"
-	productPrice := 199.99
+	productPrice := brl("199.99")
 	productDesc := "A cool new gadget"
 	testProduct := models.Product{Name: productName, Price: productPrice, Description: productDesc}
 	createdProduct := models.Product{ID: uuid.New(), Name: productName, Price: productPrice, Description: productDesc}
@@ -211,11 +442,11 @@ func TestProductHandler_CreateProduct(t *testing.T) {
 	}{
 		{
 			name:             "Success",
-			body:             fmt.Sprintf(`{"name":"%s", "price":%.2f, "description":"%s"}`, productName, productPrice, productDesc),
+			body:             fmt.Sprintf(`{"name":"%s", "price":"%s", "description":"%s"}`, productName, productPrice, productDesc),
 			mockCreateReturn: &createdProduct,
 			mockCreateError:  nil,
 			expectedStatus:   http.StatusCreated,
-			expectedBody:     fmt.Sprintf(`{"id":"%s","name":"%s","description":"%s","price":%.2f,"category_id":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, createdProduct.ID, createdProduct.Name, createdProduct.Description, createdProduct.Price),
+			expectedBody:     fmt.Sprintf(`{"id":"%s","name":"%s","description":"%s","price":%s,"category_id":null,"stock":null,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, createdProduct.ID, createdProduct.Name, createdProduct.Description, moneyJSON(createdProduct.Price)),
 		},
 		{
 			name:             "Invalid JSON",
@@ -227,7 +458,7 @@ func TestProductHandler_CreateProduct(t *testing.T) {
 		},
 		{
 			name:             "Missing Name",
-			body:             fmt.Sprintf(`{"price":%.2f}`, productPrice),
+			body:             fmt.Sprintf(`{"price":"%s"}`, productPrice),
 			mockCreateReturn: nil,
 			mockCreateError:  nil, // Not called
 			expectedStatus:   http.StatusBadRequest,
@@ -252,7 +483,7 @@ func TestProductHandler_CreateProduct(t *testing.T) {
 		},
 		{
 			name:             "Repository Error",
-			body:             fmt.Sprintf(`{"name":"%s", "price":%.2f}`, productName, productPrice),
+			body:             fmt.Sprintf(`{"name":"%s", "price":{"amount":"%s","currency":"BRL"}}`, productName, productPrice),
 			mockCreateReturn: nil,
 			mockCreateError:  assert.AnError,
 			expectedStatus:   http.StatusInternalServerError,
@@ -266,8 +497,8 @@ func TestProductHandler_CreateProduct(t *testing.T) {
 			mockProductRepo := new(MockProductRepository)
 			mockUserRepo := new(MockUserRepository)
 			productHandler.ProductRepo = mockProductRepo
-			authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
-			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID}, nil).Maybe()
+			authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(&models.User{ID: testUserID, Role: models.RoleAdmin}, nil).Maybe()
 
 			// Setup mock expectation for Create
 			if tc.expectedStatus == http.StatusCreated {
@@ -286,7 +517,7 @@ func TestProductHandler_CreateProduct(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 
 			subRouter := mux.NewRouter()
-			subRouter.Handle("/api/products", authMiddleware.Authenticate(http.HandlerFunc(productHandler.CreateProduct))).Methods("POST")
+			subRouter.Handle("/api/products", authMiddleware.Authenticate(authMiddleware.RequireRole(models.RoleAdmin)(http.HandlerFunc(productHandler.CreateProduct)))).Methods("POST")
 
 			executeRequestAndAssert(t, subRouter, req, tc.expectedStatus, tc.expectedBody)
 
@@ -318,7 +549,7 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			name:           "Success",
 			productID:      productToUpdateID.String(),
 			body:           `{"name":"Updated Gadget","description":"Better","price":129.99}`, // Include all fields
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockUpdateErr:  nil,
 			expectedStatus: http.StatusOK,
@@ -328,7 +559,7 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			name:           "Failure - Invalid UUID",
 			productID:      "not-a-uuid",
 			body:           `{"name":"Update Attempt"}`,
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockUpdateErr:  nil,
 			expectedStatus: http.StatusNotFound, // Expect 404 from router
@@ -338,7 +569,7 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			name:           "Failure - Invalid JSON",
 			productID:      productToUpdateID.String(),
 			body:           `{"name":}`, // Invalid JSON
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockUpdateErr:  nil,
 			expectedStatus: http.StatusBadRequest,
@@ -348,7 +579,7 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			name:           "Failure - Missing Name",
 			productID:      productToUpdateID.String(),
 			body:           `{"price":50.0}`, // Missing name
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockUpdateErr:  nil,
 			expectedStatus: http.StatusBadRequest,
@@ -358,7 +589,7 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			name:           "Failure - Negative Price",
 			productID:      productToUpdateID.String(),
 			body:           `{"name":"Bad Price Product","price":-1.0}`,
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockUpdateErr:  nil,
 			expectedStatus: http.StatusBadRequest,
@@ -368,7 +599,7 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			name:           "Failure - Product Not Found",
 			productID:      productToUpdateID.String(),
 			body:           `{"name":"Update Attempt","price":10.0}`,
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockUpdateErr:  products.ErrProductNotFound,
 			expectedStatus: http.StatusNotFound,
@@ -378,12 +609,22 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			name:           "Failure - Repo Update Error",
 			productID:      productToUpdateID.String(),
 			body:           `{"name":"Update Attempt","price":10.0}`,
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockUpdateErr:  errors.New("db update failed"),
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   `{"error":"failed to update product"}`,
 		},
+		{
+			name:           "Failure - Not Admin",
+			productID:      productToUpdateID.String(),
+			body:           `{"name":"Update Attempt","price":10.0}`,
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleCustomer},
+			mockUserErr:    nil,
+			mockUpdateErr:  nil,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"forbidden"}`,
+		},
 		{
 			name:           "Failure - Middleware User Check Fails",
 			productID:      productToUpdateID.String(),
@@ -418,7 +659,7 @@ func TestProductHandler_UpdateProduct(t *testing.T) {
 			}
 
 			// Mock product repo update (only if middleware/validation/parsing passes)
-			if tc.productID != "not-a-uuid" && tc.mockUserErr == nil && tc.expectedStatus != http.StatusBadRequest && tc.expectedStatus != http.StatusUnauthorized {
+			if tc.productID != "not-a-uuid" && tc.mockUserErr == nil && tc.expectedStatus != http.StatusBadRequest && tc.expectedStatus != http.StatusUnauthorized && tc.expectedStatus != http.StatusForbidden {
 				parsedID, _ := uuid.Parse(tc.productID)
 				mockProductRepo.On("Update", mock.Anything, parsedID, mock.AnythingOfType("*models.Product")).
 					Return(func(ctx context.Context, id uuid.UUID, p *models.Product) *models.Product {
@@ -468,7 +709,7 @@ func TestProductHandler_DeleteProduct(t *testing.T) {
 		{
 			name:           "Success",
 			productID:      productToDeleteID.String(),
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockDeleteErr:  nil,
 			expectedStatus: http.StatusNoContent,
@@ -477,7 +718,7 @@ func TestProductHandler_DeleteProduct(t *testing.T) {
 		{
 			name:           "Failure - Invalid UUID",
 			productID:      "not-a-uuid",
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockDeleteErr:  nil,
 			expectedStatus: http.StatusNotFound, // Expect 404 from router
@@ -486,7 +727,7 @@ func TestProductHandler_DeleteProduct(t *testing.T) {
 		{
 			name:           "Failure - Product Not Found",
 			productID:      productToDeleteID.String(),
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockDeleteErr:  products.ErrProductNotFound,
 			expectedStatus: http.StatusNotFound,
@@ -495,7 +736,7 @@ func TestProductHandler_DeleteProduct(t *testing.T) {
 		{
 			name:           "Failure - Repo Delete Error",
 			productID:      productToDeleteID.String(),
-			mockUserReturn: &models.User{ID: testUserID},
+			mockUserReturn: &models.User{ID: testUserID, Role: models.RoleAdmin},
 			mockUserErr:    nil,
 			mockDeleteErr:  errors.New("db delete failed"),
 			expectedStatus: http.StatusInternalServerError,
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/webutils"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SessionHandler lets the authenticated user review and revoke their login sessions.
+type SessionHandler struct {
+	SessionRepo sessions.SessionRepository
+}
+
+// NewSessionHandler creates a new SessionHandler.
+func NewSessionHandler(sessionRepo sessions.SessionRepository) *SessionHandler {
+	return &SessionHandler{
+		SessionRepo: sessionRepo,
+	}
+}
+
+// --- Request/Response Structs ---
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"` // True for the session making the request
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// getCurrentSessionID retrieves the session ID set by the auth middleware.
+func getCurrentSessionID(r *http.Request) uuid.UUID {
+	sessionID, _ := r.Context().Value(auth.SessionIDContextKey).(uuid.UUID)
+	return sessionID
+}
+
+// --- Handlers ---
+
+// ListSessions handles GET /api/users/me/sessions.
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	list, err := h.SessionRepo.ListActiveByUser(r.Context(), userID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to list sessions"), http.StatusInternalServerError)
+		return
+	}
+
+	currentID := getCurrentSessionID(r)
+	resp := make([]SessionResponse, 0, len(list))
+	for _, s := range list {
+		resp = append(resp, newSessionResponse(s, currentID))
+	}
+	webutils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// RevokeSession handles DELETE /api/users/me/sessions/{id}.
+// Revoking the current session logs the caller out.
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid session ID format"), http.StatusBadRequest)
+		return
+	}
+
+	// Sessions of other users are reported as not found
+	session, err := h.SessionRepo.FindByID(r.Context(), sessionID)
+	if err != nil && !errors.Is(err, sessions.ErrSessionNotFound) {
+		webutils.ErrorJSON(w, errors.New("failed to revoke session"), http.StatusInternalServerError)
+		return
+	}
+	if !session.IsActive() || session.UserID != userID {
+		webutils.ErrorJSON(w, sessions.ErrSessionNotFound, http.StatusNotFound)
+		return
+	}
+
+	if err := h.SessionRepo.Revoke(r.Context(), sessionID); err != nil {
+		if errors.Is(err, sessions.ErrSessionNotFound) {
+			webutils.ErrorJSON(w, err, http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to revoke session"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions handles POST /api/users/me/sessions/revoke-others.
+// Every session of the user except the one making the request is revoked.
+func (h *SessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	currentID := getCurrentSessionID(r)
+	if currentID == uuid.Nil {
+		webutils.ErrorJSON(w, errors.New("authentication context error"), http.StatusInternalServerError)
+		return
+	}
+
+	revoked, err := h.SessionRepo.RevokeAllExcept(r.Context(), userID, currentID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to revoke sessions"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, RevokeSessionsResponse{Revoked: revoked})
+}
+
+func newSessionResponse(s models.Session, currentID uuid.UUID) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		Current:    s.ID == currentID,
+	}
+}
@@ -0,0 +1,330 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/sessions"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSessionTest creates a SessionHandler behind the auth middleware for an authenticated user.
+// The caller's current session ID is the user ID (see generateTestToken).
+func setupSessionTest(t *testing.T, userID uuid.UUID) (*sessions.MockSessionRepository, *mux.Router, string) {
+	t.Helper()
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByID", mock.Anything, userID).Return(&models.User{ID: userID, Role: models.RoleCustomer}, nil)
+	mockSessionRepo := new(sessions.MockSessionRepository)
+	h := handlers.NewSessionHandler(mockSessionRepo)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	router.Handle("/api/users/me/sessions", authMiddleware.Authenticate(http.HandlerFunc(h.ListSessions))).Methods("GET")
+	router.Handle("/api/users/me/sessions/revoke-others", authMiddleware.Authenticate(http.HandlerFunc(h.RevokeOtherSessions))).Methods("POST")
+	router.Handle("/api/users/me/sessions/{id}", authMiddleware.Authenticate(http.HandlerFunc(h.RevokeSession))).Methods("DELETE")
+
+	testToken, err := generateTestToken(userID)
+	require.NoError(t, err)
+	return mockSessionRepo, router, testToken
+}
+
+func TestSessionHandler_ListSessions(t *testing.T) {
+	userID := uuid.New()
+	otherID := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("Success", func(t *testing.T) {
+		mockSessionRepo, router, testToken := setupSessionTest(t, userID)
+		mockSessionRepo.On("ListActiveByUser", mock.Anything, userID).Return([]models.Session{
+			{ID: otherID, UserID: userID, UserAgent: "curl/8.0", IPAddress: "198.51.100.7", CreatedAt: now, LastSeenAt: now},
+			{ID: userID, UserID: userID, UserAgent: "Mozilla/5.0", IPAddress: "203.0.113.1", CreatedAt: now, LastSeenAt: now},
+		}, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+
+		var body []handlers.SessionResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Len(t, body, 2)
+		assert.Equal(t, otherID, body[0].ID)
+		assert.False(t, body[0].Current)
+		assert.Equal(t, "curl/8.0", body[0].UserAgent)
+		assert.Equal(t, userID, body[1].ID)
+		assert.True(t, body[1].Current, "The session making the request is flagged")
+
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("No Sessions", func(t *testing.T) {
+		mockSessionRepo, router, testToken := setupSessionTest(t, userID)
+		mockSessionRepo.On("ListActiveByUser", mock.Anything, userID).Return(nil, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusOK, `[]`)
+	})
+
+	t.Run("Repository Error", func(t *testing.T) {
+		mockSessionRepo, router, testToken := setupSessionTest(t, userID)
+		mockSessionRepo.On("ListActiveByUser", mock.Anything, userID).Return(nil, assert.AnError).Once()
+
+		req, _ := http.NewRequest("GET", "/api/users/me/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusInternalServerError, `{"error":"failed to list sessions"}`)
+	})
+}
+
+func TestSessionHandler_RevokeSession(t *testing.T) {
+	userID := uuid.New()
+	targetID := uuid.New()
+	revokedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name           string
+		sessionID      string
+		mockRepo       func(*sessions.MockSessionRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "Success",
+			sessionID: targetID.String(),
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, targetID).Return(&models.Session{ID: targetID, UserID: userID}, nil).Once()
+				repo.On("Revoke", mock.Anything, targetID).Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:      "Another User's Session",
+			sessionID: targetID.String(),
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, targetID).Return(&models.Session{ID: targetID, UserID: uuid.New()}, nil).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"session not found"}`,
+		},
+		{
+			name:      "Already Revoked",
+			sessionID: targetID.String(),
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, targetID).Return(&models.Session{ID: targetID, UserID: userID, RevokedAt: &revokedAt}, nil).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"session not found"}`,
+		},
+		{
+			name:      "Unknown Session",
+			sessionID: targetID.String(),
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, targetID).Return(nil, sessions.ErrSessionNotFound).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"session not found"}`,
+		},
+		{
+			name:      "Revoke Error",
+			sessionID: targetID.String(),
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, targetID).Return(&models.Session{ID: targetID, UserID: userID}, nil).Once()
+				repo.On("Revoke", mock.Anything, targetID).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to revoke session"}`,
+		},
+		{
+			name:           "Invalid Session ID",
+			sessionID:      "not-a-uuid",
+			mockRepo:       func(repo *sessions.MockSessionRepository) { /* Not called */ },
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid session ID format"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockSessionRepo, router, testToken := setupSessionTest(t, userID)
+			tc.mockRepo(mockSessionRepo)
+
+			req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/users/me/sessions/%s", tc.sessionID), nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockSessionRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSessionHandler_RevokeOtherSessions(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("Success Keeps Current Session", func(t *testing.T) {
+		mockSessionRepo, router, testToken := setupSessionTest(t, userID)
+		mockSessionRepo.On("RevokeAllExcept", mock.Anything, userID, userID).Return(int64(3), nil).Once()
+
+		req, _ := http.NewRequest("POST", "/api/users/me/sessions/revoke-others", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusOK, `{"revoked":3}`)
+
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Repository Error", func(t *testing.T) {
+		mockSessionRepo, router, testToken := setupSessionTest(t, userID)
+		mockSessionRepo.On("RevokeAllExcept", mock.Anything, userID, userID).Return(int64(0), assert.AnError).Once()
+
+		req, _ := http.NewRequest("POST", "/api/users/me/sessions/revoke-others", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		executeRequestAndAssert(t, router, req, http.StatusInternalServerError, `{"error":"failed to revoke sessions"}`)
+	})
+}
+
+func TestMiddleware_SessionRevocation(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+	revokedAt := time.Now().Add(-time.Minute)
+	user := &models.User{ID: userID, Role: models.RoleCustomer}
+
+	token, err := auth.GenerateToken(userID, string(models.RoleCustomer), sessionID, testKeys, time.Hour)
+	require.NoError(t, err)
+	noSessionToken, err := auth.GenerateToken(userID, string(models.RoleCustomer), uuid.Nil, testKeys, time.Hour)
+	require.NoError(t, err)
+
+	// newRouter puts a protected route behind a middleware using sessionRepo.
+	newRouter := func(sessionRepo sessions.SessionRepository) *mux.Router {
+		mockUserRepo := new(MockUserRepository)
+		mockUserRepo.On("FindByID", mock.Anything, userID).Return(user, nil).Maybe()
+		authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, sessionRepo, new(apikeys.MockAPIKeyRepository))
+
+		router := mux.NewRouter()
+		router.Handle("/protected", authMiddleware.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, sessionID, r.Context().Value(auth.SessionIDContextKey))
+			w.WriteHeader(http.StatusOK)
+		}))).Methods("GET")
+		return router
+	}
+	newRequest := func(token string) *http.Request {
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		mockRepo       func(*sessions.MockSessionRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "Active Session",
+			token: token,
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, sessionID).Return(&models.Session{ID: sessionID, UserID: userID}, nil).Once()
+				repo.On("Touch", mock.Anything, sessionID).Return(nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "Touch Error Is Not Fatal",
+			token: token,
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, sessionID).Return(&models.Session{ID: sessionID, UserID: userID}, nil).Once()
+				repo.On("Touch", mock.Anything, sessionID).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "Revoked Session",
+			token: token,
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, sessionID).Return(&models.Session{ID: sessionID, UserID: userID, RevokedAt: &revokedAt}, nil).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"session has been revoked"}`,
+		},
+		{
+			name:  "Unknown Session",
+			token: token,
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, sessionID).Return(nil, sessions.ErrSessionNotFound).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"session has been revoked"}`,
+		},
+		{
+			name:  "Session Of Another User",
+			token: token,
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, sessionID).Return(&models.Session{ID: sessionID, UserID: uuid.New()}, nil).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"session has been revoked"}`,
+		},
+		{
+			name:  "Session Lookup Error",
+			token: token,
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, sessionID).Return(nil, assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"error verifying session"}`,
+		},
+		{
+			name:  "Token Without Session",
+			token: noSessionToken,
+			mockRepo: func(repo *sessions.MockSessionRepository) {
+				repo.On("FindByID", mock.Anything, uuid.Nil).Return(nil, sessions.ErrSessionNotFound).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"session has been revoked"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockSessionRepo := new(sessions.MockSessionRepository)
+			tc.mockRepo(mockSessionRepo)
+
+			executeRequestAndAssert(t, newRouter(mockSessionRepo), newRequest(tc.token), tc.expectedStatus, tc.expectedBody)
+
+			mockSessionRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Cached Lookups And Immediate Revocation", func(t *testing.T) {
+		mockSessionRepo := new(sessions.MockSessionRepository)
+		mockSessionRepo.On("FindByID", mock.Anything, sessionID).Return(&models.Session{ID: sessionID, UserID: userID}, nil).Once()
+		mockSessionRepo.On("Touch", mock.Anything, sessionID).Return(nil).Once()
+		mockSessionRepo.On("Revoke", mock.Anything, sessionID).Return(nil).Once()
+		cached := sessions.NewCachedRepository(mockSessionRepo, time.Hour)
+		router := newRouter(cached)
+
+		// Repeated requests hit the database once
+		for i := 0; i < 3; i++ {
+			executeRequestAndAssert(t, router, newRequest(token), http.StatusOK, "")
+		}
+
+		// Revoking through the cache drops the entry, so the next request sees the revocation
+		require.NoError(t, cached.Revoke(context.Background(), sessionID))
+		mockSessionRepo.On("FindByID", mock.Anything, sessionID).Return(&models.Session{ID: sessionID, UserID: userID, RevokedAt: &revokedAt}, nil).Once()
+		executeRequestAndAssert(t, router, newRequest(token), http.StatusUnauthorized, `{"error":"session has been revoked"}`)
+
+		mockSessionRepo.AssertExpectations(t)
+	})
+}
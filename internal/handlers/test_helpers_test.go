@@ -2,9 +2,15 @@ package handlers_test
 
 import (
 	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/lockout"
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
 	"bullet-cloud-api/internal/products"
+	"bullet-cloud-api/internal/sessions"
+	"bullet-cloud-api/internal/users"
+	"bullet-cloud-api/internal/variants"
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -22,6 +28,27 @@ import (
 // Define a consistent JWT secret for testing
 const testJwtSecret = "um-segredo-super-secreto-para-testes-123"
 
+// testKeys signs and verifies access tokens in tests with the default HS256 algorithm
+var testKeys = auth.NewHMACKeySet(testJwtSecret)
+
+// testPasswordPolicy applies the default rules, with a tiny breached password list
+var testPasswordPolicy = &auth.PasswordPolicy{
+	MinLength:      8,
+	MinCharClasses: 2,
+	Breached:       auth.NewBreachedPasswordList("breached-password-1"),
+}
+
+// Login lockout policy used by the auth handler tests
+const (
+	testMaxLoginAttempts = 3
+	testLockoutDuration  = 30 * time.Minute
+)
+
+// newTestLoginLimiter returns a login limiter backed by an empty in-memory store.
+func newTestLoginLimiter() *lockout.Limiter {
+	return lockout.NewLimiter(lockout.NewMemoryAttemptStore(), testMaxLoginAttempts, testLockoutDuration)
+}
+
 // --- Mock Repositories (Generated by mockery or similar) ---
 
 // MockUserRepository is a mock implementation of UserRepository
@@ -50,18 +77,56 @@ func (m *MockUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*model
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockUserRepository) Update(ctx context.Context, id uuid.UUID, name, email string) (*models.User, error) {
+	args := m.Called(ctx, id, name, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+func (m *MockUserRepository) Anonymize(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockUserRepository) List(ctx context.Context, filter users.ListFilter) ([]models.User, int, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]models.User), args.Int(1), args.Error(2)
+}
+func (m *MockUserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role models.UserRole) error {
+	args := m.Called(ctx, id, role)
+	return args.Error(0)
+}
+func (m *MockUserRepository) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	args := m.Called(ctx, id, disabled)
+	return args.Error(0)
+}
+func (m *MockUserRepository) RequirePasswordReset(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
 
 // MockProductRepository is a mock implementation of ProductRepository
 type MockProductRepository struct {
 	mock.Mock
 }
 
-func (m *MockProductRepository) FindAll(ctx context.Context) ([]models.Product, error) {
-	args := m.Called(ctx)
+func (m *MockProductRepository) FindAll(ctx context.Context, opts products.ListOptions) (*products.Page, error) {
+	args := m.Called(ctx, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]models.Product), args.Error(1)
+	return args.Get(0).(*products.Page), args.Error(1)
 }
 func (m *MockProductRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
 	args := m.Called(ctx, id)
@@ -88,12 +153,26 @@ func (m *MockProductRepository) Delete(ctx context.Context, id uuid.UUID) error
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
-func (m *MockProductRepository) Search(ctx context.Context, query string) ([]models.Product, error) {
-	args := m.Called(ctx, query)
+func (m *MockProductRepository) Search(ctx context.Context, query string, opts products.ListOptions) (*products.Page, error) {
+	args := m.Called(ctx, query, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]models.Product), args.Error(1)
+	return args.Get(0).(*products.Page), args.Error(1)
+}
+func (m *MockProductRepository) SearchSimilar(ctx context.Context, query string, opts products.ListOptions) (*products.Page, error) {
+	args := m.Called(ctx, query, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*products.Page), args.Error(1)
+}
+func (m *MockProductRepository) Suggest(ctx context.Context, prefix string, limit int) ([]products.Suggestion, error) {
+	args := m.Called(ctx, prefix, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]products.Suggestion), args.Error(1)
 }
 func (m *MockProductRepository) FindByCategoryID(ctx context.Context, categoryID uuid.UUID) ([]models.Product, error) {
 	args := m.Called(ctx, categoryID)
@@ -189,6 +268,13 @@ func (m *MockCartRepository) GetOrCreateCartByUserID(ctx context.Context, userID
 	}
 	return args.Get(0).(*models.Cart), args.Error(1)
 }
+func (m *MockCartRepository) FindCartByUserID(ctx context.Context, userID uuid.UUID) (*models.Cart, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Cart), args.Error(1)
+}
 func (m *MockCartRepository) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]models.CartItem, error) {
 	args := m.Called(ctx, cartID)
 	if args.Get(0) == nil {
@@ -196,26 +282,26 @@ func (m *MockCartRepository) GetCartItems(ctx context.Context, cartID uuid.UUID)
 	}
 	return args.Get(0).([]models.CartItem), args.Error(1)
 }
-func (m *MockCartRepository) AddItem(ctx context.Context, cartID, productID uuid.UUID, quantity int, price float64) (*models.CartItem, error) {
-	args := m.Called(ctx, cartID, productID, quantity, price)
+func (m *MockCartRepository) AddItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int, price money.Money) (*models.CartItem, error) {
+	args := m.Called(ctx, cartID, productID, variantID, quantity, price)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.CartItem), args.Error(1)
 }
-func (m *MockCartRepository) UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, quantity int) (*models.CartItem, error) {
-	args := m.Called(ctx, cartID, productID, quantity)
+func (m *MockCartRepository) UpdateItemQuantity(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int) (*models.CartItem, error) {
+	args := m.Called(ctx, cartID, productID, variantID, quantity)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.CartItem), args.Error(1)
 }
-func (m *MockCartRepository) RemoveItem(ctx context.Context, cartID, productID uuid.UUID) error {
-	args := m.Called(ctx, cartID, productID)
+func (m *MockCartRepository) RemoveItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID) error {
+	args := m.Called(ctx, cartID, productID, variantID)
 	return args.Error(0)
 }
-func (m *MockCartRepository) FindCartItem(ctx context.Context, cartID, productID uuid.UUID) (*models.CartItem, error) {
-	args := m.Called(ctx, cartID, productID)
+func (m *MockCartRepository) FindCartItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID) (*models.CartItem, error) {
+	args := m.Called(ctx, cartID, productID, variantID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -243,6 +329,47 @@ func (m *MockPasswordHasher) CheckPassword(hashedPassword, password string) erro
 	return args.Error(0)
 }
 
+// NeedsRehash mocks the NeedsRehash method from the PasswordHasher interface.
+func (m *MockPasswordHasher) NeedsRehash(hashedPassword string) bool {
+	args := m.Called(hashedPassword)
+	return args.Bool(0)
+}
+
+// MockVerificationSender is a mock implementation of VerificationSender
+type MockVerificationSender struct {
+	mock.Mock
+}
+
+func (m *MockVerificationSender) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockVerificationSender) SendEmailChangedNotice(ctx context.Context, user *models.User, previousEmail string) error {
+	args := m.Called(ctx, user, previousEmail)
+	return args.Error(0)
+}
+
+// MockPasswordResetSender is a mock implementation of PasswordResetSender
+type MockPasswordResetSender struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetSender) SendPasswordResetLink(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+// MockExportDeleter is a mock implementation of ExportDeleter
+type MockExportDeleter struct {
+	mock.Mock
+}
+
+func (m *MockExportDeleter) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 // --- Test Helpers ---
 
 // setupBaseTest initializes common components for handler tests.
@@ -261,7 +388,7 @@ func setupBaseTest(t *testing.T) (context.Context, *httptest.ResponseRecorder, *
 
 	// Generate a valid test token using the test secret
 	testUserID := uuid.New()
-	testToken, err := auth.GenerateToken(testUserID, testJwtSecret, time.Hour*1) // Use test constant
+	testToken, err := generateTestToken(testUserID)
 	require.NoError(t, err, "Failed to generate test token")
 
 	// Mock FindByID for authentication middleware success
@@ -270,10 +397,32 @@ func setupBaseTest(t *testing.T) (context.Context, *httptest.ResponseRecorder, *
 	return ctx, rr, router, mockUserRepo, mockProductRepo, mockCategoryRepo, mockAddressRepo, mockCartRepo, testToken
 }
 
-// Helper function to generate a test token for a specific user ID
+// Helper function to generate a test token for a specific user ID.
+// The token's session ID is the user ID itself, which is what newTestSessionRepo expects.
 func generateTestToken(userID uuid.UUID) (string, error) {
 	// Use test constant for secret and a default expiry
-	return auth.GenerateToken(userID, testJwtSecret, time.Hour*1)
+	return auth.GenerateToken(userID, string(models.RoleCustomer), userID, testKeys, time.Hour*1)
+}
+
+// createdSession can be passed to MockSessionRepository's Create Return to echo the session back
+// with an ID assigned, as the database would.
+func createdSession(_ context.Context, session *models.Session) *models.Session {
+	created := *session
+	created.ID = uuid.New()
+	created.CreatedAt = time.Now()
+	created.LastSeenAt = created.CreatedAt
+	return &created
+}
+
+// newTestSessionRepo returns a session repository for middleware tests in which every session
+// is active and belongs to the user whose ID equals the session ID (see generateTestToken).
+func newTestSessionRepo() *sessions.MockSessionRepository {
+	repo := new(sessions.MockSessionRepository)
+	repo.On("FindByID", mock.Anything, mock.Anything).Return(func(_ context.Context, id uuid.UUID) *models.Session {
+		return &models.Session{ID: id, UserID: id}
+	}, nil).Maybe()
+	repo.On("Touch", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return repo
 }
 
 // executeRequestAndAssert executes an HTTP request and asserts the expected status code and body.
@@ -360,44 +509,62 @@ func mockFindProductError(m *MockProductRepository, productID uuid.UUID) {
 	m.On("FindByID", mock.Anything, productID).Return(nil, assert.AnError).Once()
 }
 
+// brl returns an amount in the store currency, e.g. brl("10.50").
+func brl(amount string) money.Money {
+	return money.MustParse(amount, money.DefaultCurrency)
+}
+
+// moneyJSON returns the JSON of an amount, for expected response bodies.
+func moneyJSON(m money.Money) string {
+	return fmt.Sprintf(`{"amount":"%s","currency":"%s"}`, m, m.Currency)
+}
+
+// noVariant is the variant ID of cart items of products not sold in variants.
+var noVariant = (*uuid.UUID)(nil)
+
+// Mocks FindByProductID for a product not sold in variants
+func mockNoVariants(m *variants.MockVariantRepository, productID uuid.UUID) {
+	m.On("FindByProductID", mock.Anything, productID).Return([]models.ProductVariant{}, nil).Once()
+}
+
 // Mocks successful AddItem call
-func mockAddItemSuccess(m *MockCartRepository, cartID, productID uuid.UUID, quantity int, price float64, itemToReturn *models.CartItem) {
-	m.On("AddItem", mock.Anything, cartID, productID, quantity, price).Return(itemToReturn, nil).Once()
+func mockAddItemSuccess(m *MockCartRepository, cartID, productID uuid.UUID, quantity int, price money.Money, itemToReturn *models.CartItem) {
+	m.On("AddItem", mock.Anything, cartID, productID, noVariant, quantity, price).Return(itemToReturn, nil).Once()
 }
 
 // Mocks failed AddItem call
-func mockAddItemError(m *MockCartRepository, cartID, productID uuid.UUID, quantity int, price float64) {
-	m.On("AddItem", mock.Anything, cartID, productID, quantity, price).Return(nil, assert.AnError).Once()
+func mockAddItemError(m *MockCartRepository, cartID, productID uuid.UUID, quantity int, price money.Money) {
+	m.On("AddItem", mock.Anything, cartID, productID, noVariant, quantity, price).Return(nil, assert.AnError).Once()
 }
 
 // Mocks successful RemoveItem call
 func mockRemoveItemSuccess(m *MockCartRepository, cartID, productID uuid.UUID) {
-	m.On("RemoveItem", mock.Anything, cartID, productID).Return(nil).Once()
+	m.On("RemoveItem", mock.Anything, cartID, productID, noVariant).Return(nil).Once()
 }
 
 // Mocks RemoveItem call returning Not Found (or similar logical error)
 func mockRemoveItemNotFound(m *MockCartRepository, cartID, productID uuid.UUID) {
-	m.On("RemoveItem", mock.Anything, cartID, productID).Return(assert.AnError).Once()
+	m.On("RemoveItem", mock.Anything, cartID, productID, noVariant).Return(assert.AnError).Once()
 }
 
 // Mocks failed RemoveItem call
 func mockRemoveItemError(m *MockCartRepository, cartID, productID uuid.UUID) {
-	m.On("RemoveItem", mock.Anything, cartID, productID).Return(assert.AnError).Once()
+	m.On("RemoveItem", mock.Anything, cartID, productID, noVariant).Return(assert.AnError).Once()
 }
 
 // Mocks successful UpdateItemQuantity call
 func mockUpdateItemQuantitySuccess(m *MockCartRepository, cartID, productID uuid.UUID, quantity int, itemToReturn *models.CartItem) {
-	m.On("UpdateItemQuantity", mock.Anything, cartID, productID, quantity).Return(itemToReturn, nil).Once()
+	m.On("UpdateItemQuantity", mock.Anything, cartID, productID, noVariant, quantity).Return(itemToReturn, nil).Once()
 }
 
 // Mocks UpdateItemQuantity call returning Not Found
 func mockUpdateItemQuantityNotFound(m *MockCartRepository, cartID, productID uuid.UUID, quantity int) {
-	m.On("UpdateItemQuantity", mock.Anything, cartID, productID, quantity).Return(nil, assert.AnError).Once()
+	m.On("UpdateItemQuantity", mock.Anything, cartID, productID, noVariant, quantity).Return(nil, assert.AnError).Once()
 }
 
 // Mocks failed UpdateItemQuantity call
 func mockUpdateItemQuantityError(m *MockCartRepository, cartID, productID uuid.UUID, quantity int) {
-	m.On("UpdateItemQuantity", mock.Anything, cartID, productID, quantity).Return(nil, assert.AnError).Once()
+	m.On("UpdateItemQuantity", mock.Anything, cartID, productID, noVariant, quantity).Return(nil, assert.AnError).Once()
 }
 
 // Mocks successful ClearCart call
@@ -3,13 +3,16 @@ package handlers
 import (
 	"bullet-cloud-api/internal/addresses"
 	"bullet-cloud-api/internal/auth" // For UserIDContextKey
+	"bullet-cloud-api/internal/lockout"
 	"bullet-cloud-api/internal/models"
 	// For User model
+	"bullet-cloud-api/internal/sessions"
 	"bullet-cloud-api/internal/users"    // For UserRepository
 	"bullet-cloud-api/internal/webutils" // For JSON helpers
 	"errors"
 	"log" // Adicionado para log
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux" // Adicionado
@@ -17,15 +20,27 @@ import (
 
 // UserHandler handles user-related requests, including addresses.
 type UserHandler struct {
-	UserRepo    users.UserRepository
-	AddressRepo addresses.AddressRepository // Adicionado
+	UserRepo       users.UserRepository
+	AddressRepo    addresses.AddressRepository // Adicionado
+	SessionRepo    sessions.SessionRepository  // Other sessions are revoked when the password changes
+	Hasher         auth.PasswordHasher
+	PasswordPolicy *auth.PasswordPolicy // Rules for the new password
+	Verifier       VerificationSender   // Sends the verification link and the change notice when the email changes
+	LoginLimiter   *lockout.Limiter     // Wrong current passwords count as failed logins
+	Exports        ExportDeleter        // The account's data exports are deleted with it
 }
 
 // NewUserHandler creates a new UserHandler.
-func NewUserHandler(userRepo users.UserRepository, addressRepo addresses.AddressRepository) *UserHandler { // Adicionado addressRepo
+func NewUserHandler(userRepo users.UserRepository, addressRepo addresses.AddressRepository, sessionRepo sessions.SessionRepository, hasher auth.PasswordHasher, passwordPolicy *auth.PasswordPolicy, verifier VerificationSender, loginLimiter *lockout.Limiter, exportDeleter ExportDeleter) *UserHandler { // Adicionado addressRepo
 	return &UserHandler{
-		UserRepo:    userRepo,
-		AddressRepo: addressRepo, // Adicionado
+		UserRepo:       userRepo,
+		AddressRepo:    addressRepo, // Adicionado
+		SessionRepo:    sessionRepo,
+		Hasher:         hasher,
+		PasswordPolicy: passwordPolicy,
+		Verifier:       verifier,
+		LoginLimiter:   loginLimiter,
+		Exports:        exportDeleter,
 	}
 }
 
@@ -41,6 +56,12 @@ func getAuthenticatedUserID(r *http.Request) (uuid.UUID, error) {
 	return userID, nil
 }
 
+// isAdmin reports whether the authenticated user has the admin role.
+func isAdmin(r *http.Request) bool {
+	role, _ := r.Context().Value(auth.UserRoleContextKey).(models.UserRole)
+	return role == models.RoleAdmin
+}
+
 // Helper function to check if the authenticated user matches the user ID in the URL
 // Returns the target user ID if authorized, otherwise writes an error and returns Nil UUID.
 func checkUserAuthorization(w http.ResponseWriter, r *http.Request, targetUserIDStr string) (uuid.UUID, bool) {
@@ -56,9 +77,8 @@ func checkUserAuthorization(w http.ResponseWriter, r *http.Request, targetUserID
 		return uuid.Nil, false
 	}
 
-	// For now, only allow users to access their own data
-	// TODO: Implement admin role check here if needed for accessing other users' data
-	if authUserID != targetUserID {
+	// Users may only access their own data; admins may access anyone's
+	if authUserID != targetUserID && !isAdmin(r) {
 		webutils.ErrorJSON(w, errors.New("forbidden"), http.StatusForbidden)
 		return uuid.Nil, false
 	}
@@ -285,4 +305,221 @@ func (h *UserHandler) SetDefaultAddress(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK) // Return 200 OK on success
 }
 
-// TODO: Implement handler for UpdateUser (PUT /api/users/{id})
+// --- Account Handlers ---
+
+// UpdateMeRequest holds the profile fields to change; omitted fields are left as they are.
+type UpdateMeRequest struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	CurrentPassword string  `json:"current_password"` // Required to change the email
+}
+
+// ChangePasswordRequest is the body of POST /api/users/me/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// DeleteMeRequest is the body of DELETE /api/users/me.
+type DeleteMeRequest struct {
+	Password string `json:"password"`
+}
+
+// UpdateMe handles PATCH /api/users/me.
+// Changing the email requires the current password. The new address must be verified again,
+// so a verification link is sent to it, and the previous address is told of the change.
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	authUserID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateMeRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(r.Context(), authUserID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("user not found"), http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to update user"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	name, email := user.Name, user.Email
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+		if name == "" {
+			webutils.ErrorJSON(w, errors.New("name cannot be empty"), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Email != nil {
+		email = strings.TrimSpace(strings.ToLower(*req.Email))
+		if !isValidEmail(email) {
+			webutils.ErrorJSON(w, errors.New("invalid email address"), http.StatusBadRequest)
+			return
+		}
+	}
+	emailChanged := email != user.Email
+	if emailChanged {
+		// A stolen session must not be enough to take the account over through a reset to a new address
+		if req.CurrentPassword == "" {
+			webutils.ErrorJSON(w, errors.New("current_password is required to change the email"), http.StatusBadRequest)
+			return
+		}
+		if _, ok := h.checkCurrentPassword(w, r, authUserID, req.CurrentPassword, "failed to update user"); !ok {
+			return
+		}
+	}
+
+	updatedUser, err := h.UserRepo.Update(r.Context(), authUserID, name, email)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrEmailAlreadyExists):
+			webutils.ErrorJSON(w, errors.New("email already registered"), http.StatusConflict)
+		case errors.Is(err, users.ErrUserNotFound):
+			webutils.ErrorJSON(w, errors.New("user not found"), http.StatusNotFound)
+		default:
+			webutils.ErrorJSON(w, errors.New("failed to update user"), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if emailChanged {
+		if err := h.Verifier.SendVerificationEmail(r.Context(), updatedUser); err != nil {
+			log.Printf("Error sending verification email: %v", err)
+		}
+		if err := h.Verifier.SendEmailChangedNotice(r.Context(), updatedUser, user.Email); err != nil {
+			log.Printf("Error sending email change notice: %v", err)
+		}
+	}
+
+	updatedUser.PasswordHash = ""
+	webutils.WriteJSON(w, http.StatusOK, updatedUser)
+}
+
+// ChangePassword handles POST /api/users/me/password.
+// The current password is required; every other session of the user is signed out.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	authUserID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		webutils.ErrorJSON(w, errors.New("current_password and new_password are required"), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.checkCurrentPassword(w, r, authUserID, req.CurrentPassword, "failed to change password")
+	if !ok {
+		return
+	}
+
+	if violations := h.PasswordPolicy.Validate(req.NewPassword, user.Name, user.Email); len(violations) > 0 {
+		webutils.ValidationErrorJSON(w, passwordFieldErrors("new_password", violations))
+		return
+	}
+
+	hashedPassword, err := h.Hasher.HashPassword(req.NewPassword)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to change password"), http.StatusInternalServerError)
+		return
+	}
+	if err := h.UserRepo.UpdatePassword(r.Context(), authUserID, hashedPassword); err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to change password"), http.StatusInternalServerError)
+		return
+	}
+
+	// Keep the session that made the change; anyone else who knew the old password is signed out
+	if _, err := h.SessionRepo.RevokeAllExcept(r.Context(), authUserID, getCurrentSessionID(r)); err != nil {
+		log.Printf("Error revoking sessions after password change: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteMe handles DELETE /api/users/me.
+// The account's personal data is erased and all its sessions end; past orders are kept anonymized.
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	authUserID, err := getAuthenticatedUserID(r)
+	if err != nil {
+		webutils.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var req DeleteMeRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+	if req.Password == "" {
+		webutils.ErrorJSON(w, errors.New("password is required"), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.checkCurrentPassword(w, r, authUserID, req.Password, "failed to delete account"); !ok {
+		return
+	}
+
+	// Sessions and refresh tokens are deleted along with the account's other credentials
+	if err := h.UserRepo.Anonymize(r.Context(), authUserID); err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("user not found"), http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to delete account"), http.StatusInternalServerError)
+		}
+		return
+	}
+	// The export files hold everything erased above; the cleanup job retries if this fails
+	if err := h.Exports.DeleteForUser(r.Context(), authUserID); err != nil {
+		log.Printf("Error deleting data exports of deleted user %s: %v", authUserID, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkCurrentPassword loads the user and confirms the password they entered.
+// Wrong passwords are recorded against the login limiter, so a stolen session cannot be
+// used to guess the password. On failure it writes the response, using failureMessage for unexpected errors.
+func (h *UserHandler) checkCurrentPassword(w http.ResponseWriter, r *http.Request, userID uuid.UUID, password, failureMessage string) (*models.User, bool) {
+	user, err := h.UserRepo.FindByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			webutils.ErrorJSON(w, errors.New("user not found"), http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New(failureMessage), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	ip := clientIP(r)
+	if err := h.LoginLimiter.Check(r.Context(), user.Email, ip); err != nil {
+		respondLoginBlocked(w, err)
+		return nil, false
+	}
+
+	if err := h.Hasher.CheckPassword(user.PasswordHash, password); err != nil {
+		credentialFailed(w, r, h.LoginLimiter, user.Email, ip, errors.New("current password is incorrect"), http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// isValidEmail performs a minimal syntax check; ownership is proven by the verification link.
+func isValidEmail(email string) bool {
+	local, domain, found := strings.Cut(email, "@")
+	return found && local != "" && strings.Contains(domain, ".") && !strings.ContainsAny(email, " \t\r\n")
+}
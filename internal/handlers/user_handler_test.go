@@ -2,8 +2,10 @@ package handlers_test
 
 import (
 	"bullet-cloud-api/internal/addresses"
+	"bullet-cloud-api/internal/apikeys"
 	"bullet-cloud-api/internal/handlers"
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/sessions"
 	"bullet-cloud-api/internal/users"
 	"bytes"
 	"encoding/json"
@@ -26,10 +28,10 @@ func setupUserHandlerTest(t *testing.T) (*MockUserRepository, *MockAddressReposi
 	t.Helper()
 	_, _, router, mockUserRepo, _, _, mockAddressRepo, _, _ := setupBaseTest(t)
 
-	userHandler := handlers.NewUserHandler(mockUserRepo, mockAddressRepo)
+	userHandler := handlers.NewUserHandler(mockUserRepo, mockAddressRepo, nil, nil, nil, nil, nil, nil)
 
 	// Need to instantiate authMiddleware here as it's used for route protection
-	authMiddleware := auth.NewMiddleware(testJwtSecret, mockUserRepo)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
 
 	apiV1 := router.PathPrefix("/api").Subrouter()
 	userRoutes := apiV1.PathPrefix("/users").Subrouter()
@@ -51,7 +53,7 @@ func TestUserHandler_GetMe(t *testing.T) {
 	testUserID := uuid.New()
 	testToken, err := generateTestToken(testUserID)
 	require.NoError(t, err, "Failed to generate test token")
-	foundUser := &models.User{ID: testUserID, Name: "Test User", Email: "test@example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	foundUser := &models.User{ID: testUserID, Name: "Test User", Email: "test@example.com", Role: models.RoleCustomer, CreatedAt: time.Now(), UpdatedAt: time.Now()}
 
 	tests := []struct {
 		name              string
@@ -69,7 +71,7 @@ func TestUserHandler_GetMe(t *testing.T) {
 			mockUserReturnHnd: foundUser,
 			mockUserErrHnd:    nil,
 			expectedStatus:    http.StatusOK,
-			expectedBody:      fmt.Sprintf(`{"id":"%s","name":"%s","email":"%s","created_at":"%s","updated_at":"%s"}`, foundUser.ID, foundUser.Name, foundUser.Email, foundUser.CreatedAt.Format(time.RFC3339Nano), foundUser.UpdatedAt.Format(time.RFC3339Nano)),
+			expectedBody:      fmt.Sprintf(`{"id":"%s","name":"%s","email":"%s","role":"%s","email_verified_at":null,"created_at":"%s","updated_at":"%s"}`, foundUser.ID, foundUser.Name, foundUser.Email, foundUser.Role, foundUser.CreatedAt.Format(time.RFC3339Nano), foundUser.UpdatedAt.Format(time.RFC3339Nano)),
 		},
 		{
 			name:              "Failure - Handler Repo Error",
@@ -187,6 +189,16 @@ func TestUserHandler_ListAddresses(t *testing.T) {
 			expectedStatus:    http.StatusForbidden,
 			expectedBody:      `{"error":"forbidden"}`,
 		},
+		{
+			name:              "Success - Admin Lists Another User's Addresses",
+			targetUserIDStr:   anotherUserID.String(),
+			mockUserReturnMid: &models.User{ID: testUserID, Role: models.RoleAdmin},
+			mockUserErrMid:    nil,
+			mockAddrReturn:    []models.Address{},
+			mockAddrErr:       nil,
+			expectedStatus:    http.StatusOK,
+			expectedBody:      `[]`,
+		},
 		{
 			name:              "Failure - Invalid User ID in URL",
 			targetUserIDStr:   "not-a-uuid",
@@ -230,7 +242,7 @@ func TestUserHandler_ListAddresses(t *testing.T) {
 
 			if tc.mockUserErrMid == nil && tc.expectedStatus == http.StatusOK || (tc.expectedStatus == http.StatusInternalServerError && tc.mockAddrErr != nil) {
 				targetUUID, err := uuid.Parse(tc.targetUserIDStr)
-				if err == nil && targetUUID == testUserID {
+				if err == nil {
 					mockAddressRepo.On("FindByUserID", mock.Anything, targetUUID).Return(tc.mockAddrReturn, tc.mockAddrErr).Once()
 				}
 			}
@@ -942,3 +954,365 @@ func TestUserHandler_SetDefaultAddress(t *testing.T) {
 		})
 	}
 }
+
+// setupAccountHandlerTest wires the account self-service routes behind the auth middleware.
+// The token's session is testUserID itself (see generateTestToken).
+func setupAccountHandlerTest(t *testing.T) (*MockUserRepository, *sessions.MockSessionRepository, *MockPasswordHasher, *MockVerificationSender, *MockExportDeleter, *mux.Router) {
+	t.Helper()
+	mockUserRepo := new(MockUserRepository)
+	mockSessionRepo := newTestSessionRepo()
+	mockHasher := new(MockPasswordHasher)
+	mockVerifier := new(MockVerificationSender)
+	mockExportDeleter := new(MockExportDeleter)
+
+	userHandler := handlers.NewUserHandler(mockUserRepo, new(MockAddressRepository), mockSessionRepo, mockHasher, testPasswordPolicy, mockVerifier, newTestLoginLimiter(), mockExportDeleter)
+	authMiddleware := auth.NewMiddleware(testKeys, mockUserRepo, mockSessionRepo, new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	accountRoutes := router.PathPrefix("/api/users/me").Subrouter()
+	accountRoutes.Use(authMiddleware.Authenticate, authMiddleware.DenyAPIKeys)
+	accountRoutes.HandleFunc("", userHandler.UpdateMe).Methods("PATCH")
+	accountRoutes.HandleFunc("", userHandler.DeleteMe).Methods("DELETE")
+	accountRoutes.HandleFunc("/password", userHandler.ChangePassword).Methods("POST")
+
+	return mockUserRepo, mockSessionRepo, mockHasher, mockVerifier, mockExportDeleter, router
+}
+
+func TestUserHandler_UpdateMe(t *testing.T) {
+	testUserID := uuid.New()
+	testToken, err := generateTestToken(testUserID)
+	require.NoError(t, err, "Failed to generate test token")
+	verifiedAt := time.Now().Add(-time.Hour)
+	now := time.Now()
+	currentUser := &models.User{ID: testUserID, Name: "Test User", Email: "test@example.com", PasswordHash: "hash", Role: models.RoleCustomer, EmailVerifiedAt: &verifiedAt, CreatedAt: now, UpdatedAt: now}
+	userJSON := func(name, email string, verified bool) string {
+		verifiedJSON := "null"
+		if verified {
+			verifiedJSON = fmt.Sprintf("%q", verifiedAt.Format(time.RFC3339Nano))
+		}
+		return fmt.Sprintf(`{"id":"%s","name":"%s","email":"%s","role":"customer","email_verified_at":%s,"created_at":"%s","updated_at":"%s"}`,
+			testUserID, name, email, verifiedJSON, now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success - Name Only Keeps Verification",
+			body: `{"name":"  New Name  "}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {
+				updated := *currentUser
+				updated.Name = "New Name"
+				userRepo.On("Update", mock.Anything, testUserID, "New Name", "test@example.com").Return(&updated, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   userJSON("New Name", "test@example.com", true),
+		},
+		{
+			name: "Success - Email Change Requires Verification",
+			body: `{"email":"New@Example.com","current_password":"secret"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {
+				updated := *currentUser
+				updated.Email = "new@example.com"
+				updated.EmailVerifiedAt = nil
+				hasher.On("CheckPassword", "hash", "secret").Return(nil).Once()
+				userRepo.On("Update", mock.Anything, testUserID, "Test User", "new@example.com").Return(&updated, nil).Once()
+				verifier.On("SendVerificationEmail", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.Email == "new@example.com" })).Return(nil).Once()
+				// The previous address is told, in case someone else made the change
+				verifier.On("SendEmailChangedNotice", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.Email == "new@example.com" }), "test@example.com").Return(nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   userJSON("Test User", "new@example.com", false),
+		},
+		{
+			name: "Success - Email Failures Are Not Fatal",
+			body: `{"email":"new@example.com","current_password":"secret"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {
+				updated := *currentUser
+				updated.Email = "new@example.com"
+				updated.EmailVerifiedAt = nil
+				hasher.On("CheckPassword", "hash", "secret").Return(nil).Once()
+				userRepo.On("Update", mock.Anything, testUserID, "Test User", "new@example.com").Return(&updated, nil).Once()
+				verifier.On("SendVerificationEmail", mock.Anything, mock.Anything).Return(assert.AnError).Once()
+				verifier.On("SendEmailChangedNotice", mock.Anything, mock.Anything, "test@example.com").Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   userJSON("Test User", "new@example.com", false),
+		},
+		{
+			name: "Success - Same Email Needs No Password",
+			body: `{"email":"Test@Example.com"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {
+				unchanged := *currentUser
+				userRepo.On("Update", mock.Anything, testUserID, "Test User", "test@example.com").Return(&unchanged, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   userJSON("Test User", "test@example.com", true),
+		},
+		{
+			name:           "Failure - Email Change Without Password",
+			body:           `{"email":"new@example.com"}`,
+			mockSetup:      func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"current_password is required to change the email"}`,
+		},
+		{
+			name: "Failure - Email Change With Wrong Password",
+			body: `{"email":"new@example.com","current_password":"wrong"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {
+				hasher.On("CheckPassword", "hash", "wrong").Return(auth.ErrPasswordMismatch).Once()
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"current password is incorrect"}`,
+		},
+		{
+			name: "Failure - Email Taken",
+			body: `{"email":"taken@example.com","current_password":"secret"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {
+				hasher.On("CheckPassword", "hash", "secret").Return(nil).Once()
+				userRepo.On("Update", mock.Anything, testUserID, "Test User", "taken@example.com").Return(nil, users.ErrEmailAlreadyExists).Once()
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"email already registered"}`,
+		},
+		{
+			name:           "Failure - Empty Name",
+			body:           `{"name":"   "}`,
+			mockSetup:      func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"name cannot be empty"}`,
+		},
+		{
+			name:           "Failure - Invalid Email",
+			body:           `{"email":"not-an-email"}`,
+			mockSetup:      func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid email address"}`,
+		},
+		{
+			name:           "Failure - Invalid Body",
+			body:           `{"name":`,
+			mockSetup:      func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid request body"}`,
+		},
+		{
+			name: "Failure - Repo Error",
+			body: `{"name":"New Name"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, verifier *MockVerificationSender) {
+				userRepo.On("Update", mock.Anything, testUserID, "New Name", "test@example.com").Return(nil, assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to update user"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo, _, mockHasher, mockVerifier, _, router := setupAccountHandlerTest(t)
+			// Looked up by the middleware, and again by the handler once the body is valid
+			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(currentUser, nil)
+			tc.mockSetup(mockUserRepo, mockHasher, mockVerifier)
+
+			req, _ := http.NewRequest(http.MethodPatch, "/api/users/me", bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockUserRepo.AssertExpectations(t)
+			mockHasher.AssertExpectations(t)
+			mockVerifier.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	testUserID := uuid.New()
+	testToken, err := generateTestToken(testUserID)
+	require.NoError(t, err, "Failed to generate test token")
+	currentUser := &models.User{ID: testUserID, Name: "Test User", Email: "test@example.com", PasswordHash: "old-hash"}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(userRepo *MockUserRepository, sessionRepo *sessions.MockSessionRepository, hasher *MockPasswordHasher)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success - Other Sessions Revoked",
+			body: `{"current_password":"Old-password1","new_password":"New-password2"}`,
+			mockSetup: func(userRepo *MockUserRepository, sessionRepo *sessions.MockSessionRepository, hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", "old-hash", "Old-password1").Return(nil).Once()
+				hasher.On("HashPassword", "New-password2").Return("new-hash", nil).Once()
+				userRepo.On("UpdatePassword", mock.Anything, testUserID, "new-hash").Return(nil).Once()
+				// The current session (testUserID, see generateTestToken) is kept
+				sessionRepo.On("RevokeAllExcept", mock.Anything, testUserID, testUserID).Return(int64(2), nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Failure - Wrong Current Password",
+			body: `{"current_password":"wrong","new_password":"New-password2"}`,
+			mockSetup: func(userRepo *MockUserRepository, sessionRepo *sessions.MockSessionRepository, hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", "old-hash", "wrong").Return(auth.ErrPasswordMismatch).Once()
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"current password is incorrect"}`,
+		},
+		{
+			name: "Failure - New Password Violates Policy",
+			body: `{"current_password":"Old-password1","new_password":"breached-password-1"}`,
+			mockSetup: func(userRepo *MockUserRepository, sessionRepo *sessions.MockSessionRepository, hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", "old-hash", "Old-password1").Return(nil).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"validation failed","fields":[{"field":"new_password","code":"breached","message":"password is too common or has appeared in a data breach"}]}`,
+		},
+		{
+			name: "Failure - Missing Fields",
+			body: `{"current_password":"Old-password1"}`,
+			mockSetup: func(userRepo *MockUserRepository, sessionRepo *sessions.MockSessionRepository, hasher *MockPasswordHasher) {
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"current_password and new_password are required"}`,
+		},
+		{
+			name: "Failure - Update Error",
+			body: `{"current_password":"Old-password1","new_password":"New-password2"}`,
+			mockSetup: func(userRepo *MockUserRepository, sessionRepo *sessions.MockSessionRepository, hasher *MockPasswordHasher) {
+				hasher.On("CheckPassword", "old-hash", "Old-password1").Return(nil).Once()
+				hasher.On("HashPassword", "New-password2").Return("new-hash", nil).Once()
+				userRepo.On("UpdatePassword", mock.Anything, testUserID, "new-hash").Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to change password"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo, mockSessionRepo, mockHasher, _, _, router := setupAccountHandlerTest(t)
+			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(currentUser, nil)
+			tc.mockSetup(mockUserRepo, mockSessionRepo, mockHasher)
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/users/me/password", bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockUserRepo.AssertExpectations(t)
+			mockSessionRepo.AssertExpectations(t)
+			mockHasher.AssertExpectations(t)
+		})
+	}
+	t.Run("Locked After Max Wrong Current Passwords", func(t *testing.T) {
+		mockUserRepo, _, mockHasher, _, _, router := setupAccountHandlerTest(t)
+		mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(currentUser, nil)
+		mockHasher.On("CheckPassword", "old-hash", "wrong").Return(auth.ErrPasswordMismatch).Times(testMaxLoginAttempts)
+		changePassword := func(current string) *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, "/api/users/me/password", bytes.NewBufferString(fmt.Sprintf(`{"current_password":"%s","new_password":"New-password2"}`, current)))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			return req
+		}
+
+		for i := 1; i < testMaxLoginAttempts; i++ {
+			executeRequestAndAssert(t, router, changePassword("wrong"), http.StatusForbidden, `{"error":"current password is incorrect"}`)
+		}
+		executeRequestAndAssert(t, router, changePassword("wrong"), http.StatusLocked, "too many failed login attempts")
+
+		// Even the right password is refused while locked, without being checked
+		executeRequestAndAssert(t, router, changePassword("Old-password1"), http.StatusLocked, "too many failed login attempts")
+		mockHasher.AssertExpectations(t)
+		mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, testUserID, mock.Anything)
+	})
+}
+
+func TestUserHandler_DeleteMe(t *testing.T) {
+	testUserID := uuid.New()
+	testToken, err := generateTestToken(testUserID)
+	require.NoError(t, err, "Failed to generate test token")
+	currentUser := &models.User{ID: testUserID, Name: "Test User", Email: "test@example.com", PasswordHash: "hash"}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(userRepo *MockUserRepository, hasher *MockPasswordHasher, exportDeleter *MockExportDeleter)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			body: `{"password":"Password1"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, exportDeleter *MockExportDeleter) {
+				hasher.On("CheckPassword", "hash", "Password1").Return(nil).Once()
+				userRepo.On("Anonymize", mock.Anything, testUserID).Return(nil).Once()
+				exportDeleter.On("DeleteForUser", mock.Anything, testUserID).Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Success - Export Deletion Error Is Not Fatal",
+			body: `{"password":"Password1"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, exportDeleter *MockExportDeleter) {
+				hasher.On("CheckPassword", "hash", "Password1").Return(nil).Once()
+				userRepo.On("Anonymize", mock.Anything, testUserID).Return(nil).Once()
+				exportDeleter.On("DeleteForUser", mock.Anything, testUserID).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "Failure - Wrong Password",
+			body: `{"password":"wrong"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, exportDeleter *MockExportDeleter) {
+				hasher.On("CheckPassword", "hash", "wrong").Return(auth.ErrPasswordMismatch).Once()
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"current password is incorrect"}`,
+		},
+		{
+			name:           "Failure - Missing Password",
+			body:           `{}`,
+			mockSetup:      func(userRepo *MockUserRepository, hasher *MockPasswordHasher, exportDeleter *MockExportDeleter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"password is required"}`,
+		},
+		{
+			name: "Failure - Anonymize Error",
+			body: `{"password":"Password1"}`,
+			mockSetup: func(userRepo *MockUserRepository, hasher *MockPasswordHasher, exportDeleter *MockExportDeleter) {
+				hasher.On("CheckPassword", "hash", "Password1").Return(nil).Once()
+				userRepo.On("Anonymize", mock.Anything, testUserID).Return(assert.AnError).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to delete account"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo, _, mockHasher, _, mockExportDeleter, router := setupAccountHandlerTest(t)
+			mockUserRepo.On("FindByID", mock.Anything, testUserID).Return(currentUser, nil)
+			tc.mockSetup(mockUserRepo, mockHasher, mockExportDeleter)
+
+			req, _ := http.NewRequest(http.MethodDelete, "/api/users/me", bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+
+			executeRequestAndAssert(t, router, req, tc.expectedStatus, tc.expectedBody)
+
+			mockUserRepo.AssertExpectations(t)
+			mockHasher.AssertExpectations(t)
+			mockExportDeleter.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"bullet-cloud-api/internal/products"
+	"bullet-cloud-api/internal/variants"
+	"bullet-cloud-api/internal/webutils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Length limit of SKUs and barcodes, as in the product_variants table.
+const maxVariantCodeLength = 64
+
+// VariantHandler manages the variants of products (sizes, colors...).
+type VariantHandler struct {
+	VariantRepo variants.VariantRepository
+	ProductRepo products.ProductRepository
+}
+
+// NewVariantHandler creates a new VariantHandler.
+func NewVariantHandler(variantRepo variants.VariantRepository, productRepo products.ProductRepository) *VariantHandler {
+	return &VariantHandler{VariantRepo: variantRepo, ProductRepo: productRepo}
+}
+
+// --- Request Structs ---
+
+// VariantRequest is the body of the variant create and update requests.
+type VariantRequest struct {
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"` // e.g. {"size": "M", "color": "Azul"}
+	Price   *money.Money      `json:"price"`   // Optional, defaults to the product price
+	Barcode *string           `json:"barcode"` // Optional
+}
+
+// --- Handlers ---
+
+// ListVariants handles GET /api/products/{id}/variants.
+func (h *VariantHandler) ListVariants(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.findProductID(w, r)
+	if !ok {
+		return
+	}
+
+	productVariants, err := h.VariantRepo.FindByProductID(r.Context(), productID)
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("failed to retrieve variants"), http.StatusInternalServerError)
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, productVariants)
+}
+
+// CreateVariant handles POST /api/products/{id}/variants.
+func (h *VariantHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.findProductID(w, r)
+	if !ok {
+		return
+	}
+	variant, ok := readVariantRequest(w, r)
+	if !ok {
+		return
+	}
+	variant.ProductID = productID
+
+	created, err := h.VariantRepo.Create(r.Context(), variant)
+	if err != nil {
+		respondVariantError(w, err, "failed to create variant")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// UpdateVariant handles PUT /api/products/{id}/variants/{variantId}.
+func (h *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := parseVariantURL(w, r)
+	if !ok {
+		return
+	}
+	variant, ok := readVariantRequest(w, r)
+	if !ok {
+		return
+	}
+	variant.ID = variantID
+	variant.ProductID = productID
+
+	updated, err := h.VariantRepo.Update(r.Context(), variant)
+	if err != nil {
+		respondVariantError(w, err, "failed to update variant")
+		return
+	}
+
+	webutils.WriteJSON(w, http.StatusOK, updated)
+}
+
+// DeleteVariant handles DELETE /api/products/{id}/variants/{variantId}.
+// The variant leaves the carts holding it; variants already ordered cannot be deleted.
+func (h *VariantHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := parseVariantURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.VariantRepo.Delete(r.Context(), productID, variantID); err != nil {
+		respondVariantError(w, err, "failed to delete variant")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Helpers ---
+
+// findProductID parses the {id} URL variable and checks the product exists.
+// On failure it writes the response and returns ok == false.
+func (h *VariantHandler) findProductID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	productID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid product ID format"), http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	if _, err := h.ProductRepo.FindByID(r.Context(), productID); err != nil {
+		if errors.Is(err, products.ErrProductNotFound) {
+			webutils.ErrorJSON(w, err, http.StatusNotFound)
+		} else {
+			webutils.ErrorJSON(w, errors.New("failed to retrieve product"), http.StatusInternalServerError)
+		}
+		return uuid.Nil, false
+	}
+	return productID, true
+}
+
+// parseVariantURL parses the {id} and {variantId} URL variables.
+func parseVariantURL(w http.ResponseWriter, r *http.Request) (productID, variantID uuid.UUID, ok bool) {
+	vars := mux.Vars(r)
+	productID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid product ID format"), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	variantID, err = uuid.Parse(vars["variantId"])
+	if err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid variant ID format"), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return productID, variantID, true
+}
+
+// readVariantRequest reads and validates a VariantRequest, reporting every invalid field.
+// On failure it writes the response and returns ok == false.
+func readVariantRequest(w http.ResponseWriter, r *http.Request) (*models.ProductVariant, bool) {
+	var req VariantRequest
+	if err := webutils.ReadJSON(r, &req); err != nil {
+		webutils.ErrorJSON(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	variant := &models.ProductVariant{
+		SKU:     strings.TrimSpace(req.SKU),
+		Options: make(map[string]string, len(req.Options)),
+		Price:   req.Price,
+	}
+	var fieldErrors []webutils.FieldError
+	invalid := func(field, code, message string) {
+		fieldErrors = append(fieldErrors, webutils.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	if variant.SKU == "" {
+		invalid("sku", "required", "is required")
+	} else if len(variant.SKU) > maxVariantCodeLength {
+		invalid("sku", "too_long", fmt.Sprintf("must be at most %d characters", maxVariantCodeLength))
+	}
+	if len(req.Options) == 0 {
+		invalid("options", "required", "must name at least one option, such as size or color")
+	}
+	for name, value := range req.Options {
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			invalid("options", "invalid", "option names and values must not be empty")
+			break
+		}
+		variant.Options[name] = value
+	}
+	if req.Price != nil && !req.Price.IsPositive() {
+		invalid("price", "invalid", "must be positive")
+	} else if req.Price != nil && req.Price.Currency != money.DefaultCurrency {
+		invalid("price", "invalid", "must be in "+money.DefaultCurrency)
+	}
+	if req.Barcode != nil {
+		barcode := strings.TrimSpace(*req.Barcode)
+		switch {
+		case barcode == "":
+			// An empty barcode means none
+		case len(barcode) > maxVariantCodeLength:
+			invalid("barcode", "too_long", fmt.Sprintf("must be at most %d characters", maxVariantCodeLength))
+		default:
+			variant.Barcode = &barcode
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		webutils.ValidationErrorJSON(w, fieldErrors)
+		return nil, false
+	}
+	return variant, true
+}
+
+// respondVariantError writes the response for a failed variant change.
+func respondVariantError(w http.ResponseWriter, err error, failureMessage string) {
+	switch {
+	case errors.Is(err, variants.ErrVariantNotFound):
+		webutils.ErrorJSON(w, err, http.StatusNotFound)
+	case errors.Is(err, variants.ErrSKUExists),
+		errors.Is(err, variants.ErrBarcodeExists),
+		errors.Is(err, variants.ErrOptionCombinationUsed),
+		errors.Is(err, variants.ErrVariantOrdered):
+		webutils.ErrorJSON(w, err, http.StatusConflict)
+	default:
+		webutils.ErrorJSON(w, errors.New(failureMessage), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers_test
+
+import (
+	"bullet-cloud-api/internal/apikeys"
+	"bullet-cloud-api/internal/auth"
+	"bullet-cloud-api/internal/handlers"
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/products"
+	"bullet-cloud-api/internal/variants"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupVariantTest creates a VariantHandler behind the same middleware as in main.go.
+// It returns a token for an admin.
+func setupVariantTest(t *testing.T) (*variants.MockVariantRepository, *MockProductRepository, *mux.Router, string) {
+	t.Helper()
+	variantRepo := new(variants.MockVariantRepository)
+	productRepo := new(MockProductRepository)
+	userRepo := new(MockUserRepository)
+	adminID := uuid.New()
+	userRepo.On("FindByID", mock.Anything, adminID).Return(&models.User{ID: adminID, Role: models.RoleAdmin}, nil).Maybe()
+
+	h := handlers.NewVariantHandler(variantRepo, productRepo)
+	mw := auth.NewMiddleware(testKeys, userRepo, newTestSessionRepo(), new(apikeys.MockAPIKeyRepository))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/products/{id}/variants", h.ListVariants).Methods("GET")
+	admin := router.PathPrefix("/api/products").Subrouter()
+	admin.Use(mw.Authenticate, mw.RequireRole(models.RoleAdmin))
+	admin.HandleFunc("/{id}/variants", h.CreateVariant).Methods("POST")
+	admin.HandleFunc("/{id}/variants/{variantId}", h.UpdateVariant).Methods("PUT")
+	admin.HandleFunc("/{id}/variants/{variantId}", h.DeleteVariant).Methods("DELETE")
+
+	token, err := generateTestToken(adminID)
+	require.NoError(t, err)
+	return variantRepo, productRepo, router, token
+}
+
+func TestVariantHandler_ListVariants(t *testing.T) {
+	variantRepo, productRepo, router, _ := setupVariantTest(t)
+	productID := uuid.New()
+	listed := []models.ProductVariant{{ID: uuid.New(), ProductID: productID, SKU: "CAM-P", Options: map[string]string{"size": "P"}}}
+
+	t.Run("Success", func(t *testing.T) {
+		productRepo.On("FindByID", mock.Anything, productID).Return(&models.Product{ID: productID}, nil).Once()
+		variantRepo.On("FindByProductID", mock.Anything, productID).Return(listed, nil).Once()
+
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/products/%s/variants", productID), nil)
+		rr := executeRequestAndAssert(t, router, req, http.StatusOK, "")
+		var resp []models.ProductVariant
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, listed, resp)
+	})
+
+	t.Run("Product Not Found", func(t *testing.T) {
+		missingID := uuid.New()
+		productRepo.On("FindByID", mock.Anything, missingID).Return(nil, products.ErrProductNotFound).Once()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/products/%s/variants", missingID), nil)
+		executeRequestAndAssert(t, router, req, http.StatusNotFound, `{"error":"product not found"}`)
+	})
+
+	variantRepo.AssertExpectations(t)
+	productRepo.AssertExpectations(t)
+}
+
+func TestVariantHandler_CreateVariant(t *testing.T) {
+	variantRepo, productRepo, router, token := setupVariantTest(t)
+	productID := uuid.New()
+	path := fmt.Sprintf("/api/products/%s/variants", productID)
+
+	t.Run("Success", func(t *testing.T) {
+		price := brl("59.90")
+		barcode := "7891234567895"
+		expected := &models.ProductVariant{
+			ProductID: productID,
+			SKU:       "CAM-M-AZUL",
+			Options:   map[string]string{"size": "M", "color": "Azul"},
+			Price:     &price,
+			Barcode:   &barcode,
+		}
+		productRepo.On("FindByID", mock.Anything, productID).Return(&models.Product{ID: productID}, nil).Once()
+		variantRepo.On("Create", mock.Anything, expected).Return(expected, nil).Once()
+
+		body := `{"sku":" CAM-M-AZUL ","options":{"size":"M","color":" Azul"},"price":59.9,"barcode":"7891234567895"}`
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", path, token, body), http.StatusCreated, "")
+		assert.Contains(t, rr.Body.String(), `"sku":"CAM-M-AZUL"`)
+	})
+
+	t.Run("Validation Errors", func(t *testing.T) {
+		productRepo.On("FindByID", mock.Anything, productID).Return(&models.Product{ID: productID}, nil).Once()
+		rr := executeRequestAndAssert(t, router, newAdminRequest("POST", path, token, `{"sku":"","options":{},"price":0}`), http.StatusBadRequest, "")
+		body := rr.Body.String()
+		assert.Contains(t, body, `"field":"sku"`)
+		assert.Contains(t, body, `"field":"options"`)
+		assert.Contains(t, body, `"field":"price"`)
+	})
+
+	t.Run("Duplicate SKU", func(t *testing.T) {
+		productRepo.On("FindByID", mock.Anything, productID).Return(&models.Product{ID: productID}, nil).Once()
+		variantRepo.On("Create", mock.Anything, mock.Anything).Return(nil, variants.ErrSKUExists).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("POST", path, token, `{"sku":"CAM-P","options":{"size":"P"}}`), http.StatusConflict, `{"error":"sku already exists"}`)
+	})
+
+	t.Run("Product Not Found", func(t *testing.T) {
+		missingID := uuid.New()
+		productRepo.On("FindByID", mock.Anything, missingID).Return(nil, products.ErrProductNotFound).Once()
+		req := newAdminRequest("POST", fmt.Sprintf("/api/products/%s/variants", missingID), token, `{"sku":"CAM-P","options":{"size":"P"}}`)
+		executeRequestAndAssert(t, router, req, http.StatusNotFound, `{"error":"product not found"}`)
+	})
+
+	variantRepo.AssertExpectations(t)
+	productRepo.AssertExpectations(t)
+}
+
+func TestVariantHandler_UpdateVariant(t *testing.T) {
+	variantRepo, _, router, token := setupVariantTest(t)
+	productID := uuid.New()
+	variantID := uuid.New()
+	path := fmt.Sprintf("/api/products/%s/variants/%s", productID, variantID)
+
+	t.Run("Success", func(t *testing.T) {
+		expected := &models.ProductVariant{ID: variantID, ProductID: productID, SKU: "CAM-G", Options: map[string]string{"size": "G"}}
+		variantRepo.On("Update", mock.Anything, expected).Return(expected, nil).Once()
+		rr := executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"sku":"CAM-G","options":{"size":"G"},"barcode":""}`), http.StatusOK, "")
+		assert.Contains(t, rr.Body.String(), `"barcode":null`)
+	})
+
+	t.Run("Same Options As Another Variant", func(t *testing.T) {
+		variantRepo.On("Update", mock.Anything, mock.Anything).Return(nil, variants.ErrOptionCombinationUsed).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"sku":"CAM-G","options":{"size":"P"}}`), http.StatusConflict, `{"error":"another variant of the product has the same options"}`)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		variantRepo.On("Update", mock.Anything, mock.Anything).Return(nil, variants.ErrVariantNotFound).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("PUT", path, token, `{"sku":"CAM-G","options":{"size":"G"}}`), http.StatusNotFound, `{"error":"variant not found"}`)
+	})
+
+	variantRepo.AssertExpectations(t)
+}
+
+func TestVariantHandler_DeleteVariant(t *testing.T) {
+	variantRepo, _, router, token := setupVariantTest(t)
+	productID := uuid.New()
+	variantID := uuid.New()
+	path := fmt.Sprintf("/api/products/%s/variants/%s", productID, variantID)
+
+	t.Run("Success", func(t *testing.T) {
+		variantRepo.On("Delete", mock.Anything, productID, variantID).Return(nil).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("DELETE", path, token, ""), http.StatusNoContent, "")
+	})
+
+	t.Run("Already Ordered", func(t *testing.T) {
+		variantRepo.On("Delete", mock.Anything, productID, variantID).Return(variants.ErrVariantOrdered).Once()
+		executeRequestAndAssert(t, router, newAdminRequest("DELETE", path, token, ""), http.StatusConflict, `{"error":"variant is part of an order and cannot be deleted"}`)
+	})
+
+	variantRepo.AssertExpectations(t)
+}
@@ -0,0 +1,103 @@
+package identities
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrIdentityAlreadyLinked = errors.New("identity already linked")
+)
+
+// IdentityRepository defines the interface for external identity data operations.
+type IdentityRepository interface {
+	// Create links an external identity to a user, or returns ErrIdentityAlreadyLinked.
+	Create(ctx context.Context, identity *models.UserIdentity) (*models.UserIdentity, error)
+	// FindByProviderSubject returns the identity with the given provider and subject, or ErrIdentityNotFound.
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	// ListByUser returns the identities linked to a user, oldest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+}
+
+// postgresIdentityRepository implements IdentityRepository using PostgreSQL.
+type postgresIdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresIdentityRepository creates a new instance of postgresIdentityRepository.
+func NewPostgresIdentityRepository(db *pgxpool.Pool) IdentityRepository {
+	return &postgresIdentityRepository{db: db}
+}
+
+// identityColumns lists the columns read by scanIdentity, in order.
+const identityColumns = "id, user_id, provider, subject, email, created_at"
+
+// scanIdentity scans a row selected with identityColumns.
+func scanIdentity(row pgx.Row) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	err := row.Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+// Create inserts a new user identity into the database.
+func (r *postgresIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) (*models.UserIdentity, error) {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + identityColumns
+	created, err := scanIdentity(r.db.QueryRow(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation on (provider, subject)
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// FindByProviderSubject retrieves the identity a provider knows by the given subject.
+func (r *postgresIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE provider = $1 AND subject = $2`
+	return scanIdentity(r.db.QueryRow(ctx, query, provider, subject))
+}
+
+// ListByUser retrieves the external identities linked to a user.
+func (r *postgresIdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.UserIdentity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *identity)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,83 @@
+package identities
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIdentityRepository is a mock type for the IdentityRepository interface
+type MockIdentityRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, identity
+func (_m *MockIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) (*models.UserIdentity, error) {
+	ret := _m.Called(ctx, identity)
+
+	var r0 *models.UserIdentity
+	if rf, ok := ret.Get(0).(func(context.Context, *models.UserIdentity) *models.UserIdentity); ok {
+		r0 = rf(ctx, identity)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserIdentity)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.UserIdentity) error); ok {
+		r1 = rf(ctx, identity)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByProviderSubject provides a mock function with given fields: ctx, provider, subject
+func (_m *MockIdentityRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (*models.UserIdentity, error) {
+	ret := _m.Called(ctx, provider, subject)
+
+	var r0 *models.UserIdentity
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.UserIdentity); ok {
+		r0 = rf(ctx, provider, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserIdentity)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, provider, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListByUser provides a mock function with given fields: ctx, userID
+func (_m *MockIdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.UserIdentity
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.UserIdentity); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.UserIdentity)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,98 @@
+package inventory
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/notify"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LowStockEvent is the event of the messages reporting products low on stock.
+const LowStockEvent = "stock.low"
+
+// LowStockChecker reports the products whose stock fell below their reorder threshold.
+// Each shortage is reported once, until the stock is back at the threshold.
+type LowStockChecker struct {
+	Repo        InventoryRepository
+	Notifier    notify.Notifier
+	Recipient   string        // Who is told, e.g. the purchasing team's address
+	SalesWindow time.Duration // Recent sales used to estimate the days of cover
+}
+
+// NewLowStockChecker creates a LowStockChecker sending its alerts to recipient.
+func NewLowStockChecker(repo InventoryRepository, notifier notify.Notifier, recipient string, salesWindow time.Duration) *LowStockChecker {
+	return &LowStockChecker{Repo: repo, Notifier: notifier, Recipient: recipient, SalesWindow: salesWindow}
+}
+
+// Run checks the stock every interval until ctx is cancelled.
+func (c *LowStockChecker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Check(ctx); err != nil {
+			log.Printf("Error checking for low stock: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check sends one message listing the products that ran low since the last check.
+// They are marked as reported only once the message is sent, so a failed send is retried.
+func (c *LowStockChecker) Check(ctx context.Context) error {
+	if err := c.Repo.ResetLowStockAlerts(ctx); err != nil {
+		return err
+	}
+	lowStock, err := c.Repo.ListLowStock(ctx, c.SalesWindow)
+	if err != nil {
+		return err
+	}
+
+	var (
+		fresh []models.LowStockProduct
+		ids   []uuid.UUID
+	)
+	for _, p := range lowStock {
+		if p.AlertedAt == nil {
+			fresh = append(fresh, p)
+			ids = append(ids, p.ProductID)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	err = c.Notifier.Send(ctx, notify.Message{
+		To:      c.Recipient,
+		Subject: fmt.Sprintf("Low stock: %d product(s) below their reorder threshold", len(fresh)),
+		Body:    lowStockBody(fresh),
+		Event:   LowStockEvent,
+		Data:    fresh,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Repo.MarkLowStockAlerted(ctx, ids)
+}
+
+// lowStockBody lists the products, one per line.
+func lowStockBody(products []models.LowStockProduct) string {
+	var b strings.Builder
+	b.WriteString("These products fell below their reorder threshold:\n\n")
+	for _, p := range products {
+		cover := "no recent sales"
+		if p.DaysOfCover != nil {
+			cover = fmt.Sprintf("%.1f days of cover", *p.DaysOfCover)
+		}
+		fmt.Fprintf(&b, "- %s: %d in stock, threshold %d, %s\n", p.Name, p.Stock, p.ReorderThreshold, cover)
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package inventory
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/notify"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier keeps the messages sent, failing with err when set.
+type recordingNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (n *recordingNotifier) Send(ctx context.Context, msg notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func TestLowStockChecker_Check(t *testing.T) {
+	window := 30 * 24 * time.Hour
+	cover := 2.5
+	alertedAt := time.Now().Add(-time.Hour)
+	fresh := models.LowStockProduct{ProductID: uuid.New(), Name: "Camiseta", Stock: 5, ReorderThreshold: 10, UnitsSold: 60, DailySales: 2, DaysOfCover: &cover}
+	reported := models.LowStockProduct{ProductID: uuid.New(), Name: "Caneca", Stock: 1, ReorderThreshold: 3, AlertedAt: &alertedAt}
+
+	t.Run("Reports New Shortages Once", func(t *testing.T) {
+		repo := new(MockInventoryRepository)
+		notifier := &recordingNotifier{}
+		repo.On("ResetLowStockAlerts", mock.Anything).Return(nil).Once()
+		repo.On("ListLowStock", mock.Anything, window).Return([]models.LowStockProduct{fresh, reported}, nil).Once()
+		repo.On("MarkLowStockAlerted", mock.Anything, []uuid.UUID{fresh.ProductID}).Return(nil).Once()
+
+		checker := NewLowStockChecker(repo, notifier, "compras@example.com", window)
+		require.NoError(t, checker.Check(context.Background()))
+
+		require.Len(t, notifier.sent, 1)
+		msg := notifier.sent[0]
+		assert.Equal(t, "compras@example.com", msg.To)
+		assert.Equal(t, LowStockEvent, msg.Event)
+		assert.Equal(t, []models.LowStockProduct{fresh}, msg.Data)
+		assert.Contains(t, msg.Body, "- Camiseta: 5 in stock, threshold 10, 2.5 days of cover")
+		assert.NotContains(t, msg.Body, "Caneca")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing New", func(t *testing.T) {
+		repo := new(MockInventoryRepository)
+		notifier := &recordingNotifier{}
+		repo.On("ResetLowStockAlerts", mock.Anything).Return(nil).Once()
+		repo.On("ListLowStock", mock.Anything, window).Return([]models.LowStockProduct{reported}, nil).Once()
+
+		require.NoError(t, NewLowStockChecker(repo, notifier, "compras@example.com", window).Check(context.Background()))
+		assert.Empty(t, notifier.sent)
+		repo.AssertExpectations(t) // Not marked again
+	})
+
+	t.Run("Failed Send Is Retried", func(t *testing.T) {
+		repo := new(MockInventoryRepository)
+		notifier := &recordingNotifier{err: errors.New("webhook answered 503 Service Unavailable")}
+		repo.On("ResetLowStockAlerts", mock.Anything).Return(nil).Once()
+		repo.On("ListLowStock", mock.Anything, window).Return([]models.LowStockProduct{fresh}, nil).Once()
+
+		err := NewLowStockChecker(repo, notifier, "compras@example.com", window).Check(context.Background())
+		assert.EqualError(t, err, "webhook answered 503 Service Unavailable")
+		repo.AssertNotCalled(t, "MarkLowStockAlerted", mock.Anything, mock.Anything)
+		repo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,463 @@
+package inventory
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrLocationNotFound          = errors.New("stock location not found")
+	ErrLocationNameExists        = errors.New("stock location name already exists")
+	ErrLocationHasStock          = errors.New("stock location still holds units, transfer or remove them first")
+	ErrLocationHasOrders         = errors.New("stock location has order items and cannot be deleted")
+	ErrStockItemNotFound         = errors.New("product or variant not found")
+	ErrNegativeStock             = errors.New("stock cannot go below zero")
+	ErrInsufficientTransferStock = errors.New("the source location does not hold enough units")
+	ErrNegativeThreshold         = errors.New("reorder threshold cannot be negative")
+)
+
+// InventoryRepository defines the interface for stock locations and the stock kept at them.
+// The stock of a product sold in variants is kept by variant: a nil variantID names the
+// product itself, otherwise one of its variants.
+type InventoryRepository interface {
+	CreateLocation(ctx context.Context, location *models.StockLocation) (*models.StockLocation, error)
+	// ListLocations returns every stock location, by name.
+	ListLocations(ctx context.Context) ([]models.StockLocation, error)
+	FindLocationByID(ctx context.Context, id uuid.UUID) (*models.StockLocation, error)
+	UpdateLocation(ctx context.Context, location *models.StockLocation) (*models.StockLocation, error)
+	// DeleteLocation removes a location holding no units and never shipped from.
+	DeleteLocation(ctx context.Context, id uuid.UUID) error
+
+	// FindStock returns the stock of a product or variant at each location tracking it.
+	FindStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (*models.StockSummary, error)
+	// SetStock replaces the units at a location; a nil quantity stops tracking the stock there.
+	SetStock(ctx context.Context, locationID, productID uuid.UUID, variantID *uuid.UUID, quantity *int) (*models.StockSummary, error)
+	// AdjustStock adds delta units (negative to remove them) at a location.
+	// Unlike SetStock, it cannot overwrite units taken by concurrent checkouts.
+	AdjustStock(ctx context.Context, locationID, productID uuid.UUID, variantID *uuid.UUID, delta int) (*models.StockSummary, error)
+
+	// Transfer moves units between locations and records the transfer.
+	Transfer(ctx context.Context, transfer *models.StockTransfer) (*models.StockTransfer, error)
+	// ListTransfers returns up to limit transfers, the latest first, of locationID when not nil.
+	ListTransfers(ctx context.Context, locationID *uuid.UUID, limit int) ([]models.StockTransfer, error)
+
+	// PickingList returns the items of open (pending or processing) orders shipping from a
+	// location, the oldest orders first.
+	PickingList(ctx context.Context, locationID uuid.UUID) ([]models.PickingListItem, error)
+
+	// SetReorderThreshold sets the units below which a product is low on stock; nil disables
+	// its alerts. It returns the stock of the product.
+	SetReorderThreshold(ctx context.Context, productID uuid.UUID, threshold *int) (*models.StockSummary, error)
+	// ListLowStock returns the tracked products below their reorder threshold, the fewest days
+	// of cover first, with their sales over the salesWindow up to now.
+	ListLowStock(ctx context.Context, salesWindow time.Duration) ([]models.LowStockProduct, error)
+	// MarkLowStockAlerted records that purchasing was told the products are low on stock.
+	MarkLowStockAlerted(ctx context.Context, productIDs []uuid.UUID) error
+	// ResetLowStockAlerts forgets the alerts of products back at their threshold, so that
+	// they are reported again when they next run low.
+	ResetLowStockAlerts(ctx context.Context) error
+}
+
+// postgresInventoryRepository implements InventoryRepository using PostgreSQL.
+type postgresInventoryRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresInventoryRepository creates a new instance of postgresInventoryRepository.
+func NewPostgresInventoryRepository(db *pgxpool.Pool) InventoryRepository {
+	return &postgresInventoryRepository{db: db}
+}
+
+// handlePgError translates the constraint violations of the stock tables.
+func handlePgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.ConstraintName {
+		case "unique_stock_locations_name":
+			return ErrLocationNameExists
+		case "fk_order_items_location":
+			return ErrLocationHasOrders
+		case "fk_stock_levels_location":
+			return ErrLocationNotFound
+		case "fk_stock_levels_product", "fk_stock_levels_variant":
+			return ErrStockItemNotFound
+		case "check_stock_levels_quantity":
+			return ErrNegativeStock
+		case "check_products_reorder_threshold":
+			return ErrNegativeThreshold
+		}
+	}
+	return err
+}
+
+const locationColumns = `id, name, street, city, state, postal_code, country, created_at, updated_at`
+
+// CreateLocation inserts a new stock location.
+func (r *postgresInventoryRepository) CreateLocation(ctx context.Context, location *models.StockLocation) (*models.StockLocation, error) {
+	query := `
+		INSERT INTO stock_locations (name, street, city, state, postal_code, country)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, location.Name, location.Street, location.City, location.State, location.PostalCode, location.Country).
+		Scan(&location.ID, &location.CreatedAt, &location.UpdatedAt)
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+	return location, nil
+}
+
+// ListLocations retrieves every stock location.
+func (r *postgresInventoryRepository) ListLocations(ctx context.Context) ([]models.StockLocation, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+locationColumns+` FROM stock_locations ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.StockLocation])
+}
+
+// FindLocationByID retrieves a stock location by its ID.
+func (r *postgresInventoryRepository) FindLocationByID(ctx context.Context, id uuid.UUID) (*models.StockLocation, error) {
+	location := &models.StockLocation{}
+	err := r.db.QueryRow(ctx, `SELECT `+locationColumns+` FROM stock_locations WHERE id = $1`, id).Scan(
+		&location.ID, &location.Name, &location.Street, &location.City, &location.State, &location.PostalCode, &location.Country, &location.CreatedAt, &location.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, err
+	}
+	return location, nil
+}
+
+// UpdateLocation modifies an existing stock location.
+func (r *postgresInventoryRepository) UpdateLocation(ctx context.Context, location *models.StockLocation) (*models.StockLocation, error) {
+	query := `
+		UPDATE stock_locations
+		SET name = $1, street = $2, city = $3, state = $4, postal_code = $5, country = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, location.Name, location.Street, location.City, location.State, location.PostalCode, location.Country, location.ID).
+		Scan(&location.CreatedAt, &location.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, handlePgError(err)
+	}
+	return location, nil
+}
+
+// DeleteLocation removes a stock location along with its empty stock levels.
+func (r *postgresInventoryRepository) DeleteLocation(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM stock_locations
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM stock_levels WHERE location_id = $1 AND quantity > 0)
+	`
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return handlePgError(err)
+	}
+	if result.RowsAffected() == 0 {
+		// Tell a missing location from one still holding units
+		if _, err := r.FindLocationByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrLocationHasStock
+	}
+	return nil
+}
+
+// FindStock retrieves the stock levels of a product or variant.
+func (r *postgresInventoryRepository) FindStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (*models.StockSummary, error) {
+	// Check the item exists, reading the reorder threshold of products
+	var threshold *int
+	var err error
+	if variantID == nil {
+		err = r.db.QueryRow(ctx, `SELECT reorder_threshold FROM products WHERE id = $1`, productID).Scan(&threshold)
+	} else {
+		err = r.db.QueryRow(ctx, `SELECT NULL::int FROM product_variants WHERE id = $1 AND product_id = $2`, *variantID, productID).Scan(&threshold)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrStockItemNotFound
+		}
+		return nil, err
+	}
+
+	query := `
+		SELECT s.location_id, l.name AS location_name, s.quantity, s.updated_at
+		FROM stock_levels s
+		JOIN stock_locations l ON l.id = s.location_id
+		WHERE s.product_id = $1 AND s.variant_id IS NOT DISTINCT FROM $2
+		ORDER BY l.name ASC
+	`
+	rows, err := r.db.Query(ctx, query, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.StockLevel])
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.StockSummary{ProductID: productID, VariantID: variantID, Levels: levels, ReorderThreshold: threshold}
+	if len(levels) > 0 {
+		total := 0
+		for _, level := range levels {
+			total += level.Quantity
+		}
+		summary.Stock = &total
+	}
+	return summary, nil
+}
+
+// SetStock replaces, or stops tracking, the stock of a product or variant at a location.
+func (r *postgresInventoryRepository) SetStock(ctx context.Context, locationID, productID uuid.UUID, variantID *uuid.UUID, quantity *int) (*models.StockSummary, error) {
+	var err error
+	if quantity == nil {
+		_, err = r.db.Exec(ctx, `
+			DELETE FROM stock_levels
+			WHERE location_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3
+		`, locationID, productID, variantID)
+	} else {
+		_, err = r.db.Exec(ctx, `
+			INSERT INTO stock_levels (location_id, product_id, variant_id, quantity)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT ON CONSTRAINT unique_stock_levels_item
+			DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()
+		`, locationID, productID, variantID, *quantity)
+	}
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+	return r.FindStock(ctx, productID, variantID)
+}
+
+// AdjustStock adds delta units to the stock of a product or variant at a location,
+// starting to track it there if needed.
+func (r *postgresInventoryRepository) AdjustStock(ctx context.Context, locationID, productID uuid.UUID, variantID *uuid.UUID, delta int) (*models.StockSummary, error) {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO stock_levels (location_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT ON CONSTRAINT unique_stock_levels_item
+		DO UPDATE SET quantity = stock_levels.quantity + EXCLUDED.quantity, updated_at = NOW()
+	`, locationID, productID, variantID, delta)
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+	return r.FindStock(ctx, productID, variantID)
+}
+
+// Transfer moves units of a product or variant between two locations within a transaction.
+func (r *postgresInventoryRepository) Transfer(ctx context.Context, transfer *models.StockTransfer) (*models.StockTransfer, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locations int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM stock_locations WHERE id IN ($1, $2)`, transfer.FromLocationID, transfer.ToLocationID).Scan(&locations)
+	if err != nil {
+		return nil, err
+	}
+	if locations != 2 {
+		return nil, ErrLocationNotFound
+	}
+
+	// Lock both levels in location order, so that opposite transfers cannot deadlock
+	rows, err := tx.Query(ctx, `
+		SELECT location_id, quantity
+		FROM stock_levels
+		WHERE location_id IN ($1, $2) AND product_id = $3 AND variant_id IS NOT DISTINCT FROM $4
+		ORDER BY location_id
+		FOR UPDATE
+	`, transfer.FromLocationID, transfer.ToLocationID, transfer.ProductID, transfer.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	quantities := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			locationID uuid.UUID
+			quantity   int
+		)
+		if err := rows.Scan(&locationID, &quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		quantities[locationID] = quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if quantities[transfer.FromLocationID] < transfer.Quantity {
+		return nil, ErrInsufficientTransferStock
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE stock_levels SET quantity = quantity - $1, updated_at = NOW()
+		WHERE location_id = $2 AND product_id = $3 AND variant_id IS NOT DISTINCT FROM $4
+	`, transfer.Quantity, transfer.FromLocationID, transfer.ProductID, transfer.VariantID)
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO stock_levels (location_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT ON CONSTRAINT unique_stock_levels_item
+		DO UPDATE SET quantity = stock_levels.quantity + EXCLUDED.quantity, updated_at = NOW()
+	`, transfer.ToLocationID, transfer.ProductID, transfer.VariantID, transfer.Quantity)
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO stock_transfers (from_location_id, to_location_id, product_id, variant_id, quantity, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, transfer.FromLocationID, transfer.ToLocationID, transfer.ProductID, transfer.VariantID, transfer.Quantity, transfer.Note, transfer.CreatedBy).
+		Scan(&transfer.ID, &transfer.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// ListTransfers retrieves the latest stock transfers.
+func (r *postgresInventoryRepository) ListTransfers(ctx context.Context, locationID *uuid.UUID, limit int) ([]models.StockTransfer, error) {
+	query := `
+		SELECT id, from_location_id, to_location_id, product_id, variant_id, quantity, note, created_by, created_at
+		FROM stock_transfers
+		WHERE $1::uuid IS NULL OR from_location_id = $1 OR to_location_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, locationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.StockTransfer])
+}
+
+// PickingList retrieves the open order items to collect at a location.
+func (r *postgresInventoryRepository) PickingList(ctx context.Context, locationID uuid.UUID) ([]models.PickingListItem, error) {
+	if _, err := r.FindLocationByID(ctx, locationID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT o.id AS order_id, o.status AS order_status, o.created_at AS ordered_at,
+			oi.product_id, oi.variant_id, p.name, v.sku, oi.quantity
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN products p ON p.id = oi.product_id
+		LEFT JOIN product_variants v ON v.id = oi.variant_id
+		WHERE oi.location_id = $1 AND o.status IN ('pending', 'processing')
+		ORDER BY o.created_at ASC, o.id, p.name ASC
+	`
+	rows, err := r.db.Query(ctx, query, locationID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.PickingListItem])
+}
+
+// SetReorderThreshold sets or clears the reorder threshold of a product.
+func (r *postgresInventoryRepository) SetReorderThreshold(ctx context.Context, productID uuid.UUID, threshold *int) (*models.StockSummary, error) {
+	// A new threshold is checked afresh, even if the product was already reported
+	result, err := r.db.Exec(ctx, `
+		UPDATE products SET reorder_threshold = $1, low_stock_alerted_at = NULL, updated_at = NOW()
+		WHERE id = $2
+	`, threshold, productID)
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrStockItemNotFound
+	}
+	return r.FindStock(ctx, productID, nil)
+}
+
+// ListLowStock retrieves the low-stock products and estimates how long their stock lasts.
+func (r *postgresInventoryRepository) ListLowStock(ctx context.Context, salesWindow time.Duration) ([]models.LowStockProduct, error) {
+	// Sales count the units of orders not cancelled
+	query := `
+		WITH stock AS (
+			SELECT product_id, SUM(quantity) AS stock
+			FROM stock_levels
+			GROUP BY product_id
+		), sales AS (
+			SELECT oi.product_id, SUM(oi.quantity) AS units_sold
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.created_at >= $1 AND o.status <> 'cancelled'
+			GROUP BY oi.product_id
+		)
+		SELECT p.id AS product_id, p.name, s.stock, p.reorder_threshold,
+			COALESCE(sa.units_sold, 0) AS units_sold, p.low_stock_alerted_at
+		FROM products p
+		JOIN stock s ON s.product_id = p.id
+		LEFT JOIN sales sa ON sa.product_id = p.id
+		WHERE p.reorder_threshold IS NOT NULL AND s.stock < p.reorder_threshold
+		ORDER BY s.stock::float / NULLIF(sa.units_sold, 0) ASC NULLS LAST, p.name ASC
+	`
+	rows, err := r.db.Query(ctx, query, time.Now().Add(-salesWindow))
+	if err != nil {
+		return nil, err
+	}
+	lowStock, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.LowStockProduct])
+	if err != nil {
+		return nil, err
+	}
+
+	days := salesWindow.Hours() / 24
+	for i := range lowStock {
+		p := &lowStock[i]
+		p.DailySales = roundTenths(float64(p.UnitsSold) / days)
+		if p.UnitsSold > 0 {
+			cover := roundTenths(float64(p.Stock) * days / float64(p.UnitsSold))
+			p.DaysOfCover = &cover
+		}
+	}
+	return lowStock, nil
+}
+
+// roundTenths rounds x to one decimal place.
+func roundTenths(x float64) float64 {
+	return math.Round(x*10) / 10
+}
+
+// MarkLowStockAlerted sets the time the products were reported low on stock.
+func (r *postgresInventoryRepository) MarkLowStockAlerted(ctx context.Context, productIDs []uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE products SET low_stock_alerted_at = NOW() WHERE id = ANY($1)`, productIDs)
+	return err
+}
+
+// ResetLowStockAlerts clears the alert time of products no longer low on stock.
+func (r *postgresInventoryRepository) ResetLowStockAlerts(ctx context.Context) error {
+	query := `
+		UPDATE products p SET low_stock_alerted_at = NULL
+		WHERE p.low_stock_alerted_at IS NOT NULL
+			AND NOT (p.reorder_threshold IS NOT NULL
+				AND COALESCE((SELECT SUM(s.quantity) FROM stock_levels s WHERE s.product_id = p.id), p.reorder_threshold) < p.reorder_threshold)
+	`
+	_, err := r.db.Exec(ctx, query)
+	return err
+}
@@ -0,0 +1,333 @@
+package inventory
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInventoryRepository is a mock type for the InventoryRepository interface
+type MockInventoryRepository struct {
+	mock.Mock
+}
+
+// CreateLocation provides a mock function with given fields: ctx, location
+func (_m *MockInventoryRepository) CreateLocation(ctx context.Context, location *models.StockLocation) (*models.StockLocation, error) {
+	ret := _m.Called(ctx, location)
+
+	var r0 *models.StockLocation
+	if rf, ok := ret.Get(0).(func(context.Context, *models.StockLocation) *models.StockLocation); ok {
+		r0 = rf(ctx, location)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockLocation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.StockLocation) error); ok {
+		r1 = rf(ctx, location)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLocations provides a mock function with given fields: ctx
+func (_m *MockInventoryRepository) ListLocations(ctx context.Context) ([]models.StockLocation, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []models.StockLocation
+	if rf, ok := ret.Get(0).(func(context.Context) []models.StockLocation); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StockLocation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindLocationByID provides a mock function with given fields: ctx, id
+func (_m *MockInventoryRepository) FindLocationByID(ctx context.Context, id uuid.UUID) (*models.StockLocation, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *models.StockLocation
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.StockLocation); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockLocation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLocation provides a mock function with given fields: ctx, location
+func (_m *MockInventoryRepository) UpdateLocation(ctx context.Context, location *models.StockLocation) (*models.StockLocation, error) {
+	ret := _m.Called(ctx, location)
+
+	var r0 *models.StockLocation
+	if rf, ok := ret.Get(0).(func(context.Context, *models.StockLocation) *models.StockLocation); ok {
+		r0 = rf(ctx, location)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockLocation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.StockLocation) error); ok {
+		r1 = rf(ctx, location)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteLocation provides a mock function with given fields: ctx, id
+func (_m *MockInventoryRepository) DeleteLocation(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindStock provides a mock function with given fields: ctx, productID, variantID
+func (_m *MockInventoryRepository) FindStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (*models.StockSummary, error) {
+	ret := _m.Called(ctx, productID, variantID)
+
+	var r0 *models.StockSummary
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *uuid.UUID) *models.StockSummary); ok {
+		r0 = rf(ctx, productID, variantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockSummary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, *uuid.UUID) error); ok {
+		r1 = rf(ctx, productID, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetStock provides a mock function with given fields: ctx, locationID, productID, variantID, quantity
+func (_m *MockInventoryRepository) SetStock(ctx context.Context, locationID uuid.UUID, productID uuid.UUID, variantID *uuid.UUID, quantity *int) (*models.StockSummary, error) {
+	ret := _m.Called(ctx, locationID, productID, variantID, quantity)
+
+	var r0 *models.StockSummary
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, *int) *models.StockSummary); ok {
+		r0 = rf(ctx, locationID, productID, variantID, quantity)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockSummary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, *int) error); ok {
+		r1 = rf(ctx, locationID, productID, variantID, quantity)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AdjustStock provides a mock function with given fields: ctx, locationID, productID, variantID, delta
+func (_m *MockInventoryRepository) AdjustStock(ctx context.Context, locationID uuid.UUID, productID uuid.UUID, variantID *uuid.UUID, delta int) (*models.StockSummary, error) {
+	ret := _m.Called(ctx, locationID, productID, variantID, delta)
+
+	var r0 *models.StockSummary
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, int) *models.StockSummary); ok {
+		r0 = rf(ctx, locationID, productID, variantID, delta)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockSummary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, int) error); ok {
+		r1 = rf(ctx, locationID, productID, variantID, delta)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Transfer provides a mock function with given fields: ctx, transfer
+func (_m *MockInventoryRepository) Transfer(ctx context.Context, transfer *models.StockTransfer) (*models.StockTransfer, error) {
+	ret := _m.Called(ctx, transfer)
+
+	var r0 *models.StockTransfer
+	if rf, ok := ret.Get(0).(func(context.Context, *models.StockTransfer) *models.StockTransfer); ok {
+		r0 = rf(ctx, transfer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockTransfer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.StockTransfer) error); ok {
+		r1 = rf(ctx, transfer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTransfers provides a mock function with given fields: ctx, locationID, limit
+func (_m *MockInventoryRepository) ListTransfers(ctx context.Context, locationID *uuid.UUID, limit int) ([]models.StockTransfer, error) {
+	ret := _m.Called(ctx, locationID, limit)
+
+	var r0 []models.StockTransfer
+	if rf, ok := ret.Get(0).(func(context.Context, *uuid.UUID, int) []models.StockTransfer); ok {
+		r0 = rf(ctx, locationID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StockTransfer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *uuid.UUID, int) error); ok {
+		r1 = rf(ctx, locationID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PickingList provides a mock function with given fields: ctx, locationID
+func (_m *MockInventoryRepository) PickingList(ctx context.Context, locationID uuid.UUID) ([]models.PickingListItem, error) {
+	ret := _m.Called(ctx, locationID)
+
+	var r0 []models.PickingListItem
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.PickingListItem); ok {
+		r0 = rf(ctx, locationID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PickingListItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, locationID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetReorderThreshold provides a mock function with given fields: ctx, productID, threshold
+func (_m *MockInventoryRepository) SetReorderThreshold(ctx context.Context, productID uuid.UUID, threshold *int) (*models.StockSummary, error) {
+	ret := _m.Called(ctx, productID, threshold)
+
+	var r0 *models.StockSummary
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *int) *models.StockSummary); ok {
+		r0 = rf(ctx, productID, threshold)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StockSummary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, *int) error); ok {
+		r1 = rf(ctx, productID, threshold)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLowStock provides a mock function with given fields: ctx, salesWindow
+func (_m *MockInventoryRepository) ListLowStock(ctx context.Context, salesWindow time.Duration) ([]models.LowStockProduct, error) {
+	ret := _m.Called(ctx, salesWindow)
+
+	var r0 []models.LowStockProduct
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) []models.LowStockProduct); ok {
+		r0 = rf(ctx, salesWindow)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.LowStockProduct)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration) error); ok {
+		r1 = rf(ctx, salesWindow)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkLowStockAlerted provides a mock function with given fields: ctx, productIDs
+func (_m *MockInventoryRepository) MarkLowStockAlerted(ctx context.Context, productIDs []uuid.UUID) error {
+	ret := _m.Called(ctx, productIDs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) error); ok {
+		r0 = rf(ctx, productIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetLowStockAlerts provides a mock function with given fields: ctx
+func (_m *MockInventoryRepository) ResetLowStockAlerts(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,108 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Scope identifies what a lock applies to.
+type Scope string
+
+const (
+	ScopeAccount Scope = "account" // Failures for a single email address
+	ScopeIP      Scope = "ip"      // Failures from a single client IP, across accounts
+)
+
+// LockedError is returned when a login is refused because a key is locked.
+type LockedError struct {
+	Scope Scope
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts (%s locked until %s)", e.Scope, e.Until.Format(time.RFC3339))
+}
+
+// Limiter enforces the login attempt policy on top of an AttemptStore.
+// Failures are counted both per email and per client IP.
+type Limiter struct {
+	store           AttemptStore
+	prefix          string // Namespaces the keys of limiters made with Named
+	maxAttempts     int
+	lockoutDuration time.Duration
+}
+
+// NewLimiter creates a Limiter that locks a key for lockoutDuration after maxAttempts failures.
+func NewLimiter(store AttemptStore, maxAttempts int, lockoutDuration time.Duration) *Limiter {
+	return &Limiter{
+		store:           store,
+		maxAttempts:     maxAttempts,
+		lockoutDuration: lockoutDuration,
+	}
+}
+
+// Named returns a Limiter with the same store and policy that counts under its own keys, for
+// limiting requests other than logins (e.g. password reset emails) without locking logins.
+func (l *Limiter) Named(name string) *Limiter {
+	named := *l
+	named.prefix = l.prefix + name + ":"
+	return &named
+}
+
+func (l *Limiter) emailKey(email string) string { return l.prefix + "email:" + email }
+func (l *Limiter) ipKey(ip string) string       { return l.prefix + "ip:" + ip }
+
+// Check returns a *LockedError if either the email or the IP is currently locked.
+func (l *Limiter) Check(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	for _, k := range []struct {
+		key   string
+		scope Scope
+	}{
+		{l.emailKey(email), ScopeAccount},
+		{l.ipKey(ip), ScopeIP},
+	} {
+		attempt, err := l.store.Get(ctx, k.key)
+		if err != nil {
+			if errors.Is(err, ErrAttemptNotFound) {
+				continue
+			}
+			return err
+		}
+		if attempt.IsLocked(now) {
+			return &LockedError{Scope: k.scope, Until: *attempt.LockedUntil}
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login for the email and IP. It returns a
+// *LockedError if this failure caused either of them to be locked.
+func (l *Limiter) RecordFailure(ctx context.Context, email, ip string) error {
+	account, err := l.store.RecordFailure(ctx, l.emailKey(email), l.maxAttempts, l.lockoutDuration)
+	if err != nil {
+		return err
+	}
+	client, err := l.store.RecordFailure(ctx, l.ipKey(ip), l.maxAttempts, l.lockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if account.IsLocked(now) {
+		return &LockedError{Scope: ScopeAccount, Until: *account.LockedUntil}
+	}
+	if client.IsLocked(now) {
+		return &LockedError{Scope: ScopeIP, Until: *client.LockedUntil}
+	}
+	return nil
+}
+
+// RecordSuccess resets the failure counter for the email.
+// The IP counter is left alone so one valid account cannot be used to
+// clear the failures of a client that is guessing other accounts' passwords.
+func (l *Limiter) RecordSuccess(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, l.emailKey(email))
+}
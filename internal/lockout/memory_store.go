@@ -0,0 +1,58 @@
+package lockout
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryAttemptStore is an in-process AttemptStore. It is used by tests and is
+// suitable for single-instance deployments; lockouts are lost on restart.
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+// NewMemoryAttemptStore creates an empty MemoryAttemptStore.
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{
+		attempts: make(map[string]models.LoginAttempt),
+	}
+}
+
+// Get returns a copy of the attempt record for key.
+func (s *MemoryAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, ErrAttemptNotFound
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts a failure for key.
+func (s *MemoryAttemptStore) RecordFailure(ctx context.Context, key string, maxAttempts int, lockoutDuration time.Duration) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key, UpdatedAt: now}
+	}
+	registerFailure(&attempt, now, maxAttempts, lockoutDuration)
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+// Reset forgets key.
+func (s *MemoryAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
@@ -0,0 +1,118 @@
+package lockout
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAttemptNotFound = errors.New("login attempt not found")
+)
+
+// AttemptStore defines the interface for persisting failed login attempts.
+type AttemptStore interface {
+	// Get returns the attempt record for key, or ErrAttemptNotFound.
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordFailure atomically counts a failed login for key, locking it for
+	// lockoutDuration once maxAttempts is reached, and returns the updated record.
+	RecordFailure(ctx context.Context, key string, maxAttempts int, lockoutDuration time.Duration) (*models.LoginAttempt, error)
+	// Reset clears the failures and any lock for key.
+	Reset(ctx context.Context, key string) error
+}
+
+// registerFailure applies one failed login to the attempt record.
+// Shared by every AttemptStore so they enforce the same policy.
+func registerFailure(a *models.LoginAttempt, now time.Time, maxAttempts int, lockoutDuration time.Duration) {
+	// Start a new window once a lock has expired or the last failure is old enough
+	expired := a.LockedUntil != nil && !now.Before(*a.LockedUntil)
+	if expired || now.Sub(a.UpdatedAt) > lockoutDuration {
+		a.Failures = 0
+		a.LockedUntil = nil
+	}
+
+	a.Failures++
+	a.UpdatedAt = now
+	if a.Failures >= maxAttempts {
+		until := now.Add(lockoutDuration)
+		a.LockedUntil = &until
+	}
+}
+
+// postgresAttemptStore implements AttemptStore using PostgreSQL, so that
+// lockouts are shared by every API instance.
+type postgresAttemptStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresAttemptStore creates a new instance of postgresAttemptStore.
+func NewPostgresAttemptStore(db *pgxpool.Pool) AttemptStore {
+	return &postgresAttemptStore{db: db}
+}
+
+// Get retrieves the attempt record for a key.
+func (s *postgresAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	query := `SELECT key, failures, locked_until, updated_at FROM login_attempts WHERE key = $1`
+	attempt := &models.LoginAttempt{}
+	err := s.db.QueryRow(ctx, query, key).Scan(&attempt.Key, &attempt.Failures, &attempt.LockedUntil, &attempt.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAttemptNotFound
+		}
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// RecordFailure counts a failure within a transaction so concurrent attempts cannot undercount.
+func (s *postgresAttemptStore) RecordFailure(ctx context.Context, key string, maxAttempts int, lockoutDuration time.Duration) (*models.LoginAttempt, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
+
+	// 1. Make sure a row exists, then lock it
+	if _, err := tx.Exec(ctx, `INSERT INTO login_attempts (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key); err != nil {
+		return nil, err
+	}
+
+	findQuery := `
+		SELECT key, failures, locked_until, updated_at
+		FROM login_attempts
+		WHERE key = $1
+		FOR UPDATE
+	`
+	attempt := &models.LoginAttempt{}
+	err = tx.QueryRow(ctx, findQuery, key).Scan(&attempt.Key, &attempt.Failures, &attempt.LockedUntil, &attempt.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Apply the failure and store the result
+	registerFailure(attempt, time.Now(), maxAttempts, lockoutDuration)
+
+	updateQuery := `
+		UPDATE login_attempts
+		SET failures = $1, locked_until = $2, updated_at = $3
+		WHERE key = $4
+	`
+	if _, err := tx.Exec(ctx, updateQuery, attempt.Failures, attempt.LockedUntil, attempt.UpdatedAt, key); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// Reset deletes the attempt record for a key.
+func (s *postgresAttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
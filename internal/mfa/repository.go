@@ -0,0 +1,199 @@
+package mfa
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrMFANotFound          = errors.New("two-factor settings not found")
+	ErrMFAAlreadyEnabled    = errors.New("two-factor authentication already enabled")
+	ErrTOTPCodeReused       = errors.New("two-factor code already used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found or already used")
+)
+
+// MFARepository defines the interface for two-factor authentication data operations.
+type MFARepository interface {
+	// FindByUserID returns the user's 2FA settings (pending or enabled), or ErrMFANotFound.
+	FindByUserID(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error)
+	// UpsertPending stores a new, unconfirmed secret. It returns ErrMFAAlreadyEnabled
+	// if 2FA is already active, so an attacker with a stolen session cannot swap the secret.
+	UpsertPending(ctx context.Context, userID uuid.UUID, secret string) (*models.UserMFA, error)
+	// Enable activates 2FA, records the step of the confirming code and replaces any recovery codes.
+	Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	// Disable removes the 2FA settings and recovery codes of a user.
+	Disable(ctx context.Context, userID uuid.UUID) error
+	// RecordStep marks a TOTP time step as used. It returns ErrTOTPCodeReused if that
+	// step (or a later one) was already accepted.
+	RecordStep(ctx context.Context, userID uuid.UUID, step int64) error
+	// UseRecoveryCode consumes an unused recovery code, or returns ErrRecoveryCodeNotFound.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+}
+
+// postgresMFARepository implements MFARepository using PostgreSQL.
+type postgresMFARepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresMFARepository creates a new instance of postgresMFARepository.
+func NewPostgresMFARepository(db *pgxpool.Pool) MFARepository {
+	return &postgresMFARepository{db: db}
+}
+
+// FindByUserID retrieves the 2FA settings of a user.
+func (r *postgresMFARepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	query := `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+	settings := &models.UserMFA{}
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&settings.UserID,
+		&settings.Secret,
+		&settings.EnabledAt,
+		&settings.LastUsedStep,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotFound
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpsertPending creates or replaces a pending enrollment.
+func (r *postgresMFARepository) UpsertPending(ctx context.Context, userID uuid.UUID, secret string) (*models.UserMFA, error) {
+	// The WHERE clause leaves enabled rows untouched, in which case nothing is returned
+	query := `
+		INSERT INTO user_mfa (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = NULL, updated_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL
+		RETURNING user_id, secret, enabled_at, last_used_step, created_at, updated_at
+	`
+	settings := &models.UserMFA{}
+	err := r.db.QueryRow(ctx, query, userID, secret).Scan(
+		&settings.UserID,
+		&settings.Secret,
+		&settings.EnabledAt,
+		&settings.LastUsedStep,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Enable confirms a pending enrollment within a transaction.
+func (r *postgresMFARepository) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
+
+	// 1. Activate the pending secret
+	enableQuery := `
+		UPDATE user_mfa
+		SET enabled_at = NOW(), last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+	tag, err := tx.Exec(ctx, enableQuery, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// Either enrollment never started or it was confirmed concurrently
+		var enabled bool
+		err := tx.QueryRow(ctx, `SELECT enabled_at IS NOT NULL FROM user_mfa WHERE user_id = $1`, userID).Scan(&enabled)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrMFANotFound
+			}
+			return err
+		}
+		return ErrMFAAlreadyEnabled
+	}
+
+	// 2. Replace any leftover recovery codes
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Disable deletes the user's 2FA settings and recovery codes.
+func (r *postgresMFARepository) Disable(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFANotFound
+	}
+	return tx.Commit(ctx)
+}
+
+// RecordStep atomically advances the last used TOTP step.
+func (r *postgresMFARepository) RecordStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `
+		UPDATE user_mfa
+		SET last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPCodeReused
+	}
+	return nil
+}
+
+// UseRecoveryCode marks a recovery code as used.
+func (r *postgresMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
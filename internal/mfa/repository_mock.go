@@ -0,0 +1,116 @@
+package mfa
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMFARepository is a mock type for the MFARepository interface
+type MockMFARepository struct {
+	mock.Mock
+}
+
+// FindByUserID provides a mock function with given fields: ctx, userID
+func (_m *MockMFARepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.UserMFA
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.UserMFA); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserMFA)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpsertPending provides a mock function with given fields: ctx, userID, secret
+func (_m *MockMFARepository) UpsertPending(ctx context.Context, userID uuid.UUID, secret string) (*models.UserMFA, error) {
+	ret := _m.Called(ctx, userID, secret)
+
+	var r0 *models.UserMFA
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) *models.UserMFA); ok {
+		r0 = rf(ctx, userID, secret)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserMFA)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, userID, secret)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Enable provides a mock function with given fields: ctx, userID, step, recoveryCodeHashes
+func (_m *MockMFARepository) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	ret := _m.Called(ctx, userID, step, recoveryCodeHashes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, int64, []string) error); ok {
+		r0 = rf(ctx, userID, step, recoveryCodeHashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Disable provides a mock function with given fields: ctx, userID
+func (_m *MockMFARepository) Disable(ctx context.Context, userID uuid.UUID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordStep provides a mock function with given fields: ctx, userID, step
+func (_m *MockMFARepository) RecordStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	ret := _m.Called(ctx, userID, step)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, int64) error); ok {
+		r0 = rf(ctx, userID, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseRecoveryCode provides a mock function with given fields: ctx, userID, codeHash
+func (_m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	ret := _m.Called(ctx, userID, codeHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) error); ok {
+		r0 = rf(ctx, userID, codeHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
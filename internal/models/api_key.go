@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey is a named, scoped credential a user can mint for server-to-server integrations.
+// Only the hash of the key is persisted; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`                     // Foreign key to users table; the key acts as this user
+	Name       string     `json:"name" db:"name"`                           // Label chosen by the user, e.g. "ERP sync"
+	Prefix     string     `json:"prefix" db:"prefix"`                       // First characters of the key, to tell keys apart
+	KeyHash    string     `json:"-" db:"key_hash"`                          // SHA-256 of the key, never exposed
+	Scopes     []string   `json:"scopes" db:"scopes"`                       // Permissions granted to the key, e.g. "products:write"
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`     // Optional expiry
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"` // Last authenticated request (approximate)
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsExpired reports whether the key has an expiry in the past.
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataExportStatus is the state of a background personal data export.
+type DataExportStatus string
+
+const (
+	ExportPending DataExportStatus = "pending" // Queued or being generated
+	ExportReady   DataExportStatus = "ready"   // File generated, downloadable until ExpiresAt
+	ExportFailed  DataExportStatus = "failed"  // Generation failed; the user may request a new export
+)
+
+// DataExport is a copy of a user's personal data generated in the background (LGPD/GDPR access requests).
+type DataExport struct {
+	ID          uuid.UUID        `json:"id" db:"id"`
+	UserID      uuid.UUID        `json:"user_id" db:"user_id"` // Foreign key to users table
+	Format      string           `json:"format" db:"format"`   // "json" or "zip"
+	Status      DataExportStatus `json:"status" db:"status"`
+	FilePath    string           `json:"-" db:"file_path"`                         // Location of the generated file on the server
+	SizeBytes   int64            `json:"size_bytes" db:"size_bytes"`               // Size of the generated file
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`               // Time of the request
+	CompletedAt *time.Time       `json:"completed_at,omitempty" db:"completed_at"` // Set once ready or failed
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty" db:"expires_at"`     // The file and its download link are removed after this time
+}
+
+// IsDownloadable reports whether the export file is ready and has not expired.
+func (e *DataExport) IsDownloadable(now time.Time) bool {
+	return e.Status == ExportReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginAttempt tracks consecutive failed logins for a single key (an email or a client IP).
+type LoginAttempt struct {
+	Key         string     `json:"key" db:"key"`                             // e.g. "email:user@example.com" or "ip:203.0.113.7"
+	Failures    int        `json:"failures" db:"failures"`                   // Consecutive failures in the current window
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"` // Set once the threshold is reached
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`               // Time of the last failure
+}
+
+// IsLocked reports whether the key is locked at the given time.
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
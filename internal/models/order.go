@@ -1,6 +1,7 @@
 package models
 
 import (
+	"bullet-cloud-api/internal/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,13 +18,24 @@ const (
 	StatusCancelled  OrderStatus = "cancelled"  // Order cancelled
 )
 
+// IsValid reports whether s is one of the known order statuses.
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusProcessing, StatusShipped, StatusDelivered, StatusCancelled:
+		return true
+	}
+	return false
+}
+
 // Order represents a customer order.
 type Order struct {
 	ID                uuid.UUID   `json:"id" db:"id"`
 	UserID            uuid.UUID   `json:"user_id" db:"user_id"`                           // FK to users
 	ShippingAddressID uuid.UUID   `json:"shipping_address_id" db:"shipping_address_id"`   // FK to addresses
 	Status            OrderStatus `json:"status" db:"status"`                             // Current status of the order
-	Total             float64     `json:"total" db:"total"`                               // Total price of the order at creation
+	Total             money.Money `json:"total" db:"total"`                               // Total price of the order at creation
+	Currency          string      `json:"currency" db:"currency"`                         // The currency of Total and the item prices
+	ExchangeRate      money.Rate  `json:"exchange_rate" db:"exchange_rate"`               // Units of Currency per unit of money.DefaultCurrency at checkout
 	TrackingNumber    *string     `json:"tracking_number,omitempty" db:"tracking_number"` // Optional tracking number
 	CreatedAt         time.Time   `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time   `json:"updated_at" db:"updated_at"`
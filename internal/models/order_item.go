@@ -1,6 +1,7 @@
 package models
 
 import (
+	"bullet-cloud-api/internal/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,13 +9,15 @@ import (
 
 // OrderItem represents an item within an order.
 type OrderItem struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	OrderID   uuid.UUID `json:"order_id" db:"order_id"`     // Foreign key to orders table
-	ProductID uuid.UUID `json:"product_id" db:"product_id"` // Foreign key to products table
-	Quantity  int       `json:"quantity" db:"quantity"`     // Quantity of the product ordered
-	Price     float64   `json:"price" db:"price"`           // Price of the product at the time of order
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID         uuid.UUID   `json:"id" db:"id"`
+	OrderID    uuid.UUID   `json:"order_id" db:"order_id"`       // Foreign key to orders table
+	ProductID  uuid.UUID   `json:"product_id" db:"product_id"`   // Foreign key to products table
+	VariantID  *uuid.UUID  `json:"variant_id" db:"variant_id"`   // The variant ordered, for products sold in variants
+	LocationID *uuid.UUID  `json:"location_id" db:"location_id"` // Stock location it ships from; nil when the stock is not tracked
+	Quantity   int         `json:"quantity" db:"quantity"`       // Quantity of the product ordered
+	Price      money.Money `json:"price" db:"price"`             // Price of the product at the time of order
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at" db:"updated_at"`
 
 	// Optional: Include product details in the response (requires JOIN)
 	// ProductName string `json:"product_name,omitempty" db:"product_name"`
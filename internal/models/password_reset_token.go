@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordResetToken represents a single-use password reset token. Only the token hash is persisted.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`           // Foreign key to users table
+	TokenHash string     `json:"-" db:"token_hash"`              // SHA-256 of the opaque token, never exposed
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`     // Absolute expiry of this token
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"` // Set once the token is consumed or superseded
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"bullet-cloud-api/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExchangeRate is the value of a currency the store prices in, against the store currency.
+type ExchangeRate struct {
+	Currency  string     `json:"currency" db:"currency"` // ISO 4217 code
+	Rate      money.Rate `json:"rate" db:"rate"`         // Units of Currency worth one unit of money.DefaultCurrency
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ProductPrice is the price of a product, or of one of its variants, in a currency,
+// replacing the conversion of its price in the store currency.
+type ProductPrice struct {
+	ProductID uuid.UUID   `json:"product_id" db:"product_id"`
+	VariantID *uuid.UUID  `json:"variant_id" db:"variant_id"` // Nil for the product, and its variants without a price of their own
+	Price     money.Money `json:"price" db:"-"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
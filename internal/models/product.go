@@ -1,6 +1,7 @@
 package models
 
 import (
+	"bullet-cloud-api/internal/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,11 +9,25 @@ import (
 
 // Product represents a product in the e-commerce system.
 type Product struct {
-	ID          uuid.UUID  `json:"id" db:"id"`
-	Name        string     `json:"name" db:"name"`
-	Description string     `json:"description" db:"description"`
-	Price       float64    `json:"price" db:"price"`             // Use numeric/decimal type in DB for precision
-	CategoryID  *uuid.UUID `json:"category_id" db:"category_id"` // Pointer to allow null category initially
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID   `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name"`
+	Description string      `json:"description" db:"description"`
+	Price       money.Money `json:"price" db:"price"`             // Exact amount, stored as NUMERIC
+	CategoryID  *uuid.UUID  `json:"category_id" db:"category_id"` // Pointer to allow null category initially
+	Stock       *int        `json:"stock" db:"stock"`             // Units available at all locations; nil when the stock is not tracked
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+
+	Highlight *ProductHighlight `json:"highlight,omitempty" db:"-"` // Set only on search results
+
+	// Set only on the product detail, for products sold in variants
+	Variants []ProductVariant    `json:"variants,omitempty" db:"-"`
+	Options  map[string][]string `json:"options,omitempty" db:"-"` // Values of each option across the variants
+}
+
+// ProductHighlight holds the parts of a product that matched a search, with the matching
+// words wrapped in <mark></mark>.
+type ProductHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"` // The best matching fragments, separated by " … "
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"bullet-cloud-api/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductVariant is a purchasable version of a product, such as one size and color of a T-shirt.
+type ProductVariant struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	ProductID uuid.UUID         `json:"product_id" db:"product_id"` // Foreign key to products table
+	SKU       string            `json:"sku" db:"sku"`               // Unique stock keeping unit
+	Options   map[string]string `json:"options" db:"options"`       // Option name to value, e.g. {"size": "M", "color": "Azul"}
+	Price     *money.Money      `json:"price" db:"price"`           // Overrides the product price when set
+	Barcode   *string           `json:"barcode" db:"barcode"`       // EAN/UPC, optional
+	Stock     *int              `json:"stock" db:"stock"`           // Units available at all locations; nil when the stock is not tracked
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// EffectivePrice returns the price the variant sells for: its own, or else the product's.
+func (v *ProductVariant) EffectivePrice(productPrice money.Money) money.Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return productPrice
+}
+
+// VariantOptionMatrix returns the values each option takes across variants, in the order
+// they first appear, e.g. {"size": ["P", "M", "G"], "color": ["Azul", "Preto"]}.
+func VariantOptionMatrix(variants []ProductVariant) map[string][]string {
+	matrix := make(map[string][]string)
+	seen := make(map[[2]string]bool)
+	for _, v := range variants {
+		for name, value := range v.Options {
+			if !seen[[2]string{name, value}] {
+				seen[[2]string{name, value}] = true
+				matrix[name] = append(matrix[name], value)
+			}
+		}
+	}
+	return matrix
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken represents a stored refresh token. Only the token hash is persisted.
+type RefreshToken struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`                 // Foreign key to users table
+	FamilyID   uuid.UUID  `json:"family_id" db:"family_id"`             // Shared by all tokens rotated from the same login
+	TokenHash  string     `json:"-" db:"token_hash"`                    // SHA-256 of the opaque token, never exposed
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`           // Absolute expiry of this token
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"` // Set once the token is rotated or revoked
+	ReplacedBy *uuid.UUID `json:"replaced_by,omitempty" db:"replaced_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
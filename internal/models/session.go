@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session represents one login of a user. Its ID is the jti claim of every access token issued
+// for that login and the family ID of its refresh tokens, so revoking it invalidates both.
+type Session struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`                 // Foreign key to users table
+	UserAgent  string     `json:"user_agent" db:"user_agent"`           // User-Agent header at login
+	IPAddress  string     `json:"ip_address" db:"ip_address"`           // Client IP at login
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`           // Login time
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`       // Last authenticated request (approximate)
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"` // Set when the session is logged out or revoked
+}
+
+// IsActive reports whether the session has not been revoked.
+func (s *Session) IsActive() bool {
+	return s != nil && s.RevokedAt == nil
+}
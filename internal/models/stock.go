@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockLocation is a warehouse or other place stock is kept and shipped from.
+type StockLocation struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	Street     string    `json:"street" db:"street"`
+	City       string    `json:"city" db:"city"`
+	State      string    `json:"state" db:"state"` // Orders ship from the locations nearest to their state
+	PostalCode string    `json:"postal_code" db:"postal_code"`
+	Country    string    `json:"country" db:"country"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// StockLevel is the quantity of a product, or of one of its variants, at a location.
+type StockLevel struct {
+	LocationID   uuid.UUID `json:"location_id" db:"location_id"`
+	LocationName string    `json:"location_name" db:"location_name"`
+	Quantity     int       `json:"quantity" db:"quantity"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// StockSummary is the stock of a product, or of one of its variants, across locations.
+type StockSummary struct {
+	ProductID uuid.UUID    `json:"product_id"`
+	VariantID *uuid.UUID   `json:"variant_id"` // Set for products sold in variants
+	Stock     *int         `json:"stock"`      // Total units; nil when no location tracks the stock
+	Levels    []StockLevel `json:"levels"`
+
+	// Products only: alerts are sent when Stock falls below it. Omitted when not set.
+	ReorderThreshold *int `json:"reorder_threshold,omitempty"`
+}
+
+// StockTransfer records units moved from one location to another.
+type StockTransfer struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	FromLocationID uuid.UUID  `json:"from_location_id" db:"from_location_id"`
+	ToLocationID   uuid.UUID  `json:"to_location_id" db:"to_location_id"`
+	ProductID      uuid.UUID  `json:"product_id" db:"product_id"`
+	VariantID      *uuid.UUID `json:"variant_id" db:"variant_id"`
+	Quantity       int        `json:"quantity" db:"quantity"`
+	Note           string     `json:"note" db:"note"`
+	CreatedBy      *uuid.UUID `json:"created_by" db:"created_by"` // The admin who made it
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PickingListItem is an order item to collect at a location and ship.
+type PickingListItem struct {
+	OrderID     uuid.UUID   `json:"order_id" db:"order_id"`
+	OrderStatus OrderStatus `json:"order_status" db:"order_status"`
+	OrderedAt   time.Time   `json:"ordered_at" db:"ordered_at"`
+	ProductID   uuid.UUID   `json:"product_id" db:"product_id"`
+	VariantID   *uuid.UUID  `json:"variant_id" db:"variant_id"`
+	Name        string      `json:"name" db:"name"` // Product name
+	SKU         *string     `json:"sku" db:"sku"`   // Variant SKU
+	Quantity    int         `json:"quantity" db:"quantity"`
+}
+
+// LowStockProduct is a product whose units at all locations fell below its reorder threshold.
+type LowStockProduct struct {
+	ProductID        uuid.UUID  `json:"product_id" db:"product_id"`
+	Name             string     `json:"name" db:"name"`
+	Stock            int        `json:"stock" db:"stock"`
+	ReorderThreshold int        `json:"reorder_threshold" db:"reorder_threshold"`
+	UnitsSold        int        `json:"units_sold" db:"units_sold"`           // In the sales window
+	DailySales       float64    `json:"daily_sales" db:"-"`                   // Average units sold per day in the sales window
+	DaysOfCover      *float64   `json:"days_of_cover" db:"-"`                 // Days the stock lasts at DailySales; nil without sales
+	AlertedAt        *time.Time `json:"alerted_at" db:"low_stock_alerted_at"` // When purchasing was told; nil if not yet
+}
@@ -6,12 +6,45 @@ import (
 	"github.com/google/uuid"
 )
 
+// UserRole defines the access level of a user.
+type UserRole string
+
+const (
+	RoleCustomer UserRole = "customer" // Default role for registered users
+	RoleAdmin    UserRole = "admin"    // Manages catalog, orders and other users' data
+)
+
+// IsValid reports whether r is one of the known roles.
+func (r UserRole) IsValid() bool {
+	switch r {
+	case RoleCustomer, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 // User represents a user in the system.
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	Name         string    `json:"name" db:"name"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"` // Never expose password hash in JSON responses
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Name            string     `json:"name" db:"name"`
+	Email           string     `json:"email" db:"email"`
+	PasswordHash    string     `json:"-" db:"password_hash"` // Never expose password hash in JSON responses
+	Role            UserRole   `json:"role" db:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"` // Nil until the user confirms their email
+	EmailChangedAt  time.Time  `json:"-" db:"email_changed_at"`                  // Versions the email; verification links carry it
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+
+	DisabledAt            *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`                         // Set while an admin has disabled the account
+	PasswordResetRequired bool       `json:"password_reset_required,omitempty" db:"password_reset_required"` // Password logins are refused until the password is reset
+}
+
+// IsEmailVerified reports whether the user has confirmed their email address.
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsDisabled reports whether an admin has disabled the account.
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
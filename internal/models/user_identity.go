@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a user to an account at an external OpenID Connect provider.
+type UserIdentity struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`   // Foreign key to users table
+	Provider  string    `json:"provider" db:"provider"` // Configured provider name, e.g. "google"
+	Subject   string    `json:"subject" db:"subject"`   // The provider's stable user ID (sub claim)
+	Email     string    `json:"email" db:"email"`       // Verified email reported when the identity was linked
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
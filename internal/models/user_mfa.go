@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserMFA holds a user's TOTP two-factor authentication settings.
+// A row without EnabledAt is a pending enrollment that has not been confirmed yet.
+type UserMFA struct {
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"`                                // Base32 TOTP secret, never exposed after enrollment
+	EnabledAt    *time.Time `json:"enabled_at,omitempty" db:"enabled_at"`         // Set once the user confirms a first code
+	LastUsedStep *int64     `json:"last_used_step,omitempty" db:"last_used_step"` // Last accepted TOTP time step, prevents code replay
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsEnabled reports whether two-factor authentication is active for the user.
+func (m *UserMFA) IsEnabled() bool {
+	return m != nil && m.EnabledAt != nil
+}
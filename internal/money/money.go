@@ -0,0 +1,298 @@
+// Package money represents amounts of money exactly, as integer minor units (e.g. cents)
+// of a currency.
+//
+// Amounts with more decimals than their currency has are rounded half to even (banker's
+// rounding) wherever they come from: parsed text, JSON or NUMERIC columns.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultCurrency is the currency of the store, used for amounts given without one.
+const DefaultCurrency = "BRL"
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount, expected a decimal such as \"59.90\"")
+	ErrInvalidCurrency  = errors.New("invalid currency, expected an ISO 4217 code such as \"BRL\"")
+	ErrOutOfRange       = errors.New("amount out of range")
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+	// ErrUnsupportedCurrency is returned for currencies with more minor units than the
+	// NUMERIC(10,2) columns amounts are stored in.
+	ErrUnsupportedCurrency = errors.New("unsupported currency, amounts are stored with at most two decimals")
+)
+
+// zeroDigitCurrencies are the ISO 4217 currencies without minor units; the others have two.
+var zeroDigitCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true, "KMF": true,
+	"KRW": true, "PYG": true, "RWF": true, "UGX": true, "UYI": true, "VND": true, "VUV": true,
+	"XAF": true, "XOF": true, "XPF": true,
+}
+
+// threeDigitCurrencies are the ISO 4217 currencies with three minor units. They are refused
+// by Parse and ParseCurrency rather than rounded, so Digits never has to describe them.
+var threeDigitCurrencies = map[string]bool{
+	"BHD": true, "IQD": true, "JOD": true, "KWD": true, "LYD": true, "OMR": true, "TND": true,
+}
+
+// Digits returns the number of decimals of a currency.
+func Digits(currency string) int {
+	if zeroDigitCurrencies[currency] {
+		return 0
+	}
+	return 2
+}
+
+// Money is an amount in a currency. The zero Money is zero in no currency yet; adding an
+// amount to it takes that amount's currency.
+type Money struct {
+	Amount   int64  // Minor units of Currency, e.g. cents
+	Currency string // ISO 4217 code
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// FromUnits returns a whole number of units of currency (e.g. reais, not centavos).
+func FromUnits(units int64, currency string) Money {
+	return Money{Amount: units * pow10(Digits(currency)).Int64(), Currency: currency}
+}
+
+// Parse reads a decimal amount such as "59.90" or "-3" in currency.
+func Parse(s, currency string) (Money, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	coefficient, exponent, ok := parseDecimal(s)
+	if !ok {
+		return Money{}, ErrInvalidAmount
+	}
+	amount, err := scale(coefficient, exponent, Digits(currency))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on invalid input. It is meant for constants and tests.
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(fmt.Sprintf("money: Parse(%q, %q): %v", s, currency, err))
+	}
+	return m
+}
+
+// String returns the amount as a decimal with the currency's digits, e.g. "59.90".
+func (m Money) String() string {
+	digits := Digits(m.Currency)
+	abs := new(big.Int).Abs(big.NewInt(m.Amount)).String()
+	if digits > 0 {
+		if len(abs) <= digits {
+			abs = strings.Repeat("0", digits-len(abs)+1) + abs
+		}
+		abs = abs[:len(abs)-digits] + "." + abs[len(abs)-digits:]
+	}
+	if m.Amount < 0 {
+		return "-" + abs
+	}
+	return abs
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool { return m.Amount == 0 }
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// IsNegative reports whether the amount is less than zero.
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Mul returns the amount times n, e.g. a unit price times a quantity, or ErrOutOfRange
+// when the result does not fit.
+func (m Money) Mul(n int) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(int64(n)))
+	if !product.IsInt64() {
+		return Money{}, ErrOutOfRange
+	}
+	return Money{Amount: product.Int64(), Currency: m.Currency}, nil
+}
+
+// Add returns the sum of two amounts in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	switch {
+	case m.Currency == "" && m.Amount == 0:
+		return other, nil
+	case other.Currency == "" && other.Amount == 0:
+		return m, nil
+	case m.Currency != other.Currency:
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	case (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) || (other.Amount < 0 && m.Amount < math.MinInt64-other.Amount):
+		return Money{}, ErrOutOfRange
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// moneyJSON is the JSON form of Money. Amounts are strings, so clients never read them
+// into floating point by accident.
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"amount": "59.90", "currency": "BRL"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, _ := json.Marshal(m.String())
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return json.Marshal(moneyJSON{Amount: amount, Currency: currency})
+}
+
+// UnmarshalJSON decodes {"amount": "59.90", "currency": "BRL"}, or just the amount as a
+// string ("59.90") or number (59.90), in the currency already set or else DefaultCurrency.
+// Numbers are read from their decimal text, never through floating point.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	amount, currency := data, m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if len(data) > 0 && data[0] == '{' {
+		var v moneyJSON
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		amount = bytes.TrimSpace(v.Amount)
+		if v.Currency != "" {
+			currency = v.Currency
+		}
+	}
+	text := string(amount)
+	if len(amount) > 0 && amount[0] == '"' {
+		if err := json.Unmarshal(amount, &text); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := Parse(text, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ScanNumeric reads a NUMERIC column through pgx. The currency is kept when already set,
+// otherwise it is DefaultCurrency.
+func (m *Money) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into money.Money")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return ErrOutOfRange
+	}
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	amount, err := scale(v.Int, v.Exp, Digits(currency))
+	if err != nil {
+		return err
+	}
+	m.Amount, m.Currency = amount, currency
+	return nil
+}
+
+// NumericValue writes the amount to a NUMERIC column through pgx.
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(m.Amount), Exp: -int32(Digits(m.Currency)), Valid: true}, nil
+}
+
+// normalizeCurrency validates an ISO 4217 code, returning it in upper case.
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	if threeDigitCurrencies[currency] {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	return currency, nil
+}
+
+// parseDecimal splits a decimal such as "-12.345" into its coefficient and exponent
+// (-12345 and -3).
+func parseDecimal(s string) (*big.Int, int32, bool) {
+	s = strings.TrimSpace(s)
+	whole, fraction, _ := strings.Cut(s, ".")
+	sign := ""
+	if strings.HasPrefix(whole, "-") || strings.HasPrefix(whole, "+") {
+		sign, whole = whole[:1], whole[1:]
+	}
+	if whole == "" && fraction == "" {
+		return nil, 0, false
+	}
+	for _, c := range whole + fraction {
+		if c < '0' || c > '9' {
+			return nil, 0, false
+		}
+	}
+	coefficient, ok := new(big.Int).SetString(sign+whole+fraction, 10)
+	return coefficient, -int32(len(fraction)), ok
+}
+
+// scale converts coefficient×10^exponent to an integer count of 10^-digits (minor units,
+// for the digits of a currency), rounding half to even.
+func scale(coefficient *big.Int, exponent int32, digits int) (int64, error) {
+	shift := int(exponent) + digits
+	n := new(big.Int).Set(coefficient)
+	if shift >= 0 {
+		n.Mul(n, pow10(shift))
+	} else {
+		n = roundHalfEven(n, pow10(-shift))
+	}
+	if !n.IsInt64() {
+		return 0, ErrOutOfRange
+	}
+	return n.Int64(), nil
+}
+
+// roundHalfEven divides n by d, rounding ties to the even quotient.
+func roundHalfEven(n, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	twice := new(big.Int).Lsh(new(big.Int).Abs(r), 1)
+	if c := twice.Cmp(d); c > 0 || (c == 0 && q.Bit(0) == 1) {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
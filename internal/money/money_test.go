@@ -0,0 +1,126 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{in: "59.90", currency: "BRL", want: New(5990, "BRL")},
+		{in: "59.9", currency: "brl", want: New(5990, "BRL")},
+		{in: "7", currency: "BRL", want: New(700, "BRL")},
+		{in: ".5", currency: "BRL", want: New(50, "BRL")},
+		{in: "-3.10", currency: "BRL", want: New(-310, "BRL")},
+		{in: "1500", currency: "JPY", want: New(1500, "JPY")},
+		// Half to even
+		{in: "0.125", currency: "BRL", want: New(12, "BRL")},
+		{in: "0.135", currency: "BRL", want: New(14, "BRL")},
+		{in: "0.1251", currency: "BRL", want: New(13, "BRL")},
+		{in: "-0.125", currency: "BRL", want: New(-12, "BRL")},
+		{in: "2.5", currency: "JPY", want: New(2, "JPY")},
+		{in: "", currency: "BRL", wantErr: ErrInvalidAmount},
+		{in: "1e3", currency: "BRL", wantErr: ErrInvalidAmount},
+		{in: "1.2.3", currency: "BRL", wantErr: ErrInvalidAmount},
+		{in: "10", currency: "REAL", wantErr: ErrInvalidCurrency},
+		{in: "1.234", currency: "KWD", wantErr: ErrUnsupportedCurrency},
+		{in: "1", currency: "bhd", wantErr: ErrUnsupportedCurrency},
+		{in: "99999999999999999999", currency: "BRL", wantErr: ErrOutOfRange},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.currency)
+		if tt.wantErr != nil {
+			assert.ErrorIs(t, err, tt.wantErr, tt.in)
+			continue
+		}
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "59.90", New(5990, "BRL").String())
+	assert.Equal(t, "0.05", New(5, "BRL").String())
+	assert.Equal(t, "-0.05", New(-5, "BRL").String())
+	assert.Equal(t, "1500", New(1500, "JPY").String())
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	// 0.1 + 0.2 drifts in floating point, not here
+	sum, err := MustParse("0.10", "BRL").Add(MustParse("0.20", "BRL"))
+	require.NoError(t, err)
+	assert.Equal(t, "0.30", sum.String())
+
+	total := Money{} // Takes the currency of the first amount
+	for _, price := range []string{"19.99", "19.99", "19.99"} {
+		total, err = total.Add(MustParse(price, "BRL"))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, MustParse("59.97", "BRL"), total)
+	product, err := MustParse("19.99", "BRL").Mul(3)
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("59.97", "BRL"), product)
+
+	_, err = total.Add(MustParse("1", "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// Results beyond int64 minor units are reported, not wrapped around
+	_, err = New(math.MaxInt64/2+1, "BRL").Mul(2)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = New(math.MinInt64/3-1, "BRL").Mul(3)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = New(math.MaxInt64, "BRL").Add(New(1, "BRL"))
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = New(math.MinInt64, "BRL").Add(New(-1, "BRL"))
+	assert.ErrorIs(t, err, ErrOutOfRange)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(New(5990, "BRL"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"59.90","currency":"BRL"}`, string(data))
+
+	for _, in := range []string{`{"amount":"59.90","currency":"BRL"}`, `{"amount":59.9}`, `"59.90"`, `59.90`} {
+		var m Money
+		require.NoError(t, json.Unmarshal([]byte(in), &m), in)
+		assert.Equal(t, New(5990, "BRL"), m, in)
+	}
+
+	var usd Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"10","currency":"usd"}`), &usd))
+	assert.Equal(t, New(1000, "USD"), usd)
+
+	jpy := Money{Currency: "JPY"} // Bare amounts are read in the currency already set
+	require.NoError(t, json.Unmarshal([]byte(`"1500"`), &jpy))
+	assert.Equal(t, New(1500, "JPY"), jpy)
+
+	var m Money
+	assert.ErrorIs(t, json.Unmarshal([]byte(`"abc"`), &m), ErrInvalidAmount)
+}
+
+func TestMoney_Numeric(t *testing.T) {
+	var m Money
+	require.NoError(t, m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(1999), Exp: -2, Valid: true}))
+	assert.Equal(t, New(1999, DefaultCurrency), m)
+
+	jpy := Money{Currency: "JPY"}
+	require.NoError(t, jpy.ScanNumeric(pgtype.Numeric{Int: big.NewInt(150050), Exp: -2, Valid: true}))
+	assert.Equal(t, New(1500, "JPY"), jpy) // 1500.50 rounds half to even
+
+	assert.Error(t, m.ScanNumeric(pgtype.Numeric{}))
+
+	v, err := New(5990, "BRL").NumericValue()
+	require.NoError(t, err)
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(5990), Exp: -2, Valid: true}, v)
+}
@@ -0,0 +1,137 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RateDigits is the number of decimals of exchange rates.
+const RateDigits = 8
+
+// ErrInvalidRate is returned for exchange rates that are not positive decimals.
+var ErrInvalidRate = errors.New("invalid exchange rate, expected a positive decimal such as \"0.19\"")
+
+// Rate is an exchange rate: the units of a currency worth one unit of another, such as
+// the store currency. It is exact to RateDigits decimals, counted as an integer.
+type Rate int64
+
+// OneRate is the rate of a currency against itself.
+const OneRate Rate = 1e8
+
+// ParseRate reads a positive decimal exchange rate such as "0.19".
+func ParseRate(s string) (Rate, error) {
+	coefficient, exponent, ok := parseDecimal(s)
+	if !ok {
+		return 0, ErrInvalidRate
+	}
+	n, err := scale(coefficient, exponent, RateDigits)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, ErrInvalidRate
+	}
+	return Rate(n), nil
+}
+
+// MustParseRate is like ParseRate but panics on invalid input. It is meant for constants
+// and tests.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(fmt.Sprintf("money: ParseRate(%q): %v", s, err))
+	}
+	return r
+}
+
+// String returns the rate as a decimal without trailing zeros, e.g. "0.19".
+func (r Rate) String() string {
+	sign, abs := "", int64(r)
+	if abs < 0 {
+		sign, abs = "-", -abs
+	}
+	s := fmt.Sprintf("%0*d", RateDigits+1, abs)
+	s = s[:len(s)-RateDigits] + "." + s[len(s)-RateDigits:]
+	return sign + strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON encodes the rate as a string, e.g. "0.19".
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes a rate given as a string ("0.19") or a number (0.19).
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParseRate(text)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// ScanNumeric reads a NUMERIC column through pgx.
+func (r *Rate) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into money.Rate")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return ErrOutOfRange
+	}
+	n, err := scale(v.Int, v.Exp, RateDigits)
+	if err != nil {
+		return err
+	}
+	*r = Rate(n)
+	return nil
+}
+
+// NumericValue writes the rate to a NUMERIC column through pgx.
+func (r Rate) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(r)), Exp: -RateDigits, Valid: true}, nil
+}
+
+// ParseCurrency validates an ISO 4217 currency code, returning it in upper case.
+// Currencies with three decimals fail with ErrUnsupportedCurrency.
+func ParseCurrency(s string) (string, error) {
+	return normalizeCurrency(s)
+}
+
+// Convert returns the amount in currency, given the rates of its currency (from) and of
+// currency (to) against a common base: amount × to ÷ from, rounded half to even.
+func (m Money) Convert(currency string, from, to Rate) (Money, error) {
+	if from <= 0 || to <= 0 {
+		return Money{}, ErrInvalidRate
+	}
+	// amount / 10^digits(m) × to / from × 10^digits(currency)
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(int64(to)))
+	n.Mul(n, pow10(Digits(currency)))
+	d := new(big.Int).Mul(big.NewInt(int64(from)), pow10(Digits(m.Currency)))
+	amount := roundHalfEven(n, d)
+	if !amount.IsInt64() {
+		return Money{}, ErrOutOfRange
+	}
+	return Money{Amount: amount.Int64(), Currency: currency}, nil
+}
+
+// As returns the same decimal amount in another currency, e.g. 1500.00 BRL as 1500 JPY.
+// It relabels amounts read without their currency; Convert is for exchanging them.
+func (m Money) As(currency string) (Money, error) {
+	return m.Convert(currency, OneRate, OneRate)
+}
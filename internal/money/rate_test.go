@@ -0,0 +1,71 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	r, err := ParseRate("0.19")
+	require.NoError(t, err)
+	assert.Equal(t, Rate(19_000_000), r)
+	assert.Equal(t, "0.19", r.String())
+	assert.Equal(t, "1", OneRate.String())
+
+	r, err = ParseRate("5.123456785") // Half to even at the 8th decimal
+	require.NoError(t, err)
+	assert.Equal(t, "5.12345678", r.String())
+
+	for _, in := range []string{"", "0", "-1", "abc"} {
+		_, err := ParseRate(in)
+		assert.ErrorIs(t, err, ErrInvalidRate, in)
+	}
+}
+
+func TestRate_JSONAndNumeric(t *testing.T) {
+	data, err := json.Marshal(Rate(19_500_000))
+	require.NoError(t, err)
+	assert.Equal(t, `"0.195"`, string(data))
+
+	var r Rate
+	require.NoError(t, json.Unmarshal([]byte(`0.195`), &r))
+	assert.Equal(t, Rate(19_500_000), r)
+
+	require.NoError(t, r.ScanNumeric(pgtype.Numeric{Int: big.NewInt(525), Exp: -2, Valid: true}))
+	assert.Equal(t, Rate(525_000_000), r)
+	v, err := r.NumericValue()
+	require.NoError(t, err)
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(525_000_000), Exp: -8, Valid: true}, v)
+}
+
+func TestMoney_Convert(t *testing.T) {
+	usd, jpy := MustParseRate("0.19"), MustParseRate("28.5")
+
+	got, err := MustParse("59.90", "BRL").Convert("USD", OneRate, usd)
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("11.38", "USD"), got) // 11.381
+
+	got, err = MustParse("10.00", "USD").Convert("BRL", usd, OneRate)
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("52.63", "BRL"), got) // 52.6315...
+
+	got, err = MustParse("10.00", "USD").Convert("JPY", usd, jpy) // Through the store currency
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("1500", "JPY"), got)
+
+	got, err = MustParse("0.10", "BRL").Convert("JPY", OneRate, MustParseRate("25")) // 2.5 yen
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("2", "JPY"), got)
+
+	got, err = MustParse("1500", "BRL").As("JPY")
+	require.NoError(t, err)
+	assert.Equal(t, New(1500, "JPY"), got)
+
+	_, err = MustParse("1", "BRL").Convert("USD", 0, usd)
+	assert.ErrorIs(t, err, ErrInvalidRate)
+}
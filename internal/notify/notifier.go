@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a notification addressed to a single user, or to a team such as purchasing.
+type Message struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+
+	// Set on messages reporting an event (e.g. "stock.low"), so that their receivers can
+	// act on the details without parsing the body.
+	Event string      `json:"event,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// Notifier delivers messages to users (e.g. password reset links) and events to the team.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// logNotifier writes messages to the application log. Intended for development only,
+// since message bodies may contain secrets such as reset links.
+type logNotifier struct{}
+
+// NewLogNotifier creates a Notifier that logs every message.
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+// Send logs the message.
+func (n *logNotifier) Send(ctx context.Context, msg Message) error {
+	log.Printf("NOTIFY to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// outboxNotifier writes each message as a JSON file into a local directory,
+// so deliveries can be inspected (or picked up by another process) without a mail server.
+type outboxNotifier struct {
+	dir string
+}
+
+// NewOutboxNotifier creates a Notifier that writes messages into dir, creating it if needed.
+func NewOutboxNotifier(dir string) (Notifier, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating outbox directory: %w", err)
+	}
+	return &outboxNotifier{dir: dir}, nil
+}
+
+// Send writes the message to <dir>/<timestamp>-<id>.json.
+func (n *outboxNotifier) Send(ctx context.Context, msg Message) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.json", msg.SentAt.Format("20060102T150405.000000000"), uuid.NewString())
+	return os.WriteFile(filepath.Join(n.dir, name), data, 0o600)
+}
+
+// webhookNotifier posts each message as JSON to a URL, for a mail service or chat
+// integration to deliver.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a Notifier that posts messages to url.
+func NewWebhookNotifier(url string, client *http.Client) (Notifier, error) {
+	if url == "" {
+		return nil, errors.New("webhook URL is required")
+	}
+	return &webhookNotifier{url: url, client: client}, nil
+}
+
+// Send posts the message, failing unless the webhook answers with a 2xx status.
+func (n *webhookNotifier) Send(ctx context.Context, msg Message) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,395 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyRefreshInterval limits how often the provider's JWKS is fetched again when a token
+// names an unknown key, so forged kids cannot be used to hammer the provider.
+const keyRefreshInterval = time.Minute
+
+// maxResponseBytes caps the size of documents read from the provider.
+const maxResponseBytes = 1 << 20
+
+var (
+	ErrInvalidIDToken = errors.New("invalid ID token")
+	ErrExchangeFailed = errors.New("authorization code exchange failed")
+)
+
+// Config describes an external OpenID Connect identity provider.
+type Config struct {
+	Name         string   // Short name used in URLs, e.g. "google"
+	Issuer       string   // Issuer URL; discovery is read from {Issuer}/.well-known/openid-configuration
+	ClientID     string   // Client registered with the provider
+	ClientSecret string   // Empty for public clients, which rely on PKCE alone
+	Scopes       []string // Requested scopes; "openid" is always included
+	RedirectURL  string   // This API's callback URL, as registered with the provider
+}
+
+// Claims is the identity asserted by a verified ID token.
+type Claims struct {
+	Subject       string // Stable user ID at the provider
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider runs the authorization code flow with PKCE against one identity provider.
+// Discovery and signing keys are fetched lazily and cached.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+// metadata is the subset of the discovery document used by the flow.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider creates a Provider. No request is made until the first login.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Name returns the provider's short name.
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// --- Login flow ---
+
+// Flow holds the per-login secrets that must survive the round trip to the provider.
+// It is kept by the client between the redirect and the callback, never sent to the provider as is.
+type Flow struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`         // Echoed back by the provider, ties the callback to this login
+	Nonce        string `json:"nonce"`         // Embedded in the ID token, prevents token replay
+	CodeVerifier string `json:"code_verifier"` // PKCE secret; only its hash goes in the authorization URL
+}
+
+// NewFlow generates fresh state, nonce and PKCE verifier values for a login.
+func NewFlow(provider string) (*Flow, error) {
+	values := make([]string, 3)
+	for i := range values {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return &Flow{Provider: provider, State: values[0], Nonce: values[1], CodeVerifier: values[2]}, nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge for the flow's verifier (RFC 7636).
+func (f *Flow) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(f.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL the user is sent to in order to sign in.
+func (p *Provider) AuthCodeURL(ctx context.Context, flow *Flow) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(md.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.scopes(), " "))
+	q.Set("state", flow.State)
+	q.Set("nonce", flow.Nonce)
+	q.Set("code_challenge", flow.CodeChallenge())
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity in the verified ID token.
+// Provider errors are wrapped in ErrExchangeFailed and token problems in ErrInvalidIDToken.
+func (p *Provider) Exchange(ctx context.Context, code string, flow *Flow) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {flow.CodeVerifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic, the default client authentication method (RFC 6749 section 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var tokenResp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &tokenResp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d: %s %s", ErrExchangeFailed, status, tokenResp.Error, tokenResp.ErrorDescription)
+	}
+	if tokenResp.IDToken == "" {
+		return nil, fmt.Errorf("%w: response has no id_token", ErrExchangeFailed)
+	}
+
+	return p.verifyIDToken(ctx, tokenResp.IDToken, flow.Nonce)
+}
+
+// --- ID token verification ---
+
+// idTokenClaims are the ID token claims checked or read by verifyIDToken.
+type idTokenClaims struct {
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// flexBool accepts both JSON booleans and the "true"/"false" strings some providers send.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// verifyIDToken checks the token's signature, issuer, audience, expiry and nonce.
+func (p *Provider) verifyIDToken(ctx context.Context, rawToken, nonce string) (*Claims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidIDToken)
+	}
+
+	return &Claims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// publicKey returns the provider key with the given kid, refetching the JWKS once
+// (at most every keyRefreshInterval) if the key is unknown, e.g. after a rotation.
+// Tokens without a kid are accepted only while the provider publishes a single key.
+func (p *Provider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, errors.New("unknown signing key")
+	}
+	if err := p.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// lookupKey finds a cached key. Callers must hold p.mu.
+func (p *Provider) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+// fetchKeys replaces the cached keys with the provider's current RSA signing keys.
+// Callers must hold p.mu.
+func (p *Provider) fetchKeys(ctx context.Context) error {
+	md, err := p.discoverLocked(ctx)
+	if err != nil {
+		return err
+	}
+	p.keysFetchedAt = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, md.JWKSURI, nil)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return fmt.Errorf("fetching JWKS: %w", err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("fetching JWKS: status %d", status)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	p.keys = keys
+	return nil
+}
+
+// --- Discovery ---
+
+// discover returns the provider metadata, fetching it on first use.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.discoverLocked(ctx)
+}
+
+// discoverLocked is discover for callers already holding p.mu.
+// Failures are not cached, so a provider outage does not outlive itself.
+func (p *Provider) discoverLocked(ctx context.Context) (*metadata, error) {
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	md := &metadata{}
+	status, err := p.doJSON(req, md)
+	if err != nil {
+		return nil, fmt.Errorf("fetching discovery document: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("fetching discovery document: status %d", status)
+	}
+	// The issuer must match exactly (OpenID Connect Discovery section 4.3)
+	if md.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match configured issuer %q", md.Issuer, p.cfg.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing required endpoints")
+	}
+
+	p.metadata = md
+	return md, nil
+}
+
+// doJSON sends the request and decodes a JSON response body of any status into v.
+func (p *Provider) doJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("decoding response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// scopes returns the configured scopes, making sure "openid" is requested.
+func (p *Provider) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range p.cfg.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
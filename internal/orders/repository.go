@@ -1,7 +1,9 @@
 package orders
 
 import (
+	"bullet-cloud-api/internal/fulfillment"
 	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
 	"context"
 	"errors"
 
@@ -11,16 +13,21 @@ import (
 )
 
 var (
-	ErrOrderNotFound          = errors.New("order not found")
-	ErrOrderCannotBeCancelled = errors.New("order cannot be cancelled in its current status")
+	ErrOrderNotFound           = errors.New("order not found")
+	ErrOrderCannotBeCancelled  = errors.New("order cannot be cancelled in its current status")
+	ErrInvalidStatusTransition = errors.New("order cannot move to this status from its current status")
 )
 
 // OrderRepository defines the interface for order data operations.
 type OrderRepository interface {
 	// CreateOrderFromCart creates a new order based on the items in a user's cart.
 	// It requires the cart items and the chosen shipping address ID.
+	// The items are taken from the stock of the locations chosen by the allocation strategy,
+	// splitting an item across locations when needed; an *InsufficientStockError lists
+	// those short of it.
+	// The cart items are priced in the currency of rate, which the order records.
 	// Returns the newly created order.
-	CreateOrderFromCart(ctx context.Context, userID, cartID, shippingAddressID uuid.UUID, cartItems []models.CartItem) (*models.Order, error)
+	CreateOrderFromCart(ctx context.Context, userID, cartID, shippingAddressID uuid.UUID, cartItems []models.CartItem, rate models.ExchangeRate) (*models.Order, error)
 
 	// FindUserOrders retrieves all orders for a specific user, ordered by creation date.
 	FindUserOrders(ctx context.Context, userID uuid.UUID) ([]models.Order, error)
@@ -28,7 +35,10 @@ type OrderRepository interface {
 	// FindOrderByID retrieves a specific order by its ID, including its items.
 	FindOrderByID(ctx context.Context, orderID uuid.UUID) (*models.Order, []models.OrderItem, error)
 
-	// UpdateOrderStatus changes the status of an existing order.
+	// UpdateOrderStatus changes the status of an existing order, following the transitions
+	// allowed by CanTransition: ErrOrderCannotBeCancelled for shipped or delivered orders,
+	// ErrInvalidStatusTransition otherwise. Cancelling it returns its items to stock.
+	// Reopening a cancelled order takes the items again, failing with an *InsufficientStockError.
 	UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status models.OrderStatus) error
 
 	// UpdateOrderTracking updates the tracking number for an order.
@@ -37,16 +47,17 @@ type OrderRepository interface {
 
 // postgresOrderRepository implements OrderRepository using PostgreSQL.
 type postgresOrderRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	strategy fulfillment.Strategy // How orders choose the locations they ship from
 }
 
 // NewPostgresOrderRepository creates a new instance of postgresOrderRepository.
-func NewPostgresOrderRepository(db *pgxpool.Pool) OrderRepository {
-	return &postgresOrderRepository{db: db}
+func NewPostgresOrderRepository(db *pgxpool.Pool, strategy fulfillment.Strategy) OrderRepository {
+	return &postgresOrderRepository{db: db, strategy: strategy}
 }
 
 // CreateOrderFromCart handles the creation of an order within a transaction.
-func (r *postgresOrderRepository) CreateOrderFromCart(ctx context.Context, userID, cartID, shippingAddressID uuid.UUID, cartItems []models.CartItem) (*models.Order, error) {
+func (r *postgresOrderRepository) CreateOrderFromCart(ctx context.Context, userID, cartID, shippingAddressID uuid.UUID, cartItems []models.CartItem, rate models.ExchangeRate) (*models.Order, error) {
 	if len(cartItems) == 0 {
 		return nil, errors.New("cannot create order from empty cart")
 	}
@@ -58,28 +69,48 @@ func (r *postgresOrderRepository) CreateOrderFromCart(ctx context.Context, userI
 	}
 	defer tx.Rollback(ctx) // Ensure rollback on error
 
-	// 1. Calculate total price
-	var total float64
+	// 1. Take the items from the stock of their locations, holding the stock rows locked until commit
+	stockItems := make([]stockItem, len(cartItems))
+	for i, item := range cartItems {
+		stockItems[i] = stockItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity, Price: item.Price}
+	}
+	allocated, err := r.allocateStock(ctx, tx, shippingAddressID, stockItems)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Calculate total price
+	total := money.New(0, rate.Currency)
 	for _, item := range cartItems {
-		total += item.Price * float64(item.Quantity)
+		line, err := item.Price.Mul(item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		if total, err = total.Add(line); err != nil {
+			return nil, err
+		}
 	}
 
-	// 2. Create the order record
+	// 3. Create the order record
 	orderQuery := `
-		INSERT INTO orders (user_id, shipping_address_id, status, total)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO orders (user_id, shipping_address_id, status, total, currency, exchange_rate)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, status, created_at, updated_at
 	`
 	order := &models.Order{
 		UserID:            userID,
 		ShippingAddressID: shippingAddressID,
 		Total:             total,
+		Currency:          rate.Currency,
+		ExchangeRate:      rate.Rate,
 	}
 	err = tx.QueryRow(ctx, orderQuery,
 		userID,
 		shippingAddressID,
 		models.StatusPending, // Initial status
 		total,
+		rate.Currency,
+		rate.Rate,
 	).Scan(&order.ID, &order.Status, &order.CreatedAt, &order.UpdatedAt)
 
 	if err != nil {
@@ -87,19 +118,19 @@ func (r *postgresOrderRepository) CreateOrderFromCart(ctx context.Context, userI
 		return nil, err
 	}
 
-	// 3. Create order items from cart items
+	// 4. Create order items from cart items, one per location an item ships from
 	orderItemQuery := `
-		INSERT INTO order_items (order_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO order_items (order_id, product_id, variant_id, location_id, quantity, price)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 	batch := &pgx.Batch{}
-	for _, item := range cartItems {
-		batch.Queue(orderItemQuery, order.ID, item.ProductID, item.Quantity, item.Price)
+	for _, item := range allocated {
+		batch.Queue(orderItemQuery, order.ID, item.ProductID, item.VariantID, item.LocationID, item.Quantity, item.Price)
 	}
 
 	results := tx.SendBatch(ctx, batch)
 	// Check results for errors
-	for i := 0; i < len(cartItems); i++ {
+	for i := 0; i < len(allocated); i++ {
 		_, errItem := results.Exec()
 		if errItem != nil {
 			results.Close() // Important to close batch results
@@ -112,7 +143,7 @@ func (r *postgresOrderRepository) CreateOrderFromCart(ctx context.Context, userI
 		return nil, errClose
 	}
 
-	// 4. Clear the cart (important: use the original cartID)
+	// 5. Clear the cart (important: use the original cartID)
 	clearCartQuery := `DELETE FROM cart_items WHERE cart_id = $1`
 	_, errClear := tx.Exec(ctx, clearCartQuery, cartID)
 	if errClear != nil {
@@ -122,7 +153,7 @@ func (r *postgresOrderRepository) CreateOrderFromCart(ctx context.Context, userI
 		return nil, errClear // For now, treat as failure
 	}
 
-	// 5. Commit transaction
+	// 6. Commit transaction
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
@@ -133,7 +164,7 @@ func (r *postgresOrderRepository) CreateOrderFromCart(ctx context.Context, userI
 // FindUserOrders retrieves orders for a user.
 func (r *postgresOrderRepository) FindUserOrders(ctx context.Context, userID uuid.UUID) ([]models.Order, error) {
 	query := `
-		SELECT id, user_id, shipping_address_id, status, total, tracking_number, created_at, updated_at
+		SELECT id, user_id, shipping_address_id, status, total, currency, exchange_rate, tracking_number, created_at, updated_at
 		FROM orders
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -148,6 +179,11 @@ func (r *postgresOrderRepository) FindUserOrders(ctx context.Context, userID uui
 	if err != nil {
 		return nil, err
 	}
+	for i := range orders {
+		if err := inOrderCurrency(&orders[i], nil); err != nil {
+			return nil, err
+		}
+	}
 	return orders, nil
 }
 
@@ -162,13 +198,13 @@ func (r *postgresOrderRepository) FindOrderByID(ctx context.Context, orderID uui
 
 	// Get order details
 	orderQuery := `
-		SELECT id, user_id, shipping_address_id, status, total, tracking_number, created_at, updated_at
+		SELECT id, user_id, shipping_address_id, status, total, currency, exchange_rate, tracking_number, created_at, updated_at
 		FROM orders
 		WHERE id = $1
 	`
 	order := &models.Order{}
 	err = tx.QueryRow(ctx, orderQuery, orderID).Scan(
-		&order.ID, &order.UserID, &order.ShippingAddressID, &order.Status, &order.Total, &order.TrackingNumber, &order.CreatedAt, &order.UpdatedAt,
+		&order.ID, &order.UserID, &order.ShippingAddressID, &order.Status, &order.Total, &order.Currency, &order.ExchangeRate, &order.TrackingNumber, &order.CreatedAt, &order.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -179,7 +215,7 @@ func (r *postgresOrderRepository) FindOrderByID(ctx context.Context, orderID uui
 
 	// Get order items
 	itemsQuery := `
-		SELECT id, order_id, product_id, quantity, price, created_at, updated_at
+		SELECT id, order_id, product_id, variant_id, location_id, quantity, price, created_at, updated_at
 		FROM order_items
 		WHERE order_id = $1
 		ORDER BY created_at ASC
@@ -194,6 +230,9 @@ func (r *postgresOrderRepository) FindOrderByID(ctx context.Context, orderID uui
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := inOrderCurrency(order, items); err != nil {
+		return nil, nil, err
+	}
 
 	// Commit (read-only transaction, could use Query instead of Begin/Commit)
 	if err := tx.Commit(ctx); err != nil {
@@ -203,33 +242,76 @@ func (r *postgresOrderRepository) FindOrderByID(ctx context.Context, orderID uui
 	return order, items, nil
 }
 
-// UpdateOrderStatus changes the status.
+// inOrderCurrency relabels the amounts of an order and its items, read in the store
+// currency's decimals (which NUMERIC(10, 2) never exceeds), with the order's currency.
+func inOrderCurrency(order *models.Order, items []models.OrderItem) error {
+	total, err := order.Total.As(order.Currency)
+	if err != nil {
+		return err
+	}
+	order.Total = total
+	for i := range items {
+		price, err := items[i].Price.As(order.Currency)
+		if err != nil {
+			return err
+		}
+		items[i].Price = price
+	}
+	return nil
+}
+
+// UpdateOrderStatus changes the status, moving the order items in or out of stock when the
+// order is cancelled or reopened.
 func (r *postgresOrderRepository) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status models.OrderStatus) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the order so that concurrent status changes move its stock only once
+	var current models.OrderStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&current)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+
+	if !CanTransition(current, status) {
+		if status == models.StatusCancelled {
+			return ErrOrderCannotBeCancelled // Its items have left the stock for good
+		}
+		return ErrInvalidStatusTransition
+	}
+
+	cancelling := status == models.StatusCancelled && current != models.StatusCancelled
+	reopening := status != models.StatusCancelled && current == models.StatusCancelled
+	if cancelling || reopening {
+		items, err := orderStockItems(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		if cancelling {
+			err = releaseStock(ctx, tx, items)
+		} else {
+			err = reserveStock(ctx, tx, items)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	query := `
 		UPDATE orders
 		SET status = $1, updated_at = NOW()
 		WHERE id = $2
 	`
-	// Optional: Add checks to prevent invalid status transitions (e.g., cannot cancel if shipped)
-	// WHERE id = $2 AND status NOT IN ('shipped', 'delivered', 'cancelled')
-
-	result, err := r.db.Exec(ctx, query, status, orderID)
-	if err != nil {
+	if _, err := tx.Exec(ctx, query, status, orderID); err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		// Could be not found, or status prevented update
-		// Check if order exists to differentiate
-		_, _, findErr := r.FindOrderByID(ctx, orderID)
-		if findErr == ErrOrderNotFound {
-			return ErrOrderNotFound
-		} else if findErr != nil {
-			return findErr // Error during the check
-		}
-		// If order exists but wasn't updated, assume status prevented it
-		return ErrOrderCannotBeCancelled // Or a more generic "status update failed"
-	}
-	return nil
+	return tx.Commit(ctx)
 }
 
 // UpdateOrderTracking updates the tracking number.
@@ -0,0 +1,120 @@
+package orders
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOrderRepository is a mock type for the OrderRepository interface
+type MockOrderRepository struct {
+	mock.Mock
+}
+
+// CreateOrderFromCart provides a mock function with given fields: ctx, userID, cartID, shippingAddressID, cartItems, rate
+func (_m *MockOrderRepository) CreateOrderFromCart(ctx context.Context, userID uuid.UUID, cartID uuid.UUID, shippingAddressID uuid.UUID, cartItems []models.CartItem, rate models.ExchangeRate) (*models.Order, error) {
+	ret := _m.Called(ctx, userID, cartID, shippingAddressID, cartItems, rate)
+
+	var r0 *models.Order
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, uuid.UUID, []models.CartItem, models.ExchangeRate) *models.Order); ok {
+		r0 = rf(ctx, userID, cartID, shippingAddressID, cartItems, rate)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, uuid.UUID, []models.CartItem, models.ExchangeRate) error); ok {
+		r1 = rf(ctx, userID, cartID, shippingAddressID, cartItems, rate)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindUserOrders provides a mock function with given fields: ctx, userID
+func (_m *MockOrderRepository) FindUserOrders(ctx context.Context, userID uuid.UUID) ([]models.Order, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.Order
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Order); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Order)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindOrderByID provides a mock function with given fields: ctx, orderID
+func (_m *MockOrderRepository) FindOrderByID(ctx context.Context, orderID uuid.UUID) (*models.Order, []models.OrderItem, error) {
+	ret := _m.Called(ctx, orderID)
+
+	var r0 *models.Order
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.Order); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	var r1 []models.OrderItem
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) []models.OrderItem); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]models.OrderItem)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, uuid.UUID) error); ok {
+		r2 = rf(ctx, orderID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdateOrderStatus provides a mock function with given fields: ctx, orderID, status
+func (_m *MockOrderRepository) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status models.OrderStatus) error {
+	ret := _m.Called(ctx, orderID, status)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, models.OrderStatus) error); ok {
+		r0 = rf(ctx, orderID, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateOrderTracking provides a mock function with given fields: ctx, orderID, trackingNumber
+func (_m *MockOrderRepository) UpdateOrderTracking(ctx context.Context, orderID uuid.UUID, trackingNumber string) error {
+	ret := _m.Called(ctx, orderID, trackingNumber)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) error); ok {
+		r0 = rf(ctx, orderID, trackingNumber)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
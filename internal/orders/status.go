@@ -0,0 +1,27 @@
+package orders
+
+import "bullet-cloud-api/internal/models"
+
+// statusTransitions lists the statuses an order can move to from each status.
+// A cancelled order can be reopened as pending; shipped and delivered orders cannot be cancelled.
+var statusTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.StatusPending:    {models.StatusProcessing, models.StatusCancelled},
+	models.StatusProcessing: {models.StatusShipped, models.StatusCancelled},
+	models.StatusShipped:    {models.StatusDelivered},
+	models.StatusDelivered:  {},
+	models.StatusCancelled:  {models.StatusPending},
+}
+
+// CanTransition reports whether an order in status from can be changed to status to.
+// Setting the status an order already has is allowed and changes nothing.
+func CanTransition(from, to models.OrderStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
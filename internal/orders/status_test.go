@@ -0,0 +1,42 @@
+package orders
+
+import (
+	"bullet-cloud-api/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to models.OrderStatus
+		allowed  bool
+	}{
+		{models.StatusPending, models.StatusProcessing, true},
+		{models.StatusPending, models.StatusCancelled, true},
+		{models.StatusPending, models.StatusShipped, false},
+		{models.StatusPending, models.StatusDelivered, false},
+		{models.StatusProcessing, models.StatusShipped, true},
+		{models.StatusProcessing, models.StatusCancelled, true},
+		{models.StatusProcessing, models.StatusPending, false},
+		{models.StatusProcessing, models.StatusDelivered, false},
+		{models.StatusShipped, models.StatusDelivered, true},
+		{models.StatusShipped, models.StatusCancelled, false},
+		{models.StatusShipped, models.StatusProcessing, false},
+		{models.StatusDelivered, models.StatusShipped, false},
+		{models.StatusDelivered, models.StatusCancelled, false},
+		{models.StatusDelivered, models.StatusPending, false},
+		{models.StatusCancelled, models.StatusPending, true},
+		{models.StatusCancelled, models.StatusProcessing, false},
+		{models.StatusCancelled, models.StatusShipped, false},
+		// Setting the current status again changes nothing
+		{models.StatusShipped, models.StatusShipped, true},
+		{models.StatusCancelled, models.StatusCancelled, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.from)+" to "+string(tc.to), func(t *testing.T) {
+			assert.Equal(t, tc.allowed, CanTransition(tc.from, tc.to))
+		})
+	}
+}
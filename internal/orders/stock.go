@@ -0,0 +1,272 @@
+package orders
+
+import (
+	"bullet-cloud-api/internal/fulfillment"
+	"bullet-cloud-api/internal/money"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// StockShortage is an item of an order with less stock than the quantity ordered.
+type StockShortage struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id"`
+	Name      string     `json:"name"` // Product name
+	Requested int        `json:"requested"`
+	Available int        `json:"available"`
+}
+
+// InsufficientStockError is returned when an order cannot take the stock of its items.
+// Nothing is reserved: the order is not created or changed.
+type InsufficientStockError struct {
+	Items []StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for %d item(s)", len(e.Items))
+}
+
+// stockItem is a quantity of a product, or of one of its variants, taken from or returned
+// to the stock of a location. LocationID is nil when the stock is not tracked.
+type stockItem struct {
+	ProductID  uuid.UUID
+	VariantID  *uuid.UUID
+	LocationID *uuid.UUID
+	Quantity   int
+	Price      money.Money // Unit price, when the item is an order item
+}
+
+// lockKey orders the stock rows so that concurrent orders lock them in the same order,
+// which keeps them from deadlocking.
+func (i stockItem) lockKey() string {
+	key := "p" + i.ProductID.String()
+	if i.VariantID != nil {
+		key = "v" + i.VariantID.String()
+	}
+	if i.LocationID != nil {
+		key += "/" + i.LocationID.String()
+	}
+	return key
+}
+
+func sortForLocking(items []stockItem) []stockItem {
+	sorted := append([]stockItem(nil), items...)
+	sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].lockKey() < sorted[b].lockKey() })
+	return sorted
+}
+
+// allocateStock chooses the locations the items ship from, following r.strategy for an
+// order shipped to shippingAddressID, and takes the units from their stock within tx.
+// The stock rows are locked with SELECT ... FOR UPDATE until tx ends, so concurrent checkouts
+// cannot sell the same units. It returns the items split by location; items whose stock is
+// not tracked keep a nil LocationID. When any item is short it returns an
+// *InsufficientStockError listing all of them.
+func (r *postgresOrderRepository) allocateStock(ctx context.Context, tx pgx.Tx, shippingAddressID uuid.UUID, items []stockItem) ([]stockItem, error) {
+	var shipState string
+	if err := tx.QueryRow(ctx, `SELECT state FROM addresses WHERE id = $1`, shippingAddressID).Scan(&shipState); err != nil {
+		return nil, err
+	}
+
+	var (
+		allocated []stockItem // Untracked items
+		tracked   []stockItem
+		demands   []fulfillment.Demand
+		names     []string
+		locations = make(map[uuid.UUID]bool)
+	)
+	for _, item := range sortForLocking(items) {
+		rows, err := tx.Query(ctx, `
+			SELECT s.location_id, s.quantity, p.name
+			FROM stock_levels s
+			JOIN products p ON p.id = s.product_id
+			WHERE s.product_id = $1 AND s.variant_id IS NOT DISTINCT FROM $2
+			ORDER BY s.location_id
+			FOR UPDATE OF s
+		`, item.ProductID, item.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		demand := fulfillment.Demand{Quantity: item.Quantity, Stock: make(map[uuid.UUID]int)}
+		var name string
+		for rows.Next() {
+			var (
+				locationID uuid.UUID
+				quantity   int
+			)
+			if err := rows.Scan(&locationID, &quantity, &name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			demand.Stock[locationID] = quantity
+			locations[locationID] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		if len(demand.Stock) == 0 {
+			allocated = append(allocated, item)
+			continue
+		}
+		tracked = append(tracked, item)
+		demands = append(demands, demand)
+		names = append(names, name)
+	}
+	if len(tracked) == 0 {
+		return allocated, nil
+	}
+
+	candidates, err := findLocations(ctx, tx, locations)
+	if err != nil {
+		return nil, err
+	}
+	picks, short := fulfillment.Allocate(r.strategy, shipState, candidates, demands)
+	if len(short) > 0 {
+		shortages := make([]StockShortage, len(short))
+		for i, index := range short {
+			available := 0
+			for _, quantity := range demands[index].Stock {
+				available += quantity
+			}
+			shortages[i] = StockShortage{
+				ProductID: tracked[index].ProductID,
+				VariantID: tracked[index].VariantID,
+				Name:      names[index],
+				Requested: tracked[index].Quantity,
+				Available: available,
+			}
+		}
+		return nil, &InsufficientStockError{Items: shortages}
+	}
+
+	for _, pick := range picks {
+		item := tracked[pick.Item]
+		locationID := pick.LocationID
+		item.LocationID = &locationID
+		item.Quantity = pick.Quantity
+		if err := changeStock(ctx, tx, item, -item.Quantity); err != nil {
+			return nil, err
+		}
+		allocated = append(allocated, item)
+	}
+	return allocated, nil
+}
+
+// findLocations loads the stock locations with the given IDs.
+func findLocations(ctx context.Context, tx pgx.Tx, ids map[uuid.UUID]bool) ([]fulfillment.Location, error) {
+	list := make([]uuid.UUID, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	rows, err := tx.Query(ctx, `SELECT id, name, state FROM stock_locations WHERE id = ANY($1) ORDER BY id`, list)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []fulfillment.Location
+	for rows.Next() {
+		var l fulfillment.Location
+		if err := rows.Scan(&l.ID, &l.Name, &l.State); err != nil {
+			return nil, err
+		}
+		locations = append(locations, l)
+	}
+	return locations, rows.Err()
+}
+
+// reserveStock takes the items again from the locations recorded for them, within tx, as
+// when a cancelled order is reopened. When any location lacks the units it returns an
+// *InsufficientStockError listing those items.
+func reserveStock(ctx context.Context, tx pgx.Tx, items []stockItem) error {
+	var shortages []StockShortage
+	for _, item := range sortForLocking(items) {
+		if item.LocationID == nil {
+			continue
+		}
+		var (
+			available int
+			name      string
+		)
+		err := tx.QueryRow(ctx, `
+			SELECT s.quantity, p.name
+			FROM stock_levels s
+			JOIN products p ON p.id = s.product_id
+			WHERE s.location_id = $1 AND s.product_id = $2 AND s.variant_id IS NOT DISTINCT FROM $3
+			FOR UPDATE OF s
+		`, *item.LocationID, item.ProductID, item.VariantID).Scan(&available, &name)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The location stopped tracking the item since the order was cancelled
+			err = tx.QueryRow(ctx, `SELECT name FROM products WHERE id = $1`, item.ProductID).Scan(&name)
+		}
+		if err != nil {
+			return err
+		}
+		if available < item.Quantity {
+			shortages = append(shortages, StockShortage{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Name:      name,
+				Requested: item.Quantity,
+				Available: available,
+			})
+			continue
+		}
+		if err := changeStock(ctx, tx, item, -item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	if len(shortages) > 0 {
+		return &InsufficientStockError{Items: shortages}
+	}
+	return nil
+}
+
+// releaseStock returns the items to the stock of the locations they were taken from,
+// within tx. Locations that stopped tracking an item start tracking it again.
+func releaseStock(ctx context.Context, tx pgx.Tx, items []stockItem) error {
+	for _, item := range sortForLocking(items) {
+		if item.LocationID == nil {
+			continue
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO stock_levels (location_id, product_id, variant_id, quantity)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT ON CONSTRAINT unique_stock_levels_item
+			DO UPDATE SET quantity = stock_levels.quantity + EXCLUDED.quantity, updated_at = NOW()
+		`, *item.LocationID, item.ProductID, item.VariantID, item.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changeStock adds delta units to the stock of the item at its location.
+func changeStock(ctx context.Context, tx pgx.Tx, item stockItem, delta int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE stock_levels SET quantity = quantity + $1, updated_at = NOW()
+		WHERE location_id = $2 AND product_id = $3 AND variant_id IS NOT DISTINCT FROM $4
+	`, delta, *item.LocationID, item.ProductID, item.VariantID)
+	return err
+}
+
+// orderStockItems returns the items of an order, with the locations they ship from.
+func orderStockItems(ctx context.Context, tx pgx.Tx, orderID uuid.UUID) ([]stockItem, error) {
+	rows, err := tx.Query(ctx, `SELECT product_id, variant_id, location_id, quantity FROM order_items WHERE order_id = $1`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (stockItem, error) {
+		var item stockItem
+		err := row.Scan(&item.ProductID, &item.VariantID, &item.LocationID, &item.Quantity)
+		return item, err
+	})
+}
@@ -0,0 +1,149 @@
+package pricing
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Quote prices one response in a currency. Products and variants with a price of their own
+// in that currency use it; other amounts of the store currency are converted at the
+// current exchange rate.
+type Quote struct {
+	Rate   models.ExchangeRate
+	prices map[priceKey]money.Money
+}
+
+// priceKey names a product, or one of its variants, in a price list.
+type priceKey struct {
+	productID uuid.UUID
+	variantID uuid.UUID // uuid.Nil for the product
+}
+
+func newPriceKey(productID uuid.UUID, variantID *uuid.UUID) priceKey {
+	if variantID == nil {
+		return priceKey{productID: productID}
+	}
+	return priceKey{productID: productID, variantID: *variantID}
+}
+
+// NewQuote loads the exchange rate of currency, failing with ErrRateNotFound when the store
+// does not price in it, and the prices in it of the products and their variants.
+func NewQuote(ctx context.Context, repo PricingRepository, currency string, productIDs []uuid.UUID) (*Quote, error) {
+	q := &Quote{
+		Rate:   models.ExchangeRate{Currency: money.DefaultCurrency, Rate: money.OneRate},
+		prices: make(map[priceKey]money.Money),
+	}
+	if currency == money.DefaultCurrency {
+		return q, nil // The store currency has no rate or price lists to load
+	}
+
+	rate, err := repo.FindRate(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	q.Rate = *rate
+	if len(productIDs) == 0 {
+		return q, nil
+	}
+	prices, err := repo.FindPrices(ctx, currency, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range prices {
+		q.prices[newPriceKey(p.ProductID, p.VariantID)] = p.Price
+	}
+	return q, nil
+}
+
+// Currency returns the currency of the quote.
+func (q *Quote) Currency() string {
+	return q.Rate.Currency
+}
+
+// Convert converts an amount of the store currency at the quote's exchange rate.
+func (q *Quote) Convert(m money.Money) (money.Money, error) {
+	if m.Currency == q.Currency() {
+		return m, nil
+	}
+	if m.Currency != money.DefaultCurrency {
+		return money.Money{}, fmt.Errorf("%w: %s is not the store currency", money.ErrCurrencyMismatch, m.Currency)
+	}
+	return m.Convert(q.Currency(), money.OneRate, q.Rate.Rate)
+}
+
+// Price returns the price of a product, or of one of its variants, that sells for base in
+// the store currency: the price of the variant in the quote currency, or else that of the
+// product, or else base converted. variantPriced reports that the variant overrides the
+// product's price in the store currency; the product's price then does not apply to it.
+func (q *Quote) Price(productID uuid.UUID, variantID *uuid.UUID, base money.Money, variantPriced bool) (money.Money, error) {
+	if price, ok := q.prices[newPriceKey(productID, variantID)]; ok {
+		return price, nil
+	}
+	if price, ok := q.prices[newPriceKey(productID, nil)]; ok && variantID != nil && !variantPriced {
+		return price, nil
+	}
+	return q.Convert(base)
+}
+
+// PriceProduct replaces the prices of a product, and of its variants, with their prices in
+// the quote currency. Variants without a price of their own keep following the product's.
+func (q *Quote) PriceProduct(p *models.Product) error {
+	base := p.Price
+	price, err := q.Price(p.ID, nil, base, false)
+	if err != nil {
+		return err
+	}
+	p.Price = price
+
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		if _, ok := q.prices[newPriceKey(p.ID, &v.ID)]; !ok && v.Price == nil {
+			continue
+		}
+		price, err := q.Price(p.ID, &v.ID, v.EffectivePrice(base), v.Price != nil)
+		if err != nil {
+			return err
+		}
+		v.Price = &price
+	}
+	return nil
+}
+
+// PriceCartItems replaces the prices of cart items, taken in the store currency when they
+// were added, with their prices in the quote currency.
+func (q *Quote) PriceCartItems(items []models.CartItem) error {
+	for i := range items {
+		price, err := q.Price(items[i].ProductID, items[i].VariantID, items[i].Price, items[i].VariantPriced)
+		if err != nil {
+			return err
+		}
+		items[i].Price = price
+	}
+	return nil
+}
+
+// PriceOrder shows an order, and its items, in the quote currency. Their amounts go back to
+// the store currency at the exchange rate recorded at checkout, then to the quote currency
+// at its current rate; orders in the quote currency are left as they were paid.
+func (q *Quote) PriceOrder(order *models.Order, items []models.OrderItem) error {
+	if order.Currency == q.Currency() {
+		return nil
+	}
+	total, err := order.Total.Convert(q.Currency(), order.ExchangeRate, q.Rate.Rate)
+	if err != nil {
+		return err
+	}
+	order.Total = total
+	for i := range items {
+		price, err := items[i].Price.Convert(q.Currency(), order.ExchangeRate, q.Rate.Rate)
+		if err != nil {
+			return err
+		}
+		items[i].Price = price
+	}
+	return nil
+}
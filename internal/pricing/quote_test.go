@@ -0,0 +1,125 @@
+package pricing
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func usd(amount string) money.Money { return money.MustParse(amount, "USD") }
+func brl(amount string) money.Money { return money.MustParse(amount, money.DefaultCurrency) }
+
+func TestNewQuote(t *testing.T) {
+	t.Run("Store Currency Loads Nothing", func(t *testing.T) {
+		repo := new(MockPricingRepository)
+		q, err := NewQuote(context.Background(), repo, money.DefaultCurrency, []uuid.UUID{uuid.New()})
+		require.NoError(t, err)
+
+		price, err := q.Price(uuid.New(), nil, brl("59.90"), false)
+		require.NoError(t, err)
+		assert.Equal(t, brl("59.90"), price)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Currency", func(t *testing.T) {
+		repo := new(MockPricingRepository)
+		repo.On("FindRate", mock.Anything, "EUR").Return(nil, ErrRateNotFound).Once()
+
+		_, err := NewQuote(context.Background(), repo, "EUR", nil)
+		assert.ErrorIs(t, err, ErrRateNotFound)
+	})
+}
+
+func TestQuote_PriceProduct(t *testing.T) {
+	productID, listedVariant, overriddenVariant, plainVariant := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	repo := new(MockPricingRepository)
+	repo.On("FindRate", mock.Anything, "USD").Return(&models.ExchangeRate{Currency: "USD", Rate: money.MustParseRate("0.2")}, nil).Once()
+	repo.On("FindPrices", mock.Anything, "USD", []uuid.UUID{productID}).Return([]models.ProductPrice{
+		{ProductID: productID, Price: usd("19.99")},
+		{ProductID: productID, VariantID: &listedVariant, Price: usd("24.99")},
+	}, nil).Once()
+
+	q, err := NewQuote(context.Background(), repo, "USD", []uuid.UUID{productID})
+	require.NoError(t, err)
+
+	override := brl("150.00")
+	product := models.Product{ID: productID, Price: brl("100.00"), Variants: []models.ProductVariant{
+		{ID: listedVariant},
+		{ID: overriddenVariant, Price: &override},
+		{ID: plainVariant},
+	}}
+	require.NoError(t, q.PriceProduct(&product))
+
+	assert.Equal(t, usd("19.99"), product.Price) // Its price list, not 20.00 converted
+	assert.Equal(t, usd("24.99"), *product.Variants[0].Price)
+	assert.Equal(t, usd("30.00"), *product.Variants[1].Price) // Its own price converted, not the product's price list
+	assert.Nil(t, product.Variants[2].Price)                  // Follows the product price, so its price list
+
+	unlisted, err := q.Price(uuid.New(), nil, brl("59.90"), false)
+	require.NoError(t, err)
+	assert.Equal(t, usd("11.98"), unlisted)
+	repo.AssertExpectations(t)
+}
+
+func TestQuote_VariantOverride(t *testing.T) {
+	productID, variantID := uuid.New(), uuid.New()
+	repo := new(MockPricingRepository)
+	repo.On("FindRate", mock.Anything, "USD").Return(&models.ExchangeRate{Currency: "USD", Rate: money.MustParseRate("0.2")}, nil).Once()
+	repo.On("FindPrices", mock.Anything, "USD", []uuid.UUID{productID}).
+		Return([]models.ProductPrice{{ProductID: productID, Price: usd("10.00")}}, nil).Once()
+
+	q, err := NewQuote(context.Background(), repo, "USD", []uuid.UUID{productID})
+	require.NoError(t, err)
+
+	// The variant's own price in the store currency wins over the product's price list
+	override := brl("120.00")
+	product := models.Product{ID: productID, Price: brl("50.00"), Variants: []models.ProductVariant{{ID: variantID, Price: &override}}}
+	require.NoError(t, q.PriceProduct(&product))
+	assert.Equal(t, usd("10.00"), product.Price)
+	assert.Equal(t, usd("24.00"), *product.Variants[0].Price)
+
+	items := []models.CartItem{
+		{ProductID: productID, VariantID: &variantID, Price: brl("120.00"), VariantPriced: true},
+		{ProductID: productID, VariantID: &variantID, Price: brl("50.00")},
+	}
+	require.NoError(t, q.PriceCartItems(items))
+	assert.Equal(t, usd("24.00"), items[0].Price)
+	assert.Equal(t, usd("10.00"), items[1].Price) // Follows the product, so its price list
+	repo.AssertExpectations(t)
+}
+
+func TestQuote_PriceOrder(t *testing.T) {
+	paidInUSD := func() (*models.Order, []models.OrderItem) {
+		order := &models.Order{Total: usd("30.00"), Currency: "USD", ExchangeRate: money.MustParseRate("0.2")}
+		return order, []models.OrderItem{{Quantity: 2, Price: usd("15.00")}}
+	}
+
+	t.Run("Back To The Store Currency At The Checkout Rate", func(t *testing.T) {
+		q, err := NewQuote(context.Background(), new(MockPricingRepository), money.DefaultCurrency, nil)
+		require.NoError(t, err)
+
+		order, items := paidInUSD()
+		require.NoError(t, q.PriceOrder(order, items))
+		assert.Equal(t, brl("150.00"), order.Total)
+		assert.Equal(t, brl("75.00"), items[0].Price)
+		assert.Equal(t, "USD", order.Currency) // Still records what was paid
+	})
+
+	t.Run("Same Currency Is Left As Paid", func(t *testing.T) {
+		repo := new(MockPricingRepository)
+		repo.On("FindRate", mock.Anything, "USD").Return(&models.ExchangeRate{Currency: "USD", Rate: money.MustParseRate("0.25")}, nil).Once()
+		q, err := NewQuote(context.Background(), repo, "USD", nil)
+		require.NoError(t, err)
+
+		order, items := paidInUSD()
+		require.NoError(t, q.PriceOrder(order, items))
+		assert.Equal(t, usd("30.00"), order.Total) // Not repriced at today's rate
+		assert.Equal(t, usd("15.00"), items[0].Price)
+	})
+}
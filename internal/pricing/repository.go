@@ -0,0 +1,211 @@
+package pricing
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRateNotFound      = errors.New("exchange rate not found")
+	ErrStoreCurrency     = errors.New("the store currency has no exchange rate or price list, its rate is always 1")
+	ErrPriceNotFound     = errors.New("price not found")
+	ErrPriceItemNotFound = errors.New("product or variant not found")
+)
+
+// PricingRepository defines the interface for exchange rates and the per-currency price
+// lists of products. A nil variantID names the product itself, otherwise one of its variants.
+type PricingRepository interface {
+	// ListRates returns the exchange rates, by currency.
+	ListRates(ctx context.Context) ([]models.ExchangeRate, error)
+	// FindRate returns the exchange rate of a currency; that of money.DefaultCurrency is
+	// always money.OneRate.
+	FindRate(ctx context.Context, currency string) (*models.ExchangeRate, error)
+	// SetRate creates or replaces the exchange rate of a currency.
+	SetRate(ctx context.Context, currency string, rate money.Rate) (*models.ExchangeRate, error)
+	// DeleteRate stops pricing in a currency; its price lists are kept.
+	DeleteRate(ctx context.Context, currency string) error
+
+	// ListPrices returns the prices of a product and its variants in every currency.
+	ListPrices(ctx context.Context, productID uuid.UUID) ([]models.ProductPrice, error)
+	// FindPrices returns the prices in a currency of the products and their variants.
+	FindPrices(ctx context.Context, currency string, productIDs []uuid.UUID) ([]models.ProductPrice, error)
+	// SetPrice creates or replaces the price of a product or variant in the price's currency.
+	SetPrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, price money.Money) (*models.ProductPrice, error)
+	// DeletePrice removes the price of a product or variant in a currency, which is then
+	// converted again.
+	DeletePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) error
+}
+
+// postgresPricingRepository implements PricingRepository using PostgreSQL.
+type postgresPricingRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresPricingRepository creates a new instance of postgresPricingRepository.
+func NewPostgresPricingRepository(db *pgxpool.Pool) PricingRepository {
+	return &postgresPricingRepository{db: db}
+}
+
+// handlePgError translates the constraint violations of the price lists.
+func handlePgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.ConstraintName {
+		case "fk_product_prices_product", "fk_product_prices_variant":
+			return ErrPriceItemNotFound
+		}
+	}
+	return err
+}
+
+// ListRates retrieves every exchange rate.
+func (r *postgresPricingRepository) ListRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	rows, err := r.db.Query(ctx, `SELECT currency, rate, updated_at FROM exchange_rates ORDER BY currency ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.ExchangeRate])
+}
+
+// FindRate retrieves the exchange rate of a currency.
+func (r *postgresPricingRepository) FindRate(ctx context.Context, currency string) (*models.ExchangeRate, error) {
+	if currency == money.DefaultCurrency {
+		return &models.ExchangeRate{Currency: currency, Rate: money.OneRate}, nil
+	}
+	rate := &models.ExchangeRate{}
+	err := r.db.QueryRow(ctx, `SELECT currency, rate, updated_at FROM exchange_rates WHERE currency = $1`, currency).
+		Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRateNotFound
+		}
+		return nil, err
+	}
+	return rate, nil
+}
+
+// SetRate upserts the exchange rate of a currency.
+func (r *postgresPricingRepository) SetRate(ctx context.Context, currency string, rate money.Rate) (*models.ExchangeRate, error) {
+	if currency == money.DefaultCurrency {
+		return nil, ErrStoreCurrency
+	}
+	query := `
+		INSERT INTO exchange_rates (currency, rate)
+		VALUES ($1, $2)
+		ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate
+		RETURNING currency, rate, updated_at
+	`
+	saved := &models.ExchangeRate{}
+	if err := r.db.QueryRow(ctx, query, currency, rate).Scan(&saved.Currency, &saved.Rate, &saved.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// DeleteRate removes the exchange rate of a currency.
+func (r *postgresPricingRepository) DeleteRate(ctx context.Context, currency string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM exchange_rates WHERE currency = $1`, currency)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRateNotFound
+	}
+	return nil
+}
+
+// ListPrices retrieves the prices of a product and its variants.
+func (r *postgresPricingRepository) ListPrices(ctx context.Context, productID uuid.UUID) ([]models.ProductPrice, error) {
+	query := `
+		SELECT product_id, variant_id, currency, price, created_at, updated_at
+		FROM product_prices
+		WHERE product_id = $1
+		ORDER BY currency ASC, variant_id ASC NULLS FIRST
+	`
+	return r.queryPrices(ctx, query, productID)
+}
+
+// FindPrices retrieves the prices of products and their variants in a currency.
+func (r *postgresPricingRepository) FindPrices(ctx context.Context, currency string, productIDs []uuid.UUID) ([]models.ProductPrice, error) {
+	query := `
+		SELECT product_id, variant_id, currency, price, created_at, updated_at
+		FROM product_prices
+		WHERE currency = $1 AND product_id = ANY($2)
+	`
+	return r.queryPrices(ctx, query, currency, productIDs)
+}
+
+// SetPrice upserts the price of a product or variant in a currency.
+func (r *postgresPricingRepository) SetPrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, price money.Money) (*models.ProductPrice, error) {
+	if price.Currency == money.DefaultCurrency {
+		return nil, ErrStoreCurrency
+	}
+	query := `
+		INSERT INTO product_prices (product_id, variant_id, currency, price)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT ON CONSTRAINT unique_product_prices_item DO UPDATE SET price = EXCLUDED.price
+		RETURNING product_id, variant_id, currency, price, created_at, updated_at
+	`
+	saved, err := scanPrice(r.db.QueryRow(ctx, query, productID, variantID, price.Currency, price))
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+	return saved, nil
+}
+
+// DeletePrice removes the price of a product or variant in a currency.
+func (r *postgresPricingRepository) DeletePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) error {
+	query := `
+		DELETE FROM product_prices
+		WHERE product_id = $1 AND variant_id IS NOT DISTINCT FROM $2 AND currency = $3
+	`
+	result, err := r.db.Exec(ctx, query, productID, variantID, currency)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPriceNotFound
+	}
+	return nil
+}
+
+func (r *postgresPricingRepository) queryPrices(ctx context.Context, query string, args ...interface{}) ([]models.ProductPrice, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.ProductPrice{}
+	for rows.Next() {
+		price, err := scanPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, *price)
+	}
+	return prices, rows.Err()
+}
+
+// scanPrice reads a product_prices row. The price column is read in the store currency's
+// decimals, which NUMERIC(10, 2) never exceeds, then relabeled with the row's currency.
+func scanPrice(row pgx.Row) (*models.ProductPrice, error) {
+	p := &models.ProductPrice{}
+	var currency string
+	if err := row.Scan(&p.ProductID, &p.VariantID, &currency, &p.Price, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	price, err := p.Price.As(currency)
+	if err != nil {
+		return nil, err
+	}
+	p.Price = price
+	return p, nil
+}
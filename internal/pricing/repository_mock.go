@@ -0,0 +1,181 @@
+package pricing
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPricingRepository is a mock type for the PricingRepository interface
+type MockPricingRepository struct {
+	mock.Mock
+}
+
+// ListRates provides a mock function with given fields: ctx
+func (_m *MockPricingRepository) ListRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []models.ExchangeRate
+	if rf, ok := ret.Get(0).(func(context.Context) []models.ExchangeRate); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ExchangeRate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindRate provides a mock function with given fields: ctx, currency
+func (_m *MockPricingRepository) FindRate(ctx context.Context, currency string) (*models.ExchangeRate, error) {
+	ret := _m.Called(ctx, currency)
+
+	var r0 *models.ExchangeRate
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.ExchangeRate); ok {
+		r0 = rf(ctx, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ExchangeRate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRate provides a mock function with given fields: ctx, currency, rate
+func (_m *MockPricingRepository) SetRate(ctx context.Context, currency string, rate money.Rate) (*models.ExchangeRate, error) {
+	ret := _m.Called(ctx, currency, rate)
+
+	var r0 *models.ExchangeRate
+	if rf, ok := ret.Get(0).(func(context.Context, string, money.Rate) *models.ExchangeRate); ok {
+		r0 = rf(ctx, currency, rate)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ExchangeRate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, money.Rate) error); ok {
+		r1 = rf(ctx, currency, rate)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteRate provides a mock function with given fields: ctx, currency
+func (_m *MockPricingRepository) DeleteRate(ctx context.Context, currency string) error {
+	ret := _m.Called(ctx, currency)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, currency)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListPrices provides a mock function with given fields: ctx, productID
+func (_m *MockPricingRepository) ListPrices(ctx context.Context, productID uuid.UUID) ([]models.ProductPrice, error) {
+	ret := _m.Called(ctx, productID)
+
+	var r0 []models.ProductPrice
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.ProductPrice); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ProductPrice)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindPrices provides a mock function with given fields: ctx, currency, productIDs
+func (_m *MockPricingRepository) FindPrices(ctx context.Context, currency string, productIDs []uuid.UUID) ([]models.ProductPrice, error) {
+	ret := _m.Called(ctx, currency, productIDs)
+
+	var r0 []models.ProductPrice
+	if rf, ok := ret.Get(0).(func(context.Context, string, []uuid.UUID) []models.ProductPrice); ok {
+		r0 = rf(ctx, currency, productIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ProductPrice)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, []uuid.UUID) error); ok {
+		r1 = rf(ctx, currency, productIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetPrice provides a mock function with given fields: ctx, productID, variantID, price
+func (_m *MockPricingRepository) SetPrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, price money.Money) (*models.ProductPrice, error) {
+	ret := _m.Called(ctx, productID, variantID, price)
+
+	var r0 *models.ProductPrice
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *uuid.UUID, money.Money) *models.ProductPrice); ok {
+		r0 = rf(ctx, productID, variantID, price)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProductPrice)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, *uuid.UUID, money.Money) error); ok {
+		r1 = rf(ctx, productID, variantID, price)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeletePrice provides a mock function with given fields: ctx, productID, variantID, currency
+func (_m *MockPricingRepository) DeletePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) error {
+	ret := _m.Called(ctx, productID, variantID, currency)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *uuid.UUID, string) error); ok {
+		r0 = rf(ctx, productID, variantID, currency)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,191 @@
+package products
+
+import (
+	"bullet-cloud-api/internal/money"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// priceRangeBounds are the limits between the price ranges of the search facets, in whole
+// units of the store currency: [0, 50), [50, 100), ... [1000, ∞).
+var priceRangeBounds = []int64{50, 100, 250, 500, 1000}
+
+// Facets summarizes the results of a search, for filter sidebars. The category and price
+// facets count the products matching the query and every filter except their own, so
+// selecting a category still shows how many products the other categories have.
+type Facets struct {
+	Categories  []CategoryFacet   `json:"categories"`   // Categories with matches, the most matches first
+	PriceRanges []PriceRangeFacet `json:"price_ranges"` // Every range, including empty ones
+	Options     []OptionFacet     `json:"options"`      // Variant options (size, color...), by name
+}
+
+// CategoryFacet is the number of results in a category.
+type CategoryFacet struct {
+	ID    *uuid.UUID `json:"id"` // Nil for uncategorized products
+	Name  string     `json:"name"`
+	Count int        `json:"count"`
+}
+
+// PriceRangeFacet is the number of results priced from Min (inclusive) to Max (exclusive).
+type PriceRangeFacet struct {
+	Min   money.Money  `json:"min"`
+	Max   *money.Money `json:"max"` // Nil for the last, unbounded range
+	Count int          `json:"count"`
+}
+
+// OptionFacet counts the results sold in a variant with each value of an option.
+type OptionFacet struct {
+	Name   string             `json:"name"`
+	Values []OptionValueFacet `json:"values"` // The most matches first
+}
+
+// OptionValueFacet is the number of results with a variant having an option value.
+type OptionValueFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// clone returns a copy of q that can be extended without changing q.
+func (q *listQuery) clone() *listQuery {
+	return &listQuery{
+		conditions: append([]string(nil), q.conditions...),
+		args:       append([]interface{}(nil), q.args...),
+	}
+}
+
+// facets computes the facets of a search; base holds the search conditions, before filters.
+func (r *postgresProductRepository) facets(ctx context.Context, base *listQuery, opts ListOptions) (*Facets, error) {
+	categories, err := r.categoryFacets(ctx, base, opts)
+	if err != nil {
+		return nil, err
+	}
+	priceRanges, err := r.priceRangeFacets(ctx, base, opts)
+	if err != nil {
+		return nil, err
+	}
+	options, err := r.optionFacets(ctx, base, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Facets{Categories: categories, PriceRanges: priceRanges, Options: options}, nil
+}
+
+func (r *postgresProductRepository) categoryFacets(ctx context.Context, base *listQuery, opts ListOptions) ([]CategoryFacet, error) {
+	q := base.clone()
+	opts.CategoryID = nil
+	q.addFilters(opts)
+	query := fmt.Sprintf(`
+		SELECT f.category_id, COALESCE(c.name, ''), f.count
+		FROM (
+			SELECT category_id, COUNT(*) AS count
+			FROM products
+			%s
+			GROUP BY category_id
+		) f
+		LEFT JOIN categories c ON c.id = f.category_id
+		ORDER BY f.count DESC, c.name NULLS LAST
+	`, q.whereClause())
+
+	rows, err := r.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := make([]CategoryFacet, 0)
+	for rows.Next() {
+		var f CategoryFacet
+		if err := rows.Scan(&f.ID, &f.Name, &f.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+func (r *postgresProductRepository) priceRangeFacets(ctx context.Context, base *listQuery, opts ListOptions) ([]PriceRangeFacet, error) {
+	facets := make([]PriceRangeFacet, len(priceRangeBounds)+1)
+	for i := range facets {
+		facets[i].Min = money.New(0, money.DefaultCurrency)
+		if i > 0 {
+			facets[i].Min = money.FromUnits(priceRangeBounds[i-1], money.DefaultCurrency)
+		}
+		if i < len(priceRangeBounds) {
+			max := money.FromUnits(priceRangeBounds[i], money.DefaultCurrency)
+			facets[i].Max = &max
+		}
+	}
+
+	q := base.clone()
+	opts.MinPrice, opts.MaxPrice = nil, nil
+	q.addFilters(opts)
+	// width_bucket numbers the ranges from 0 (below the first bound) to len(bounds)
+	query := fmt.Sprintf(`
+		SELECT width_bucket(price, %s::numeric[]) AS bucket, COUNT(*)
+		FROM products
+		%s
+		GROUP BY bucket
+	`, q.arg(priceRangeBounds), q.whereClause())
+
+	rows, err := r.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		if bucket >= 0 && bucket < len(facets) {
+			facets[bucket].Count = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+func (r *postgresProductRepository) optionFacets(ctx context.Context, base *listQuery, opts ListOptions) ([]OptionFacet, error) {
+	q := base.clone()
+	q.addFilters(opts)
+	query := fmt.Sprintf(`
+		SELECT o.key, o.value, COUNT(DISTINCT v.product_id) AS count
+		FROM product_variants v
+		CROSS JOIN LATERAL jsonb_each_text(v.options) o
+		WHERE v.product_id IN (SELECT id FROM products %s)
+		GROUP BY o.key, o.value
+		ORDER BY o.key, count DESC, o.value
+	`, q.whereClause())
+
+	rows, err := r.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := make([]OptionFacet, 0)
+	for rows.Next() {
+		var name string
+		var value OptionValueFacet
+		if err := rows.Scan(&name, &value.Value, &value.Count); err != nil {
+			return nil, err
+		}
+		if len(facets) == 0 || facets[len(facets)-1].Name != name {
+			facets = append(facets, OptionFacet{Name: name})
+		}
+		last := &facets[len(facets)-1]
+		last.Values = append(last.Values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
@@ -0,0 +1,198 @@
+package products
+
+import (
+	"bullet-cloud-api/internal/models"
+	"bullet-cloud-api/internal/money"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued for the requested sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Sort selects the order of a product listing.
+type Sort string
+
+const (
+	SortNewest    Sort = "newest"     // Most recently created first (default for listings)
+	SortPriceAsc  Sort = "price_asc"  // Cheapest first
+	SortPriceDesc Sort = "price_desc" // Most expensive first
+	SortName      Sort = "name"       // Alphabetical
+	SortRelevance Sort = "relevance"  // Best match first (default for searches, only valid with a query)
+)
+
+// IsValid reports whether s is one of the known sort orders.
+func (s Sort) IsValid() bool {
+	switch s {
+	case SortNewest, SortPriceAsc, SortPriceDesc, SortName, SortRelevance:
+		return true
+	}
+	return false
+}
+
+// ListOptions filters, sorts and paginates FindAll and Search. Zero values match everything.
+type ListOptions struct {
+	CategoryID  *uuid.UUID
+	MinPrice    *money.Money
+	MaxPrice    *money.Money
+	CreatedFrom *time.Time // Created at or after
+	CreatedTo   *time.Time // Created before
+	Sort        Sort       // Defaults to SortNewest, or SortRelevance for searches
+	Limit       int
+	After       *Cursor // Continue after the last product of a previous page
+}
+
+// Page is one page of a product listing.
+type Page struct {
+	Products []models.Product
+	Total    int     // Products matching the filters, across all pages
+	Next     *Cursor // Nil on the last page
+	Facets   *Facets // Set only for searches
+}
+
+// Cursor marks the position of the last product of a page (keyset pagination): it holds the
+// values of the sort columns of that product, so the next page starts right after it even
+// when products are added in between.
+type Cursor struct {
+	Sort Sort     `json:"s"`
+	Keys []string `json:"k"` // Sort column values as rendered by PostgreSQL
+}
+
+// Encode returns the opaque form of the cursor handed to clients.
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || !c.Sort.IsValid() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// sortColumn is one column of an ORDER BY clause.
+type sortColumn struct {
+	expr string // SQL expression, evaluated against the products table
+	cast string // Type the cursor value is cast back to
+	desc bool
+}
+
+// sortColumns returns the ORDER BY columns of each sort order. The last columns are always
+// created_at and id, so the order is total and every row has a unique position.
+// rankExpr is the relevance of a product to the search query, higher is better.
+func sortColumns(sort Sort, rankExpr string) []sortColumn {
+	tieBreak := []sortColumn{
+		{expr: "created_at", cast: "timestamptz", desc: true},
+		{expr: "id", cast: "uuid", desc: true},
+	}
+	switch sort {
+	case SortPriceAsc:
+		return append([]sortColumn{{expr: "price", cast: "numeric"}}, tieBreak...)
+	case SortPriceDesc:
+		return append([]sortColumn{{expr: "price", cast: "numeric", desc: true}}, tieBreak...)
+	case SortName:
+		return append([]sortColumn{{expr: "name", cast: "text"}}, tieBreak...)
+	case SortRelevance:
+		return append([]sortColumn{{expr: rankExpr, cast: "real", desc: true}}, tieBreak...)
+	default:
+		return tieBreak
+	}
+}
+
+// listQuery accumulates the WHERE conditions and arguments of a listing query.
+type listQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg adds an argument and returns its placeholder.
+func (q *listQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where adds a condition; %s in condition is replaced by the placeholder of value.
+func (q *listQuery) where(condition string, value interface{}) {
+	q.conditions = append(q.conditions, fmt.Sprintf(condition, q.arg(value)))
+}
+
+// addFilters adds the conditions for the filters of opts.
+func (q *listQuery) addFilters(opts ListOptions) {
+	if opts.CategoryID != nil {
+		q.where("category_id = %s", *opts.CategoryID)
+	}
+	if opts.MinPrice != nil {
+		q.where("price >= %s", *opts.MinPrice)
+	}
+	if opts.MaxPrice != nil {
+		q.where("price <= %s", *opts.MaxPrice)
+	}
+	if opts.CreatedFrom != nil {
+		q.where("created_at >= %s", *opts.CreatedFrom)
+	}
+	if opts.CreatedTo != nil {
+		q.where("created_at < %s", *opts.CreatedTo)
+	}
+}
+
+// addAfter adds the condition selecting the rows that sort after the cursor:
+// (a > x) OR (a = x AND b > y) OR ..., with < for descending columns.
+func (q *listQuery) addAfter(columns []sortColumn, after *Cursor) error {
+	if after == nil {
+		return nil
+	}
+	if len(after.Keys) != len(columns) {
+		return ErrInvalidCursor
+	}
+
+	var alternatives []string
+	for i, col := range columns {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = %s::%s", columns[j].expr, q.arg(after.Keys[j]), columns[j].cast))
+		}
+		op := ">"
+		if col.desc {
+			op = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s %s::%s", col.expr, op, q.arg(after.Keys[i]), col.cast))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	q.conditions = append(q.conditions, "("+strings.Join(alternatives, " OR ")+")")
+	return nil
+}
+
+// whereClause returns the WHERE clause for the conditions added so far.
+func (q *listQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// orderBy renders the ORDER BY list and the text form of the sort keys, selected for the cursor.
+func orderBy(columns []sortColumn) (order, keys string) {
+	orderTerms := make([]string, len(columns))
+	keyTerms := make([]string, len(columns))
+	for i, col := range columns {
+		orderTerms[i] = col.expr
+		if col.desc {
+			orderTerms[i] += " DESC"
+		}
+		keyTerms[i] = fmt.Sprintf("(%s)::text", col.expr)
+	}
+	return strings.Join(orderTerms, ", "), strings.Join(keyTerms, ", ")
+}
@@ -4,6 +4,8 @@ import (
 	"bullet-cloud-api/internal/models"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -14,16 +16,41 @@ var (
 	ErrProductNotFound = errors.New("product not found")
 )
 
+// Kinds of Suggestion.
+const (
+	SuggestionProduct  = "product"
+	SuggestionCategory = "category"
+)
+
+// Suggestion is a product or category name offered while the user types a search.
+type Suggestion struct {
+	Type       string    `json:"type"` // SuggestionProduct or SuggestionCategory
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	OrderCount int       `json:"order_count"` // Orders including the product, or a product of the category
+}
+
 // ProductRepository defines the interface for product data operations.
 type ProductRepository interface {
 	Create(ctx context.Context, product *models.Product) (*models.Product, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*models.Product, error)
-	FindAll(ctx context.Context /* TODO: Add filtering/pagination params */) ([]models.Product, error)
-	Search(ctx context.Context, query string) ([]models.Product, error)
+	// FindAll returns one page of the products matching opts.
+	FindAll(ctx context.Context, opts ListOptions) (*Page, error)
+	// Search returns one page of the products whose name or description matches query and opts.
+	Search(ctx context.Context, query string, opts ListOptions) (*Page, error)
+	// SearchSimilar is like Search, but matches product names resembling query, despite typos.
+	SearchSimilar(ctx context.Context, query string, opts ListOptions) (*Page, error)
+	// Suggest returns up to limit product and category names for type-ahead, matching prefix
+	// or resembling it; the most ordered come first.
+	Suggest(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
 	Update(ctx context.Context, id uuid.UUID, product *models.Product) (*models.Product, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
+// stockColumn totals the units of a product at every stock location; NULL when no location
+// tracks its stock.
+const stockColumn = `(SELECT SUM(s.quantity) FROM stock_levels s WHERE s.product_id = products.id) AS stock`
+
 // postgresProductRepository implements ProductRepository using PostgreSQL.
 type postgresProductRepository struct {
 	db *pgxpool.Pool
@@ -58,7 +85,7 @@ func (r *postgresProductRepository) Create(ctx context.Context, product *models.
 // FindByID retrieves a product by its ID.
 func (r *postgresProductRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
 	query := `
-		SELECT id, name, description, price, category_id, created_at, updated_at
+		SELECT id, name, description, price, category_id, ` + stockColumn + `, created_at, updated_at
 		FROM products
 		WHERE id = $1
 	`
@@ -69,6 +96,7 @@ func (r *postgresProductRepository) FindByID(ctx context.Context, id uuid.UUID)
 		&product.Description,
 		&product.Price,
 		&product.CategoryID,
+		&product.Stock,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 	)
@@ -82,65 +110,186 @@ func (r *postgresProductRepository) FindByID(ctx context.Context, id uuid.UUID)
 	return product, nil
 }
 
-// FindAll retrieves all products (potentially with pagination/filtering in the future).
-func (r *postgresProductRepository) FindAll(ctx context.Context) ([]models.Product, error) {
+// FindAll retrieves a page of products, newest first unless opts says otherwise.
+func (r *postgresProductRepository) FindAll(ctx context.Context, opts ListOptions) (*Page, error) {
+	// Without a query there is nothing to rank by
+	if opts.Sort == "" || opts.Sort == SortRelevance {
+		opts.Sort = SortNewest
+	}
+	return r.list(ctx, &listQuery{}, nil, opts)
+}
+
+// Search retrieves a page of products matching a web-style search query ("camisa azul",
+// "notebook -usado", "\"tênis de corrida\""), using the products' full-text search vector.
+// Accents are ignored and words are stemmed, so "camisas" matches "Camisa". By default the
+// best matches come first, names weighing more than descriptions.
+func (r *postgresProductRepository) Search(ctx context.Context, query string, opts ListOptions) (*Page, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortRelevance
+	}
+	q := &listQuery{}
+	tsQuery := fmt.Sprintf("websearch_to_tsquery('%s', %s)", searchConfig, q.arg(query))
+	q.conditions = append(q.conditions, "search_vector @@ "+tsQuery)
+	return r.list(ctx, q, &fullTextSearch{tsQuery: tsQuery}, opts)
+}
+
+// SearchSimilar retrieves a page of products whose name resembles the query (trigram word
+// similarity), for queries that full-text search cannot match, such as misspelled words.
+// By default the most similar names come first.
+func (r *postgresProductRepository) SearchSimilar(ctx context.Context, query string, opts ListOptions) (*Page, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortRelevance
+	}
+	q := &listQuery{}
+	term := q.arg(query)
+	q.conditions = append(q.conditions, fmt.Sprintf("%s <%% name", term))
+	return r.list(ctx, q, &trigramSearch{term: term}, opts)
+}
+
+// Suggest retrieves product and category names starting with prefix (at the start of any
+// word), or similar enough to it. Prefix matches come before similar names; within each
+// group, the most popular (orders not cancelled) first.
+func (r *postgresProductRepository) Suggest(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
 	query := `
-		SELECT id, name, description, price, category_id, created_at, updated_at
-		FROM products
-		ORDER BY created_at DESC -- Example ordering
-		-- TODO: Add LIMIT and OFFSET for pagination
+		WITH popularity AS (
+			SELECT oi.product_id, COUNT(DISTINCT oi.order_id) AS order_count
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.status <> 'cancelled'
+			GROUP BY oi.product_id
+		), matches AS (
+			SELECT 'product' AS type, p.id, p.name,
+				(p.name ILIKE $1 OR p.name ILIKE $2) AS is_prefix,
+				word_similarity($3, p.name) AS similarity,
+				COALESCE(pop.order_count, 0) AS order_count
+			FROM products p
+			LEFT JOIN popularity pop ON pop.product_id = p.id
+			WHERE p.name ILIKE $1 OR p.name ILIKE $2 OR $3 <% p.name
+			UNION ALL
+			SELECT 'category', c.id, c.name,
+				(c.name ILIKE $1 OR c.name ILIKE $2),
+				word_similarity($3, c.name),
+				COALESCE((
+					SELECT SUM(pop.order_count)
+					FROM products p
+					JOIN popularity pop ON pop.product_id = p.id
+					WHERE p.category_id = c.id
+				), 0)
+			FROM categories c
+			WHERE c.name ILIKE $1 OR c.name ILIKE $2 OR $3 <% c.name
+		)
+		SELECT type, id, name, order_count
+		FROM matches
+		ORDER BY is_prefix DESC, order_count DESC, similarity DESC, name
+		LIMIT $4
 	`
-	return r.scanProductRows(ctx, query)
+	escaped := escapeLike(prefix)
+	rows, err := r.db.Query(ctx, query, escaped+"%", "% "+escaped+"%", prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]Suggestion, 0)
+	for rows.Next() {
+		var s Suggestion
+		if err := rows.Scan(&s.Type, &s.ID, &s.Name, &s.OrderCount); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
 }
 
-// Search retrieves products that match the search query (name or description).
-func (r *postgresProductRepository) Search(ctx context.Context, query string) ([]models.Product, error) {
-	searchQuery := `
-		SELECT id, name, description, price, category_id, created_at, updated_at
+// list runs a listing query: q holds the conditions specific to the caller, the filters,
+// sort order and cursor come from opts. search is nil for listings.
+// Products are highlighted when the search mode supports it, and searches come with facets.
+func (r *postgresProductRepository) list(ctx context.Context, q *listQuery, search searchMode, opts ListOptions) (*Page, error) {
+	if opts.After != nil && opts.After.Sort != opts.Sort {
+		return nil, ErrInvalidCursor
+	}
+	var rankExpr string
+	var extraColumns []string
+	if search != nil {
+		rankExpr = search.rank()
+		extraColumns = search.highlights()
+	}
+	columns := sortColumns(opts.Sort, rankExpr)
+
+	page := &Page{Products: make([]models.Product, 0)}
+	if search != nil {
+		facets, err := r.facets(ctx, q, opts)
+		if err != nil {
+			return nil, err
+		}
+		page.Facets = facets
+	}
+
+	q.addFilters(opts)
+	countQuery := `SELECT COUNT(*) FROM products ` + q.whereClause()
+	if err := r.db.QueryRow(ctx, countQuery, q.args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if err := q.addAfter(columns, opts.After); err != nil {
+		return nil, err
+	}
+	order, keys := orderBy(columns)
+	selected := append([]string{"id, name, description, price, category_id, " + stockColumn + ", created_at, updated_at", keys}, extraColumns...)
+	// One extra row tells whether there is a next page
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM products
-		WHERE name ILIKE $1 OR description ILIKE $1
-		ORDER BY
-			CASE
-				WHEN name ILIKE $1 THEN 1
-				ELSE 2
-			END,
-			created_at DESC
-	`
-	searchPattern := "%" + query + "%"
-	return r.scanProductRows(ctx, searchQuery, searchPattern)
-}
+		%s
+		ORDER BY %s
+		LIMIT %s
+	`, strings.Join(selected, ", "), q.whereClause(), order, q.arg(opts.Limit+1))
 
-// scanProductRows is a helper function to scan multiple product rows and reduce code duplication.
-func (r *postgresProductRepository) scanProductRows(ctx context.Context, query string, args ...interface{}) ([]models.Product, error) {
-	rows, err := r.db.Query(ctx, query, args...)
+	rows, err := r.db.Query(ctx, query, q.args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	products := make([]models.Product, 0)
+	var lastKeys []string
 	for rows.Next() {
+		if len(page.Products) == opts.Limit {
+			page.Next = &Cursor{Sort: opts.Sort, Keys: lastKeys}
+			break
+		}
 		var product models.Product
-		err := rows.Scan(
+		rowKeys := make([]string, len(columns))
+		dest := []interface{}{
 			&product.ID,
 			&product.Name,
 			&product.Description,
 			&product.Price,
 			&product.CategoryID,
+			&product.Stock,
 			&product.CreatedAt,
 			&product.UpdatedAt,
-		)
-		if err != nil {
+		}
+		for i := range rowKeys {
+			dest = append(dest, &rowKeys[i])
+		}
+		if len(extraColumns) > 0 {
+			product.Highlight = &models.ProductHighlight{}
+			dest = append(dest, &product.Highlight.Name, &product.Highlight.Description)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
-		products = append(products, product)
+		page.Products = append(page.Products, product)
+		lastKeys = rowKeys
 	}
-
-	if rows.Err() != nil {
-		return nil, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return products, nil
+	return page, nil
 }
 
 // Update modifies an existing product in the database.
@@ -149,7 +298,7 @@ func (r *postgresProductRepository) Update(ctx context.Context, id uuid.UUID, pr
 		UPDATE products
 		SET name = $1, description = $2, price = $3, category_id = $4, updated_at = NOW()
 		WHERE id = $5
-		RETURNING updated_at
+		RETURNING ` + stockColumn + `, updated_at
 	`
 	// Note: We fetch updated_at generated by the DB trigger (or NOW() if no trigger)
 	err := r.db.QueryRow(ctx, query,
@@ -158,7 +307,7 @@ func (r *postgresProductRepository) Update(ctx context.Context, id uuid.UUID, pr
 		product.Price,
 		product.CategoryID,
 		id,
-	).Scan(&product.UpdatedAt)
+	).Scan(&product.Stock, &product.UpdatedAt)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -59,22 +59,22 @@ func (_m *MockProductRepository) FindByID(ctx context.Context, id uuid.UUID) (*m
 	return r0, r1
 }
 
-// FindAll provides a mock function with given fields: ctx
-func (_m *MockProductRepository) FindAll(ctx context.Context) ([]models.Product, error) {
-	ret := _m.Called(ctx)
+// FindAll provides a mock function with given fields: ctx, opts
+func (_m *MockProductRepository) FindAll(ctx context.Context, opts ListOptions) (*Page, error) {
+	ret := _m.Called(ctx, opts)
 
-	var r0 []models.Product
-	if rf, ok := ret.Get(0).(func(context.Context) []models.Product); ok {
-		r0 = rf(ctx)
+	var r0 *Page
+	if rf, ok := ret.Get(0).(func(context.Context, ListOptions) *Page); ok {
+		r0 = rf(ctx, opts)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.Product)
+			r0 = ret.Get(0).(*Page)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, ListOptions) error); ok {
+		r1 = rf(ctx, opts)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -82,22 +82,68 @@ func (_m *MockProductRepository) FindAll(ctx context.Context) ([]models.Product,
 	return r0, r1
 }
 
-// Search provides a mock function with given fields: ctx, query
-func (_m *MockProductRepository) Search(ctx context.Context, query string) ([]models.Product, error) {
-	ret := _m.Called(ctx, query)
+// Search provides a mock function with given fields: ctx, query, opts
+func (_m *MockProductRepository) Search(ctx context.Context, query string, opts ListOptions) (*Page, error) {
+	ret := _m.Called(ctx, query, opts)
 
-	var r0 []models.Product
-	if rf, ok := ret.Get(0).(func(context.Context, string) []models.Product); ok {
-		r0 = rf(ctx, query)
+	var r0 *Page
+	if rf, ok := ret.Get(0).(func(context.Context, string, ListOptions) *Page); ok {
+		r0 = rf(ctx, query, opts)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.Product)
+			r0 = ret.Get(0).(*Page)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, query)
+	if rf, ok := ret.Get(1).(func(context.Context, string, ListOptions) error); ok {
+		r1 = rf(ctx, query, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchSimilar provides a mock function with given fields: ctx, query, opts
+func (_m *MockProductRepository) SearchSimilar(ctx context.Context, query string, opts ListOptions) (*Page, error) {
+	ret := _m.Called(ctx, query, opts)
+
+	var r0 *Page
+	if rf, ok := ret.Get(0).(func(context.Context, string, ListOptions) *Page); ok {
+		r0 = rf(ctx, query, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Page)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, ListOptions) error); ok {
+		r1 = rf(ctx, query, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Suggest provides a mock function with given fields: ctx, prefix, limit
+func (_m *MockProductRepository) Suggest(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	ret := _m.Called(ctx, prefix, limit)
+
+	var r0 []Suggestion
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []Suggestion); ok {
+		r0 = rf(ctx, prefix, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Suggestion)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, prefix, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
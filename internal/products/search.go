@@ -0,0 +1,61 @@
+package products
+
+import (
+	"fmt"
+	"strings"
+)
+
+// searchConfig is the text search configuration of the products' search_vector column:
+// Portuguese stemming on accent-free words.
+const searchConfig = "portuguese_unaccent"
+
+// rankWeights are the ts_rank weights of the {D, C, B, A} labels. Names are labelled A and
+// descriptions B, so a match in the name counts five times as much.
+const rankWeights = "{0.1, 0.1, 0.2, 1.0}"
+
+// headlineOptions configure the highlighted snippets returned with search results.
+const (
+	nameHeadlineOptions        = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	descriptionHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
+// searchMode renders the SQL expressions that rank and highlight the results of a search.
+type searchMode interface {
+	// rank returns the relevance of a product to the query, higher is better.
+	rank() string
+	// highlights returns the highlighted name and description of a product, or nil.
+	highlights() []string
+}
+
+// fullTextSearch matches the products' full-text search vector.
+type fullTextSearch struct {
+	tsQuery string // SQL expression of the parsed query, e.g. websearch_to_tsquery(...)
+}
+
+func (s *fullTextSearch) rank() string {
+	return fmt.Sprintf("ts_rank('%s', search_vector, %s)", rankWeights, s.tsQuery)
+}
+
+func (s *fullTextSearch) highlights() []string {
+	return []string{
+		fmt.Sprintf("ts_headline('%s', name, %s, '%s')", searchConfig, s.tsQuery, nameHeadlineOptions),
+		fmt.Sprintf("ts_headline('%s', coalesce(description, ''), %s, '%s')", searchConfig, s.tsQuery, descriptionHeadlineOptions),
+	}
+}
+
+// trigramSearch matches product names sharing enough trigrams with the query, which
+// tolerates typos ("notbook" finds "Notebook") but cannot highlight the matches.
+type trigramSearch struct {
+	term string // Placeholder of the query text
+}
+
+func (s *trigramSearch) rank() string {
+	return fmt.Sprintf("word_similarity(%s, name)", s.term)
+}
+
+func (s *trigramSearch) highlights() []string {
+	return nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, so it is matched literally.
+var escapeLike = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace
@@ -0,0 +1,125 @@
+package sessions
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxCachedSessions bounds the cache; stale entries are swept once it is exceeded.
+const maxCachedSessions = 10000
+
+// cachedRepository decorates a SessionRepository so that the per-request session checks made
+// by the auth middleware are served from memory. Sessions are re-read after ttl, and last-seen
+// updates are written at most once per ttl. Revocations made through this repository take
+// effect immediately; revocations made by other instances are picked up within ttl.
+type cachedRepository struct {
+	SessionRepository // Create and the List methods go straight to the wrapped repository
+
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[uuid.UUID]*cacheEntry
+}
+
+type cacheEntry struct {
+	session   models.Session
+	loadedAt  time.Time
+	touchedAt time.Time
+}
+
+// NewCachedRepository wraps repo with an in-memory cache of session lookups.
+func NewCachedRepository(repo SessionRepository, ttl time.Duration) SessionRepository {
+	return &cachedRepository{
+		SessionRepository: repo,
+		ttl:               ttl,
+		entries:           map[uuid.UUID]*cacheEntry{},
+	}
+}
+
+// FindByID returns the cached session while it is fresh, otherwise reloads it.
+func (c *cachedRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if entry, ok := c.entries[id]; ok && now.Sub(entry.loadedAt) < c.ttl {
+		session := entry.session
+		c.mu.Unlock()
+		return &session, nil
+	}
+	c.mu.Unlock()
+
+	session, err := c.SessionRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedSessions {
+		c.sweep(now)
+	}
+	entry, ok := c.entries[id]
+	if !ok {
+		entry = &cacheEntry{}
+		c.entries[id] = entry
+	}
+	entry.session = *session
+	entry.loadedAt = now
+	return session, nil
+}
+
+// Touch writes the last-seen time unless it was already written within ttl.
+func (c *cachedRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[id]
+	if ok && now.Sub(entry.touchedAt) < c.ttl {
+		c.mu.Unlock()
+		return nil
+	}
+	if ok {
+		entry.touchedAt = now
+	}
+	c.mu.Unlock()
+
+	return c.SessionRepository.Touch(ctx, id)
+}
+
+// Revoke revokes the session and drops it from the cache.
+func (c *cachedRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	err := c.SessionRepository.Revoke(ctx, id)
+
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+
+	return err
+}
+
+// RevokeAllExcept revokes the user's other sessions and drops them from the cache.
+func (c *cachedRepository) RevokeAllExcept(ctx context.Context, userID, keepID uuid.UUID) (int64, error) {
+	n, err := c.SessionRepository.RevokeAllExcept(ctx, userID, keepID)
+
+	c.mu.Lock()
+	for id, entry := range c.entries {
+		if entry.session.UserID == userID && id != keepID {
+			delete(c.entries, id)
+		}
+	}
+	c.mu.Unlock()
+
+	return n, err
+}
+
+// sweep removes stale entries. It must be called with c.mu held.
+func (c *cachedRepository) sweep(now time.Time) {
+	for id, entry := range c.entries {
+		if now.Sub(entry.loadedAt) >= c.ttl {
+			delete(c.entries, id)
+		}
+	}
+}
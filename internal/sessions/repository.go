@@ -0,0 +1,203 @@
+package sessions
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// SessionRepository defines the interface for login session data operations.
+type SessionRepository interface {
+	// Create stores a new session. A zero ID is replaced with a newly generated one.
+	Create(ctx context.Context, session *models.Session) (*models.Session, error)
+	// FindByID returns a session (active or revoked), or ErrSessionNotFound.
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	// ListActiveByUser returns the user's sessions that have not been revoked, most recently used first.
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	// ListByUser returns all of the user's sessions, including revoked ones, newest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	// Touch updates the last-seen time of an active session.
+	Touch(ctx context.Context, id uuid.UUID) error
+	// Revoke revokes an active session and its refresh tokens, or returns ErrSessionNotFound.
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// RevokeAllExcept revokes every active session of the user and their refresh tokens, except keepID.
+	// Pass uuid.Nil to revoke all of them. It returns the number of sessions revoked.
+	RevokeAllExcept(ctx context.Context, userID, keepID uuid.UUID) (int64, error)
+}
+
+// postgresSessionRepository implements SessionRepository using PostgreSQL.
+type postgresSessionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresSessionRepository creates a new instance of postgresSessionRepository.
+func NewPostgresSessionRepository(db *pgxpool.Pool) SessionRepository {
+	return &postgresSessionRepository{db: db}
+}
+
+// sessionColumns lists the columns read by scanSession, in order.
+const sessionColumns = "id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at"
+
+// scanSession scans a row selected with sessionColumns.
+func scanSession(row pgx.Row) (*models.Session, error) {
+	session := &models.Session{}
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// Create inserts a new session into the database.
+func (r *postgresSessionRepository) Create(ctx context.Context, session *models.Session) (*models.Session, error) {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + sessionColumns
+	return scanSession(r.db.QueryRow(ctx, query, session.ID, session.UserID, session.UserAgent, session.IPAddress))
+}
+
+// FindByID retrieves a session by its ID.
+func (r *postgresSessionRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+	return scanSession(r.db.QueryRow(ctx, query, id))
+}
+
+// ListActiveByUser retrieves the active sessions of a user.
+func (r *postgresSessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *session)
+	}
+	return list, rows.Err()
+}
+
+// ListByUser retrieves the login history of a user.
+func (r *postgresSessionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *session)
+	}
+	return list, rows.Err()
+}
+
+// Touch records activity on a session.
+func (r *postgresSessionRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE sessions SET last_seen_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	return err
+}
+
+// Revoke revokes a session and its refresh token family within a transaction.
+func (r *postgresSessionRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
+
+	// 1. Revoke the session itself
+	tag, err := tx.Exec(ctx, `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	// 2. Its refresh tokens can no longer be used to start it again
+	_, err = tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RevokeAllExcept revokes the user's other sessions and refresh tokens within a transaction.
+func (r *postgresSessionRepository) RevokeAllExcept(ctx context.Context, userID, keepID uuid.UUID) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
+
+	// 1. Revoke the sessions
+	tag, err := tx.Exec(ctx, `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Revoke every other refresh token family, including logins older than the sessions table
+	_, err = tx.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+	`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,155 @@
+package sessions
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSessionRepository is a mock type for the SessionRepository interface
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, session
+func (_m *MockSessionRepository) Create(ctx context.Context, session *models.Session) (*models.Session, error) {
+	ret := _m.Called(ctx, session)
+
+	var r0 *models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) *models.Session); ok {
+		r0 = rf(ctx, session)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Session) error); ok {
+		r1 = rf(ctx, session)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByID provides a mock function with given fields: ctx, id
+func (_m *MockSessionRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.Session); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListActiveByUser provides a mock function with given fields: ctx, userID
+func (_m *MockSessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Session); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListByUser provides a mock function with given fields: ctx, userID
+func (_m *MockSessionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Session); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Touch provides a mock function with given fields: ctx, id
+func (_m *MockSessionRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Revoke provides a mock function with given fields: ctx, id
+func (_m *MockSessionRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeAllExcept provides a mock function with given fields: ctx, userID, keepID
+func (_m *MockSessionRepository) RevokeAllExcept(ctx context.Context, userID uuid.UUID, keepID uuid.UUID) (int64, error) {
+	ret := _m.Called(ctx, userID, keepID)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) int64); ok {
+		r0 = rf(ctx, userID, keepID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID, keepID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
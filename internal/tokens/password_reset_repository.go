@@ -0,0 +1,141 @@
+package tokens
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrResetTokenNotFound = errors.New("password reset token not found")
+	ErrResetTokenExpired  = errors.New("password reset token expired")
+	ErrResetTokenUsed     = errors.New("password reset token already used")
+)
+
+// PasswordResetRepository defines the interface for password reset token data operations.
+type PasswordResetRepository interface {
+	// Create stores a new reset token.
+	Create(ctx context.Context, token *models.PasswordResetToken) (*models.PasswordResetToken, error)
+	// FindValid returns the token identified by tokenHash without consuming it, or the same
+	// errors Consume would return.
+	FindValid(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	// Consume marks the token identified by tokenHash as used and returns it. Every other
+	// outstanding token of the same user is invalidated as well.
+	Consume(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+}
+
+// postgresPasswordResetRepository implements PasswordResetRepository using PostgreSQL.
+type postgresPasswordResetRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresPasswordResetRepository creates a new instance of postgresPasswordResetRepository.
+func NewPostgresPasswordResetRepository(db *pgxpool.Pool) PasswordResetRepository {
+	return &postgresPasswordResetRepository{db: db}
+}
+
+// Create inserts a new reset token into the database.
+func (r *postgresPasswordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) (*models.PasswordResetToken, error) {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// FindValid looks up a reset token and checks that it can still be used.
+func (r *postgresPasswordResetRepository) FindValid(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+	`
+	token := &models.PasswordResetToken{}
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrResetTokenNotFound
+		}
+		return nil, err
+	}
+
+	if token.UsedAt != nil {
+		return nil, ErrResetTokenUsed
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrResetTokenExpired
+	}
+	return token, nil
+}
+
+// Consume validates and burns a reset token within a transaction.
+func (r *postgresPasswordResetRepository) Consume(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
+
+	// 1. Lock the presented token so it cannot be used twice concurrently
+	findQuery := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+	token := &models.PasswordResetToken{}
+	err = tx.QueryRow(ctx, findQuery, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrResetTokenNotFound
+		}
+		return nil, err
+	}
+
+	if token.UsedAt != nil {
+		return nil, ErrResetTokenUsed
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrResetTokenExpired
+	}
+
+	// 2. Burn this token and any other outstanding token of the user
+	burnQuery := `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	if _, err := tx.Exec(ctx, burnQuery, token.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return token, nil
+}
@@ -0,0 +1,82 @@
+package tokens
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasswordResetRepository is a mock type for the PasswordResetRepository interface
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, token
+func (_m *MockPasswordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) (*models.PasswordResetToken, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *models.PasswordResetToken
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PasswordResetToken) *models.PasswordResetToken); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PasswordResetToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PasswordResetToken) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindValid provides a mock function with given fields: ctx, tokenHash
+func (_m *MockPasswordResetRepository) FindValid(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 *models.PasswordResetToken
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.PasswordResetToken); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PasswordResetToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Consume provides a mock function with given fields: ctx, tokenHash
+func (_m *MockPasswordResetRepository) Consume(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 *models.PasswordResetToken
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.PasswordResetToken); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PasswordResetToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,182 @@
+package tokens
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected")
+)
+
+// RefreshTokenRepository defines the interface for refresh token data operations.
+type RefreshTokenRepository interface {
+	// Create stores a new refresh token. A zero FamilyID starts a new token family.
+	Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error)
+	// Rotate consumes the active token identified by oldHash and stores a replacement
+	// (newHash) in the same family. Presenting a token that was already rotated or
+	// revoked revokes the entire family and returns ErrRefreshTokenReused.
+	Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.RefreshToken, error)
+	// RevokeFamily revokes every active token in the family of the token identified by tokenHash
+	// and returns the family ID.
+	RevokeFamily(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	// RevokeAllForUser revokes every active refresh token of a user (e.g. after a password change).
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// postgresRefreshTokenRepository implements RefreshTokenRepository using PostgreSQL.
+type postgresRefreshTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRefreshTokenRepository creates a new instance of postgresRefreshTokenRepository.
+func NewPostgresRefreshTokenRepository(db *pgxpool.Pool) RefreshTokenRepository {
+	return &postgresRefreshTokenRepository{db: db}
+}
+
+// Create inserts a new refresh token into the database.
+func (r *postgresRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error) {
+	if token.FamilyID == uuid.Nil {
+		token.FamilyID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(ctx, query,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Rotate replaces a refresh token within a transaction, detecting reuse of old tokens.
+func (r *postgresRefreshTokenRepository) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.RefreshToken, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
+
+	// 1. Lock the presented token so concurrent refreshes cannot both succeed
+	findQuery := `
+		SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+	current := &models.RefreshToken{}
+	err = tx.QueryRow(ctx, findQuery, oldHash).Scan(
+		&current.ID,
+		&current.UserID,
+		&current.FamilyID,
+		&current.TokenHash,
+		&current.ExpiresAt,
+		&current.RevokedAt,
+		&current.ReplacedBy,
+		&current.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	// 2. Reuse of a consumed token means it leaked: kill the whole family
+	if current.RevokedAt != nil {
+		if _, err := tx.Exec(ctx, revokeFamilyQuery, current.FamilyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	// 3. Issue the replacement in the same family
+	insertQuery := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	next := &models.RefreshToken{
+		UserID:    current.UserID,
+		FamilyID:  current.FamilyID,
+		TokenHash: newHash,
+		ExpiresAt: expiresAt,
+	}
+	err = tx.QueryRow(ctx, insertQuery, next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt).Scan(&next.ID, &next.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Mark the presented token as consumed
+	consumeQuery := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW(), replaced_by = $1
+		WHERE id = $2
+	`
+	if _, err := tx.Exec(ctx, consumeQuery, next.ID, current.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// revokeFamilyQuery revokes all still-active tokens of a family.
+const revokeFamilyQuery = `
+	UPDATE refresh_tokens
+	SET revoked_at = NOW()
+	WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+// RevokeFamily revokes the family the given token belongs to.
+func (r *postgresRefreshTokenRepository) RevokeFamily(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var familyID uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = $1`, tokenHash).Scan(&familyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrRefreshTokenNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	if _, err := r.db.Exec(ctx, revokeFamilyQuery, familyID); err != nil {
+		return uuid.Nil, err
+	}
+	return familyID, nil
+}
+
+// RevokeAllForUser revokes all active tokens of a user, logging them out everywhere.
+func (r *postgresRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
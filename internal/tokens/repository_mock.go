@@ -0,0 +1,96 @@
+package tokens
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRefreshTokenRepository is a mock type for the RefreshTokenRepository interface
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, token
+func (_m *MockRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *models.RefreshToken
+	if rf, ok := ret.Get(0).(func(context.Context, *models.RefreshToken) *models.RefreshToken); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.RefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.RefreshToken) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Rotate provides a mock function with given fields: ctx, oldHash, newHash, expiresAt
+func (_m *MockRefreshTokenRepository) Rotate(ctx context.Context, oldHash string, newHash string, expiresAt time.Time) (*models.RefreshToken, error) {
+	ret := _m.Called(ctx, oldHash, newHash, expiresAt)
+
+	var r0 *models.RefreshToken
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *models.RefreshToken); ok {
+		r0 = rf(ctx, oldHash, newHash, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.RefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, oldHash, newHash, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeFamily provides a mock function with given fields: ctx, tokenHash
+func (_m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 uuid.UUID
+	if rf, ok := ret.Get(0).(func(context.Context, string) uuid.UUID); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		r0 = ret.Get(0).(uuid.UUID)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeAllForUser provides a mock function with given fields: ctx, userID
+func (_m *MockRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -4,6 +4,9 @@ import (
 	"bullet-cloud-api/internal/models"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -21,6 +24,60 @@ type UserRepository interface {
 	Create(ctx context.Context, name, email, passwordHash string) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
+	// Update changes a user's name and email. Changing the email clears its verification.
+	Update(ctx context.Context, id uuid.UUID, name, email string) (*models.User, error)
+	// Anonymize deletes a user's account: personal data is erased and credentials are removed,
+	// while the user row and their orders are kept for the sales history.
+	Anonymize(ctx context.Context, id uuid.UUID) error
+	// List returns a page of users matching the filter, newest first, and the total number of matches.
+	List(ctx context.Context, filter ListFilter) ([]models.User, int, error)
+	// UpdateRole changes a user's role.
+	UpdateRole(ctx context.Context, id uuid.UUID, role models.UserRole) error
+	// SetDisabled disables or re-enables a user's account.
+	SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error
+	// RequirePasswordReset refuses password logins until the user resets their password.
+	RequirePasswordReset(ctx context.Context, id uuid.UUID) error
+}
+
+// ListFilter selects the users returned by List. Zero values match everything.
+type ListFilter struct {
+	Email       string          // Case-insensitive substring of the email
+	Name        string          // Case-insensitive substring of the name
+	Role        models.UserRole // Exact role
+	CreatedFrom *time.Time      // Created at or after
+	CreatedTo   *time.Time      // Created before
+	Limit       int
+	Offset      int
+}
+
+// userColumns lists the columns read into models.User, in scanUser order.
+const userColumns = `id, name, email, password_hash, role, email_verified_at, email_changed_at, created_at, updated_at, disabled_at, password_reset_required`
+
+// scanUser reads a row selected with userColumns.
+func scanUser(row pgx.Row) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.EmailChangedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DisabledAt,
+		&user.PasswordResetRequired,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
 }
 
 // postgresUserRepository implements UserRepository using PostgreSQL.
@@ -38,17 +95,8 @@ func (r *postgresUserRepository) Create(ctx context.Context, name, email, passwo
 	query := `
 		INSERT INTO users (name, email, password_hash)
 		VALUES ($1, $2, $3)
-		RETURNING id, name, email, created_at, updated_at
-	`
-	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, name, email, passwordHash).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
+		RETURNING ` + userColumns
+	user, err := scanUser(r.db.QueryRow(ctx, query, name, email, passwordHash))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -65,56 +113,241 @@ func (r *postgresUserRepository) Create(ctx context.Context, name, email, passwo
 	return user, nil
 }
 
-// FindByEmail retrieves a user by their email address.
+// FindByEmail retrieves a user by their email address. Deleted accounts are never found.
 func (r *postgresUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1 AND deleted_at IS NULL`
+	return scanUser(r.db.QueryRow(ctx, query, email))
+}
+
+// FindByID retrieves a user by their ID. Deleted accounts are never found.
+func (r *postgresUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NULL`
+	return scanUser(r.db.QueryRow(ctx, query, id))
+}
+
+// UpdatePassword replaces a user's password hash, satisfying any reset required by an admin.
+func (r *postgresUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
 	query := `
-		SELECT id, name, email, password_hash, created_at, updated_at
-		FROM users
-		WHERE email = $1
+		UPDATE users
+		SET password_hash = $1, password_reset_required = false
+		WHERE id = $2 AND deleted_at IS NULL
 	`
-	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	result, err := r.db.Exec(ctx, query, passwordHash, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
 
+// MarkEmailVerified records that the user confirmed their email. Already verified users keep their original timestamp.
+func (r *postgresUserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, id)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrUserNotFound
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Update changes a user's name and email. A new email address starts out unverified.
+func (r *postgresUserRepository) Update(ctx context.Context, id uuid.UUID, name, email string) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET name = $1,
+			email = $2,
+			email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END,
+			email_changed_at = CASE WHEN email = $2 THEN email_changed_at ELSE NOW() END
+		WHERE id = $3 AND deleted_at IS NULL
+		RETURNING ` + userColumns
+	user, err := scanUser(r.db.QueryRow(ctx, query, name, email, id))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrEmailAlreadyExists
 		}
 		return nil, err
 	}
 
+	user.PasswordHash = ""
 	return user, nil
 }
 
-// FindByID retrieves a user by their ID.
-func (r *postgresUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	query := `
-		SELECT id, name, email, password_hash, created_at, updated_at
-		FROM users
-		WHERE id = $1
-	`
-	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+// Anonymize deletes a user's account in a single transaction. The user row stays (orders
+// reference it) but its name and email are replaced and it can no longer log in. Addresses
+// used by orders are reduced to city, state and country; all other personal data is deleted.
+func (r *postgresUserRepository) Anonymize(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Ensure rollback on error
 
+	// 1. Lock the user, remembering the email for the lockout counters
+	var email string
+	err = tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&email)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrUserNotFound
+			return ErrUserNotFound
 		}
-		return nil, err
+		return err
 	}
 
-	return user, nil
+	// 2. Erase the personal data. The placeholder email keeps the column unique and can never receive mail.
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET name = 'Deleted user',
+			email = 'deleted-' || id::text || '@deleted.invalid',
+			password_hash = '',
+			email_verified_at = NULL,
+			deleted_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	// 3. Scrub the addresses orders were shipped to, and delete the rest
+	_, err = tx.Exec(ctx, `
+		UPDATE addresses
+		SET street = '', postal_code = '', is_default = false
+		WHERE user_id = $1 AND id IN (SELECT shipping_address_id FROM orders WHERE user_id = $1)
+	`, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM addresses
+		WHERE user_id = $1 AND id NOT IN (SELECT shipping_address_id FROM orders WHERE user_id = $1)
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	// 4. Delete credentials, sessions, linked identities and the cart
+	for _, table := range []string{"api_keys", "user_identities", "mfa_recovery_codes", "user_mfa", "password_reset_tokens", "refresh_tokens", "sessions", "carts"} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, id); err != nil {
+			return err
+		}
+	}
+
+	// 5. Forget the failed login counter kept for the email
+	if _, err := tx.Exec(ctx, `DELETE FROM login_attempts WHERE key = $1`, "email:"+email); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// escapeLike escapes the LIKE wildcards in s, so it is matched literally.
+var escapeLike = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace
+
+// List retrieves a page of users matching the filter. Deleted accounts are never listed.
+func (r *postgresUserRepository) List(ctx context.Context, filter ListFilter) ([]models.User, int, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Email != "" {
+		addCondition("email ILIKE $%d", "%"+escapeLike(filter.Email)+"%")
+	}
+	if filter.Name != "" {
+		addCondition("name ILIKE $%d", "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.Role != "" {
+		addCondition("role = $%d", filter.Role)
+	}
+	if filter.CreatedFrom != nil {
+		addCondition("created_at >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		addCondition("created_at < $%d", *filter.CreatedTo)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM users
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, userColumns, where, len(args)+1, len(args)+2)
+	rows, err := r.db.Query(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		user.PasswordHash = ""
+		list = append(list, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// UpdateRole changes a user's role.
+func (r *postgresUserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role models.UserRole) error {
+	query := `UPDATE users SET role = $1 WHERE id = $2 AND deleted_at IS NULL`
+	result, err := r.db.Exec(ctx, query, role, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetDisabled disables or re-enables a user. Disabling an already disabled user keeps the original timestamp.
+func (r *postgresUserRepository) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	query := `
+		UPDATE users
+		SET disabled_at = CASE WHEN $1 THEN COALESCE(disabled_at, NOW()) ELSE NULL END
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, disabled, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RequirePasswordReset flags the user as having to choose a new password.
+func (r *postgresUserRepository) RequirePasswordReset(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE users SET password_reset_required = true WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
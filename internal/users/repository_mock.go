@@ -79,3 +79,140 @@ func (_m *MockUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*mode
 
 	return r0, r1
 }
+
+// UpdatePassword provides a mock function with given fields: ctx, id, passwordHash
+func (_m *MockUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	ret := _m.Called(ctx, id, passwordHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) error); ok {
+		r0 = rf(ctx, id, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkEmailVerified provides a mock function with given fields: ctx, id
+func (_m *MockUserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: ctx, id, name, email
+func (_m *MockUserRepository) Update(ctx context.Context, id uuid.UUID, name string, email string) (*models.User, error) {
+	ret := _m.Called(ctx, id, name, email)
+
+	var r0 *models.User
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, string) *models.User); ok {
+		r0 = rf(ctx, id, name, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string, string) error); ok {
+		r1 = rf(ctx, id, name, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Anonymize provides a mock function with given fields: ctx, id
+func (_m *MockUserRepository) Anonymize(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: ctx, filter
+func (_m *MockUserRepository) List(ctx context.Context, filter ListFilter) ([]models.User, int, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []models.User
+	if rf, ok := ret.Get(0).(func(context.Context, ListFilter) []models.User); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(context.Context, ListFilter) int); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Int(1)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, ListFilter) error); ok {
+		r2 = rf(ctx, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdateRole provides a mock function with given fields: ctx, id, role
+func (_m *MockUserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role models.UserRole) error {
+	ret := _m.Called(ctx, id, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, models.UserRole) error); ok {
+		r0 = rf(ctx, id, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetDisabled provides a mock function with given fields: ctx, id, disabled
+func (_m *MockUserRepository) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	ret := _m.Called(ctx, id, disabled)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, bool) error); ok {
+		r0 = rf(ctx, id, disabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RequirePasswordReset provides a mock function with given fields: ctx, id
+func (_m *MockUserRepository) RequirePasswordReset(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
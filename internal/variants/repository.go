@@ -0,0 +1,138 @@
+package variants
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrVariantNotFound       = errors.New("variant not found")
+	ErrSKUExists             = errors.New("sku already exists")
+	ErrBarcodeExists         = errors.New("barcode already exists")
+	ErrOptionCombinationUsed = errors.New("another variant of the product has the same options")
+	ErrVariantOrdered        = errors.New("variant is part of an order and cannot be deleted")
+)
+
+// VariantRepository defines the interface for product variant data operations.
+type VariantRepository interface {
+	Create(ctx context.Context, variant *models.ProductVariant) (*models.ProductVariant, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*models.ProductVariant, error)
+	// FindByProductID returns the variants of a product, oldest first.
+	FindByProductID(ctx context.Context, productID uuid.UUID) ([]models.ProductVariant, error)
+	// Update changes the variant with the ID and product ID of variant.
+	Update(ctx context.Context, variant *models.ProductVariant) (*models.ProductVariant, error)
+	Delete(ctx context.Context, productID, id uuid.UUID) error
+}
+
+// postgresVariantRepository implements VariantRepository using PostgreSQL.
+type postgresVariantRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresVariantRepository creates a new instance of postgresVariantRepository.
+func NewPostgresVariantRepository(db *pgxpool.Pool) VariantRepository {
+	return &postgresVariantRepository{db: db}
+}
+
+// handlePgError translates the constraint violations of the product_variants table.
+func handlePgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.ConstraintName {
+		case "unique_product_variants_sku":
+			return ErrSKUExists
+		case "unique_product_variants_barcode":
+			return ErrBarcodeExists
+		case "unique_product_variants_options":
+			return ErrOptionCombinationUsed
+		case "fk_order_items_variant":
+			return ErrVariantOrdered
+		}
+	}
+	return err
+}
+
+// stockColumn totals the units of a variant at every stock location; NULL when no location
+// tracks its stock.
+const stockColumn = `(SELECT SUM(s.quantity) FROM stock_levels s WHERE s.variant_id = product_variants.id) AS stock`
+
+const variantColumns = `id, product_id, sku, options, price, barcode, ` + stockColumn + `, created_at, updated_at`
+
+// Create inserts a new variant of variant.ProductID.
+func (r *postgresVariantRepository) Create(ctx context.Context, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	query := `
+		INSERT INTO product_variants (product_id, sku, options, price, barcode)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, variant.ProductID, variant.SKU, variant.Options, variant.Price, variant.Barcode).
+		Scan(&variant.ID, &variant.CreatedAt, &variant.UpdatedAt)
+	if err != nil {
+		return nil, handlePgError(err)
+	}
+	return variant, nil
+}
+
+// FindByID retrieves a variant by its ID.
+func (r *postgresVariantRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE id = $1`
+	variant := &models.ProductVariant{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&variant.ID, &variant.ProductID, &variant.SKU, &variant.Options, &variant.Price, &variant.Barcode, &variant.Stock, &variant.CreatedAt, &variant.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return variant, nil
+}
+
+// FindByProductID retrieves the variants of a product.
+func (r *postgresVariantRepository) FindByProductID(ctx context.Context, productID uuid.UUID) ([]models.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE product_id = $1 ORDER BY created_at ASC, sku ASC`
+	rows, err := r.db.Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.ProductVariant])
+}
+
+// Update modifies an existing variant; the variant must belong to variant.ProductID.
+func (r *postgresVariantRepository) Update(ctx context.Context, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	query := `
+		UPDATE product_variants
+		SET sku = $1, options = $2, price = $3, barcode = $4, updated_at = NOW()
+		WHERE id = $5 AND product_id = $6
+		RETURNING ` + stockColumn + `, created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, variant.SKU, variant.Options, variant.Price, variant.Barcode, variant.ID, variant.ProductID).
+		Scan(&variant.Stock, &variant.CreatedAt, &variant.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, handlePgError(err)
+	}
+	return variant, nil
+}
+
+// Delete removes a variant of a product. It is also removed from carts, but variants
+// already ordered cannot be deleted.
+func (r *postgresVariantRepository) Delete(ctx context.Context, productID, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM product_variants WHERE id = $1 AND product_id = $2`, id, productID)
+	if err != nil {
+		return handlePgError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package variants
+
+import (
+	"bullet-cloud-api/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockVariantRepository is a mock type for the VariantRepository interface
+type MockVariantRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, variant
+func (_m *MockVariantRepository) Create(ctx context.Context, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	var r0 *models.ProductVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ProductVariant) *models.ProductVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProductVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ProductVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByID provides a mock function with given fields: ctx, id
+func (_m *MockVariantRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.ProductVariant, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *models.ProductVariant
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.ProductVariant); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProductVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByProductID provides a mock function with given fields: ctx, productID
+func (_m *MockVariantRepository) FindByProductID(ctx context.Context, productID uuid.UUID) ([]models.ProductVariant, error) {
+	ret := _m.Called(ctx, productID)
+
+	var r0 []models.ProductVariant
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.ProductVariant); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ProductVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, variant
+func (_m *MockVariantRepository) Update(ctx context.Context, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	var r0 *models.ProductVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ProductVariant) *models.ProductVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProductVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ProductVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, productID, id
+func (_m *MockVariantRepository) Delete(ctx context.Context, productID uuid.UUID, id uuid.UUID) error {
+	ret := _m.Called(ctx, productID, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(ctx, productID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
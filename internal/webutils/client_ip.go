@@ -0,0 +1,46 @@
+package webutils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP is a middleware that sets r.RemoteAddr to the address of the client when the
+// server runs behind trustedProxies reverse proxies (e.g. 1 behind Render's or a load
+// balancer), taken from X-Forwarded-For. Each proxy appends the address it received the
+// request from, so the client is trustedProxies entries from the end; entries before it
+// are whatever the client sent and are ignored. With 0 the header is not trusted at all.
+func RealIP(trustedProxies int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedFor(r, trustedProxies); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedFor returns the client address added by the outermost trusted proxy, or "" when
+// there is none to trust.
+func forwardedFor(r *http.Request, trustedProxies int) string {
+	if trustedProxies <= 0 {
+		return ""
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) == 0 {
+		return ""
+	}
+	// Fewer hops than proxies: the request came in past some of them, so the first is the client
+	ip := hops[max(len(hops)-trustedProxies, 0)]
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
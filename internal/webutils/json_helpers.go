@@ -31,3 +31,21 @@ type jsonError struct {
 func ErrorJSON(w http.ResponseWriter, err error, status int) {
 	WriteJSON(w, status, jsonError{Error: err.Error()})
 }
+
+// FieldError describes why one field of a request was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`    // Stable, machine-readable reason, e.g. "too_short"
+	Message string `json:"message"` // Human-readable explanation
+}
+
+// validationError is the body of a response rejecting one or more request fields.
+type validationError struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+// ValidationErrorJSON sends a 400 response listing the rejected fields.
+func ValidationErrorJSON(w http.ResponseWriter, fields []FieldError) {
+	WriteJSON(w, http.StatusBadRequest, validationError{Error: "validation failed", Fields: fields})
+}